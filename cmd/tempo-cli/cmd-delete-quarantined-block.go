@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+type deleteQuarantinedBlockCmd struct {
+	backendOptions
+
+	TenantID string `arg:"" help:"tenant-id within the bucket"`
+	BlockID  string `arg:"" help:"block ID to delete"`
+}
+
+func (cmd *deleteQuarantinedBlockCmd) Run(ctx *globalOptions) error {
+	r, _, c, err := loadBackend(&cmd.backendOptions, ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(cmd.BlockID)
+	if err != nil {
+		return err
+	}
+
+	// only blocks quarantined by the scrubber are deleted, other blocks are cleared by retention
+	meta, err := c.CompactedBlockMeta(id, cmd.TenantID)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		_, err = r.BlockMeta(context.Background(), id, cmd.TenantID)
+		if err == nil {
+			return fmt.Errorf("block %s is not quarantined", id)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if meta.Quarantined == "" {
+		return fmt.Errorf("block %s is not quarantined", id)
+	}
+
+	fmt.Println("deleting block", id, "quarantined because:", meta.Quarantined)
+	return c.ClearBlock(id, cmd.TenantID)
+}
@@ -78,8 +78,10 @@ func displayResults(results []blockStats, windowDuration time.Duration, includeC
 			case "age":
 				s = fmt.Sprint(time.Since(r.EndTime).Round(time.Second))
 			case "cmp":
-				// Compacted?
-				if r.compacted {
+				// Compacted? Quarantined blocks are compacted
+				if r.BlockMeta.Quarantined != "" {
+					s = "Q"
+				} else if r.compacted {
 					s = "Y"
 				} else {
 					s = " "
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+type walVerifyCmd struct {
+	Path string `arg:"" type:"existingdir" help:"path to the wal directory of an ingester"`
+}
+
+type walRepairCmd struct {
+	Path string `arg:"" type:"existingdir" help:"path to the wal directory of an ingester"`
+}
+
+func (cmd *walVerifyCmd) Run(_ *globalOptions) error {
+	files, err := walFiles(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	damaged := 0
+	for _, filename := range files {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+
+		_, _, _, enc, _, err := wal.ParseFilename(filepath.Base(filename))
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+
+		records, warning, err := wal.ReplayWALAndGetRecords(f, enc, func([]byte) error { return nil })
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to replay %s: %w", filename, err)
+		}
+
+		corrupt, truncated := replayWarningStats(warning)
+		if warning != nil {
+			damaged++
+		}
+		fmt.Printf("%s records: %d corrupt records: %d truncated bytes: %d\n", filename, len(records), corrupt, truncated)
+	}
+
+	if damaged > 0 {
+		return fmt.Errorf("%d of %d wal files are damaged. run tempo-cli wal repair to remove corrupt records", damaged, len(files))
+	}
+	return nil
+}
+
+func (cmd *walRepairCmd) Run(_ *globalOptions) error {
+	files, err := walFiles(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range files {
+		warning, err := wal.RepairWALFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to repair %s: %w", filename, err)
+		}
+		if warning == nil {
+			continue
+		}
+
+		corrupt, truncated := replayWarningStats(warning)
+		fmt.Printf("%s repaired. removed corrupt records: %d truncated bytes: %d\n", filename, corrupt, truncated)
+	}
+
+	return nil
+}
+
+// walFiles returns the wal files in the directory and its search subdirectory
+func walFiles(path string) ([]string, error) {
+	var files []string
+	for _, dir := range []string{path, filepath.Join(path, "search")} {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			if _, _, _, _, _, err := wal.ParseFilename(e.Name()); err != nil {
+				continue
+			}
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+func replayWarningStats(warning error) (int, int64) {
+	var replayWarning *wal.ReplayWarning
+	if errors.As(warning, &replayWarning) {
+		return replayWarning.CorruptRecords, replayWarning.TruncatedBytes
+	}
+	return 0, 0
+}
@@ -7,11 +7,13 @@ import (
 
 	"github.com/grafana/tempo/cmd/tempo/app"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/filesystem"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"gopkg.in/yaml.v2"
 
 	"github.com/alecthomas/kong"
 	"github.com/grafana/tempo/tempodb/backend/azure"
+	"github.com/grafana/tempo/tempodb/backend/encryption"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/s3"
 )
@@ -27,7 +29,7 @@ type globalOptions struct {
 }
 
 type backendOptions struct {
-	Backend string `help:"backend to connect to (s3/gcs/local/azure), optional, overrides backend in config file" enum:",s3,gcs,local,azure"`
+	Backend string `help:"backend to connect to (s3/gcs/local/filesystem/azure), optional, overrides backend in config file" enum:",s3,gcs,local,filesystem,azure"`
 	Bucket  string `help:"bucket (or path on local backend) to scan, optional, overrides bucket in config file"`
 
 	S3Endpoint string `name:"s3-endpoint" help:"s3 endpoint (s3.dualstack.us-east-2.amazonaws.com), optional, overrides endpoint in config file"`
@@ -70,6 +72,15 @@ var cli struct {
 	Search struct {
 		Blocks searchBlocksCmd `cmd:"" help:"search for a traceid directly from backend blocks"`
 	} `cmd:""`
+
+	Delete struct {
+		QuarantinedBlock deleteQuarantinedBlockCmd `cmd:"" help:"Delete a block quarantined by the scrubber of the compactor"`
+	} `cmd:""`
+
+	WAL struct {
+		Verify walVerifyCmd `cmd:"" help:"Verify the checksums of the wal files of an ingester"`
+		Repair walRepairCmd `cmd:"" help:"Remove corrupt records from the wal files of an ingester"`
+	} `cmd:""`
 }
 
 func main() {
@@ -108,6 +119,7 @@ func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.W
 
 	if b.Bucket != "" {
 		cfg.StorageConfig.Trace.Local.Path = b.Bucket
+		cfg.StorageConfig.Trace.Filesystem.Path = b.Bucket
 		cfg.StorageConfig.Trace.GCS.BucketName = b.Bucket
 		cfg.StorageConfig.Trace.S3.Bucket = b.Bucket
 		cfg.StorageConfig.Trace.Azure.ContainerName = b.Bucket
@@ -125,6 +137,8 @@ func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.W
 	switch cfg.StorageConfig.Trace.Backend {
 	case "local":
 		r, w, c, err = local.New(cfg.StorageConfig.Trace.Local)
+	case "filesystem":
+		r, w, c, err = filesystem.New(cfg.StorageConfig.Trace.Filesystem)
 	case "gcs":
 		r, w, c, err = gcs.New(cfg.StorageConfig.Trace.GCS)
 	case "s3":
@@ -139,5 +153,17 @@ func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.W
 		return nil, nil, nil, err
 	}
 
+	if cfg.StorageConfig.Trace.Encryption.Enabled() {
+		keys, err := encryption.NewKeyProvider(cfg.StorageConfig.Trace.Encryption)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		r, w, err = encryption.NewEncryption(r, w, keys, cfg.StorageConfig.Trace.Encryption.ChunkSizeBytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	return backend.NewReader(r), backend.NewWriter(w), c, nil
 }
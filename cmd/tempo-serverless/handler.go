@@ -9,13 +9,18 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/go-kit/log"
 	"github.com/google/uuid"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/tempo/pkg/api"
+	pkg_cache "github.com/grafana/tempo/pkg/cache"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/tempodb"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/azure"
+	"github.com/grafana/tempo/tempodb/backend/cache"
+	"github.com/grafana/tempo/tempodb/backend/cache/disk"
+	"github.com/grafana/tempo/tempodb/backend/encryption"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/backend/s3"
@@ -34,7 +39,7 @@ const (
 
 // used to initialize a reader one time
 var (
-	reader       backend.Reader
+	readers      map[string]backend.Reader
 	readerErr    error
 	readerConfig *tempodb.Config
 	readerOnce   sync.Once
@@ -59,11 +64,17 @@ func Handler(r *http.Request) (*tempopb.SearchResponse, *HTTPError) {
 	}
 
 	// load config, fields are set through env vars TEMPO_
-	reader, cfg, err := loadBackend()
+	readers, cfg, err := loadBackend()
 	if err != nil {
 		return nil, httpError("loading backend", err, http.StatusInternalServerError)
 	}
 
+	// blocks are read from the backend of their tier
+	reader, ok := readers[searchReq.Tier]
+	if !ok {
+		return nil, httpError("loading backend", fmt.Errorf("storage tier %s not configured", searchReq.Tier), http.StatusBadRequest)
+	}
+
 	tenant, _, err := user.ExtractOrgIDFromHTTPRequest(r)
 	if err != nil {
 		return nil, httpError("extracting org id", err, http.StatusBadRequest)
@@ -90,6 +101,7 @@ func Handler(r *http.Request) (*tempopb.SearchResponse, *HTTPError) {
 		DataEncoding:  searchReq.DataEncoding,
 		Size:          searchReq.Size_,
 		FooterSize:    searchReq.FooterSize,
+		Tier:          searchReq.Tier,
 	}
 
 	block, err := encoding.OpenBlock(meta, reader)
@@ -113,7 +125,7 @@ func Handler(r *http.Request) (*tempopb.SearchResponse, *HTTPError) {
 	return resp, nil
 }
 
-func loadBackend() (backend.Reader, *tempodb.Config, error) {
+func loadBackend() (map[string]backend.Reader, *tempodb.Config, error) {
 	readerOnce.Do(func() {
 		cfg, err := loadConfig()
 		if err != nil {
@@ -121,34 +133,97 @@ func loadBackend() (backend.Reader, *tempodb.Config, error) {
 			return
 		}
 
-		var r backend.RawReader
-
-		// Create the backend with NewNoConfirm() to prevent an extra call to the various backends on
-		// startup. This extra call exists just to confirm the bucket is accessible and force the
-		// standard Tempo components to fail during startup. If permissions are not correct this Lambda
-		// will fail instantly anyway and in a heavy query environment the extra calls will start to add up.
-		switch cfg.Backend {
-		case "local":
-			err = fmt.Errorf("local backend not supported for serverless functions")
-		case "gcs":
-			r, _, _, err = gcs.NewNoConfirm(cfg.GCS)
-		case "s3":
-			r, _, _, err = s3.NewNoConfirm(cfg.S3)
-		case "azure":
-			r, _, _, err = azure.NewNoConfirm(cfg.Azure)
-		default:
-			err = fmt.Errorf("unknown backend %s", cfg.Backend)
+		// the disk cache is kept by the instance between requests. stores are not done in the background, the
+		// instance may be frozen once the request is done.
+		var caches map[cache.Role]pkg_cache.Cache
+		if cfg.Cache == "disk" {
+			var c pkg_cache.Cache
+			c, err = disk.NewClient("tempo", cfg.Disk, nil, log.NewNopLogger())
+			if err != nil {
+				readerErr = err
+				return
+			}
+
+			caches = map[cache.Role]pkg_cache.Cache{}
+			for _, role := range cache.Roles {
+				caches[role] = c
+			}
 		}
+
+		var keys encryption.KeyProvider
+		if cfg.Encryption.Enabled() {
+			keys, err = encryption.NewKeyProvider(cfg.Encryption)
+			if err != nil {
+				readerErr = err
+				return
+			}
+		}
+
+		readers = map[string]backend.Reader{}
+		readers[""], err = newReader(cfg, "", cfg.Backend, cfg.GCS, cfg.S3, cfg.Azure, caches, keys)
 		if err != nil {
 			readerErr = err
 			return
 		}
 
+		if cfg.ColdTier.Enabled() {
+			readers[backend.TierCold], err = newReader(cfg, backend.TierCold, cfg.ColdTier.Backend, cfg.ColdTier.GCS, cfg.ColdTier.S3, cfg.ColdTier.Azure, caches, keys)
+			if err != nil {
+				readerErr = fmt.Errorf("failed to create cold tier: %w", err)
+				return
+			}
+		}
+
 		readerConfig = cfg
-		reader = backend.NewReader(r)
 	})
 
-	return reader, readerConfig, readerErr
+	return readers, readerConfig, readerErr
+}
+
+// newReader creates the reader of the backend of a tier
+func newReader(cfg *tempodb.Config, tier string, backendName string, gcsCfg *gcs.Config, s3Cfg *s3.Config, azureCfg *azure.Config, caches map[cache.Role]pkg_cache.Cache, keys encryption.KeyProvider) (backend.Reader, error) {
+	var r backend.RawReader
+	var err error
+
+	// Create the backend with NewNoConfirm() to prevent an extra call to the various backends on
+	// startup. This extra call exists just to confirm the bucket is accessible and force the
+	// standard Tempo components to fail during startup. If permissions are not correct this Lambda
+	// will fail instantly anyway and in a heavy query environment the extra calls will start to add up.
+	switch backendName {
+	case "local":
+		err = fmt.Errorf("local backend not supported for serverless functions")
+	case "filesystem":
+		err = fmt.Errorf("filesystem backend not supported for serverless functions")
+	case "gcs":
+		r, _, _, err = gcs.NewNoConfirm(gcsCfg)
+	case "s3":
+		r, _, _, err = s3.NewNoConfirm(s3Cfg)
+	case "azure":
+		r, _, _, err = azure.NewNoConfirm(azureCfg)
+	default:
+		err = fmt.Errorf("unknown backend %s", backendName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the cache generations of deleted tenants are not known to serverless functions
+	if len(caches) > 0 {
+		r, _, err = cache.NewCache(r, nil, caches, cfg.CacheKeyPrefixForTier(tier), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// objects are encrypted above the cache, so the cache only holds encrypted objects
+	if keys != nil {
+		r, _, err = encryption.NewEncryption(r, nil, keys, cfg.Encryption.ChunkSizeBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return backend.NewReader(r), nil
 }
 
 func loadConfig() (*tempodb.Config, error) {
@@ -161,6 +236,15 @@ func loadConfig() (*tempodb.Config, error) {
 		GCS:   &gcs.Config{},
 		S3:    &s3.Config{},
 		Azure: &azure.Config{},
+		Disk:  &disk.Config{},
+		Encryption: &encryption.Config{
+			Keyfile: &encryption.KeyfileConfig{},
+		},
+		ColdTier: &tempodb.ColdTierConfig{
+			GCS:   &gcs.Config{},
+			S3:    &s3.Config{},
+			Azure: &azure.Config{},
+		},
 	}
 
 	// horrible viper dance since it won't unmarshal to a struct from env: https://github.com/spf13/viper/issues/188
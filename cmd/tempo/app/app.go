@@ -41,6 +41,7 @@ import (
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/modules/querier"
 	"github.com/grafana/tempo/modules/storage"
+	"github.com/grafana/tempo/pkg/ingest"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/log"
 	"github.com/grafana/tempo/tempodb"
@@ -78,6 +79,7 @@ type Config struct {
 	Ingester        ingester.Config         `yaml:"ingester,omitempty"`
 	Generator       generator.Config        `yaml:"metrics_generator,omitempty"`
 	StorageConfig   storage.Config          `yaml:"storage,omitempty"`
+	Ingest          ingest.Config           `yaml:"ingest,omitempty"`
 	LimitsConfig    overrides.Limits        `yaml:"overrides,omitempty"`
 	MemberlistKV    memberlist.KVConfig     `yaml:"memberlist,omitempty"`
 }
@@ -134,6 +136,7 @@ func (c *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
 	c.Frontend.RegisterFlagsAndApplyDefaults(util.PrefixConfig(prefix, "frontend"), f)
 	c.Compactor.RegisterFlagsAndApplyDefaults(util.PrefixConfig(prefix, "compactor"), f)
 	c.StorageConfig.RegisterFlagsAndApplyDefaults(util.PrefixConfig(prefix, "storage"), f)
+	c.Ingest.RegisterFlagsAndApplyDefaults(util.PrefixConfig(prefix, "ingest"), f)
 
 }
 
@@ -175,6 +178,16 @@ func (c *Config) CheckConfig() {
 	if c.Distributor.LogReceivedTraces {
 		level.Warn(log.Logger).Log("msg", "c.Distributor.LogReceivedTraces is deprecated. The new flag is c.Distributor.log_received_spans.enabled")
 	}
+
+	if c.Querier.ExtraQueryDelay > 0 && c.Ingester.LifecyclerConfig.RingConfig.ZoneAwarenessEnabled {
+		level.Warn(log.Logger).Log("msg", "c.Querier.ExtraQueryDelay is set but zone awareness is enabled",
+			"explain", "Queriers send all requests to the ingesters right away when zone awareness is enabled")
+	}
+
+	if c.Distributor.TenantFromResource.Enabled && !c.MultitenancyIsEnabled() {
+		level.Warn(log.Logger).Log("msg", "c.Distributor.TenantFromResource.Enabled is set but multitenancy is disabled",
+			"explain", "The tenant is only read from resource attributes when multitenancy is enabled")
+	}
 }
 
 func (c *Config) Describe(ch chan<- *prometheus.Desc) {
@@ -228,6 +241,7 @@ type App struct {
 	ingester      *ingester.Ingester
 	generator     *generator.Generator
 	store         storage.Store
+	ingestLog     ingest.Log
 	MemberlistKV  *memberlist.KVInitService
 
 	HTTPAuthMiddleware       middleware.Interface
@@ -243,7 +257,9 @@ func New(cfg Config) (*App, error) {
 		cfg: cfg,
 	}
 
-	app.setupAuthMiddleware()
+	if err := app.setupAuthMiddleware(); err != nil {
+		return nil, fmt.Errorf("failed to setup auth middleware %w", err)
+	}
 
 	if err := app.setupModuleManager(); err != nil {
 		return nil, fmt.Errorf("failed to setup module manager %w", err)
@@ -252,7 +268,7 @@ func New(cfg Config) (*App, error) {
 	return app, nil
 }
 
-func (t *App) setupAuthMiddleware() {
+func (t *App) setupAuthMiddleware() error {
 	if t.cfg.MultitenancyIsEnabled() {
 
 		// don't check auth for these gRPC methods, since single call is used for multiple users
@@ -282,7 +298,15 @@ func (t *App) setupAuthMiddleware() {
 			},
 		}
 		t.HTTPAuthMiddleware = middleware.AuthenticateUser
-		t.TracesConsumerMiddleware = receiver.MultiTenancyMiddleware()
+		if t.cfg.Distributor.TenantFromResource.Enabled {
+			m, err := receiver.TenantFromResourceMiddleware(t.cfg.Distributor.TenantFromResource)
+			if err != nil {
+				return fmt.Errorf("invalid distributor tenant_from_resource config: %w", err)
+			}
+			t.TracesConsumerMiddleware = m
+		} else {
+			t.TracesConsumerMiddleware = receiver.MultiTenancyMiddleware()
+		}
 	} else {
 		t.cfg.Server.GRPCMiddleware = []grpc.UnaryServerInterceptor{
 			fakeGRPCAuthUniaryMiddleware,
@@ -293,6 +317,8 @@ func (t *App) setupAuthMiddleware() {
 		t.HTTPAuthMiddleware = fakeHTTPAuthMiddleware
 		t.TracesConsumerMiddleware = receiver.FakeTenantMiddleware()
 	}
+
+	return nil
 }
 
 // Run starts, and blocks until a signal is received.
@@ -26,9 +26,11 @@ import (
 	"github.com/grafana/tempo/modules/querier"
 	tempo_storage "github.com/grafana/tempo/modules/storage"
 	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/ingest"
 	tempo_ring "github.com/grafana/tempo/pkg/ring"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 )
 
 // The various modules that make up tempo.
@@ -44,6 +46,7 @@ const (
 	QueryFrontend        string = "query-frontend"
 	Compactor            string = "compactor"
 	Store                string = "store"
+	IngestLog            string = "ingest-log"
 	MemberlistKV         string = "memberlist-kv"
 	SingleBinary         string = "all"
 	ScalableSingleBinary string = "scalable-single-binary"
@@ -121,7 +124,9 @@ func (t *App) initOverrides() (services.Service, error) {
 
 func (t *App) initDistributor() (services.Service, error) {
 	// todo: make ingester client a module instead of passing the config everywhere
-	distributor, err := distributor.New(t.cfg.Distributor, t.cfg.IngesterClient, t.ring, t.cfg.GeneratorClient, t.generatorRing, t.overrides, t.TracesConsumerMiddleware, log.Logger, t.cfg.Server.LogLevel, t.cfg.SearchEnabled, t.cfg.MetricsGeneratorEnabled, prometheus.DefaultRegisterer)
+	// ingesters completing blocks to vParquet search traces directly and drop the flatbuffer search data
+	extractSearchData := t.cfg.SearchEnabled && t.cfg.StorageConfig.Trace.Block.Version != vparquet.VersionString
+	distributor, err := distributor.New(t.cfg.Distributor, t.cfg.IngesterClient, t.ring, t.cfg.GeneratorClient, t.generatorRing, t.overrides, t.ingestLog, t.TracesConsumerMiddleware, log.Logger, t.cfg.Server.LogLevel, extractSearchData, t.cfg.MetricsGeneratorEnabled, prometheus.DefaultRegisterer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create distributor %w", err)
 	}
@@ -131,12 +136,17 @@ func (t *App) initDistributor() (services.Service, error) {
 		t.Server.HTTP.Handle("/distributor/ring", distributor.DistributorRing)
 	}
 
+	if t.cfg.Distributor.Tap.Enabled {
+		t.Server.HTTP.Handle("/distributor/tap", t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(distributor.TapHandler)))
+	}
+
 	return t.distributor, nil
 }
 
 func (t *App) initIngester() (services.Service, error) {
 	t.cfg.Ingester.LifecyclerConfig.ListenPort = t.cfg.Server.GRPCListenPort
-	ingester, err := ingester.New(t.cfg.Ingester, t.store, t.overrides, prometheus.DefaultRegisterer)
+	t.cfg.Ingester.SearchTagsDenyList = t.cfg.Distributor.SearchTagsDenyList
+	ingester, err := ingester.New(t.cfg.Ingester, t.cfg.IngesterClient, t.store, t.overrides, t.ingestLog, t.ring, prometheus.DefaultRegisterer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ingester: %w", err)
 	}
@@ -146,6 +156,7 @@ func (t *App) initIngester() (services.Service, error) {
 	tempopb.RegisterQuerierServer(t.Server.GRPC, t.ingester)
 	t.Server.HTTP.Path("/flush").Handler(http.HandlerFunc(t.ingester.FlushHandler))
 	t.Server.HTTP.Path("/shutdown").Handler(http.HandlerFunc(t.ingester.ShutdownHandler))
+	t.Server.HTTP.Handle("/ingester/traces/{traceID}", t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.ingester.InspectTraceHandler)))
 	return t.ingester, nil
 }
 
@@ -179,6 +190,8 @@ func (t *App) initQuerier() (services.Service, error) {
 		t.store.EnablePolling(nil)
 	}
 
+	t.cfg.Querier.ZoneAwarenessEnabled = t.cfg.Ingester.LifecyclerConfig.RingConfig.ZoneAwarenessEnabled
+
 	// todo: make ingester client a module instead of passing config everywhere
 	querier, err := querier.New(t.cfg.Querier, t.cfg.IngesterClient, t.ring, t.store, t.overrides)
 	if err != nil {
@@ -264,10 +277,22 @@ func (t *App) initCompactor() (services.Service, error) {
 		t.Server.HTTP.Handle("/compactor/ring", t.compactor.Ring)
 	}
 
+	// the deletion endpoints are admin endpoints, they must not be exposed to tenants
+	if t.cfg.Compactor.DeletionAPIEnabled {
+		t.Server.HTTP.Handle("/admin/compactor/delete/tenant", t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.compactor.DeleteTenantHandler)))
+		t.Server.HTTP.Handle("/admin/compactor/delete/traces", t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.compactor.DeleteTracesHandler)))
+		t.Server.HTTP.Handle("/admin/compactor/delete/status", t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.compactor.DeletionStatusHandler)))
+	}
+
 	return t.compactor, nil
 }
 
 func (t *App) initStore() (services.Service, error) {
+	// the backend rate limits depend on the target
+	if t.cfg.StorageConfig.Trace.RateLimit != nil {
+		t.cfg.StorageConfig.Trace.RateLimit.Component = t.cfg.Target
+	}
+
 	store, err := tempo_storage.NewStore(t.cfg.StorageConfig, log.Logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create store %w", err)
@@ -277,6 +302,19 @@ func (t *App) initStore() (services.Service, error) {
 	return t.store, nil
 }
 
+func (t *App) initIngestLog() (services.Service, error) {
+	// ingesters only consume the log, the distributor is the single writer
+	ingestLog, err := ingest.New(t.cfg.Ingest, t.cfg.Target == Ingester)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingest log %w", err)
+	}
+	t.ingestLog = ingestLog
+
+	return services.NewIdleService(nil, func(_ error) error {
+		return t.ingestLog.Close()
+	}), nil
+}
+
 func (t *App) initMemberlistKV() (services.Service, error) {
 	reg := prometheus.DefaultRegisterer
 	t.cfg.MemberlistKV.MetricsRegisterer = reg
@@ -321,6 +359,7 @@ func (t *App) setupModuleManager() error {
 	mm.RegisterModule(Compactor, t.initCompactor)
 	mm.RegisterModule(MetricsGenerator, t.initGenerator)
 	mm.RegisterModule(Store, t.initStore, modules.UserInvisibleModule)
+	mm.RegisterModule(IngestLog, t.initIngestLog, modules.UserInvisibleModule)
 	mm.RegisterModule(SingleBinary, nil)
 	mm.RegisterModule(ScalableSingleBinary, nil)
 
@@ -348,6 +387,17 @@ func (t *App) setupModuleManager() error {
 		deps[SingleBinary] = append(deps[SingleBinary], MetricsGenerator)
 	}
 
+	if t.cfg.Ingest.Enabled {
+		// The distributor appends to the ingest log and the ingesters consume the partitions they own in the ring
+		deps[Distributor] = append(deps[Distributor], IngestLog)
+		deps[Ingester] = append(deps[Ingester], IngestLog, Ring)
+	}
+
+	if t.cfg.Ingester.HandOff.Enabled {
+		// The ingesters hand off traces to the ingesters taking over their tokens in the ring
+		deps[Ingester] = append(deps[Ingester], Ring)
+	}
+
 	for mod, targets := range deps {
 		if err := mm.AddDependency(mod, targets...); err != nil {
 			return err
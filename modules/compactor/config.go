@@ -16,6 +16,10 @@ type Config struct {
 	ShardingRing    RingConfig              `yaml:"ring,omitempty"`
 	Compactor       tempodb.CompactorConfig `yaml:"compaction"`
 	OverrideRingKey string                  `yaml:"override_ring_key"`
+
+	// DeletionAPIEnabled serves the deletion endpoints under /admin/. The tenant of a request is taken from its
+	// X-Scope-OrgID header, so the admin endpoints must only be reachable by operators.
+	DeletionAPIEnabled bool `yaml:"deletion_api_enabled"`
 }
 
 // RegisterFlagsAndApplyDefaults registers the flags.
@@ -28,6 +32,8 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 		IteratorBufferSize:      tempodb.DefaultIteratorBufferSize,
 		MaxTimePerTenant:        tempodb.DefaultMaxTimePerTenant,
 		CompactionCycle:         tempodb.DefaultCompactionCycle,
+		ScrubBlocksPerTenant:    tempodb.DefaultScrubBlocksPerTenant,
+		DeletionGracePeriod:     tempodb.DefaultDeletionGracePeriod,
 	}
 
 	flagext.DefaultValues(&cfg.ShardingRing)
@@ -37,6 +43,7 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.IntVar(&cfg.Compactor.MaxCompactionObjects, util.PrefixConfig(prefix, "compaction.max-objects-per-block"), 6000000, "Maximum number of traces in a compacted block.")
 	f.Uint64Var(&cfg.Compactor.MaxBlockBytes, util.PrefixConfig(prefix, "compaction.max-block-bytes"), 100*1024*1024*1024 /* 100GB */, "Maximum size of a compacted block.")
 	f.DurationVar(&cfg.Compactor.MaxCompactionRange, util.PrefixConfig(prefix, "compaction.compaction-window"), time.Hour, "Maximum time window across which to compact blocks.")
+	f.BoolVar(&cfg.DeletionAPIEnabled, util.PrefixConfig(prefix, "deletion-api-enabled"), false, "Serve the admin endpoints to delete tenants and traces.")
 	cfg.OverrideRingKey = compactorRingKey
 }
 
@@ -0,0 +1,98 @@
+package compactor
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+const urlParamTraceID = "traceID"
+
+// DeleteTenantHandler requests the deletion of all traces of the tenant. It responds with the deletion request.
+func (c *Compactor) DeleteTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	req, err := c.store.DeleteTenant(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, req)
+}
+
+// DeleteTracesHandler requests the deletion of the traces of the traceID parameters. It responds with the deletion
+// request.
+func (c *Compactor) DeleteTracesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()[urlParamTraceID]
+	if len(params) == 0 {
+		http.Error(w, "please provide a traceID", http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]common.ID, 0, len(params))
+	for _, param := range params {
+		id, err := util.HexStringToTraceID(param)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	req, err := c.store.DeleteTraces(r.Context(), tenantID, ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, req)
+}
+
+// DeletionStatusHandler responds with the deletion requests of the tenant and their status
+func (c *Compactor) DeletionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	reqs, err := c.store.DeletionRequests(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reqs)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/grafana/dskit/flagext"
 	ring_client "github.com/grafana/dskit/ring/client"
+
+	"github.com/grafana/tempo/modules/distributor/receiver"
 	"github.com/grafana/tempo/pkg/util"
 )
 
@@ -41,6 +43,12 @@ type Config struct {
 
 	SearchTagsDenyList []string `yaml:"search_tags_deny_list"`
 
+	// streams a sample of received spans over http for debugging
+	Tap TapConfig `yaml:"tap"`
+
+	// splits incoming batches by a resource attribute and pushes each part under its own tenant
+	TenantFromResource receiver.TenantFromResourceConfig `yaml:"tenant_from_resource"`
+
 	// For testing.
 	factory func(addr string) (ring_client.PoolClient, error) `yaml:"-"`
 }
@@ -64,4 +72,6 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.BoolVar(&cfg.LogReceivedSpans.Enabled, util.PrefixConfig(prefix, "log-received-spans.enabled"), false, "Enable to log every received span to help debug ingestion or calculate span error distributions using the logs.")
 	f.BoolVar(&cfg.LogReceivedSpans.IncludeAllAttributes, util.PrefixConfig(prefix, "log-received-spans.include-attributes"), false, "Enable to include span attributes in the logs.")
 	f.BoolVar(&cfg.LogReceivedSpans.FilterByStatusError, util.PrefixConfig(prefix, "log-received-spans.filter-by-status-error"), false, "Enable to filter out spans without status error.")
+
+	cfg.Tap.RegisterFlagsAndApplyDefaults(util.PrefixConfig(prefix, "tap"), f)
 }
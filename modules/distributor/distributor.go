@@ -32,6 +32,7 @@ import (
 	ingester_client "github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
 	_ "github.com/grafana/tempo/pkg/gogocodec" // force gogo codec registration
+	"github.com/grafana/tempo/pkg/ingest"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
@@ -47,6 +48,8 @@ const (
 	reasonTraceTooLarge = "trace_too_large"
 	// reasonLiveTracesExceeded indicates that tempo is already tracking too many live traces in the ingesters for this user
 	reasonLiveTracesExceeded = "live_traces_exceeded"
+	// reasonLiveTracesBytesExceeded indicates that the live traces of this user already use too many bytes in the ingesters
+	reasonLiveTracesBytesExceeded = "live_traces_bytes_exceeded"
 	// reasonInternalError indicates an unexpected error occurred processing these spans. analogous to a 500
 	reasonInternalError = "internal_error"
 
@@ -100,6 +103,16 @@ var (
 		Name:      "distributor_metrics_generator_clients",
 		Help:      "The current number of metrics-generator clients.",
 	})
+	metricIngestLogAppends = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_ingest_log_appends_total",
+		Help:      "The total number of batch appends to the ingest log.",
+	})
+	metricIngestLogAppendFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_ingest_log_append_failures_total",
+		Help:      "The total number of failed batch appends to the ingest log.",
+	})
 )
 
 // rebatchedTrace is used to more cleanly pass the set of data
@@ -122,6 +135,9 @@ type Distributor struct {
 	overrides       *overrides.Overrides
 	traceEncoder    model.SegmentDecoder
 
+	// durable log ingesters consume from, if set traces are not pushed to the ingesters
+	ingestLog ingest.Log
+
 	// search
 	searchEnabled    bool
 	globalTagsToDrop map[string]struct{}
@@ -136,6 +152,9 @@ type Distributor struct {
 	// Per-user rate limiter.
 	ingestionRateLimiter *limiter.RateLimiter
 
+	// debug taps
+	tapper *tapper
+
 	// Manager for subservices
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -144,7 +163,7 @@ type Distributor struct {
 }
 
 // New a distributor creates.
-func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRing, generatorClientCfg generator_client.Config, generatorsRing ring.ReadRing, o *overrides.Overrides, middleware receiver.Middleware, logger log.Logger, loggingLevel logging.Level, searchEnabled bool, metricsGeneratorEnabled bool, reg prometheus.Registerer) (*Distributor, error) {
+func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRing, generatorClientCfg generator_client.Config, generatorsRing ring.ReadRing, o *overrides.Overrides, ingestLog ingest.Log, middleware receiver.Middleware, logger log.Logger, loggingLevel logging.Level, searchEnabled bool, metricsGeneratorEnabled bool, reg prometheus.Registerer) (*Distributor, error) {
 	factory := cfg.factory
 	if factory == nil {
 		factory = func(addr string) (ring_client.PoolClient, error) {
@@ -196,6 +215,7 @@ func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRi
 		cfg:                     cfg,
 		clientCfg:               clientCfg,
 		ingestersRing:           ingestersRing,
+		ingestLog:               ingestLog,
 		pool:                    pool,
 		DistributorRing:         distributorRing,
 		ingestionRateLimiter:    limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second),
@@ -206,6 +226,7 @@ func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRi
 		globalTagsToDrop:        tagsToDrop,
 		overrides:               o,
 		traceEncoder:            model.MustNewSegmentDecoder(model.CurrentEncoding),
+		tapper:                  newTapper(cfg.Tap),
 		logger:                  logger,
 	}
 
@@ -292,6 +313,13 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 		}
 	}
 
+	batches, dropped := validateBatches(batches, d.overrides.SpanValidation(userID), userID)
+	for reason, count := range dropped {
+		overrides.RecordDiscardedSpans(count, reason, userID)
+	}
+
+	d.tapper.offer(userID, batches)
+
 	// metric size
 	size := 0
 	spanCount := 0
@@ -341,7 +369,11 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 		})
 	}
 
-	err = d.sendToIngestersViaBytes(ctx, userID, rebatchedTraces, searchData, keys)
+	if d.ingestLog != nil {
+		err = d.sendToIngestLog(userID, rebatchedTraces, searchData, keys)
+	} else {
+		err = d.sendToIngestersViaBytes(ctx, userID, rebatchedTraces, searchData, keys)
+	}
 	if err != nil {
 		recordDiscaredSpans(err, userID, spanCount)
 		return nil, err
@@ -356,13 +388,9 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 
 func (d *Distributor) sendToIngestersViaBytes(ctx context.Context, userID string, traces []*rebatchedTrace, searchData [][]byte, keys []uint32) error {
 	// Marshal to bytes once
-	marshalledTraces := make([][]byte, len(traces))
-	for i, t := range traces {
-		b, err := d.traceEncoder.PrepareForWrite(t.trace, t.start, t.end)
-		if err != nil {
-			return errors.Wrap(err, "failed to marshal PushRequest")
-		}
-		marshalledTraces[i] = b
+	marshalledTraces, err := d.marshalTraces(traces)
+	if err != nil {
+		return err
 	}
 
 	op := ring.WriteNoExtend
@@ -370,7 +398,7 @@ func (d *Distributor) sendToIngestersViaBytes(ctx context.Context, userID string
 		op = ring.Write
 	}
 
-	err := ring.DoBatch(ctx, op, d.ingestersRing, keys, func(ingester ring.InstanceDesc, indexes []int) error {
+	err = ring.DoBatch(ctx, op, d.ingestersRing, keys, func(ingester ring.InstanceDesc, indexes []int) error {
 		localCtx, cancel := context.WithTimeout(ctx, d.clientCfg.RemoteTimeout)
 		defer cancel()
 		localCtx = user.InjectOrgID(localCtx, userID)
@@ -407,6 +435,65 @@ func (d *Distributor) sendToIngestersViaBytes(ctx context.Context, userID string
 	return err
 }
 
+// sendToIngestLog appends the traces to the partitions of their trace ids. Replication is left to the ingesters
+// consuming the partitions. If an append fails the client retries the whole request and the traces appended to the
+// other partitions are appended again, the ingesters skip the traces they pushed recently.
+func (d *Distributor) sendToIngestLog(userID string, traces []*rebatchedTrace, searchData [][]byte, keys []uint32) error {
+	marshalledTraces, err := d.marshalTraces(traces)
+	if err != nil {
+		return err
+	}
+
+	partitions := d.ingestLog.Partitions()
+	requests := map[int]*tempopb.PushBytesRequest{}
+	for i, key := range keys {
+		p := ingest.PartitionFor(key, partitions)
+		req, ok := requests[p]
+		if !ok {
+			req = &tempopb.PushBytesRequest{}
+			requests[p] = req
+		}
+
+		req.Traces = append(req.Traces, tempopb.PreallocBytes{Slice: marshalledTraces[i]})
+		req.Ids = append(req.Ids, tempopb.PreallocBytes{Slice: traces[i].id})
+
+		// Search data optional
+		var search []byte
+		if len(searchData) > i {
+			search = searchData[i]
+		}
+		req.SearchData = append(req.SearchData, tempopb.PreallocBytes{Slice: search})
+	}
+
+	for p, req := range requests {
+		b, err := req.Marshal()
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal PushBytesRequest")
+		}
+
+		err = d.ingestLog.Append(p, []*ingest.Record{{TenantID: userID, Value: b}})
+		metricIngestLogAppends.Inc()
+		if err != nil {
+			metricIngestLogAppendFailures.Inc()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Distributor) marshalTraces(traces []*rebatchedTrace) ([][]byte, error) {
+	marshalledTraces := make([][]byte, len(traces))
+	for i, t := range traces {
+		b, err := d.traceEncoder.PrepareForWrite(t.trace, t.start, t.end)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal PushRequest")
+		}
+		marshalledTraces[i] = b
+	}
+	return marshalledTraces, nil
+}
+
 func (d *Distributor) sendToGenerators(ctx context.Context, userID string, keys []uint32, traces []*rebatchedTrace) error {
 	// If an instance is unhealthy write to the next one (i.e. write extend is enabled)
 	op := ring.Write
@@ -533,6 +620,8 @@ func recordDiscaredSpans(err error, userID string, spanCount int) {
 
 	if strings.HasPrefix(desc, overrides.ErrorPrefixLiveTracesExceeded) {
 		overrides.RecordDiscardedSpans(spanCount, reasonLiveTracesExceeded, userID)
+	} else if strings.HasPrefix(desc, overrides.ErrorPrefixLiveTracesBytesExceeded) {
+		overrides.RecordDiscardedSpans(spanCount, reasonLiveTracesBytesExceeded, userID)
 	} else if strings.HasPrefix(desc, overrides.ErrorPrefixTraceTooLarge) {
 		overrides.RecordDiscardedSpans(spanCount, reasonTraceTooLarge, userID)
 	} else {
@@ -33,6 +33,7 @@ import (
 	generator_client "github.com/grafana/tempo/modules/generator/client"
 	ingester_client "github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/ingest"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
@@ -665,6 +666,49 @@ func TestDistributor(t *testing.T) {
 	}
 }
 
+func TestDistributorIngestLog(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+	d := prepare(t, limits, nil, nil)
+
+	l, err := ingest.New(ingest.Config{Enabled: true, Path: t.TempDir(), Partitions: 4, MaxSegmentBytes: 1024 * 1024}, false)
+	require.NoError(t, err)
+	defer l.Close()
+	d.ingestLog = l
+
+	var batches []*v1.ResourceSpans
+	expectedIDs := map[string]struct{}{}
+	for i := 0; i < 20; i++ {
+		id := test.ValidTraceID(nil)
+		expectedIDs[string(id)] = struct{}{}
+		batches = append(batches, test.MakeTrace(1, id).Batches...)
+	}
+
+	_, err = d.PushBatches(ctx, batches)
+	require.NoError(t, err)
+
+	// every trace is appended once to the partition of its token
+	actualIDs := map[string]struct{}{}
+	for p := 0; p < l.Partitions(); p++ {
+		records, err := l.Fetch(p, 0, 100)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(records), 1)
+
+		for _, r := range records {
+			assert.Equal(t, "test", r.TenantID)
+
+			req := &tempopb.PushBytesRequest{}
+			require.NoError(t, req.Unmarshal(r.Value))
+			require.Len(t, req.Traces, len(req.Ids))
+			for _, id := range req.Ids {
+				assert.Equal(t, p, ingest.PartitionFor(util.TokenFor("test", id.Slice), l.Partitions()))
+				actualIDs[string(id.Slice)] = struct{}{}
+			}
+		}
+	}
+	assert.Equal(t, expectedIDs, actualIDs)
+}
+
 func TestLogSpans(t *testing.T) {
 	for i, tc := range []struct {
 		LogReceivedTraces       bool // Backwards compatibility with old config
@@ -995,7 +1039,7 @@ func prepare(t *testing.T, limits *overrides.Limits, kvStore kv.Client, logger l
 	l := logging.Level{}
 	_ = l.Set("error")
 	mw := receiver.MultiTenancyMiddleware()
-	d, err := New(distributorConfig, clientConfig, ingestersRing, generator_client.Config{}, nil, overrides, mw, logger, l, false, false, prometheus.NewPedanticRegistry())
+	d, err := New(distributorConfig, clientConfig, ingestersRing, generator_client.Config{}, nil, overrides, nil, mw, logger, l, false, false, prometheus.NewPedanticRegistry())
 	require.NoError(t, err)
 
 	return d
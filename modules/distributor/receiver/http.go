@@ -0,0 +1,230 @@
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/jaegerreceiver"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/zipkinreceiver"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+const (
+	jaegerThriftHTTPPath = "/api/traces"
+)
+
+// httpServer serves the HTTP protocol of a receiver. gzip and deflate bodies are decompressed, the compressed body size
+// is limited and CORS is applied by confighttp before a request reaches the handler.
+type httpServer struct {
+	cfg               *confighttp.HTTPServerSettings
+	settings          component.ReceiverCreateSettings
+	handler           http.Handler
+	onDecompressError func(w http.ResponseWriter, req *http.Request, errMsg string, statusCode int)
+
+	server *http.Server
+	wg     sync.WaitGroup
+}
+
+// Start implements component.Component
+func (s *httpServer) Start(_ context.Context, host component.Host) error {
+	var err error
+	s.server, err = s.newServer(host)
+	if err != nil {
+		return err
+	}
+
+	ln, err := s.cfg.ToListener()
+	if err != nil {
+		return fmt.Errorf("failed to bind to address %s: %w", s.cfg.Endpoint, err)
+	}
+
+	s.settings.Logger.Info("Starting HTTP server on endpoint " + s.cfg.Endpoint)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(ln); err != http.ErrServerClosed {
+			host.ReportFatalError(err)
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown implements component.Component
+func (s *httpServer) Shutdown(ctx context.Context) error {
+	var err error
+	if s.server != nil {
+		err = s.server.Shutdown(ctx)
+	}
+	s.wg.Wait()
+	return err
+}
+
+func (s *httpServer) newServer(host component.Host) (*http.Server, error) {
+	return s.cfg.ToServer(host, s.settings.TelemetrySettings, s.handler, confighttp.WithErrorHandler(s.onDecompressError))
+}
+
+// newDecodingHTTPServer serves the HTTP handler of a receiver that is not aware of zstd, the decompressed body size or
+// the decode errors metric. The body is decompressed and limited before it is passed to the handler, and the responses
+// of the handler are counted as decode errors by status code. If contentTypes are passed, other content types are
+// refused.
+func newDecodingHTTPServer(id config.ComponentID, cfg *confighttp.HTTPServerSettings, set component.ReceiverCreateSettings, pattern string, next http.Handler, contentTypes ...string) *httpServer {
+	name := id.String()
+
+	mux := http.NewServeMux()
+	mux.Handle(pattern, decodingHandler(name, cfg.MaxRequestBodySize, contentTypes, next))
+
+	return &httpServer{
+		cfg:      cfg,
+		settings: set,
+		handler:  mux,
+		onDecompressError: func(w http.ResponseWriter, _ *http.Request, errMsg string, statusCode int) {
+			metricReceiverDecodeErrors.WithLabelValues(name, decodeErrorEncoding).Inc()
+			http.Error(w, errMsg, statusCode)
+		},
+	}
+}
+
+func decodingHandler(name string, maxSize int64, contentTypes []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(contentTypes) > 0 && !hasContentType(req, contentTypes) {
+			metricReceiverDecodeErrors.WithLabelValues(name, decodeErrorContentType).Inc()
+			http.Error(w, fmt.Sprintf("unsupported content type, supported: %v", contentTypes), http.StatusBadRequest)
+			return
+		}
+
+		body, reason, err := readBody(req, maxSize)
+		if err != nil {
+			metricReceiverDecodeErrors.WithLabelValues(name, reason).Inc()
+			statusCode := http.StatusBadRequest
+			if reason == decodeErrorTooLarge {
+				statusCode = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+
+		// the body is passed on decompressed
+		req.Header.Del("Content-Encoding")
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+
+		rec := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, req)
+
+		switch rec.statusCode {
+		case http.StatusBadRequest:
+			metricReceiverDecodeErrors.WithLabelValues(name, decodeErrorBody).Inc()
+		case http.StatusUnsupportedMediaType:
+			metricReceiverDecodeErrors.WithLabelValues(name, decodeErrorContentType).Inc()
+		}
+	})
+}
+
+func hasContentType(req *http.Request, contentTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	for _, ct := range contentTypes {
+		if mediaType == ct {
+			return true
+		}
+	}
+	return false
+}
+
+// statusRecorder records the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// zipkinFactory wraps the zipkin receiver factory. The zipkin receiver is not started itself, it handles the requests
+// of a decoding httpServer.
+type zipkinFactory struct {
+	component.ReceiverFactory
+}
+
+func newZipkinFactory() component.ReceiverFactory {
+	return &zipkinFactory{ReceiverFactory: zipkinreceiver.NewFactory()}
+}
+
+// CreateTracesReceiver implements component.ReceiverFactory
+func (f *zipkinFactory) CreateTracesReceiver(ctx context.Context, set component.ReceiverCreateSettings, cfg config.Receiver, next consumer.Traces) (component.TracesReceiver, error) {
+	zipkinCfg, ok := cfg.(*zipkinreceiver.Config)
+	if !ok {
+		return f.ReceiverFactory.CreateTracesReceiver(ctx, set, cfg, next)
+	}
+
+	r, err := f.ReceiverFactory.CreateTracesReceiver(ctx, set, cfg, next)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := r.(http.Handler)
+	if !ok {
+		return r, nil
+	}
+
+	return newDecodingHTTPServer(zipkinCfg.ID(), &zipkinCfg.HTTPServerSettings, set, "/", handler), nil
+}
+
+// jaegerFactory wraps the jaeger receiver factory. The thrift_http protocol is served by a decoding httpServer that
+// passes the requests to a jaeger receiver that is not started. The other protocols are still served by the jaeger
+// receiver.
+type jaegerFactory struct {
+	component.ReceiverFactory
+}
+
+func newJaegerFactory() component.ReceiverFactory {
+	return &jaegerFactory{ReceiverFactory: jaegerreceiver.NewFactory()}
+}
+
+type thriftHTTPHandler interface {
+	HandleThriftHTTPBatch(w http.ResponseWriter, r *http.Request)
+}
+
+// CreateTracesReceiver implements component.ReceiverFactory
+func (f *jaegerFactory) CreateTracesReceiver(ctx context.Context, set component.ReceiverCreateSettings, cfg config.Receiver, next consumer.Traces) (component.TracesReceiver, error) {
+	jaegerCfg, ok := cfg.(*jaegerreceiver.Config)
+	if !ok || jaegerCfg.ThriftHTTP == nil {
+		return f.ReceiverFactory.CreateTracesReceiver(ctx, set, cfg, next)
+	}
+
+	r, err := f.ReceiverFactory.CreateTracesReceiver(ctx, set, cfg, next)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := r.(thriftHTTPHandler)
+	if !ok {
+		return r, nil
+	}
+
+	receivers := multiReceiver{newDecodingHTTPServer(jaegerCfg.ID(), jaegerCfg.ThriftHTTP, set, jaegerThriftHTTPPath,
+		http.HandlerFunc(handler.HandleThriftHTTPBatch), "application/x-thrift", "application/vnd.apache.thrift.binary")}
+
+	otherCfg := *jaegerCfg
+	otherCfg.ThriftHTTP = nil
+
+	r, err = f.ReceiverFactory.CreateTracesReceiver(ctx, set, &otherCfg, next)
+	if err != nil {
+		return nil, err
+	}
+	receivers = append(receivers, r)
+
+	return receivers, nil
+}
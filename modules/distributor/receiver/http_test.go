@@ -0,0 +1,100 @@
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/jaegerreceiver"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/zipkinreceiver"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+const zipkinBody = `[{"traceId":"0102000000000000","id":"0100000000000000","name":"test","localEndpoint":{"serviceName":"test"}}]`
+
+func TestZipkinFactory(t *testing.T) {
+	f := newZipkinFactory()
+	cfg := f.CreateDefaultConfig().(*zipkinreceiver.Config)
+	cfg.SetIDName(t.Name())
+	cfg.MaxRequestBodySize = int64(len(zipkinBody) * 2)
+
+	consumed := 0
+	r, err := f.CreateTracesReceiver(context.Background(), testReceiverCreateSettings(), cfg, ConsumeTracesFunc(func(context.Context, pdata.Traces) error {
+		consumed++
+		return nil
+	}))
+	require.NoError(t, err)
+	require.IsType(t, &httpServer{}, r)
+
+	name := cfg.ID().String()
+	tests := []otlpHTTPTestCase{
+		{name: "json", contentType: "application/json", body: []byte(zipkinBody), expectedStatus: http.StatusAccepted},
+		{name: "json gzip", contentType: "application/json", contentEncoding: "gzip", body: gzipBytes(t, []byte(zipkinBody)), expectedStatus: http.StatusAccepted},
+		{name: "json zstd", contentType: "application/json", contentEncoding: "zstd", body: zstdBytes(t, []byte(zipkinBody)), expectedStatus: http.StatusAccepted},
+		{name: "corrupt json", contentType: "application/json", body: []byte("["), expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorBody},
+		{name: "too large", contentType: "application/json", contentEncoding: "zstd", body: zstdBytes(t, bytes.Repeat([]byte(zipkinBody), 3)), expectedStatus: http.StatusRequestEntityTooLarge, expectedReason: decodeErrorTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testDecodingHTTPRequest(t, r.(*httpServer), name, "/api/v2/spans", tc)
+		})
+	}
+	assert.Equal(t, 3, consumed)
+}
+
+func TestJaegerFactory(t *testing.T) {
+	f := newJaegerFactory()
+	cfg := f.CreateDefaultConfig().(*jaegerreceiver.Config)
+	cfg.SetIDName(t.Name())
+	cfg.ThriftHTTP = &confighttp.HTTPServerSettings{Endpoint: "localhost:0"}
+
+	r, err := f.CreateTracesReceiver(context.Background(), testReceiverCreateSettings(), cfg, ConsumeTracesFunc(func(context.Context, pdata.Traces) error { return nil }))
+	require.NoError(t, err)
+	require.IsType(t, multiReceiver{}, r)
+	require.Len(t, r.(multiReceiver), 2)
+	require.IsType(t, &httpServer{}, r.(multiReceiver)[0])
+
+	name := cfg.ID().String()
+	tests := []otlpHTTPTestCase{
+		{name: "unknown content type", contentType: "application/json", body: []byte("{}"), expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorContentType},
+		{name: "corrupt thrift", contentType: "application/x-thrift", body: []byte{0xff, 0xff}, expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorBody},
+		{name: "corrupt zstd", contentType: "application/x-thrift", contentEncoding: "zstd", body: []byte{0xff, 0xff}, expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorEncoding},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testDecodingHTTPRequest(t, r.(multiReceiver)[0].(*httpServer), name, jaegerThriftHTTPPath, tc)
+		})
+	}
+}
+
+func testDecodingHTTPRequest(t *testing.T, s *httpServer, name string, path string, tc otlpHTTPTestCase) {
+	server, err := s.newServer(&receiversShim{})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server.Handler)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+path, bytes.NewReader(tc.body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", tc.contentType)
+	if tc.contentEncoding != "" {
+		req.Header.Set("Content-Encoding", tc.contentEncoding)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+	if tc.expectedReason != "" {
+		assert.Equal(t, 1.0, testutil.ToFloat64(metricReceiverDecodeErrors.WithLabelValues(name, tc.expectedReason)))
+	}
+}
@@ -2,10 +2,13 @@ package receiver
 
 import (
 	"context"
+	"errors"
+	"strings"
 
 	"github.com/weaveworks/common/user"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/multierr"
 
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/log"
@@ -73,3 +76,135 @@ func (m *multiTenancyMiddleware) Wrap(next consumer.Traces) consumer.Traces {
 		return next.ConsumeTraces(ctx, td)
 	})
 }
+
+// TenantFromResourceConfig configures the TenantFromResourceMiddleware
+type TenantFromResourceConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Attribute is the resource attribute the tenant is read from, e.g. tenant.id or k8s.namespace.name
+	Attribute string `yaml:"attribute"`
+	// Mapping maps attribute values to tenants.
+	Mapping map[string]string `yaml:"mapping"`
+	// AllowedTenants are the attribute values that are used as tenant if there is no mapping.
+	AllowedTenants []string `yaml:"allowed_tenants"`
+	// FallbackTenant receives batches without the attribute or with a value that is not mapped or allowed. If it is
+	// empty the tenant of the request (X-Scope-OrgID) is used instead.
+	FallbackTenant string `yaml:"fallback_tenant"`
+}
+
+// Validate checks the config. Either a mapping or allowed tenants are required, otherwise any client could write
+// into any tenant by setting the attribute.
+func (cfg *TenantFromResourceConfig) Validate() error {
+	if cfg.Attribute == "" {
+		return errors.New("attribute must be set")
+	}
+	if len(cfg.Mapping) == 0 && len(cfg.AllowedTenants) == 0 {
+		return errors.New("mapping or allowed_tenants must be set")
+	}
+	return nil
+}
+
+type tenantFromResourceMiddleware struct {
+	cfg     TenantFromResourceConfig
+	allowed map[string]struct{}
+}
+
+// TenantFromResourceMiddleware splits incoming traces by the tenant found in a resource attribute and pushes
+// each part separately under its own tenant. This allows shared collectors to send data of many tenants
+// over one connection.
+//
+// The parts are pushed one after the other. If pushing some of them fails, the request fails, and a client that
+// retries it pushes the parts that succeeded again. Traces are combined by ID, so retried spans are deduplicated
+// by queries, but they are counted twice towards the ingestion limits.
+func TenantFromResourceMiddleware(cfg TenantFromResourceConfig) (Middleware, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]struct{}, len(cfg.AllowedTenants))
+	for _, tenant := range cfg.AllowedTenants {
+		allowed[tenant] = struct{}{}
+	}
+
+	return &tenantFromResourceMiddleware{cfg: cfg, allowed: allowed}, nil
+}
+
+func (m *tenantFromResourceMiddleware) Wrap(next consumer.Traces) consumer.Traces {
+	return ConsumeTracesFunc(func(ctx context.Context, td pdata.Traces) error {
+		fallback := m.cfg.FallbackTenant
+		if fallback == "" {
+			// not having a tenant on the request is only an error if some batches need it
+			fallback, _, _ = user.ExtractFromGRPCRequest(ctx)
+		}
+
+		rss := td.ResourceSpans()
+		resourceTenants := make([]string, rss.Len())
+		tracesByTenant := map[string]pdata.Traces{}
+		var tenants []string
+
+		for i := 0; i < rss.Len(); i++ {
+			tenant := m.tenantFor(rss.At(i).Resource())
+			if tenant == "" {
+				tenant = fallback
+			}
+			if tenant == "" {
+				log.Logger.Log("msg", "failed to determine tenant from resource", "attribute", m.cfg.Attribute)
+				return user.ErrNoOrgID
+			}
+
+			resourceTenants[i] = tenant
+			if _, ok := tracesByTenant[tenant]; !ok {
+				tracesByTenant[tenant] = pdata.NewTraces()
+				tenants = append(tenants, tenant)
+			}
+		}
+
+		// avoid copying if the request only contains a single tenant
+		if len(tenants) == 1 {
+			return next.ConsumeTraces(user.InjectOrgID(ctx, tenants[0]), td)
+		}
+
+		for i := 0; i < rss.Len(); i++ {
+			rss.At(i).CopyTo(tracesByTenant[resourceTenants[i]].ResourceSpans().AppendEmpty())
+		}
+
+		var errs []error
+		for _, tenant := range tenants {
+			err := next.ConsumeTraces(user.InjectOrgID(ctx, tenant), tracesByTenant[tenant])
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return multierr.Combine(errs...)
+	})
+}
+
+// tenantFor returns the tenant for the resource or an empty string if none was found
+func (m *tenantFromResourceMiddleware) tenantFor(r pdata.Resource) string {
+	v, ok := r.Attributes().Get(m.cfg.Attribute)
+	if !ok || v.Type() != pdata.AttributeValueTypeString {
+		return ""
+	}
+
+	value := v.StringVal()
+	tenant, ok := m.cfg.Mapping[value]
+	if !ok {
+		if _, allowed := m.allowed[value]; !allowed {
+			return ""
+		}
+		tenant = value
+	}
+
+	if !validTenantID(tenant) {
+		return ""
+	}
+	return tenant
+}
+
+// validTenantID rejects tenant ids that are unsafe to use in backend paths or that would be read as multiple tenants
+func validTenantID(tenant string) bool {
+	if tenant == "" || tenant == "." || tenant == ".." {
+		return false
+	}
+	return !strings.ContainsAny(tenant, `/\|`)
+}
@@ -77,3 +77,118 @@ func TestMultiTenancyMiddleware(t *testing.T) {
 		require.EqualError(t, m.Wrap(consumer).ConsumeTraces(ctx, pdata.Traces{}), "no org id")
 	})
 }
+
+func TestTenantFromResourceMiddleware(t *testing.T) {
+	makeTraces := func(tenants ...string) pdata.Traces {
+		td := pdata.NewTraces()
+		for _, tenant := range tenants {
+			rs := td.ResourceSpans().AppendEmpty()
+			rs.Resource().Attributes().InsertString("service.name", "svc-"+tenant)
+			if tenant != "" {
+				rs.Resource().Attributes().InsertString("tenant.id", tenant)
+			}
+			rs.InstrumentationLibrarySpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+		}
+		return td
+	}
+
+	allowed := []string{"a", "b", "../etc"}
+
+	tests := []struct {
+		name          string
+		cfg           TenantFromResourceConfig
+		orgID         string
+		traces        pdata.Traces
+		expectedSpans map[string]int
+		expectedErr   error
+	}{
+		{
+			name:          "single tenant",
+			cfg:           TenantFromResourceConfig{Attribute: "tenant.id", AllowedTenants: allowed},
+			traces:        makeTraces("a", "a"),
+			expectedSpans: map[string]int{"a": 2},
+		},
+		{
+			name:          "split by tenant",
+			cfg:           TenantFromResourceConfig{Attribute: "tenant.id", AllowedTenants: allowed},
+			traces:        makeTraces("a", "b", "a"),
+			expectedSpans: map[string]int{"a": 2, "b": 1},
+		},
+		{
+			name:          "fallback tenant",
+			cfg:           TenantFromResourceConfig{Attribute: "tenant.id", AllowedTenants: allowed, FallbackTenant: "fallback"},
+			traces:        makeTraces("a", "", "../etc"),
+			expectedSpans: map[string]int{"a": 1, "fallback": 2},
+		},
+		{
+			name:          "fallback to org id",
+			cfg:           TenantFromResourceConfig{Attribute: "tenant.id", AllowedTenants: allowed},
+			orgID:         "org",
+			traces:        makeTraces("a", ""),
+			expectedSpans: map[string]int{"a": 1, "org": 1},
+		},
+		{
+			name:          "not allowed",
+			cfg:           TenantFromResourceConfig{Attribute: "tenant.id", AllowedTenants: allowed, FallbackTenant: "fallback"},
+			traces:        makeTraces("a", "c"),
+			expectedSpans: map[string]int{"a": 1, "fallback": 1},
+		},
+		{
+			name:          "mapping",
+			cfg:           TenantFromResourceConfig{Attribute: "tenant.id", Mapping: map[string]string{"a": "team-1", "b": "team-1"}, FallbackTenant: "fallback"},
+			traces:        makeTraces("a", "b", "c"),
+			expectedSpans: map[string]int{"team-1": 2, "fallback": 1},
+		},
+		{
+			name:        "no tenant",
+			cfg:         TenantFromResourceConfig{Attribute: "tenant.id", AllowedTenants: allowed},
+			traces:      makeTraces("a", ""),
+			expectedErr: user.ErrNoOrgID,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualSpans := map[string]int{}
+			next := ConsumeTracesFunc(func(ctx context.Context, td pdata.Traces) error {
+				orgID, err := user.ExtractOrgID(ctx)
+				require.NoError(t, err)
+				actualSpans[orgID] += td.SpanCount()
+				return nil
+			})
+
+			ctx := context.Background()
+			if tc.orgID != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("X-Scope-OrgID", tc.orgID))
+			}
+
+			m, err := TenantFromResourceMiddleware(tc.cfg)
+			require.NoError(t, err)
+
+			err = m.Wrap(next).ConsumeTraces(ctx, tc.traces)
+			if tc.expectedErr != nil {
+				require.Equal(t, tc.expectedErr, err)
+				require.Empty(t, actualSpans)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedSpans, actualSpans)
+		})
+	}
+}
+
+func TestTenantFromResourceConfigValidate(t *testing.T) {
+	cfg := TenantFromResourceConfig{}
+	require.Error(t, cfg.Validate())
+
+	// any client could write into any tenant
+	cfg.Attribute = "tenant.id"
+	require.Error(t, cfg.Validate())
+
+	cfg.AllowedTenants = []string{"a"}
+	require.NoError(t, cfg.Validate())
+
+	cfg.AllowedTenants = nil
+	cfg.Mapping = map[string]string{"a": "b"}
+	require.NoError(t, cfg.Validate())
+}
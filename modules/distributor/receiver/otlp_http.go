@@ -0,0 +1,331 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/klauspost/compress/zstd"
+	prom_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.opentelemetry.io/collector/obsreport"
+	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+	"go.uber.org/multierr"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/tempo/pkg/util"
+)
+
+const (
+	otlpHTTPTracesPath = "/v1/traces"
+
+	pbContentType    = "application/x-protobuf"
+	pbAltContentType = "application/protobuf"
+	jsonContentType  = "application/json"
+
+	decodeErrorContentType = "content_type"
+	decodeErrorEncoding    = "content_encoding"
+	decodeErrorTooLarge    = "too_large"
+	decodeErrorBody        = "body"
+)
+
+var (
+	metricReceiverDecodeErrors = promauto.NewCounterVec(prom_client.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_receiver_decode_errors_total",
+		Help:      "The total number of requests that could not be decoded per receiver and reason.",
+	}, []string{"receiver", "reason"})
+
+	errRequestTooLarge = errors.New("request body too large")
+)
+
+// otlpFactory wraps the otlp receiver factory. The gRPC protocol is still served by the otlp receiver, but the HTTP
+// protocol is served by an otlpHTTPReceiver. This adds support for zstd compressed bodies, records decode errors per
+// receiver and accepts the tenant in the X-Scope-OrgID header.
+type otlpFactory struct {
+	component.ReceiverFactory
+}
+
+func newOTLPFactory() component.ReceiverFactory {
+	return &otlpFactory{ReceiverFactory: otlpreceiver.NewFactory()}
+}
+
+// CreateTracesReceiver implements component.ReceiverFactory
+func (f *otlpFactory) CreateTracesReceiver(ctx context.Context, set component.ReceiverCreateSettings, cfg config.Receiver, next consumer.Traces) (component.TracesReceiver, error) {
+	otlpCfg, ok := cfg.(*otlpreceiver.Config)
+	if !ok || otlpCfg.HTTP == nil {
+		return f.ReceiverFactory.CreateTracesReceiver(ctx, set, cfg, next)
+	}
+
+	receivers := multiReceiver{newOTLPHTTPReceiver(otlpCfg.ID(), otlpCfg.HTTP, set, next)}
+
+	if otlpCfg.GRPC != nil {
+		grpcCfg := *otlpCfg
+		grpcCfg.HTTP = nil
+
+		r, err := f.ReceiverFactory.CreateTracesReceiver(ctx, set, &grpcCfg, next)
+		if err != nil {
+			return nil, err
+		}
+		receivers = append(receivers, r)
+	}
+
+	return receivers, nil
+}
+
+// multiReceiver starts and stops a set of receivers as one
+type multiReceiver []component.TracesReceiver
+
+func (m multiReceiver) Start(ctx context.Context, host component.Host) error {
+	for _, r := range m {
+		if err := r.Start(ctx, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiReceiver) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, r := range m {
+		if err := r.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return multierr.Combine(errs...)
+}
+
+// otlpEncoding describes how to decode a request and encode the response for a content type
+type otlpEncoding struct {
+	contentType   string
+	format        string
+	unmarshaler   pdata.TracesUnmarshaler
+	marshalStatus func(*spb.Status) ([]byte, error)
+	emptyResponse []byte
+}
+
+var (
+	pbEncoding = &otlpEncoding{
+		contentType:   pbContentType,
+		format:        "protobuf",
+		unmarshaler:   otlp.NewProtobufTracesUnmarshaler(),
+		marshalStatus: func(s *spb.Status) ([]byte, error) { return proto.Marshal(s) },
+		emptyResponse: []byte{},
+	}
+	jsonEncoding = &otlpEncoding{
+		contentType:   jsonContentType,
+		format:        "json",
+		unmarshaler:   otlp.NewJSONTracesUnmarshaler(),
+		marshalStatus: func(s *spb.Status) ([]byte, error) { return protojson.Marshal(s) },
+		emptyResponse: []byte("{}"),
+	}
+)
+
+type otlpHTTPReceiver struct {
+	httpServer
+
+	name    string
+	next    consumer.Traces
+	obsrecv *obsreport.Receiver
+}
+
+func newOTLPHTTPReceiver(id config.ComponentID, cfg *confighttp.HTTPServerSettings, set component.ReceiverCreateSettings, next consumer.Traces) *otlpHTTPReceiver {
+	r := &otlpHTTPReceiver{
+		name: id.String(),
+		next: next,
+		obsrecv: obsreport.NewReceiver(obsreport.ReceiverSettings{
+			ReceiverID:             id,
+			Transport:              "http",
+			ReceiverCreateSettings: set,
+		}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(otlpHTTPTracesPath, r.handleTraces)
+	r.httpServer = httpServer{
+		cfg:               cfg,
+		settings:          set,
+		handler:           mux,
+		onDecompressError: r.handleDecompressError,
+	}
+
+	return r
+}
+
+func (r *otlpHTTPReceiver) handleTraces(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeResponse(w, "text/plain", http.StatusMethodNotAllowed, []byte(fmt.Sprintf("%d method not allowed, supported: [POST]", http.StatusMethodNotAllowed)))
+		return
+	}
+
+	enc := encodingFor(req)
+	if enc == nil {
+		metricReceiverDecodeErrors.WithLabelValues(r.name, decodeErrorContentType).Inc()
+		writeResponse(w, "text/plain", http.StatusUnsupportedMediaType, []byte(fmt.Sprintf("%d unsupported media type, supported: [%s, %s]", http.StatusUnsupportedMediaType, jsonContentType, pbContentType)))
+		return
+	}
+
+	body, reason, err := readBody(req, r.cfg.MaxRequestBodySize)
+	if err != nil {
+		metricReceiverDecodeErrors.WithLabelValues(r.name, reason).Inc()
+		statusCode := http.StatusBadRequest
+		if reason == decodeErrorTooLarge {
+			statusCode = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, enc, status.New(codes.InvalidArgument, err.Error()), statusCode)
+		return
+	}
+
+	td, err := enc.unmarshaler.UnmarshalTraces(body)
+	if err != nil {
+		metricReceiverDecodeErrors.WithLabelValues(r.name, decodeErrorBody).Inc()
+		writeError(w, enc, status.New(codes.InvalidArgument, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	spanCount := td.SpanCount()
+	if spanCount > 0 {
+		ctx := r.obsrecv.StartTracesOp(contextWithOrgID(req))
+		err = r.next.ConsumeTraces(ctx, td)
+		r.obsrecv.EndTracesOp(ctx, enc.format, spanCount, err)
+		if err != nil {
+			s, _ := status.FromError(err)
+			writeError(w, enc, s, httpStatusFromCode(s.Code()))
+			return
+		}
+	}
+
+	writeResponse(w, enc.contentType, http.StatusOK, enc.emptyResponse)
+}
+
+// handleDecompressError is called by confighttp if a gzip or deflate body cannot be decompressed
+func (r *otlpHTTPReceiver) handleDecompressError(w http.ResponseWriter, req *http.Request, errMsg string, statusCode int) {
+	metricReceiverDecodeErrors.WithLabelValues(r.name, decodeErrorEncoding).Inc()
+
+	enc := encodingFor(req)
+	if enc == nil {
+		enc = jsonEncoding
+	}
+	writeError(w, enc, status.New(codes.InvalidArgument, errMsg), statusCode)
+}
+
+func encodingFor(req *http.Request) *otlpEncoding {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+
+	switch mediaType {
+	case pbContentType, pbAltContentType:
+		return pbEncoding
+	case jsonContentType:
+		return jsonEncoding
+	}
+	return nil
+}
+
+// readBody reads the decompressed request body. If maxSize is set, bodies that decompress to more than maxSize bytes
+// are refused. On failure the reason is returned for the decode errors metric.
+func readBody(req *http.Request, maxSize int64) ([]byte, string, error) {
+	defer req.Body.Close()
+
+	var body io.Reader = req.Body
+
+	// gzip and deflate were already handled by confighttp and the header removed
+	switch encoding := req.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+	case "zstd":
+		zr, err := zstd.NewReader(req.Body)
+		if err != nil {
+			return nil, decodeErrorEncoding, err
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		return nil, decodeErrorEncoding, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	if maxSize > 0 {
+		body = io.LimitReader(body, maxSize+1)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		// http.MaxBytesReader is applied by confighttp to the compressed body
+		if util.IsRequestBodyTooLarge(err) {
+			return nil, decodeErrorTooLarge, errRequestTooLarge
+		}
+		if req.Header.Get("Content-Encoding") == "zstd" {
+			return nil, decodeErrorEncoding, err
+		}
+		return nil, decodeErrorBody, err
+	}
+
+	if maxSize > 0 && int64(len(b)) > maxSize {
+		return nil, decodeErrorTooLarge, errRequestTooLarge
+	}
+
+	return b, "", nil
+}
+
+// contextWithOrgID copies the X-Scope-OrgID header into the incoming gRPC metadata so that it is found by
+// the MultiTenancyMiddleware like for the gRPC receivers.
+func contextWithOrgID(req *http.Request) context.Context {
+	ctx := req.Context()
+
+	orgID := req.Header.Get(user.OrgIDHeaderName)
+	if orgID == "" {
+		return ctx
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Set(user.OrgIDHeaderName, orgID)
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return http.StatusUnauthorized
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError encodes the error as a google.rpc.Status as required by the OTLP protocol
+func writeError(w http.ResponseWriter, enc *otlpEncoding, s *status.Status, statusCode int) {
+	msg, err := enc.marshalStatus(s.Proto())
+	if err != nil {
+		writeResponse(w, "text/plain", http.StatusInternalServerError, []byte(err.Error()))
+		return
+	}
+	writeResponse(w, enc.contentType, statusCode, msg)
+}
+
+func writeResponse(w http.ResponseWriter, contentType string, statusCode int, msg []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	// nothing we can do if we cannot write the response
+	_, _ = w.Write(msg)
+}
@@ -0,0 +1,215 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/status"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+type otlpHTTPTestCase struct {
+	name            string
+	contentType     string
+	contentEncoding string
+	body            []byte
+	expectedStatus  int
+	expectedReason  string
+}
+
+func TestOTLPHTTPReceiver(t *testing.T) {
+	td := testTraces()
+	pbBody, err := otlp.NewProtobufTracesMarshaler().MarshalTraces(td)
+	require.NoError(t, err)
+	jsonBody, err := otlp.NewJSONTracesMarshaler().MarshalTraces(td)
+	require.NoError(t, err)
+
+	tests := []otlpHTTPTestCase{
+		{name: "protobuf", contentType: pbContentType, body: pbBody, expectedStatus: http.StatusOK},
+		{name: "protobuf alt content type", contentType: pbAltContentType, body: pbBody, expectedStatus: http.StatusOK},
+		{name: "json", contentType: jsonContentType, body: jsonBody, expectedStatus: http.StatusOK},
+		{name: "json with charset", contentType: jsonContentType + "; charset=utf-8", body: jsonBody, expectedStatus: http.StatusOK},
+		{name: "protobuf gzip", contentType: pbContentType, contentEncoding: "gzip", body: gzipBytes(t, pbBody), expectedStatus: http.StatusOK},
+		{name: "json gzip", contentType: jsonContentType, contentEncoding: "gzip", body: gzipBytes(t, jsonBody), expectedStatus: http.StatusOK},
+		{name: "protobuf zstd", contentType: pbContentType, contentEncoding: "zstd", body: zstdBytes(t, pbBody), expectedStatus: http.StatusOK},
+		{name: "json zstd", contentType: jsonContentType, contentEncoding: "zstd", body: zstdBytes(t, jsonBody), expectedStatus: http.StatusOK},
+		{name: "unknown content type", contentType: "text/plain", body: jsonBody, expectedStatus: http.StatusUnsupportedMediaType, expectedReason: decodeErrorContentType},
+		{name: "unknown content encoding", contentType: pbContentType, contentEncoding: "br", body: pbBody, expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorEncoding},
+		{name: "corrupt gzip", contentType: pbContentType, contentEncoding: "gzip", body: pbBody, expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorEncoding},
+		{name: "corrupt zstd", contentType: pbContentType, contentEncoding: "zstd", body: pbBody, expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorEncoding},
+		{name: "corrupt json", contentType: jsonContentType, body: []byte("{"), expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorBody},
+		{name: "corrupt protobuf", contentType: pbContentType, body: []byte{0xff, 0xff}, expectedStatus: http.StatusBadRequest, expectedReason: decodeErrorBody},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			consumed := 0
+			r := newTestOTLPHTTPReceiver(t, &confighttp.HTTPServerSettings{}, ConsumeTracesFunc(func(ctx context.Context, actual pdata.Traces) error {
+				consumed++
+				assert.Equal(t, td.SpanCount(), actual.SpanCount())
+				return nil
+			}))
+
+			testOTLPHTTPRequest(t, r, tc)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, 1, consumed)
+			} else {
+				assert.Equal(t, 0, consumed)
+			}
+		})
+	}
+}
+
+func TestOTLPHTTPReceiverMaxRequestBodySize(t *testing.T) {
+	pbBody, err := otlp.NewProtobufTracesMarshaler().MarshalTraces(testTraces())
+	require.NoError(t, err)
+
+	// a body that compresses well is used to check the limit is enforced on the decompressed size
+	large := bytes.Repeat(pbBody, 100)
+	cfg := &confighttp.HTTPServerSettings{MaxRequestBodySize: int64(len(pbBody) * 10)}
+
+	tests := []otlpHTTPTestCase{
+		{name: "within limit", contentType: pbContentType, body: pbBody, expectedStatus: http.StatusOK},
+		{name: "too large", contentType: pbContentType, body: large, expectedStatus: http.StatusRequestEntityTooLarge, expectedReason: decodeErrorTooLarge},
+		{name: "too large gzip", contentType: pbContentType, contentEncoding: "gzip", body: gzipBytes(t, large), expectedStatus: http.StatusRequestEntityTooLarge, expectedReason: decodeErrorTooLarge},
+		{name: "too large zstd", contentType: pbContentType, contentEncoding: "zstd", body: zstdBytes(t, large), expectedStatus: http.StatusRequestEntityTooLarge, expectedReason: decodeErrorTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestOTLPHTTPReceiver(t, cfg, ConsumeTracesFunc(func(context.Context, pdata.Traces) error { return nil }))
+			testOTLPHTTPRequest(t, r, tc)
+		})
+	}
+}
+
+func TestOTLPHTTPReceiverTenantAndErrors(t *testing.T) {
+	pbBody, err := otlp.NewProtobufTracesMarshaler().MarshalTraces(testTraces())
+	require.NoError(t, err)
+
+	var consumeErr error
+	r := newTestOTLPHTTPReceiver(t, &confighttp.HTTPServerSettings{}, MultiTenancyMiddleware().Wrap(ConsumeTracesFunc(func(ctx context.Context, _ pdata.Traces) error {
+		orgID, err := user.ExtractOrgID(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant", orgID)
+		return consumeErr
+	})))
+
+	server := httptest.NewServer(r.server.Handler)
+	defer server.Close()
+
+	push := func(orgID string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+otlpHTTPTracesPath, bytes.NewReader(pbBody))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", pbContentType)
+		if orgID != "" {
+			req.Header.Set(user.OrgIDHeaderName, orgID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, push("tenant"))
+	assert.Equal(t, http.StatusInternalServerError, push(""))
+
+	consumeErr = status.Error(codes.ResourceExhausted, "rate limited")
+	assert.Equal(t, http.StatusTooManyRequests, push("tenant"))
+}
+
+func TestOTLPFactory(t *testing.T) {
+	f := newOTLPFactory()
+	cfg := f.CreateDefaultConfig()
+
+	// the default config enables grpc and http
+	r, err := f.CreateTracesReceiver(context.Background(), testReceiverCreateSettings(), cfg, ConsumeTracesFunc(func(context.Context, pdata.Traces) error { return nil }))
+	require.NoError(t, err)
+	require.IsType(t, multiReceiver{}, r)
+	require.Len(t, r.(multiReceiver), 2)
+	require.IsType(t, &otlpHTTPReceiver{}, r.(multiReceiver)[0])
+}
+
+func newTestOTLPHTTPReceiver(t *testing.T, cfg *confighttp.HTTPServerSettings, next consumer.Traces) *otlpHTTPReceiver {
+	r := newOTLPHTTPReceiver(config.NewComponentIDWithName("otlp", t.Name()), cfg, testReceiverCreateSettings(), next)
+
+	var err error
+	r.server, err = r.newServer(&receiversShim{})
+	require.NoError(t, err)
+
+	return r
+}
+
+func testOTLPHTTPRequest(t *testing.T, r *otlpHTTPReceiver, tc otlpHTTPTestCase) {
+	server := httptest.NewServer(r.server.Handler)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+otlpHTTPTracesPath, bytes.NewReader(tc.body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", tc.contentType)
+	if tc.contentEncoding != "" {
+		req.Header.Set("Content-Encoding", tc.contentEncoding)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+	if tc.expectedReason != "" {
+		assert.Equal(t, 1.0, testutil.ToFloat64(metricReceiverDecodeErrors.WithLabelValues(r.name, tc.expectedReason)))
+	}
+}
+
+func testReceiverCreateSettings() component.ReceiverCreateSettings {
+	return component.ReceiverCreateSettings{TelemetrySettings: component.TelemetrySettings{
+		Logger:         zap.NewNop(),
+		TracerProvider: trace.NewNoopTracerProvider(),
+		MeterProvider:  metric.NewNoopMeterProvider(),
+	}}
+}
+
+func testTraces() pdata.Traces {
+	td := pdata.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().InsertString("service.name", "test")
+	span := rs.InstrumentationLibrarySpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(pdata.NewTraceID([16]byte{0x01, 0x02}))
+	span.SetSpanID(pdata.NewSpanID([8]byte{0x01}))
+	span.SetName("test")
+	return td
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, b []byte) []byte {
+	w, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer w.Close()
+	return w.EncodeAll(b, nil)
+}
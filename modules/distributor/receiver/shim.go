@@ -10,10 +10,8 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/services"
 	zaplogfmt "github.com/jsternberg/zap-logfmt"
-	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/jaegerreceiver"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/kafkareceiver"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/opencensusreceiver"
-	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/zipkinreceiver"
 	"github.com/opentracing/opentracing-go"
 	prom_client "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -28,7 +26,6 @@ import (
 	"go.opentelemetry.io/collector/external/obsreportconfig"
 	"go.opentelemetry.io/collector/model/otlp"
 	"go.opentelemetry.io/collector/model/pdata"
-	"go.opentelemetry.io/collector/receiver/otlpreceiver"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
@@ -86,10 +83,10 @@ func New(receiverCfg map[string]interface{}, pusher BatchPusher, middleware Midd
 
 	// load config
 	receiverFactories, err := component.MakeReceiverFactoryMap(
-		jaegerreceiver.NewFactory(),
-		zipkinreceiver.NewFactory(),
+		newJaegerFactory(),
+		newZipkinFactory(),
 		opencensusreceiver.NewFactory(),
-		otlpreceiver.NewFactory(),
+		newOTLPFactory(),
 		kafkareceiver.NewFactory(),
 	)
 	if err != nil {
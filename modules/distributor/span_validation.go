@@ -0,0 +1,199 @@
+package distributor
+
+import (
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/model/trace"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/log"
+)
+
+const (
+	// reasonZeroTraceID indicates that a span was received with an empty or all zero trace id
+	reasonZeroTraceID = "zero_trace_id"
+	// reasonInvalidTraceIDLength indicates that a span was received with a trace id that is not 128 bits
+	reasonInvalidTraceIDLength = "invalid_trace_id_length"
+	// reasonEndBeforeStart indicates that a span was received with an end time before its start time
+	reasonEndBeforeStart = "end_before_start"
+	// reasonMissingServiceName indicates that a span was received in a batch without a service.name resource attribute
+	reasonMissingServiceName = "missing_service_name"
+
+	// unknownServiceName is the service name assigned to batches without one when repairing. It matches
+	// the default used by the OpenTelemetry SDKs.
+	unknownServiceName = "unknown_service"
+
+	traceIDLength = 16
+)
+
+var metricSpanValidationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "distributor_span_validation_failures_total",
+	Help:      "The total number of spans that failed validation per tenant, reason and the action taken.",
+}, []string{"tenant", "reason", "action"})
+
+var metricSpanValidationUnknownMode = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "distributor_span_validation_unknown_mode_total",
+	Help:      "The total number of requests that were not validated because the span validation mode of the tenant is unknown.",
+}, []string{"tenant"})
+
+// unknownModeLogger looks up the global logger on every log, the global logger is replaced once it is initialized
+var unknownModeLogger = log.NewRateLimitedLogger(1, kitlog.LoggerFunc(func(keyvals ...interface{}) error {
+	return level.Warn(log.Logger).Log(keyvals...)
+}))
+
+// validateBatches checks every span for malformed trace ids, timestamps and a missing service.name and, depending on
+// the mode, drops or repairs them in place. It returns the batches to continue with and the number of spans dropped
+// per reason.
+func validateBatches(batches []*v1.ResourceSpans, mode string, userID string) ([]*v1.ResourceSpans, map[string]int) {
+	switch mode {
+	case overrides.SpanValidationReject, overrides.SpanValidationRepair:
+	case overrides.SpanValidationDisabled, "":
+		return batches, nil
+	default:
+		// unknown modes are refused when the overrides are loaded, count them in case one slips through
+		metricSpanValidationUnknownMode.WithLabelValues(userID).Inc()
+		unknownModeLogger.Log("msg", "unknown span validation mode, spans are not validated", "tenant", userID, "mode", mode)
+		return batches, nil
+	}
+
+	repair := mode == overrides.SpanValidationRepair
+
+	var dropped map[string]int
+	recordFailure := func(reason string, drop bool, n int) {
+		if !drop {
+			metricSpanValidationFailures.WithLabelValues(userID, reason, "repaired").Add(float64(n))
+			return
+		}
+		if dropped == nil {
+			dropped = map[string]int{}
+		}
+		dropped[reason] += n
+		metricSpanValidationFailures.WithLabelValues(userID, reason, "rejected").Add(float64(n))
+	}
+
+	validBatches := batches[:0]
+	for _, b := range batches {
+		if !hasServiceName(b.Resource) {
+			spanCount := 0
+			for _, ils := range b.InstrumentationLibrarySpans {
+				spanCount += len(ils.Spans)
+			}
+			if spanCount > 0 {
+				if !repair {
+					recordFailure(reasonMissingServiceName, true, spanCount)
+					continue
+				}
+				recordFailure(reasonMissingServiceName, false, spanCount)
+				b.Resource = withUnknownServiceName(b.Resource)
+			}
+		}
+
+		for _, ils := range b.InstrumentationLibrarySpans {
+			validSpans := ils.Spans[:0]
+			for _, s := range ils.Spans {
+				reason, ok := validateSpan(s, repair)
+				if !ok {
+					recordFailure(reason, true, 1)
+					continue
+				}
+				if reason != "" {
+					recordFailure(reason, false, 1)
+				}
+				validSpans = append(validSpans, s)
+			}
+			ils.Spans = validSpans
+		}
+
+		validBatches = append(validBatches, b)
+	}
+
+	return validBatches, dropped
+}
+
+// validateSpan checks a single span. If the span is invalid it returns the reason. The bool is false if
+// the span must be dropped and true if it is valid or has been repaired.
+func validateSpan(s *v1.Span, repair bool) (string, bool) {
+	if isZeroTraceID(s.TraceId) {
+		// there is nothing to recover from an id that carries no information
+		return reasonZeroTraceID, false
+	}
+
+	reason := ""
+	if len(s.TraceId) != traceIDLength {
+		if !repair || len(s.TraceId) > traceIDLength {
+			return reasonInvalidTraceIDLength, false
+		}
+		s.TraceId = padTraceID(s.TraceId)
+		reason = reasonInvalidTraceIDLength
+	}
+
+	if s.EndTimeUnixNano < s.StartTimeUnixNano {
+		if !repair {
+			return reasonEndBeforeStart, false
+		}
+		if s.EndTimeUnixNano == 0 {
+			// clamp a missing end to the start so the span has a zero duration
+			s.EndTimeUnixNano = s.StartTimeUnixNano
+		} else {
+			s.StartTimeUnixNano, s.EndTimeUnixNano = s.EndTimeUnixNano, s.StartTimeUnixNano
+		}
+		reason = reasonEndBeforeStart
+	}
+
+	return reason, true
+}
+
+// padTraceID left pads the trace id with zeroes to 128 bits. This matches how 64 bit jaeger trace ids are
+// represented in 128 bits.
+func padTraceID(id []byte) []byte {
+	padded := make([]byte, traceIDLength)
+	copy(padded[traceIDLength-len(id):], id)
+	return padded
+}
+
+func isZeroTraceID(id []byte) bool {
+	for _, b := range id {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hasServiceName(r *v1_resource.Resource) bool {
+	if r == nil {
+		return false
+	}
+	for _, a := range r.Attributes {
+		if a.Key == trace.ServiceNameTag {
+			s, ok := extractValueAsString(a.Value)
+			return ok && s != ""
+		}
+	}
+	return false
+}
+
+// withUnknownServiceName sets service.name to unknownServiceName, replacing an existing empty value.
+func withUnknownServiceName(r *v1_resource.Resource) *v1_resource.Resource {
+	if r == nil {
+		r = &v1_resource.Resource{}
+	}
+
+	value := &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: unknownServiceName}}
+	for _, a := range r.Attributes {
+		if a.Key == trace.ServiceNameTag {
+			a.Value = value
+			return r
+		}
+	}
+
+	r.Attributes = append(r.Attributes, &v1_common.KeyValue{Key: trace.ServiceNameTag, Value: value})
+	return r
+}
@@ -0,0 +1,141 @@
+package distributor
+
+import (
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/modules/overrides"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+func TestValidateBatches(t *testing.T) {
+	validID := []byte{0x0A, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F}
+	shortID := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	paddedID := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	longID := append(validID, 0x01)
+	zeroID := make([]byte, 16)
+
+	batch := func(serviceName bool, spans ...*v1.Span) *v1.ResourceSpans {
+		rs := &v1.ResourceSpans{
+			InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: spans}},
+		}
+		if serviceName {
+			rs.Resource = &v1_resource.Resource{Attributes: []*v1_common.KeyValue{makeAttribute("service.name", "svc")}}
+		}
+		return rs
+	}
+	span := func(id []byte, start, end uint64) *v1.Span {
+		return &v1.Span{TraceId: id, StartTimeUnixNano: start, EndTimeUnixNano: end}
+	}
+
+	tests := []struct {
+		name            string
+		mode            string
+		batches         []*v1.ResourceSpans
+		expectedBatches []*v1.ResourceSpans
+		expectedDropped map[string]int
+	}{
+		{
+			name:            "disabled",
+			mode:            overrides.SpanValidationDisabled,
+			batches:         []*v1.ResourceSpans{batch(false, span(zeroID, 2, 1))},
+			expectedBatches: []*v1.ResourceSpans{batch(false, span(zeroID, 2, 1))},
+		},
+		{
+			name:            "unknown mode",
+			mode:            "rejct",
+			batches:         []*v1.ResourceSpans{batch(false, span(zeroID, 2, 1))},
+			expectedBatches: []*v1.ResourceSpans{batch(false, span(zeroID, 2, 1))},
+		},
+		{
+			name:            "reject valid",
+			mode:            overrides.SpanValidationReject,
+			batches:         []*v1.ResourceSpans{batch(true, span(validID, 1, 2))},
+			expectedBatches: []*v1.ResourceSpans{batch(true, span(validID, 1, 2))},
+		},
+		{
+			name: "reject",
+			mode: overrides.SpanValidationReject,
+			batches: []*v1.ResourceSpans{
+				batch(true, span(validID, 1, 2), span(zeroID, 1, 2), span(nil, 1, 2), span(shortID, 1, 2), span(validID, 2, 1)),
+				batch(false, span(validID, 1, 2), span(validID, 1, 2)),
+			},
+			expectedBatches: []*v1.ResourceSpans{batch(true, span(validID, 1, 2))},
+			expectedDropped: map[string]int{
+				reasonZeroTraceID:          2,
+				reasonInvalidTraceIDLength: 1,
+				reasonEndBeforeStart:       1,
+				reasonMissingServiceName:   2,
+			},
+		},
+		{
+			name: "repair",
+			mode: overrides.SpanValidationRepair,
+			batches: []*v1.ResourceSpans{
+				batch(true, span(validID, 1, 2), span(zeroID, 1, 2), span(longID, 1, 2), span(shortID, 1, 2), span(validID, 2, 1), span(validID, 2, 0)),
+			},
+			expectedBatches: []*v1.ResourceSpans{
+				batch(true, span(validID, 1, 2), span(paddedID, 1, 2), span(validID, 1, 2), span(validID, 2, 2)),
+			},
+			expectedDropped: map[string]int{
+				reasonZeroTraceID:          1,
+				reasonInvalidTraceIDLength: 1,
+			},
+		},
+		{
+			name:    "repair service name",
+			mode:    overrides.SpanValidationRepair,
+			batches: []*v1.ResourceSpans{batch(false, span(validID, 1, 2))},
+			expectedBatches: []*v1.ResourceSpans{
+				{
+					Resource:                    &v1_resource.Resource{Attributes: []*v1_common.KeyValue{makeAttribute("service.name", unknownServiceName)}},
+					InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{span(validID, 1, 2)}}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknownModeBefore := testutil.ToFloat64(metricSpanValidationUnknownMode.WithLabelValues("test"))
+
+			actual, dropped := validateBatches(tt.batches, tt.mode, "test")
+			assert.Equal(t, tt.expectedBatches, actual)
+			assert.Equal(t, tt.expectedDropped, dropped)
+
+			// unknown modes are counted
+			unknownMode := testutil.ToFloat64(metricSpanValidationUnknownMode.WithLabelValues("test")) - unknownModeBefore
+			assert.Equal(t, tt.mode == "rejct", unknownMode == 1)
+		})
+	}
+}
+
+func TestDistributorSpanValidation(t *testing.T) {
+	shortID := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	batches := func() []*v1.ResourceSpans {
+		return []*v1.ResourceSpans{
+			makeResourceSpans("test", []*v1.InstrumentationLibrarySpans{
+				{Spans: []*v1.Span{{TraceId: shortID, StartTimeUnixNano: 1, EndTimeUnixNano: 2}}},
+			}),
+		}
+	}
+
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+	d := prepare(t, limits, nil, nil)
+
+	_, err := d.PushBatches(ctx, batches())
+	require.Error(t, err)
+
+	limits.SpanValidation = overrides.SpanValidationRepair
+	d = prepare(t, limits, nil, nil)
+
+	_, err = d.PushBatches(ctx, batches())
+	require.NoError(t, err)
+}
@@ -0,0 +1,362 @@
+package distributor
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/go-logfmt/logfmt"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+)
+
+const (
+	urlParamTapTenant   = "tenant"
+	urlParamTapFilter   = "filter"
+	urlParamTapDuration = "duration"
+	urlParamTapRate     = "rate"
+
+	defaultTapDuration = time.Minute
+	defaultTapRate     = 10
+
+	// tapBufferSize is the number of spans that can wait to be written to a tap. spans are dropped if the
+	// client does not keep up.
+	tapBufferSize = 100
+
+	// special filter keys, all other keys are matched against span and resource attributes
+	tapFilterName    = "name"
+	tapFilterStatus  = "status"
+	tapFilterTraceID = "trace_id"
+)
+
+var (
+	metricTapsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "distributor_taps_active",
+		Help:      "The current number of active debug taps.",
+	})
+	metricTapSpans = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_tap_spans_total",
+		Help:      "The total number of spans matching a debug tap per tenant and whether they were sent or dropped.",
+	}, []string{"tenant", "result"})
+)
+
+// TapConfig configures the debug tap endpoint
+type TapConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	MaxConcurrent     int           `yaml:"max_concurrent"`
+	MaxDuration       time.Duration `yaml:"max_duration"`
+	MaxSpansPerSecond float64       `yaml:"max_spans_per_second"`
+}
+
+// RegisterFlagsAndApplyDefaults registers flags and applies defaults
+func (cfg *TapConfig) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, util.PrefixConfig(prefix, "enabled"), false, "Enable the /distributor/tap endpoint to stream a sample of received spans.")
+	f.IntVar(&cfg.MaxConcurrent, util.PrefixConfig(prefix, "max-concurrent"), 5, "Maximum number of concurrent taps per distributor.")
+	f.DurationVar(&cfg.MaxDuration, util.PrefixConfig(prefix, "max-duration"), 5*time.Minute, "Maximum duration of a tap.")
+	f.Float64Var(&cfg.MaxSpansPerSecond, util.PrefixConfig(prefix, "max-spans-per-second"), 100, "Maximum number of spans per second sent to a tap.")
+}
+
+// tap is a single client watching the spans of one tenant
+type tap struct {
+	tenant  string
+	filter  tapFilter
+	limiter *rate.Limiter
+	spans   chan []byte
+}
+
+// tapper keeps track of the active taps and offers them received spans
+type tapper struct {
+	cfg TapConfig
+
+	mtx    sync.RWMutex
+	taps   map[*tap]struct{}
+	active atomic.Int32
+}
+
+func newTapper(cfg TapConfig) *tapper {
+	return &tapper{
+		cfg:  cfg,
+		taps: map[*tap]struct{}{},
+	}
+}
+
+func (t *tapper) add(tp *tap) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if len(t.taps) >= t.cfg.MaxConcurrent {
+		return false
+	}
+
+	t.taps[tp] = struct{}{}
+	t.active.Store(int32(len(t.taps)))
+	metricTapsActive.Set(float64(len(t.taps)))
+	return true
+}
+
+func (t *tapper) remove(tp *tap) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	delete(t.taps, tp)
+	t.active.Store(int32(len(t.taps)))
+	metricTapsActive.Set(float64(len(t.taps)))
+}
+
+// offer sends the spans matching each tap of the tenant, within the rate limit of the tap. This is on the push path
+// and must never block.
+func (t *tapper) offer(userID string, batches []*v1.ResourceSpans) {
+	if t.active.Load() == 0 {
+		return
+	}
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	for tp := range t.taps {
+		if tp.tenant != userID {
+			continue
+		}
+
+		for _, b := range batches {
+			for _, ils := range b.InstrumentationLibrarySpans {
+				for _, s := range ils.Spans {
+					if !tp.filter.matches(b, s) {
+						continue
+					}
+					if !tp.limiter.Allow() {
+						metricTapSpans.WithLabelValues(userID, "rate_limited").Inc()
+						continue
+					}
+
+					line, err := marshalTapSpan(b, ils, s)
+					if err != nil {
+						continue
+					}
+
+					select {
+					case tp.spans <- line:
+						metricTapSpans.WithLabelValues(userID, "sent").Inc()
+					default:
+						metricTapSpans.WithLabelValues(userID, "dropped").Inc()
+					}
+				}
+			}
+		}
+	}
+}
+
+// TapHandler streams a rate limited sample of the received spans of a tenant matching a filter as newline
+// delimited json. The tap stops when the duration has passed or the client disconnects.
+func (d *Distributor) TapHandler(w http.ResponseWriter, r *http.Request) {
+	if !d.cfg.Tap.Enabled {
+		http.Error(w, "tap is not enabled", http.StatusNotFound)
+		return
+	}
+
+	orgID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tp, duration, err := d.parseTapRequest(r, orgID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if !d.tapper.add(tp) {
+		http.Error(w, fmt.Sprintf("too many active taps, max %d", d.cfg.Tap.MaxConcurrent), http.StatusTooManyRequests)
+		return
+	}
+	defer d.tapper.remove(tp)
+
+	level.Info(d.logger).Log("msg", "tap started", "tenant", tp.tenant, "duration", duration, "filter", r.URL.Query().Get(urlParamTapFilter))
+	defer level.Info(d.logger).Log("msg", "tap stopped", "tenant", tp.tenant)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+			return
+		case line := <-tp.spans:
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (d *Distributor) parseTapRequest(r *http.Request, orgID string) (*tap, time.Duration, error) {
+	q := r.URL.Query()
+
+	tenant := q.Get(urlParamTapTenant)
+	if tenant == "" {
+		tenant = orgID
+	}
+	if tenant != orgID {
+		return nil, 0, fmt.Errorf("tenant %s does not match the tenant of the request", tenant)
+	}
+
+	filter, err := parseTapFilter(q.Get(urlParamTapFilter))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	duration := defaultTapDuration
+	if s := q.Get(urlParamTapDuration); s != "" {
+		duration, err = time.ParseDuration(s)
+		if err != nil || duration <= 0 {
+			return nil, 0, fmt.Errorf("invalid duration: %s", s)
+		}
+	}
+	if d.cfg.Tap.MaxDuration > 0 && duration > d.cfg.Tap.MaxDuration {
+		duration = d.cfg.Tap.MaxDuration
+	}
+
+	spansPerSecond := float64(defaultTapRate)
+	if s := q.Get(urlParamTapRate); s != "" {
+		spansPerSecond, err = strconv.ParseFloat(s, 64)
+		if err != nil || spansPerSecond <= 0 {
+			return nil, 0, fmt.Errorf("invalid rate: %s", s)
+		}
+	}
+	if d.cfg.Tap.MaxSpansPerSecond > 0 && spansPerSecond > d.cfg.Tap.MaxSpansPerSecond {
+		spansPerSecond = d.cfg.Tap.MaxSpansPerSecond
+	}
+
+	burst := int(spansPerSecond)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tap{
+		tenant:  tenant,
+		filter:  filter,
+		limiter: rate.NewLimiter(rate.Limit(spansPerSecond), burst),
+		spans:   make(chan []byte, tapBufferSize),
+	}, duration, nil
+}
+
+// tapFilter matches spans where all keys have the given value
+type tapFilter map[string]string
+
+// parseTapFilter parses a logfmt encoded filter like the tags of the search api, e.g. `name="GET /api" status=error`
+func parseTapFilter(s string) (tapFilter, error) {
+	filter := tapFilter{}
+
+	decoder := logfmt.NewDecoder(strings.NewReader(s))
+	for decoder.ScanRecord() {
+		for decoder.ScanKeyval() {
+			filter[string(decoder.Key())] = string(decoder.Value())
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	if status, ok := filter[tapFilterStatus]; ok {
+		switch status {
+		case "ok", "error", "unset":
+		default:
+			return nil, fmt.Errorf("invalid filter: status must be one of ok, error or unset")
+		}
+	}
+
+	return filter, nil
+}
+
+func (f tapFilter) matches(rs *v1.ResourceSpans, s *v1.Span) bool {
+	for k, v := range f {
+		switch k {
+		case tapFilterName:
+			if s.Name != v {
+				return false
+			}
+		case tapFilterStatus:
+			if statusString(s.Status) != v {
+				return false
+			}
+		case tapFilterTraceID:
+			if equal, err := util.EqualHexStringTraceIDs(util.TraceIDToHexString(s.TraceId), v); err != nil || !equal {
+				return false
+			}
+		default:
+			if !hasAttribute(s.Attributes, k, v) && (rs.Resource == nil || !hasAttribute(rs.Resource.Attributes, k, v)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func statusString(s *v1.Status) string {
+	switch s.GetCode() {
+	case v1.Status_STATUS_CODE_OK:
+		return "ok"
+	case v1.Status_STATUS_CODE_ERROR:
+		return "error"
+	}
+	return "unset"
+}
+
+func hasAttribute(attrs []*v1_common.KeyValue, key, value string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value != nil && util.StringifyAnyValue(a.Value) == value
+		}
+	}
+	return false
+}
+
+// marshalTapSpan returns the span with its resource and instrumentation library as a line of json
+func marshalTapSpan(rs *v1.ResourceSpans, ils *v1.InstrumentationLibrarySpans, s *v1.Span) ([]byte, error) {
+	single := &v1.ResourceSpans{
+		Resource: rs.Resource,
+		InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{
+			InstrumentationLibrary: ils.InstrumentationLibrary,
+			Spans:                  []*v1.Span{s},
+		}},
+	}
+
+	buf := &bytes.Buffer{}
+	m := &jsonpb.Marshaler{}
+	if err := m.Marshal(buf, single); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,175 @@
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/tempo/modules/overrides"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+func TestParseTapFilter(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected tapFilter
+		err      bool
+	}{
+		{filter: "", expected: tapFilter{}},
+		{filter: `name="GET /api" status=error`, expected: tapFilter{"name": "GET /api", "status": "error"}},
+		{filter: "http.status_code=500 trace_id=1234", expected: tapFilter{"http.status_code": "500", "trace_id": "1234"}},
+		{filter: "status=failed", err: true},
+		{filter: `name="unterminated`, err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			actual, err := parseTapFilter(tc.filter)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTapFilterMatches(t *testing.T) {
+	span := makeSpan("0a0102030405060708090a0b0c0d0e0f", "dad44adc9a83b370", &v1.Status{Code: v1.Status_STATUS_CODE_ERROR}, makeAttribute("http.method", "GET"))
+	span.Name = "GET /api"
+	rs := makeResourceSpans("test-service", []*v1.InstrumentationLibrarySpans{makeInstrumentationLibrary(span)}, makeAttribute("cluster", "prod"))
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{filter: "", expected: true},
+		{filter: `name="GET /api"`, expected: true},
+		{filter: `name="POST /api"`, expected: false},
+		{filter: "status=error", expected: true},
+		{filter: "status=ok", expected: false},
+		{filter: "trace_id=a0102030405060708090a0b0c0d0e0f", expected: true},
+		{filter: "trace_id=1234", expected: false},
+		{filter: "http.method=GET", expected: true},
+		{filter: "service.name=test-service cluster=prod", expected: true},
+		{filter: "cluster=dev", expected: false},
+		{filter: "missing=value", expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := parseTapFilter(tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, filter.matches(rs, span))
+		})
+	}
+}
+
+func TestTapHandler(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+	d := prepare(t, limits, nil, nil)
+
+	// disabled
+	rec := httptest.NewRecorder()
+	d.TapHandler(rec, httptest.NewRequest(http.MethodGet, "/distributor/tap", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	d.cfg.Tap = TapConfig{Enabled: true, MaxConcurrent: 1, MaxDuration: time.Minute, MaxSpansPerSecond: 100}
+	d.tapper = newTapper(d.cfg.Tap)
+
+	// tenant mismatch
+	rec = httptest.NewRecorder()
+	d.TapHandler(rec, httptest.NewRequest(http.MethodGet, "/distributor/tap?tenant=other", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// invalid duration
+	rec = httptest.NewRecorder()
+	d.TapHandler(rec, httptest.NewRequest(http.MethodGet, "/distributor/tap?duration=forever", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// stream the matching spans until the client goes away
+	tapCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rec = httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.TapHandler(rec, httptest.NewRequest(http.MethodGet, "/distributor/tap?filter=status%3Derror", nil).WithContext(tapCtx))
+	}()
+
+	require.Eventually(t, func() bool { return d.tapper.active.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// only one tap is allowed
+	second := httptest.NewRecorder()
+	d.TapHandler(second, httptest.NewRequest(http.MethodGet, "/distributor/tap", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+
+	errorSpan := makeSpan("0a0102030405060708090a0b0c0d0e0f", "dad44adc9a83b370", &v1.Status{Code: v1.Status_STATUS_CODE_ERROR})
+	okSpan := makeSpan("0a0102030405060708090a0b0c0d0e0f", "dad44adc9a83b371", nil)
+
+	// spans of other tenants are never sent
+	d.tapper.offer("other", []*v1.ResourceSpans{makeResourceSpans("test-service", []*v1.InstrumentationLibrarySpans{makeInstrumentationLibrary(errorSpan)})})
+
+	_, err := d.PushBatches(ctx, []*v1.ResourceSpans{makeResourceSpans("test-service", []*v1.InstrumentationLibrarySpans{makeInstrumentationLibrary(errorSpan, okSpan)})})
+	require.NoError(t, err)
+
+	// offer is synchronous, wait for the handler to write the span before disconnecting
+	require.Eventually(t, func() bool { return pendingTapSpans(d.tapper) == 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"spanId":"2tRK3JqDs3A="`)
+	assert.Equal(t, int32(0), d.tapper.active.Load())
+}
+
+func TestTapperOfferNeverBlocks(t *testing.T) {
+	tpr := newTapper(TapConfig{MaxConcurrent: 1})
+
+	filter, err := parseTapFilter("")
+	require.NoError(t, err)
+	tp := &tap{
+		tenant:  "test",
+		filter:  filter,
+		limiter: rate.NewLimiter(rate.Inf, 0),
+		spans:   make(chan []byte, 1),
+	}
+	require.True(t, tpr.add(tp))
+	require.False(t, tpr.add(&tap{}))
+
+	span := makeSpan("0a0102030405060708090a0b0c0d0e0f", "dad44adc9a83b370", nil)
+	batches := []*v1.ResourceSpans{makeResourceSpans("test-service", []*v1.InstrumentationLibrarySpans{makeInstrumentationLibrary(span, span, span)})}
+
+	// the buffer only holds one span, the others are dropped
+	tpr.offer("test", batches)
+	assert.Len(t, tp.spans, 1)
+
+	tpr.remove(tp)
+	assert.Equal(t, int32(0), tpr.active.Load())
+}
+
+// pendingTapSpans returns the number of spans waiting to be written to the active taps
+func pendingTapSpans(t *tapper) int {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	pending := 0
+	for tp := range t.taps {
+		pending += len(tp.spans)
+	}
+	return pending
+}
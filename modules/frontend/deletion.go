@@ -0,0 +1,62 @@
+package frontend
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb"
+)
+
+// tombstonesForRequest returns the deleted traces of the tenant of the request
+func tombstonesForRequest(reader tempodb.Reader, r *http.Request) (tempodb.Tombstones, error) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.Tombstones(r.Context(), tenantID)
+}
+
+// isSearch returns true if the request is a search, not a search of tags or tag values
+func isSearch(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, api.PathSearch)
+}
+
+// filterDeletedTraces removes the deleted traces from a successful search response
+func filterDeletedTraces(resp *http.Response, tombstones tempodb.Tombstones) (*http.Response, error) {
+	if resp == nil || resp.StatusCode != http.StatusOK || len(tombstones) == 0 {
+		return resp, nil
+	}
+
+	results := &tempopb.SearchResponse{}
+	err := jsonpb.Unmarshal(resp.Body, results)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	traces := results.Traces[:0]
+	for _, t := range results.Traces {
+		if !tombstones.HasHexString(t.TraceID) {
+			traces = append(traces, t)
+		}
+	}
+	results.Traces = traces
+
+	m := &jsonpb.Marshaler{}
+	body, err := m.MarshalToString(results)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader([]byte(body)))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
@@ -0,0 +1,62 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb"
+)
+
+func TestFilterDeletedTraces(t *testing.T) {
+	results := &tempopb.SearchResponse{
+		Traces: []*tempopb.TraceSearchMetadata{
+			{TraceID: "1"},
+			{TraceID: "2"},
+			{TraceID: "3"},
+		},
+		Metrics: &tempopb.SearchMetrics{InspectedTraces: 3},
+	}
+	m := &jsonpb.Marshaler{}
+	body, err := m.MarshalToString(results)
+	require.NoError(t, err)
+
+	resp, err := filterDeletedTraces(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}, tempodb.Tombstones{"2": {}})
+	require.NoError(t, err)
+
+	actual := &tempopb.SearchResponse{}
+	err = jsonpb.Unmarshal(resp.Body, actual)
+	require.NoError(t, err)
+	assert.Equal(t, []*tempopb.TraceSearchMetadata{{TraceID: "1"}, {TraceID: "3"}}, actual.Traces)
+	assert.Equal(t, uint32(3), actual.Metrics.InspectedTraces)
+
+	// unsuccessful responses are not filtered
+	resp = &http.Response{StatusCode: http.StatusInternalServerError}
+	actualResp, err := filterDeletedTraces(resp, tempodb.Tombstones{"2": {}})
+	require.NoError(t, err)
+	assert.Same(t, resp, actualResp)
+}
+
+func TestSearchResponseSkipsTombstones(t *testing.T) {
+	sr := newSearchResponse(context.Background(), 10)
+	sr.tombstones = tempodb.Tombstones{"2": {}}
+	sr.addResponse(&tempopb.SearchResponse{
+		Traces: []*tempopb.TraceSearchMetadata{
+			{TraceID: "1"},
+			{TraceID: "2"},
+		},
+		Metrics: &tempopb.SearchMetrics{},
+	})
+
+	assert.Equal(t, []*tempopb.TraceSearchMetadata{{TraceID: "1"}}, sr.result().Traces)
+}
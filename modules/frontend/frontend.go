@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/go-kit/log"
@@ -67,7 +68,7 @@ func New(cfg Config, next http.RoundTripper, o *overrides.Overrides, store stora
 	retryWare := newRetryWare(cfg.MaxRetries, registerer)
 
 	// tracebyid middleware
-	traceByIDMiddleware := MergeMiddlewares(newTraceByIDMiddleware(cfg, logger), retryWare)
+	traceByIDMiddleware := MergeMiddlewares(newTraceByIDMiddleware(cfg, store, logger), retryWare)
 	searchMiddleware := MergeMiddlewares(newSearchMiddleware(cfg, o, store, logger), retryWare)
 
 	traceByIDCounter := queriesPerTenant.MustCurryWith(prometheus.Labels{
@@ -89,7 +90,7 @@ func New(cfg Config, next http.RoundTripper, o *overrides.Overrides, store stora
 }
 
 // newTraceByIDMiddleware creates a new frontend middleware responsible for handling get traces requests.
-func newTraceByIDMiddleware(cfg Config, logger log.Logger) Middleware {
+func newTraceByIDMiddleware(cfg Config, reader tempodb.Reader, logger log.Logger) Middleware {
 	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
 		// We're constructing middleware in this statement, each middleware wraps the next one from left-to-right
 		// - the Deduper dedupes Span IDs for Zipkin support
@@ -99,7 +100,7 @@ func newTraceByIDMiddleware(cfg Config, logger log.Logger) Middleware {
 
 		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
 			// validate traceID
-			_, err := api.ParseTraceID(r)
+			traceID, err := api.ParseTraceID(r)
 			if err != nil {
 				return &http.Response{
 					StatusCode: http.StatusBadRequest,
@@ -108,6 +109,19 @@ func newTraceByIDMiddleware(cfg Config, logger log.Logger) Middleware {
 				}, nil
 			}
 
+			// deleted traces are not found
+			tombstones, err := tombstonesForRequest(reader, r)
+			if err != nil {
+				return nil, err
+			}
+			if tombstones.Has(traceID) {
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       io.NopCloser(strings.NewReader("trace not found")),
+					Header:     http.Header{},
+				}, nil
+			}
+
 			//validate start and end parameter
 			_, _, _, _, _, reqErr := api.ValidateAndSanitizeRequest(r)
 			if reqErr != nil {
@@ -145,6 +159,9 @@ func newTraceByIDMiddleware(cfg Config, logger log.Logger) Middleware {
 				if responseObject.Metrics.FailedBlocks > 0 {
 					resp.StatusCode = http.StatusPartialContent
 				}
+				if responseObject.Metrics.DroppedSpans > 0 && resp.Header != nil {
+					resp.Header.Set(api.HeaderDroppedSpans, strconv.FormatUint(uint64(responseObject.Metrics.DroppedSpans), 10))
+				}
 
 				if marshallingFormat == api.HeaderAcceptJSON {
 					var jsonTrace bytes.Buffer
@@ -194,7 +211,21 @@ func newSearchMiddleware(cfg Config, o *overrides.Overrides, reader tempodb.Read
 			r.Header.Set(user.OrgIDHeaderName, orgID)
 			r.RequestURI = buildUpstreamRequestURI(r.RequestURI, nil)
 
-			return ingesterSearchRT.RoundTrip(r)
+			if !isSearch(r) {
+				return ingesterSearchRT.RoundTrip(r)
+			}
+
+			// deleted traces are removed from the results
+			tombstones, err := tombstonesForRequest(reader, r)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := ingesterSearchRT.RoundTrip(r)
+			if err != nil {
+				return nil, err
+			}
+			return filterDeletedTraces(resp, tombstones)
 		})
 	})
 }
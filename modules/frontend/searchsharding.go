@@ -19,6 +19,8 @@ import (
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/tempodb"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/search"
 	"github.com/opentracing/opentracing-go"
 	"github.com/weaveworks/common/user"
 )
@@ -39,6 +41,9 @@ type searchResponse struct {
 	resultsMap     map[string]*tempopb.TraceSearchMetadata
 	resultsMetrics *tempopb.SearchMetrics
 
+	// tombstones are the deleted traces that are not returned
+	tombstones tempodb.Tombstones
+
 	limit int
 	mtx   sync.Mutex
 }
@@ -73,6 +78,10 @@ func (r *searchResponse) addResponse(res *tempopb.SearchResponse) {
 	defer r.mtx.Unlock()
 
 	for _, t := range res.Traces {
+		if r.tombstones.HasHexString(t.TraceID) {
+			continue
+		}
+
 		// todo: determine a better way to combine?
 		if _, ok := r.resultsMap[t.TraceID]; !ok {
 			r.resultsMap[t.TraceID] = t
@@ -84,6 +93,7 @@ func (r *searchResponse) addResponse(res *tempopb.SearchResponse) {
 	r.resultsMetrics.InspectedTraces += res.Metrics.InspectedTraces
 	r.resultsMetrics.SkippedBlocks += res.Metrics.SkippedBlocks
 	r.resultsMetrics.SkippedTraces += res.Metrics.SkippedTraces
+	search.CombineSearchStageMetrics(r.resultsMetrics, res.Metrics)
 }
 
 func (r *searchResponse) shouldQuit() bool {
@@ -200,10 +210,16 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 		return nil, err
 	}
 
+	tombstones, err := s.reader.Tombstones(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
 	start, end := s.backendRange(searchReq)
 
-	blocks := s.blockMetas(int64(start), int64(end), tenantID)
+	blocks, skipped := s.blockMetas(int64(start), int64(end), tenantID, searchReq)
 	span.SetTag("block-count", len(blocks))
+	span.SetTag("skipped-block-count", skipped)
 
 	var reqs []*http.Request
 	// add backend requests if we need them
@@ -224,6 +240,7 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 	// execute requests
 	wg := boundedwaitgroup.New(uint(s.cfg.ConcurrentRequests))
 	overallResponse := newSearchResponse(ctx, int(searchReq.Limit))
+	overallResponse.tombstones = tombstones
 	overallResponse.resultsMetrics.InspectedBlocks = uint32(len(blocks))
 
 	totalBlockBytes := uint64(0)
@@ -322,19 +339,25 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 	}, nil
 }
 
-// blockMetas returns all relevant blockMetas given a start/end
-func (s *searchSharder) blockMetas(start, end int64, tenantID string) []*backend.BlockMeta {
+// blockMetas returns all relevant blockMetas given a start/end. Blocks whose summary can't match the search request
+// are skipped, the number of skipped blocks is returned as well.
+func (s *searchSharder) blockMetas(start, end int64, tenantID string, searchReq *tempopb.SearchRequest) ([]*backend.BlockMeta, int) {
 	// reduce metas to those in the requested range
 	metas := []*backend.BlockMeta{}
+	skipped := 0
 	allMetas := s.reader.BlockMetas(tenantID)
 	for _, m := range allMetas {
 		if m.StartTime.Unix() <= end &&
 			m.EndTime.Unix() >= start {
+			if !vparquet.SummaryMatches(m.Summary, searchReq) {
+				skipped++
+				continue
+			}
 			metas = append(metas, m)
 		}
 	}
 
-	return metas
+	return metas, skipped
 }
 
 // backendRequests returns a slice of requests that cover all blocks in the store
@@ -371,6 +394,7 @@ func (s *searchSharder) backendRequests(ctx context.Context, tenantID string, pa
 				Version:       m.Version,
 				Size_:         m.Size,
 				FooterSize:    m.FooterSize,
+				Tier:          m.Tier,
 			})
 
 			if err != nil {
@@ -19,6 +19,7 @@ import (
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/api"
 	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/blocklist"
 	"github.com/grafana/tempo/tempodb/encoding/common"
@@ -30,7 +31,8 @@ import (
 
 // implements tempodb.Reader interface
 type mockReader struct {
-	metas []*backend.BlockMeta
+	metas      []*backend.BlockMeta
+	tombstones tempodb.Tombstones
 }
 
 func (m *mockReader) Find(ctx context.Context, tenantID string, id common.ID, blockStart string, blockEnd string, timeStart int64, timeEnd int64) ([]*tempopb.Trace, []error, error) {
@@ -43,6 +45,9 @@ func (m *mockReader) BlockMetas(tenantID string) []*backend.BlockMeta {
 func (m *mockReader) Search(ctx context.Context, meta *backend.BlockMeta, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
 	return nil, nil
 }
+func (m *mockReader) Tombstones(ctx context.Context, tenantID string) (tempodb.Tombstones, error) {
+	return m.tombstones, nil
+}
 func (m *mockReader) EnablePolling(sharder blocklist.JobSharder) {}
 func (m *mockReader) Shutdown()                                  {}
 
@@ -737,3 +742,93 @@ func TestMaxDuration(t *testing.T) {
 	actual = sharder.maxDuration("test")
 	assert.Equal(t, 10*time.Minute, actual)
 }
+
+func TestBlockMetas(t *testing.T) {
+	summary := func(serviceName string, minDuration, maxDuration time.Duration) *backend.BlockSummary {
+		b := backend.NewBlockSummaryBuilder()
+		b.AddServiceName(serviceName)
+		b.AddAttribute("foo", "bar")
+		b.AddTrace(uint64(minDuration))
+		b.AddTrace(uint64(maxDuration))
+		return b.Summary()
+	}
+
+	inRange := func(s *backend.BlockSummary) *backend.BlockMeta {
+		return &backend.BlockMeta{
+			BlockID:   uuid.New(),
+			StartTime: time.Unix(100, 0),
+			EndTime:   time.Unix(200, 0),
+			Summary:   s,
+		}
+	}
+
+	frontend := inRange(summary("frontend", time.Second, 2*time.Second))
+	backendMeta := inRange(summary("backend", 10*time.Second, 20*time.Second))
+	notSummarized := inRange(nil)
+	outOfRange := &backend.BlockMeta{
+		BlockID:   uuid.New(),
+		StartTime: time.Unix(300, 0),
+		EndTime:   time.Unix(400, 0),
+	}
+
+	sharder := &searchSharder{
+		reader: &mockReader{
+			metas: []*backend.BlockMeta{frontend, backendMeta, notSummarized, outOfRange},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		req             *tempopb.SearchRequest
+		expectedMetas   []*backend.BlockMeta
+		expectedSkipped int
+	}{
+		{
+			name:          "no conditions",
+			req:           &tempopb.SearchRequest{},
+			expectedMetas: []*backend.BlockMeta{frontend, backendMeta, notSummarized},
+		},
+		{
+			name:            "service name",
+			req:             &tempopb.SearchRequest{Tags: map[string]string{"service.name": "FRONT"}},
+			expectedMetas:   []*backend.BlockMeta{frontend, notSummarized},
+			expectedSkipped: 1,
+		},
+		{
+			name:          "attribute",
+			req:           &tempopb.SearchRequest{Tags: map[string]string{"foo": "bar"}},
+			expectedMetas: []*backend.BlockMeta{frontend, backendMeta, notSummarized},
+		},
+		{
+			name:            "unknown attribute",
+			req:             &tempopb.SearchRequest{Tags: map[string]string{"foo": "baz"}},
+			expectedMetas:   []*backend.BlockMeta{notSummarized},
+			expectedSkipped: 2,
+		},
+		{
+			name:          "not summarized attribute",
+			req:           &tempopb.SearchRequest{Tags: map[string]string{"cluster": "baz"}},
+			expectedMetas: []*backend.BlockMeta{frontend, backendMeta, notSummarized},
+		},
+		{
+			name:            "min duration",
+			req:             &tempopb.SearchRequest{MinDurationMs: 5000},
+			expectedMetas:   []*backend.BlockMeta{backendMeta, notSummarized},
+			expectedSkipped: 1,
+		},
+		{
+			name:            "max duration",
+			req:             &tempopb.SearchRequest{MaxDurationMs: 5000},
+			expectedMetas:   []*backend.BlockMeta{frontend, notSummarized},
+			expectedSkipped: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metas, skipped := sharder.blockMetas(150, 160, "test", tc.req)
+			assert.Equal(t, tc.expectedMetas, metas)
+			assert.Equal(t, tc.expectedSkipped, skipped)
+		})
+	}
+}
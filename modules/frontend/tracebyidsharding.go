@@ -65,7 +65,7 @@ func (s shardQuery) RoundTrip(r *http.Request) (*http.Response, error) {
 	mtx := sync.Mutex{}
 
 	var overallError error
-	var totalFailedBlocks uint32
+	var totalFailedBlocks, droppedSpans uint32
 	combiner := trace.NewCombiner()
 	combiner.Consume(&tempopb.Trace{}) // The query path returns a non-nil result even if no inputs (which is different than other paths which return nil for no inputs)
 	statusCode := http.StatusNotFound
@@ -127,6 +127,9 @@ func (s shardQuery) RoundTrip(r *http.Request) (*http.Response, error) {
 			}
 
 			if traceResp.Metrics != nil {
+				if traceResp.Metrics.DroppedSpans > droppedSpans {
+					droppedSpans = traceResp.Metrics.DroppedSpans
+				}
 				totalFailedBlocks += traceResp.Metrics.FailedBlocks
 				if totalFailedBlocks > s.maxFailedBlocks {
 					overallError = fmt.Errorf("too many failed block queries %d (max %d)", totalFailedBlocks, s.maxFailedBlocks)
@@ -170,6 +173,7 @@ func (s shardQuery) RoundTrip(r *http.Request) (*http.Response, error) {
 		Trace: overallTrace,
 		Metrics: &tempopb.TraceByIDMetrics{
 			FailedBlocks: totalFailedBlocks,
+			DroppedSpans: droppedSpans,
 		},
 	})
 	if err != nil {
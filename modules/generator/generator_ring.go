@@ -22,6 +22,9 @@ type RingConfig struct {
 	InstanceID             string   `yaml:"instance_id"`
 	InstanceInterfaceNames []string `yaml:"instance_interface_names"`
 	InstanceAddr           string   `yaml:"instance_addr"`
+	InstanceZone           string   `yaml:"instance_availability_zone"`
+
+	ZoneAwarenessEnabled bool `yaml:"zone_awareness_enabled"`
 
 	// Injected internally
 	ListenPort int `yaml:"-"`
@@ -51,6 +54,7 @@ func (cfg *RingConfig) ToRingConfig() ring.Config {
 	rc.HeartbeatTimeout = cfg.HeartbeatTimeout
 	rc.ReplicationFactor = 1
 	rc.SubringCacheDisabled = true
+	rc.ZoneAwarenessEnabled = cfg.ZoneAwarenessEnabled
 
 	return rc
 }
@@ -67,6 +71,7 @@ func (cfg *RingConfig) toLifecyclerConfig() (ring.BasicLifecyclerConfig, error)
 	return ring.BasicLifecyclerConfig{
 		ID:              cfg.InstanceID,
 		Addr:            fmt.Sprintf("%s:%d", instanceAddr, instancePort),
+		Zone:            cfg.InstanceZone,
 		HeartbeatPeriod: cfg.HeartbeatPeriod,
 		NumTokens:       ringNumTokens,
 	}, nil
@@ -23,8 +23,21 @@ type Config struct {
 	MaxTraceIdle         time.Duration `yaml:"trace_idle_period"`
 	MaxBlockDuration     time.Duration `yaml:"max_block_duration"`
 	MaxBlockBytes        uint64        `yaml:"max_block_bytes"`
+	MaxLiveTracesBytes   uint64        `yaml:"max_live_traces_bytes"`
 	CompleteBlockTimeout time.Duration `yaml:"complete_block_timeout"`
 	OverrideRingKey      string        `yaml:"override_ring_key"`
+	HandOff              HandOffConfig `yaml:"hand_off"`
+
+	// SearchTagsDenyList is the deny list of the distributors, set by the app. Traces searched directly without
+	// flatbuffer search data apply it the same way.
+	SearchTagsDenyList []string `yaml:"-"`
+}
+
+// HandOffConfig configures handing off the live traces and the traces in the wal to the ingesters taking over when
+// leaving the ring.
+type HandOffConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 // RegisterFlagsAndApplyDefaults registers the flags.
@@ -42,8 +55,12 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.DurationVar(&cfg.MaxTraceIdle, prefix+".trace-idle-period", 10*time.Second, "Duration after which to consider a trace complete if no spans have been received")
 	f.DurationVar(&cfg.MaxBlockDuration, prefix+".max-block-duration", time.Hour, "Maximum duration which the head block can be appended to before cutting it.")
 	f.Uint64Var(&cfg.MaxBlockBytes, prefix+".max-block-bytes", 1024*1024*1024, "Maximum size of the head block before cutting it.")
+	f.Uint64Var(&cfg.MaxLiveTracesBytes, prefix+".max-live-traces-bytes", 0, "Maximum size of the live traces of all tenants before pushes are refused. The oldest traces are cut to the head block as the limit is approached. 0 to disable.")
 	f.DurationVar(&cfg.CompleteBlockTimeout, prefix+".complete-block-timeout", 3*tempodb.DefaultBlocklistPoll, "Duration to keep blocks in the ingester after they have been flushed.")
 
+	f.BoolVar(&cfg.HandOff.Enabled, prefix+".hand-off.enabled", false, "Hand off live traces and the wal to the ingesters taking over when leaving the ring instead of flushing them to the wal.")
+	f.DurationVar(&cfg.HandOff.Timeout, prefix+".hand-off.timeout", time.Minute, "Maximum duration of the hand-off before falling back to flushing traces to the wal.")
+
 	hostname, err := os.Hostname()
 	if err != nil {
 		level.Error(log.Logger).Log("msg", "failed to get hostname", "err", err)
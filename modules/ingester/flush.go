@@ -147,18 +147,25 @@ func (i *Ingester) sweepAllInstances(immediate bool) {
 	for _, instance := range instances {
 		i.sweepInstance(instance, immediate)
 	}
+
+	// traces that have been cut are in the wal now, the ingest log doesn't have to keep them for us anymore
+	if i.ingestLogConsumer != nil {
+		i.ingestLogConsumer.commit()
+	}
 }
 
 func (i *Ingester) sweepInstance(instance *instance, immediate bool) {
+	maxTraceIdle, maxBlockDuration, maxBlockBytes := i.blockCutPolicy(instance.instanceID)
+
 	// cut traces internally
-	err := instance.CutCompleteTraces(i.cfg.MaxTraceIdle, immediate)
+	err := instance.CutCompleteTraces(maxTraceIdle, immediate)
 	if err != nil {
 		level.Error(log.WithUserID(instance.instanceID, log.Logger)).Log("msg", "failed to cut traces", "err", err)
 		return
 	}
 
 	// see if it's ready to cut a block
-	blockID, err := instance.CutBlockIfReady(i.cfg.MaxBlockDuration, i.cfg.MaxBlockBytes, immediate)
+	blockID, err := instance.CutBlockIfReady(maxBlockDuration, maxBlockBytes, immediate)
 	if err != nil {
 		level.Error(log.WithUserID(instance.instanceID, log.Logger)).Log("msg", "failed to cut block", "err", err)
 		return
@@ -182,6 +189,27 @@ func (i *Ingester) sweepInstance(instance *instance, immediate bool) {
 	}
 }
 
+// blockCutPolicy returns the trace idle period, max block duration and max block bytes of the tenant. Settings that
+// are not overridden for the tenant fall back to the ingester config.
+func (i *Ingester) blockCutPolicy(userID string) (maxTraceIdle time.Duration, maxBlockDuration time.Duration, maxBlockBytes uint64) {
+	maxTraceIdle = i.cfg.MaxTraceIdle
+	if d := i.limiter.limits.MaxTraceIdle(userID); d > 0 {
+		maxTraceIdle = d
+	}
+
+	maxBlockDuration = i.cfg.MaxBlockDuration
+	if d := i.limiter.limits.MaxBlockDuration(userID); d > 0 {
+		maxBlockDuration = d
+	}
+
+	maxBlockBytes = i.cfg.MaxBlockBytes
+	if b := i.limiter.limits.MaxBlockBytes(userID); b > 0 {
+		maxBlockBytes = b
+	}
+
+	return
+}
+
 func (i *Ingester) flushLoop(j int) {
 	defer func() {
 		level.Debug(log.Logger).Log("msg", "Ingester.flushLoop() exited")
@@ -0,0 +1,273 @@
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/modules/ingester/client"
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+// handOffBatchBytes is the size of the push requests sent to the ingesters taking over the traces
+const handOffBatchBytes = 1024 * 1024
+
+var metricHandOffSegmentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "ingester_hand_off_segments_total",
+	Help:      "The total number of trace segments handed off to other ingesters when leaving the ring per tenant.",
+}, []string{"tenant"})
+
+// TransferOut implements ring.FlushTransferer. If hand-off is enabled the live traces and the traces in the head and
+// completing blocks of all tenants are pushed to the ingesters that take over the tokens of this ingester. If it fails
+// the lifecycler falls back to Flush.
+func (i *Ingester) TransferOut(ctx context.Context) error {
+	if !i.cfg.HandOff.Enabled || i.ingestersRing == nil {
+		return ring.ErrTransferDisabled
+	}
+
+	// traces pushed from now on would not be handed off
+	i.stopIncomingRequests()
+
+	ctx, cancel := context.WithTimeout(ctx, i.cfg.HandOff.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	level.Info(log.Logger).Log("msg", "beginning hand-off of live traces")
+
+	// the new owners of the tokens are only known once the ring shows this ingester leaving
+	err := i.awaitLeaving(ctx)
+	if err != nil {
+		return fmt.Errorf("ingester not leaving the ring: %w", err)
+	}
+
+	h := newHandOff(i.ingestersRing, i.clientCfg, i.handOffClient)
+	defer h.close()
+
+	for _, inst := range i.getInstances() {
+		err = inst.handOff(ctx, h)
+		if err != nil {
+			return fmt.Errorf("failed to hand off traces of tenant %s: %w", inst.instanceID, err)
+		}
+	}
+
+	level.Info(log.Logger).Log("msg", "hand-off of live traces complete", "duration", time.Since(start))
+	return nil
+}
+
+func (i *Ingester) awaitLeaving(ctx context.Context) error {
+	for {
+		state, err := i.ingestersRing.GetInstanceState(i.lifecycler.ID)
+		if err == nil && state == ring.LEAVING {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// handOff pushes the trace segments of one tenant at a time to the ingesters that own their tokens after this
+// ingester left the ring.
+type handOff struct {
+	ring      ring.ReadRing
+	clientCfg client.Config
+	newClient func(addr string, cfg client.Config) (*client.Client, error)
+	clients   map[string]*client.Client
+
+	tenant     string
+	batches    map[string]*tempopb.PushBytesRequest
+	batchBytes map[string]int
+}
+
+func newHandOff(r ring.ReadRing, clientCfg client.Config, newClient func(addr string, cfg client.Config) (*client.Client, error)) *handOff {
+	return &handOff{
+		ring:       r,
+		clientCfg:  clientCfg,
+		newClient:  newClient,
+		clients:    map[string]*client.Client{},
+		batches:    map[string]*tempopb.PushBytesRequest{},
+		batchBytes: map[string]int{},
+	}
+}
+
+// newOwners returns the addresses of the ingesters that take over the token. These are the ingesters of the write
+// replica set, which skips leaving ingesters, that are not replicas of the token already.
+func (h *handOff) newOwners(token uint32) ([]string, error) {
+	current, err := h.ring.Get(token, ring.Reporting, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	replicas := make(map[string]struct{}, len(current.Instances))
+	for _, inst := range current.Instances {
+		replicas[inst.Addr] = struct{}{}
+	}
+
+	next, err := h.ring.Get(token, ring.Write, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, inst := range next.Instances {
+		if _, ok := replicas[inst.Addr]; !ok {
+			addrs = append(addrs, inst.Addr)
+		}
+	}
+
+	return addrs, nil
+}
+
+func (h *handOff) push(ctx context.Context, id []byte, segment []byte, searchData []byte) error {
+	addrs, err := h.newOwners(util.TokenFor(h.tenant, id))
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		req, ok := h.batches[addr]
+		if !ok {
+			req = &tempopb.PushBytesRequest{}
+			h.batches[addr] = req
+		}
+		req.Ids = append(req.Ids, tempopb.PreallocBytes{Slice: id})
+		req.Traces = append(req.Traces, tempopb.PreallocBytes{Slice: segment})
+		req.SearchData = append(req.SearchData, tempopb.PreallocBytes{Slice: searchData})
+		h.batchBytes[addr] += len(segment) + len(searchData)
+
+		if h.batchBytes[addr] >= handOffBatchBytes {
+			err = h.send(ctx, addr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	metricHandOffSegmentsTotal.WithLabelValues(h.tenant).Inc()
+	return nil
+}
+
+// flush sends the remaining batches of the tenant
+func (h *handOff) flush(ctx context.Context) error {
+	for addr := range h.batches {
+		err := h.send(ctx, addr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handOff) send(ctx context.Context, addr string) error {
+	req := h.batches[addr]
+	delete(h.batches, addr)
+	delete(h.batchBytes, addr)
+
+	c, ok := h.clients[addr]
+	if !ok {
+		var err error
+		c, err = h.newClient(addr, h.clientCfg)
+		if err != nil {
+			return fmt.Errorf("failed to create client for ingester %s: %w", addr, err)
+		}
+		h.clients[addr] = c
+	}
+
+	_, err := c.PushBytesV2(user.InjectOrgID(ctx, h.tenant), req)
+	if err != nil {
+		return fmt.Errorf("failed to push traces to ingester %s: %w", addr, err)
+	}
+	return nil
+}
+
+func (h *handOff) close() {
+	for _, c := range h.clients {
+		_ = c.Close()
+	}
+}
+
+// handOff pushes the live traces and the traces in the head and completing blocks to their new owners. The search data
+// of live traces is handed off as well, traces in blocks are only found by id on their new owners until the blocks
+// are flushed.
+func (i *instance) handOff(ctx context.Context, h *handOff) error {
+	h.tenant = i.instanceID
+
+	i.tracesMtx.Lock()
+	traces := make([]*liveTrace, 0, len(i.traces))
+	for _, t := range i.traces {
+		traces = append(traces, t)
+	}
+	i.tracesMtx.Unlock()
+
+	for _, t := range traces {
+		for j, segment := range t.batches {
+			var searchData []byte
+			if j < len(t.searchData) {
+				searchData = t.searchData[j]
+			}
+
+			err := h.push(ctx, t.traceID, segment, searchData)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// hold the lock so completing blocks are not cleared while being iterated
+	i.blocksMtx.RLock()
+	defer i.blocksMtx.RUnlock()
+	blocks := append([]*wal.AppendBlock{i.headBlock}, i.completingBlocks...)
+
+	segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	for _, b := range blocks {
+		objectDecoder := model.MustNewObjectDecoder(b.Meta().DataEncoding)
+
+		var pushErr error
+		err := b.IterateObjects(ctx, model.StaticCombiner, func(id common.ID, obj []byte) bool {
+			pushErr = handOffObject(ctx, h, objectDecoder, segmentDecoder, id, obj)
+			return pushErr == nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to iterate block %s: %w", b.BlockID(), err)
+		}
+		if pushErr != nil {
+			return pushErr
+		}
+	}
+
+	return h.flush(ctx)
+}
+
+func handOffObject(ctx context.Context, h *handOff, objectDecoder model.ObjectDecoder, segmentDecoder model.SegmentDecoder, id common.ID, obj []byte) error {
+	tr, err := objectDecoder.PrepareForRead(obj)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal trace: %w", err)
+	}
+
+	// the range is not available for all encodings
+	start, end, err := objectDecoder.FastRange(obj)
+	if err != nil {
+		start, end = 0, 0
+	}
+
+	segment, err := segmentDecoder.PrepareForWrite(tr, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %w", err)
+	}
+
+	// the iterator may reuse the id
+	return h.push(ctx, append([]byte(nil), id...), segment, nil)
+}
@@ -0,0 +1,127 @@
+package ingester
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/tempo/modules/ingester/client"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+// leavingRing shows the ingester with the id leaving the ring and hands all tokens from the ingester with
+// the current address to the ingester with the next address
+type leavingRing struct {
+	ring.ReadRing
+	id      string
+	current string
+	next    string
+}
+
+func (r *leavingRing) Get(_ uint32, op ring.Operation, buf []ring.InstanceDesc, _, _ []string) (ring.ReplicationSet, error) {
+	addr := r.next
+	if op == ring.Reporting {
+		addr = r.current
+	}
+	return ring.ReplicationSet{Instances: append(buf[:0], ring.InstanceDesc{Addr: addr})}, nil
+}
+
+func (r *leavingRing) GetInstanceState(id string) (ring.InstanceState, error) {
+	if id == r.id {
+		return ring.LEAVING, nil
+	}
+	return ring.ACTIVE, nil
+}
+
+// ingesterPusher pushes to the ingester in process
+type ingesterPusher struct {
+	i *Ingester
+}
+
+func (p *ingesterPusher) PushBytes(ctx context.Context, req *tempopb.PushBytesRequest, _ ...grpc.CallOption) (*tempopb.PushResponse, error) {
+	return p.i.PushBytes(ctx, req)
+}
+
+func (p *ingesterPusher) PushBytesV2(ctx context.Context, req *tempopb.PushBytesRequest, _ ...grpc.CallOption) (*tempopb.PushResponse, error) {
+	return p.i.PushBytesV2(ctx, req)
+}
+
+func (p *ingesterPusher) Close() error {
+	return nil
+}
+
+func TestTransferOut(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "test")
+
+	// the ingester taking over
+	receiver := defaultIngesterModule(t, t.TempDir())
+
+	i := defaultIngesterModule(t, t.TempDir())
+	i.cfg.HandOff = HandOffConfig{Enabled: true, Timeout: time.Minute}
+	i.ingestersRing = &leavingRing{id: i.lifecycler.ID, current: "localhost", next: "receiver"}
+	i.handOffClient = func(addr string, _ client.Config) (*client.Client, error) {
+		require.Equal(t, "receiver", addr)
+		p := &ingesterPusher{i: receiver}
+		return &client.Client{PusherClient: p, Closer: p}, nil
+	}
+
+	// one trace in each of the completing block, the head block and the live traces
+	ids := make([][]byte, 0, 3)
+	traces := make([]*tempopb.Trace, 0, 3)
+	for j := 0; j < 3; j++ {
+		id := test.ValidTraceID(nil)
+		batch := test.MakeBatch(10, id)
+		pushBatchV2(t, i, batch, id)
+
+		ids = append(ids, id)
+		traces = append(traces, &tempopb.Trace{Batches: []*v1.ResourceSpans{batch}})
+
+		inst, ok := i.getInstanceByID("test")
+		require.True(t, ok)
+		switch j {
+		case 0:
+			require.NoError(t, inst.CutCompleteTraces(0, true))
+			_, err := inst.CutBlockIfReady(0, 0, true)
+			require.NoError(t, err)
+		case 1:
+			require.NoError(t, inst.CutCompleteTraces(0, true))
+		}
+	}
+
+	err := i.TransferOut(context.Background())
+	require.NoError(t, err)
+
+	// pushes are refused after the hand-off
+	_, err = i.PushBytesV2(ctx, &tempopb.PushBytesRequest{})
+	require.Equal(t, ErrReadOnly, err)
+
+	for j, id := range ids {
+		resp, err := receiver.FindTraceByID(ctx, &tempopb.TraceByIDRequest{TraceID: id})
+		require.NoError(t, err)
+		require.True(t, proto.Equal(traces[j], resp.Trace))
+	}
+
+	// nothing is handed off when disabled
+	i.cfg.HandOff.Enabled = false
+	require.Equal(t, ring.ErrTransferDisabled, i.TransferOut(context.Background()))
+}
+
+func TestHandOffNewOwners(t *testing.T) {
+	h := newHandOff(&leavingRing{current: "a", next: "a"}, client.Config{}, client.New)
+	addrs, err := h.newOwners(0)
+	require.NoError(t, err)
+	require.Empty(t, addrs)
+
+	h = newHandOff(&leavingRing{current: "a", next: "b"}, client.Config{}, client.New)
+	addrs, err = h.newOwners(0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, addrs)
+}
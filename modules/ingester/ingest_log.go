@@ -0,0 +1,391 @@
+package ingester
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/ingest"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util/log"
+)
+
+const (
+	ingestLogFetchSize       = 100
+	ingestLogPollPeriod      = 250 * time.Millisecond
+	ingestLogOwnershipPeriod = 10 * time.Second
+	// ingestLogCommitRefreshPeriod is the period unchanged offsets are committed again, so the log doesn't expire the
+	// consumer group of a partition without new records
+	ingestLogCommitRefreshPeriod = time.Minute
+	// ingestLogDedupeSize is the number of recently pushed traces remembered per partition to skip the duplicates
+	// appended by clients retrying a request that partially failed
+	ingestLogDedupeSize = 10_000
+)
+
+var (
+	metricIngestLogRecordsConsumed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_ingest_log_records_consumed_total",
+		Help:      "The total number of records consumed from the ingest log.",
+	})
+	metricIngestLogRecordsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_ingest_log_records_failed_total",
+		Help:      "The total number of records from the ingest log that could not be pushed.",
+	})
+	metricIngestLogPushRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_ingest_log_push_retries_total",
+		Help:      "The total number of pushes of ingest log records retried because the tenant was over its live traces limits.",
+	})
+	metricIngestLogDuplicateTraces = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_ingest_log_duplicate_traces_total",
+		Help:      "The total number of traces skipped because they were appended to the ingest log again by a retrying client.",
+	})
+	metricIngestLogCommittedOffset = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_ingest_log_committed_offset",
+		Help:      "The last committed offset per ingest log partition.",
+	}, []string{"partition"})
+	metricIngestLogOwnedPartitions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_ingest_log_owned_partitions",
+		Help:      "The current number of ingest log partitions consumed by this ingester.",
+	})
+)
+
+// pendingRecord is a consumed record that has traces that are not written to the wal yet
+type pendingRecord struct {
+	offset int64
+	tenant string
+	ids    [][]byte
+}
+
+type ingestLogPartition struct {
+	next        int64 // next offset to fetch
+	pushed      int   // traces of the record at next that have been pushed already
+	committed   int64
+	committedAt time.Time // last time the offset was committed
+	pending     []pendingRecord
+
+	// recently pushed traces
+	seen      map[uint64]struct{}
+	seenOrder []uint64
+}
+
+// markSeen remembers the trace and returns false if it has been seen recently
+func (p *ingestLogPartition) markSeen(hash uint64) bool {
+	if _, ok := p.seen[hash]; ok {
+		return false
+	}
+
+	if len(p.seenOrder) >= ingestLogDedupeSize {
+		delete(p.seen, p.seenOrder[0])
+		p.seenOrder = p.seenOrder[1:]
+	}
+	p.seen[hash] = struct{}{}
+	p.seenOrder = append(p.seenOrder, hash)
+	return true
+}
+
+// ingestLogConsumer pushes the records of the partitions owned by the ingester. The partitions are owned by the
+// ingesters the ring would replicate the partition token to, so every replica consumes the partition on its own.
+// Offsets are only committed once all traces of a record have been cut to the wal, after a restart the ingester
+// continues from the committed offset and no acknowledged data is lost. Records of tenants over their live traces
+// limits are retried until they can be pushed.
+type ingestLogConsumer struct {
+	i     *Ingester
+	log   ingest.Log
+	ring  ring.ReadRing
+	group string
+	addr  string
+
+	mtx        sync.Mutex
+	partitions map[int]*ingestLogPartition
+}
+
+func newIngestLogConsumer(i *Ingester, l ingest.Log, r ring.ReadRing, group, addr string) *ingestLogConsumer {
+	return &ingestLogConsumer{
+		i:          i,
+		log:        l,
+		ring:       r,
+		group:      group,
+		addr:       addr,
+		partitions: map[int]*ingestLogPartition{},
+	}
+}
+
+func (c *ingestLogConsumer) running(ctx context.Context) {
+	c.updatePartitions()
+	lastOwnershipCheck := time.Now()
+
+	for ctx.Err() == nil {
+		if time.Since(lastOwnershipCheck) > ingestLogOwnershipPeriod {
+			c.updatePartitions()
+			lastOwnershipCheck = time.Now()
+		}
+
+		consumed, err := c.consume(ctx)
+		if err != nil {
+			level.Warn(log.Logger).Log("msg", "stopped consuming ingest log", "err", err)
+			return
+		}
+		if consumed > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ingestLogPollPeriod):
+		}
+	}
+}
+
+// updatePartitions starts consuming newly owned partitions at their committed offset and forgets the partitions
+// owned by other ingesters now
+func (c *ingestLogConsumer) updatePartitions() {
+	partitions := c.log.Partitions()
+	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+
+	owned := map[int]struct{}{}
+	for p := 0; p < partitions; p++ {
+		rs, err := c.ring.Get(ingest.PartitionToken(p, partitions), ring.WriteNoExtend, bufDescs, bufHosts, bufZones)
+		if err != nil {
+			level.Warn(log.Logger).Log("msg", "failed to find owners of ingest log partition", "partition", p, "err", err)
+			return
+		}
+		if rs.Includes(c.addr) {
+			owned[p] = struct{}{}
+		}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for p := range c.partitions {
+		if _, ok := owned[p]; !ok {
+			level.Info(log.Logger).Log("msg", "stopped consuming ingest log partition", "partition", p)
+			delete(c.partitions, p)
+
+			// the records pushed so far are in the wal or live traces of this ingester, the committed offset would
+			// only keep the log from deleting the segments of the partition
+			if err := c.log.Release(c.group, p); err != nil {
+				level.Error(log.Logger).Log("msg", "failed to release ingest log partition", "partition", p, "err", err)
+			}
+		}
+	}
+
+	for p := range owned {
+		if _, ok := c.partitions[p]; ok {
+			continue
+		}
+
+		offset, _, err := c.log.Committed(c.group, p)
+		if err != nil {
+			level.Error(log.Logger).Log("msg", "failed to read committed ingest log offset", "partition", p, "err", err)
+			continue
+		}
+
+		level.Info(log.Logger).Log("msg", "started consuming ingest log partition", "partition", p, "offset", offset)
+		c.partitions[p] = &ingestLogPartition{next: offset, committed: offset, seen: map[uint64]struct{}{}}
+	}
+
+	metricIngestLogOwnedPartitions.Set(float64(len(c.partitions)))
+}
+
+// consume pushes the next records of all owned partitions and returns how many there were
+func (c *ingestLogConsumer) consume(ctx context.Context) (int, error) {
+	c.mtx.Lock()
+	next := make(map[int]int64, len(c.partitions))
+	for p, part := range c.partitions {
+		next[p] = part.next
+	}
+	c.mtx.Unlock()
+
+	consumed := 0
+	for p, offset := range next {
+		records, err := c.log.Fetch(p, offset, ingestLogFetchSize)
+		if err != nil {
+			level.Error(log.Logger).Log("msg", "failed to fetch from ingest log", "partition", p, "offset", offset, "err", err)
+			continue
+		}
+
+		for _, rec := range records {
+			c.mtx.Lock()
+			part, ok := c.partitions[p]
+			c.mtx.Unlock()
+			if !ok {
+				break
+			}
+
+			pending, err := c.push(ctx, part, rec)
+			if err == ErrReadOnly {
+				return consumed, err
+			}
+			if err != nil {
+				// the partition continues with the record on the next poll
+				metricIngestLogPushRetries.Inc()
+				level.Warn(log.WithUserID(rec.TenantID, log.Logger)).Log("msg", "retrying ingest log record", "partition", p, "offset", rec.Offset, "err", err)
+				break
+			}
+			consumed++
+
+			c.mtx.Lock()
+			if len(pending.ids) > 0 {
+				part.pending = append(part.pending, pending)
+			}
+			part.next = rec.Offset + 1
+			part.pushed = 0
+			c.mtx.Unlock()
+		}
+	}
+
+	return consumed, nil
+}
+
+// push pushes the traces of the record to the tenant instance. Pushing stops at the first trace that failed with an
+// error that can go away, it returns the error and the partition remembers how many traces have been pushed. Traces
+// that fail with any other error are skipped, retrying them will not succeed.
+func (c *ingestLogConsumer) push(ctx context.Context, part *ingestLogPartition, rec *ingest.Record) (pendingRecord, error) {
+	pending := pendingRecord{offset: rec.Offset, tenant: rec.TenantID}
+
+	req := &tempopb.PushBytesRequest{}
+	if err := req.Unmarshal(rec.Value); err != nil || len(req.Traces) != len(req.Ids) {
+		metricIngestLogRecordsConsumed.Inc()
+		metricIngestLogRecordsFailed.Inc()
+		level.Warn(log.WithUserID(rec.TenantID, log.Logger)).Log("msg", "skipping invalid ingest log record", "offset", rec.Offset, "err", err)
+		return pending, nil
+	}
+
+	for _, id := range req.Ids {
+		pending.ids = append(pending.ids, append([]byte(nil), id.Slice...))
+	}
+
+	c.mtx.Lock()
+	start := 0
+	if part.next == rec.Offset {
+		start = part.pushed
+	}
+	c.mtx.Unlock()
+
+	ctx = user.InjectOrgID(ctx, rec.TenantID)
+	failed := false
+	for j := start; j < len(req.Traces); j++ {
+		hash := xxhash.New()
+		_, _ = hash.WriteString(rec.TenantID)
+		_, _ = hash.Write(req.Ids[j].Slice)
+		_, _ = hash.Write(req.Traces[j].Slice)
+
+		c.mtx.Lock()
+		unseen := part.markSeen(hash.Sum64())
+		c.mtx.Unlock()
+		if !unseen {
+			metricIngestLogDuplicateTraces.Inc()
+			continue
+		}
+
+		trace := &tempopb.PushBytesRequest{
+			Traces: req.Traces[j : j+1],
+			Ids:    req.Ids[j : j+1],
+		}
+		if len(req.SearchData) > j {
+			trace.SearchData = req.SearchData[j : j+1]
+		}
+
+		_, err := c.i.PushBytesV2(ctx, trace)
+		if err == ErrReadOnly || (err != nil && retryablePushError(err)) {
+			c.mtx.Lock()
+			// the trace has not been pushed, it must not be skipped as a duplicate on retry
+			delete(part.seen, hash.Sum64())
+			part.next = rec.Offset
+			part.pushed = j
+			c.mtx.Unlock()
+			return pending, err
+		}
+		if err != nil {
+			failed = true
+			level.Warn(log.WithUserID(rec.TenantID, log.Logger)).Log("msg", "failed to push trace of ingest log record", "offset", rec.Offset, "err", err)
+		}
+	}
+
+	metricIngestLogRecordsConsumed.Inc()
+	if failed {
+		metricIngestLogRecordsFailed.Inc()
+	}
+	return pending, nil
+}
+
+// retryablePushError returns true if pushing the trace can succeed later, once the live traces of the tenant have
+// been cut. Invalid and too large traces fail the same way every time.
+func retryablePushError(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	if st.Code() != codes.FailedPrecondition {
+		return st.Code() == codes.Unavailable || st.Code() == codes.ResourceExhausted
+	}
+	return strings.HasPrefix(st.Message(), overrides.ErrorPrefixLiveTracesExceeded) ||
+		strings.HasPrefix(st.Message(), overrides.ErrorPrefixLiveTracesBytesExceeded)
+}
+
+// commit stores the offset of the oldest record with traces that are still only in memory for every partition.
+// It has to be called after the traces have been cut.
+func (c *ingestLogConsumer) commit() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for p, part := range c.partitions {
+		for len(part.pending) > 0 && !c.i.isLive(part.pending[0].tenant, part.pending[0].ids) {
+			part.pending = part.pending[1:]
+		}
+
+		offset := part.next
+		if len(part.pending) > 0 {
+			offset = part.pending[0].offset
+		}
+		if offset == part.committed && time.Since(part.committedAt) < ingestLogCommitRefreshPeriod {
+			continue
+		}
+
+		err := c.log.Commit(c.group, p, offset)
+		if err != nil {
+			level.Error(log.Logger).Log("msg", "failed to commit ingest log offset", "partition", p, "offset", offset, "err", err)
+			continue
+		}
+
+		part.committed = offset
+		part.committedAt = time.Now()
+		metricIngestLogCommittedOffset.WithLabelValues(strconv.Itoa(p)).Set(float64(offset))
+	}
+}
+
+// release removes the committed offsets of all owned partitions. It's called once the ingester left the ring for good,
+// the ingesters taking over the partitions consume them with their own offsets.
+func (c *ingestLogConsumer) release() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for p := range c.partitions {
+		if err := c.log.Release(c.group, p); err != nil {
+			level.Error(log.Logger).Log("msg", "failed to release ingest log partition", "partition", p, "err", err)
+		}
+		delete(c.partitions, p)
+	}
+
+	metricIngestLogOwnedPartitions.Set(0)
+}
@@ -0,0 +1,138 @@
+package ingester
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/ingest"
+	"github.com/grafana/tempo/pkg/model"
+	model_v2 "github.com/grafana/tempo/pkg/model/v2"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+// partitionRing assigns every partition token to the instances with the given addresses
+type partitionRing struct {
+	ring.ReadRing
+	owners []string
+}
+
+func (r *partitionRing) Get(key uint32, _ ring.Operation, buf []ring.InstanceDesc, _, _ []string) (ring.ReplicationSet, error) {
+	rs := ring.ReplicationSet{Instances: buf[:0]}
+	for _, addr := range r.owners {
+		rs.Instances = append(rs.Instances, ring.InstanceDesc{Addr: addr})
+	}
+	return rs, nil
+}
+
+func TestIngestLogConsumer(t *testing.T) {
+	i := defaultIngesterModule(t, t.TempDir())
+
+	l, err := ingest.New(ingest.Config{Enabled: true, Path: t.TempDir(), Partitions: 2, MaxSegmentBytes: 1024 * 1024}, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+	expected := test.MakeTrace(10, id)
+
+	traceBytes, err := model.MustNewSegmentDecoder(model_v2.Encoding).PrepareForWrite(expected, 0, 0)
+	require.NoError(t, err)
+	req := &tempopb.PushBytesRequest{
+		Traces: []tempopb.PreallocBytes{{Slice: traceBytes}},
+		Ids:    []tempopb.PreallocBytes{{Slice: id}},
+	}
+	value, err := req.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, l.Append(1, []*ingest.Record{{TenantID: "test", Value: value}}))
+
+	// partitions owned by other ingesters are not consumed
+	c := newIngestLogConsumer(i, l, &partitionRing{owners: []string{"other:9095"}}, "ingester-1", "ingester-1:9095")
+	c.updatePartitions()
+	consumed, err := c.consume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, consumed)
+
+	c = newIngestLogConsumer(i, l, &partitionRing{owners: []string{"other:9095", "ingester-1:9095"}}, "ingester-1", "ingester-1:9095")
+	i.ingestLogConsumer = c
+	c.updatePartitions()
+	consumed, err = c.consume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, consumed)
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	resp, err := i.FindTraceByID(ctx, &tempopb.TraceByIDRequest{TraceID: id})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Trace)
+
+	// the trace is only in memory, the record can't be committed yet
+	c.commit()
+	offset, ok, err := l.Committed("ingester-1", 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), offset)
+
+	// once the trace is cut to the wal the record is committed
+	i.sweepAllInstances(true)
+	offset, ok, err = l.Committed("ingester-1", 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), offset)
+
+	// a restarted consumer continues at the committed offset
+	c = newIngestLogConsumer(i, l, &partitionRing{owners: []string{"ingester-1:9095"}}, "ingester-1", "ingester-1:9095")
+	c.updatePartitions()
+	consumed, err = c.consume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, consumed)
+
+	// the other replica consumes the partition independently
+	other := newIngestLogConsumer(i, l, &partitionRing{owners: []string{"other:9095"}}, "other", "other:9095")
+	other.updatePartitions()
+	consumed, err = other.consume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, consumed)
+
+	// a client retry appends the same trace again, it is skipped
+	require.NoError(t, l.Append(1, []*ingest.Record{{TenantID: "test", Value: value}}))
+	consumed, err = other.consume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, consumed)
+	assert.Equal(t, 1, len(other.partitions[1].seen))
+
+	// a consumer that stops owning the partition releases its offset
+	c.ring = &partitionRing{owners: []string{"other:9095"}}
+	c.updatePartitions()
+	_, ok, err = l.Committed("ingester-1", 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// a consumer of an ingester that left the ring releases the offsets of all partitions
+	require.NoError(t, l.Commit("other", 0, 0))
+	other.release()
+	for p := 0; p < l.Partitions(); p++ {
+		_, ok, err = l.Committed("other", p)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+	assert.Empty(t, other.partitions)
+}
+
+func TestRetryablePushError(t *testing.T) {
+	assert.True(t, retryablePushError(errors.New("failed to cut head block")))
+	assert.True(t, retryablePushError(status.Errorf(codes.FailedPrecondition, "%s max live traces exceeded", overrides.ErrorPrefixLiveTracesExceeded)))
+	assert.True(t, retryablePushError(status.Errorf(codes.FailedPrecondition, "%s max live traces bytes exceeded", overrides.ErrorPrefixLiveTracesBytesExceeded)))
+	assert.False(t, retryablePushError(status.Errorf(codes.FailedPrecondition, "%s trace too large", overrides.ErrorPrefixTraceTooLarge)))
+	assert.False(t, retryablePushError(status.Errorf(codes.InvalidArgument, "not a valid traceid")))
+}
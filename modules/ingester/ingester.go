@@ -3,6 +3,7 @@ package ingester
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,10 +21,12 @@ import (
 	"github.com/weaveworks/common/user"
 	"google.golang.org/grpc/codes"
 
+	"github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/modules/storage"
 	"github.com/grafana/tempo/pkg/flushqueues"
 	_ "github.com/grafana/tempo/pkg/gogocodec" // force gogo codec registration
+	"github.com/grafana/tempo/pkg/ingest"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/model/decoder"
 	v1 "github.com/grafana/tempo/pkg/model/v1"
@@ -67,19 +70,35 @@ type Ingester struct {
 	flushQueues     *flushqueues.ExclusiveQueues
 	flushQueuesDone sync.WaitGroup
 
-	limiter *Limiter
+	limiter    *Limiter
+	tagsToDrop map[string]struct{}
+
+	// used to hand off traces to the ingesters taking over when leaving the ring, optional
+	ingestersRing ring.ReadRing
+	clientCfg     client.Config
+	handOffClient func(addr string, cfg client.Config) (*client.Client, error) // this var exists so tests can hand off without grpc
+
+	// consumes the ingest log instead of receiving pushes from the distributors, optional
+	ingestLogConsumer *ingestLogConsumer
 
 	subservicesWatcher *services.FailureWatcher
 }
 
 // New makes a new Ingester.
-func New(cfg Config, store storage.Store, limits *overrides.Overrides, reg prometheus.Registerer) (*Ingester, error) {
+func New(cfg Config, clientCfg client.Config, store storage.Store, limits *overrides.Overrides, ingestLog ingest.Log, ingestersRing ring.ReadRing, reg prometheus.Registerer) (*Ingester, error) {
 	i := &Ingester{
-		cfg:          cfg,
-		instances:    map[string]*instance{},
-		store:        store,
-		flushQueues:  flushqueues.New(cfg.ConcurrentFlushes, metricFlushQueueLength),
-		replayJitter: true,
+		cfg:           cfg,
+		instances:     map[string]*instance{},
+		store:         store,
+		flushQueues:   flushqueues.New(cfg.ConcurrentFlushes, metricFlushQueueLength),
+		replayJitter:  true,
+		ingestersRing: ingestersRing,
+		clientCfg:     clientCfg,
+		handOffClient: client.New,
+		tagsToDrop:    map[string]struct{}{},
+	}
+	for _, tag := range cfg.SearchTagsDenyList {
+		i.tagsToDrop[tag] = struct{}{}
 	}
 
 	i.local = store.WAL().LocalBackend()
@@ -97,7 +116,11 @@ func New(cfg Config, store storage.Store, limits *overrides.Overrides, reg prome
 
 	// Now that the lifecycler has been created, we can create the limiter
 	// which depends on it.
-	i.limiter = NewLimiter(limits, i.lifecycler, cfg.LifecyclerConfig.RingConfig.ReplicationFactor)
+	i.limiter = NewLimiter(limits, i.lifecycler, cfg.LifecyclerConfig.RingConfig.ReplicationFactor, int(cfg.MaxLiveTracesBytes))
+
+	if ingestLog != nil {
+		i.ingestLogConsumer = newIngestLogConsumer(i, ingestLog, ingestersRing, i.lifecycler.ID, i.lifecycler.Addr)
+	}
 
 	i.subservicesWatcher = services.NewFailureWatcher()
 	i.subservicesWatcher.WatchService(i.lifecycler)
@@ -130,6 +153,19 @@ func (i *Ingester) starting(ctx context.Context) error {
 }
 
 func (i *Ingester) loop(ctx context.Context) error {
+	if i.ingestLogConsumer != nil {
+		consumerCtx, cancel := context.WithCancel(ctx)
+		consumerDone := make(chan struct{})
+		go func() {
+			defer close(consumerDone)
+			i.ingestLogConsumer.running(consumerCtx)
+		}()
+		defer func() {
+			cancel()
+			<-consumerDone
+		}()
+	}
+
 	flushTicker := time.NewTicker(i.cfg.FlushCheckPeriod)
 	defer flushTicker.Stop()
 
@@ -138,6 +174,9 @@ func (i *Ingester) loop(ctx context.Context) error {
 		case <-flushTicker.C:
 			i.sweepAllInstances(false)
 
+		case <-i.limiter.evict:
+			i.evictLiveTraces()
+
 		case <-ctx.Done():
 			return nil
 
@@ -147,10 +186,72 @@ func (i *Ingester) loop(ctx context.Context) error {
 	}
 }
 
+// evictLiveTraces cuts live traces to the head blocks until all tenants and the ingester are back below the eviction
+// target of their live traces bytes limits. Tenants over their own limit lose their oldest traces first, then the
+// oldest traces across all tenants are cut, so a tenant doesn't pay for the live traces of another one.
+func (i *Ingester) evictLiveTraces() {
+	type instanceTraceAge struct {
+		inst *instance
+		age  liveTraceAge
+	}
+
+	var remaining []instanceTraceAge
+	for _, inst := range i.getInstances() {
+		instAges := inst.liveTraceAges()
+
+		toEvict := i.limiter.userLiveTracesBytesToEvict(inst.instanceID, int(inst.liveTracesBytes.Load()))
+		var tokens []uint32
+		for _, age := range instAges {
+			if toEvict <= 0 {
+				remaining = append(remaining, instanceTraceAge{inst, age})
+				continue
+			}
+			tokens = append(tokens, age.token)
+			toEvict -= age.bytes
+		}
+		i.evictTraces(inst, tokens)
+	}
+
+	toEvict := i.limiter.liveTracesBytesToEvict()
+	if toEvict <= 0 {
+		return
+	}
+
+	sort.Slice(remaining, func(a, b int) bool {
+		return remaining[a].age.lastAppend.Before(remaining[b].age.lastAppend)
+	})
+	tokens := map[*instance][]uint32{}
+	for _, r := range remaining {
+		if toEvict <= 0 {
+			break
+		}
+		tokens[r.inst] = append(tokens[r.inst], r.age.token)
+		toEvict -= r.age.bytes
+	}
+	for inst, instTokens := range tokens {
+		i.evictTraces(inst, instTokens)
+	}
+}
+
+func (i *Ingester) evictTraces(inst *instance, tokens []uint32) {
+	if len(tokens) == 0 {
+		return
+	}
+	if err := inst.evictTraces(tokens); err != nil {
+		level.Error(log.Logger).Log("msg", "failed to evict live traces", "tenant", inst.instanceID, "err", err)
+	}
+}
+
 // stopping is run when ingester is asked to stop
 func (i *Ingester) stopping(_ error) error {
 	i.markUnavailable()
 
+	// an ingester that left the ring doesn't consume its partitions anymore, its offsets would keep the ingest log from
+	// deleting their segments. ingesters that stay in the ring continue from their offsets after a restart.
+	if i.ingestLogConsumer != nil && i.lifecycler != nil && i.lifecycler.ShouldUnregisterOnShutdown() {
+		i.ingestLogConsumer.release()
+	}
+
 	if i.flushQueues != nil {
 		i.flushQueues.Stop()
 		i.flushQueuesDone.Wait()
@@ -259,9 +360,15 @@ func (i *Ingester) FindTraceByID(ctx context.Context, req *tempopb.TraceByIDRequ
 
 	span.LogFields(ot_log.Bool("trace found", trace != nil))
 
-	return &tempopb.TraceByIDResponse{
+	resp := &tempopb.TraceByIDResponse{
 		Trace: trace,
-	}, nil
+	}
+	if dropped := inst.droppedSpans(req.TraceID); dropped > 0 {
+		resp.Metrics = &tempopb.TraceByIDMetrics{
+			DroppedSpans: uint32(dropped),
+		}
+	}
+	return resp, nil
 }
 
 func (i *Ingester) CheckReady(ctx context.Context) error {
@@ -287,6 +394,7 @@ func (i *Ingester) getOrCreateInstance(instanceID string) (*instance, error) {
 		if err != nil {
 			return nil, err
 		}
+		inst.tagsToDrop = i.tagsToDrop
 		i.instances[instanceID] = inst
 	}
 	return inst, nil
@@ -300,6 +408,15 @@ func (i *Ingester) getInstanceByID(id string) (*instance, bool) {
 	return inst, ok
 }
 
+// isLive returns true if any of the traces of the tenant is still only held in memory
+func (i *Ingester) isLive(tenant string, ids [][]byte) bool {
+	inst, ok := i.getInstanceByID(tenant)
+	if !ok {
+		return false
+	}
+	return inst.isLive(ids)
+}
+
 func (i *Ingester) getInstances() []*instance {
 	i.instancesMtx.RLock()
 	defer i.instancesMtx.RUnlock()
@@ -319,11 +436,6 @@ func (i *Ingester) stopIncomingRequests() {
 	i.readonly = true
 }
 
-// TransferOut implements ring.Lifecycler.
-func (i *Ingester) TransferOut(ctx context.Context) error {
-	return ring.ErrTransferDisabled
-}
-
 func (i *Ingester) replayWal() error {
 	level.Info(log.Logger).Log("msg", "beginning wal replay")
 
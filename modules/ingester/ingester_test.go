@@ -4,6 +4,7 @@ import (
 	"context"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -12,10 +13,12 @@ import (
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/kv/consul"
 	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/user"
 
+	"github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/modules/storage"
 	"github.com/grafana/tempo/pkg/model"
@@ -310,6 +313,55 @@ func TestFlush(t *testing.T) {
 	}
 }
 
+func TestSweepInstanceBlockCutPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	ingester := defaultIngesterModule(t, tmpDir)
+	ingester.cfg.MaxBlockDuration = 2 * time.Hour
+	ingester.cfg.MaxBlockBytes = 1024 * 1024
+
+	// tenant test cuts traces and blocks right away, tenant other uses the ingester config
+	overridesFile := filepath.Join(t.TempDir(), "overrides.yaml")
+	err := os.WriteFile(overridesFile, []byte(`
+overrides:
+  test:
+    trace_idle_period: 1ms
+    max_block_duration: 1h
+    max_block_bytes: 1
+`), os.ModePerm)
+	require.NoError(t, err)
+
+	limitsCfg := defaultLimitsTestConfig()
+	limitsCfg.PerTenantOverrideConfig = overridesFile
+	limits, err := overrides.NewOverrides(limitsCfg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), limits))
+	ingester.limiter.limits = limits
+
+	maxTraceIdle, maxBlockDuration, maxBlockBytes := ingester.blockCutPolicy("test")
+	require.Equal(t, time.Millisecond, maxTraceIdle)
+	require.Equal(t, time.Hour, maxBlockDuration)
+	require.Equal(t, uint64(1), maxBlockBytes)
+
+	maxTraceIdle, maxBlockDuration, maxBlockBytes = ingester.blockCutPolicy("other")
+	require.Equal(t, ingester.cfg.MaxTraceIdle, maxTraceIdle)
+	require.Equal(t, ingester.cfg.MaxBlockDuration, maxBlockDuration)
+	require.Equal(t, ingester.cfg.MaxBlockBytes, maxBlockBytes)
+
+	for tenant, expectedToCutBlock := range map[string]bool{"test": true, "other": false} {
+		inst, err := ingester.getOrCreateInstance(tenant)
+		require.NoError(t, err)
+
+		err = inst.PushBytesRequest(context.Background(), makeRequest(test.ValidTraceID(nil)))
+		require.NoError(t, err)
+
+		lastCutTime := inst.lastBlockCut
+		time.Sleep(10 * time.Millisecond)
+		ingester.sweepInstance(inst, false)
+
+		require.Equal(t, expectedToCutBlock, inst.lastBlockCut.After(lastCutTime), tenant)
+	}
+}
+
 func defaultIngesterModule(t *testing.T, tmpDir string) *Ingester {
 	ingesterConfig := defaultIngesterTestConfig()
 	limits, err := overrides.NewOverrides(defaultLimitsTestConfig())
@@ -336,7 +388,7 @@ func defaultIngesterModule(t *testing.T, tmpDir string) *Ingester {
 	}, log.NewNopLogger())
 	require.NoError(t, err, "unexpected error store")
 
-	ingester, err := New(ingesterConfig, s, limits, prometheus.NewPedanticRegistry())
+	ingester, err := New(ingesterConfig, client.Config{}, s, limits, nil, nil, prometheus.NewPedanticRegistry())
 	require.NoError(t, err, "unexpected error creating ingester")
 	ingester.replayJitter = false
 
@@ -0,0 +1,193 @@
+package ingester
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+const (
+	traceLocationLive       = "live"
+	traceLocationHead       = "head"
+	traceLocationCompleting = "completing"
+	traceLocationComplete   = "complete"
+	traceLocationFlushed    = "flushed"
+)
+
+// TraceInspection describes where the ingester currently holds the data of a trace
+type TraceInspection struct {
+	TraceID          string          `json:"traceID"`
+	Tenant           string          `json:"tenant"`
+	MaxBytesPerTrace int             `json:"maxBytesPerTrace"`
+	Locations        []TraceLocation `json:"locations"`
+}
+
+// TraceLocation is the part of a trace held in the live traces or in one block
+type TraceLocation struct {
+	Location string `json:"location"`
+	BlockID  string `json:"blockID,omitempty"`
+	Bytes    int    `json:"bytes"`
+	Spans    int    `json:"spans"`
+
+	// live traces only
+	LastAppend   *time.Time `json:"lastAppend,omitempty"`
+	Truncated    bool       `json:"truncated,omitempty"`
+	DroppedSpans int        `json:"droppedSpans,omitempty"`
+
+	// flushed blocks only
+	FlushedTime *time.Time `json:"flushedTime,omitempty"`
+}
+
+// InspectTraceHandler returns where the trace of the tenant currently resides in this ingester
+func (i *Ingester) InspectTraceHandler(w http.ResponseWriter, r *http.Request) {
+	instanceID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := api.ParseTraceID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inst, ok := i.getInstanceByID(instanceID)
+	if !ok || inst == nil {
+		http.Error(w, "trace not found", http.StatusNotFound)
+		return
+	}
+
+	inspection, err := inst.InspectTrace(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(inspection.Locations) == 0 {
+		http.Error(w, "trace not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	_ = json.NewEncoder(w).Encode(inspection)
+}
+
+// InspectTrace returns the live trace and every block that contain data of the trace
+func (i *instance) InspectTrace(ctx context.Context, id []byte) (*TraceInspection, error) {
+	inspection := &TraceInspection{
+		TraceID:          hex.EncodeToString(id),
+		Tenant:           i.instanceID,
+		MaxBytesPerTrace: i.limiter.limits.MaxBytesPerTrace(i.instanceID),
+		Locations:        []TraceLocation{},
+	}
+
+	// live traces
+	i.tracesMtx.Lock()
+	if t, ok := i.traces[i.tokenForTraceID(id)]; ok {
+		tr, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForRead(t.batches)
+		if err != nil {
+			i.tracesMtx.Unlock()
+			return nil, fmt.Errorf("unable to unmarshal liveTrace: %w", err)
+		}
+
+		bytes := 0
+		for _, b := range t.batches {
+			bytes += len(b)
+		}
+		lastAppend := t.lastAppend
+		inspection.Locations = append(inspection.Locations, TraceLocation{
+			Location:     traceLocationLive,
+			Bytes:        bytes,
+			Spans:        countSpans(tr),
+			LastAppend:   &lastAppend,
+			Truncated:    t.truncated,
+			DroppedSpans: t.droppedSpans,
+		})
+	}
+	i.tracesMtx.Unlock()
+
+	i.blocksMtx.RLock()
+	defer i.blocksMtx.RUnlock()
+
+	inspectAppendBlock := func(b *wal.AppendBlock, location string) error {
+		obj, err := b.Find(id, model.StaticCombiner)
+		if err != nil {
+			return fmt.Errorf("%s block find failed: %w", location, err)
+		}
+		if obj == nil {
+			return nil
+		}
+
+		tr, err := model.MustNewObjectDecoder(b.Meta().DataEncoding).PrepareForRead(obj)
+		if err != nil {
+			return fmt.Errorf("%s block unmarshal failed: %w", location, err)
+		}
+
+		inspection.Locations = append(inspection.Locations, TraceLocation{
+			Location: location,
+			BlockID:  b.BlockID().String(),
+			Bytes:    len(obj),
+			Spans:    countSpans(tr),
+		})
+		return nil
+	}
+
+	// headBlock
+	err := inspectAppendBlock(i.headBlock, traceLocationHead)
+	if err != nil {
+		return nil, err
+	}
+
+	// completingBlock
+	for _, c := range i.completingBlocks {
+		err = inspectAppendBlock(c, traceLocationCompleting)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// completeBlock
+	for _, c := range i.completeBlocks {
+		tr, err := c.FindTraceByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("completeBlock.FindTraceByID failed: %w", err)
+		}
+		if tr == nil {
+			continue
+		}
+
+		location := TraceLocation{
+			Location: traceLocationComplete,
+			BlockID:  c.BlockMeta().BlockID.String(),
+			Bytes:    tr.Size(),
+			Spans:    countSpans(tr),
+		}
+		if flushedTime := c.FlushedTime(); !flushedTime.IsZero() {
+			location.Location = traceLocationFlushed
+			location.FlushedTime = &flushedTime
+		}
+		inspection.Locations = append(inspection.Locations, location)
+	}
+
+	return inspection, nil
+}
+
+func countSpans(tr *tempopb.Trace) int {
+	count := 0
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			count += len(ils.Spans)
+		}
+	}
+	return count
+}
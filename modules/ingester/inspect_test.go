@@ -0,0 +1,95 @@
+package ingester
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+func TestInspectTraceHandler(t *testing.T) {
+	i := defaultIngesterModule(t, t.TempDir())
+	id := test.ValidTraceID(nil)
+
+	inspect := func(id []byte) (int, *TraceInspection) {
+		req := httptest.NewRequest(http.MethodGet, "/ingester/traces/"+hex.EncodeToString(id), nil)
+		req = mux.SetURLVars(req, map[string]string{"traceID": hex.EncodeToString(id)})
+		req = req.WithContext(user.InjectOrgID(req.Context(), "test"))
+
+		rec := httptest.NewRecorder()
+		i.InspectTraceHandler(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+
+		inspection := &TraceInspection{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), inspection))
+		return rec.Code, inspection
+	}
+
+	locations := func(inspection *TraceInspection) []string {
+		var l []string
+		for _, loc := range inspection.Locations {
+			l = append(l, loc.Location)
+			assert.Equal(t, 10, loc.Spans)
+			assert.Greater(t, loc.Bytes, 0)
+		}
+		return l
+	}
+
+	// unknown tenant
+	code, _ := inspect(id)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	pushBatchV2(t, i, test.MakeBatch(10, id), id)
+
+	// unknown trace
+	code, _ = inspect(test.ValidTraceID(nil))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, inspection := inspect(id)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, hex.EncodeToString(id), inspection.TraceID)
+	assert.Equal(t, "test", inspection.Tenant)
+	assert.Equal(t, defaultLimitsTestConfig().MaxBytesPerTrace, inspection.MaxBytesPerTrace)
+	assert.Equal(t, []string{traceLocationLive}, locations(inspection))
+	assert.NotNil(t, inspection.Locations[0].LastAppend)
+
+	inst, ok := i.getInstanceByID("test")
+	require.True(t, ok)
+
+	err := inst.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+	_, inspection = inspect(id)
+	assert.Equal(t, []string{traceLocationHead}, locations(inspection))
+	assert.Equal(t, inst.headBlock.BlockID().String(), inspection.Locations[0].BlockID)
+
+	blockID, err := inst.CutBlockIfReady(0, 0, true)
+	require.NoError(t, err)
+	_, inspection = inspect(id)
+	assert.Equal(t, []string{traceLocationCompleting}, locations(inspection))
+	assert.Equal(t, blockID.String(), inspection.Locations[0].BlockID)
+
+	err = inst.CompleteBlock(blockID)
+	require.NoError(t, err)
+	err = inst.ClearCompletingBlock(blockID)
+	require.NoError(t, err)
+	_, inspection = inspect(id)
+	assert.Equal(t, []string{traceLocationComplete}, locations(inspection))
+	assert.Equal(t, blockID.String(), inspection.Locations[0].BlockID)
+
+	err = inst.GetBlockToBeFlushed(blockID).SetFlushed(context.Background())
+	require.NoError(t, err)
+	_, inspection = inspect(id)
+	assert.Equal(t, []string{traceLocationFlushed}, locations(inspection))
+	assert.NotNil(t, inspection.Locations[0].FlushedTime)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/gogo/status"
 	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -31,6 +32,7 @@ import (
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 	"github.com/grafana/tempo/tempodb/search"
 	"github.com/grafana/tempo/tempodb/wal"
 )
@@ -61,6 +63,9 @@ var (
 	ErrTraceMissing = errors.New("Trace missing")
 )
 
+// reasonTraceTruncated is the discard reason of spans dropped from truncated traces
+const reasonTraceTruncated = "trace_truncated"
+
 const (
 	traceDataType  = "trace"
 	searchDataType = "search"
@@ -77,11 +82,26 @@ var (
 		Name:      "ingester_live_traces",
 		Help:      "The current number of lives traces per tenant.",
 	}, []string{"tenant"})
+	metricLiveTracesBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_live_traces_bytes",
+		Help:      "The current size in bytes of the live traces per tenant.",
+	}, []string{"tenant"})
+	metricTracesCutUnderPressureTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_traces_cut_under_pressure_total",
+		Help:      "The total number of live traces cut early because the live traces bytes limits were reached per tenant.",
+	}, []string{"tenant"})
 	metricBlocksClearedTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Namespace: "tempo",
 		Name:      "ingester_blocks_cleared_total",
 		Help:      "The total number of blocks cleared.",
 	})
+	metricTracesTruncatedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_traces_truncated_total",
+		Help:      "The total number of traces that exceeded the max bytes per trace and were truncated per tenant.",
+	}, []string{"tenant"})
 	metricBytesReceivedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "tempo",
 		Name:      "ingester_bytes_received_total",
@@ -93,13 +113,20 @@ type instance struct {
 	tracesMtx   sync.Mutex
 	traces      map[uint32]*liveTrace
 	largeTraces map[uint32]int // maxBytes that trace exceeded
-	traceCount  atomic.Int32
+	// truncatedTraces are the spans dropped from traces that exceeded maxBytes for tenants that truncate them
+	truncatedTraces map[uint32]int
+	traceCount      atomic.Int32
+	liveTracesBytes atomic.Int64
 
 	blocksMtx        sync.RWMutex
 	headBlock        *wal.AppendBlock
 	completingBlocks []*wal.AppendBlock
 	completeBlocks   []*wal.LocalBlock
 
+	// searchParquet is set when blocks are completed to vparquet. No flatbuffer search data is kept then,
+	// live traces and wal blocks are searched by decoding the traces and complete blocks are searched directly.
+	searchParquet        bool
+	tagsToDrop           map[string]struct{} // global search tags deny list, nil if there is none
 	searchHeadBlock      *searchStreamingBlockEntry
 	searchAppendBlocks   map[*wal.AppendBlock]*searchStreamingBlockEntry
 	searchCompleteBlocks map[*wal.LocalBlock]*searchLocalBlockEntry
@@ -119,11 +146,15 @@ type instance struct {
 	hash hash.Hash32
 }
 
+// searchStreamingBlockEntry guards searches of a wal block. b is nil if the block has no flatbuffer search data
+// and is searched directly.
 type searchStreamingBlockEntry struct {
 	b   *search.StreamingSearchBlock
 	mtx sync.RWMutex
 }
 
+// searchLocalBlockEntry guards searches of a local block. b is nil if the block has no flatbuffer search data
+// and is searched directly.
 type searchLocalBlockEntry struct {
 	b   *search.BackendSearchBlock
 	mtx sync.RWMutex
@@ -133,8 +164,10 @@ func newInstance(instanceID string, limiter *Limiter, writer tempodb.Writer, l *
 	i := &instance{
 		traces:               map[uint32]*liveTrace{},
 		largeTraces:          map[uint32]int{},
+		truncatedTraces:      map[uint32]int{},
 		searchAppendBlocks:   map[*wal.AppendBlock]*searchStreamingBlockEntry{},
 		searchCompleteBlocks: map[*wal.LocalBlock]*searchLocalBlockEntry{},
+		searchParquet:        writer.BlockVersion() == vparquet.VersionString,
 
 		instanceID:         instanceID,
 		tracesCreatedTotal: metricTracesCreatedTotal.WithLabelValues(instanceID),
@@ -185,7 +218,19 @@ func (i *instance) PushBytes(ctx context.Context, id []byte, traceBytes []byte,
 		return status.Errorf(codes.FailedPrecondition, "%s max live traces exceeded for tenant %s: %v", overrides.ErrorPrefixLiveTracesExceeded, i.instanceID, err)
 	}
 
-	return i.push(ctx, id, traceBytes, searchData)
+	// check for max live traces bytes. the ingester evicts live traces in the background once they get close to the
+	// limits, pushes are only refused if eviction can't keep up.
+	err = i.limiter.AssertMaxLiveTracesBytes(i.instanceID, int(i.liveTracesBytes.Load()), len(traceBytes))
+	if err != nil {
+		i.limiter.signalEviction()
+		return status.Errorf(codes.FailedPrecondition, "%s max live traces bytes exceeded for tenant %s: %v", overrides.ErrorPrefixLiveTracesBytesExceeded, i.instanceID, err)
+	}
+
+	err = i.push(ctx, id, traceBytes, searchData)
+	if i.limiter.liveTracesBytesAboveThreshold(i.instanceID, int(i.liveTracesBytes.Load())) {
+		i.limiter.signalEviction()
+	}
+	return err
 }
 
 func (i *instance) push(ctx context.Context, id, traceBytes, searchData []byte) error {
@@ -198,8 +243,23 @@ func (i *instance) push(ctx context.Context, id, traceBytes, searchData []byte)
 		return status.Errorf(codes.FailedPrecondition, (newTraceTooLargeError(id, i.instanceID, maxBytes, len(traceBytes)).Error()))
 	}
 
+	// the search data is not needed, traces are searched directly
+	if i.searchParquet {
+		searchData = nil
+	}
+
 	trace := i.getOrCreateTrace(id)
+	droppedSpans := trace.droppedSpans
+	currentBytes := trace.currentBytes
 	err := trace.Push(ctx, i.instanceID, traceBytes, searchData)
+	i.addLiveTracesBytes(trace.currentBytes - currentBytes)
+	if trace.truncated {
+		if _, ok := i.truncatedTraces[tkn]; !ok {
+			metricTracesTruncatedTotal.WithLabelValues(i.instanceID).Inc()
+		}
+		i.truncatedTraces[tkn] = trace.droppedSpans
+		overrides.RecordDiscardedSpans(trace.droppedSpans-droppedSpans, reasonTraceTruncated, i.instanceID)
+	}
 	if err != nil {
 		if e, ok := err.(*traceTooLargeError); ok {
 			i.largeTraces[tkn] = trace.maxBytes
@@ -220,10 +280,28 @@ func (i *instance) measureReceivedBytes(traceBytes []byte, searchData []byte) {
 
 // Moves any complete traces out of the map to complete traces.
 func (i *instance) CutCompleteTraces(cutoff time.Duration, immediate bool) error {
-	tracesToCut := i.tracesToCut(cutoff, immediate)
+	return i.cutTraces(i.tracesToCut(cutoff, immediate))
+}
+
+// evictTraces cuts the live traces with the given tokens to the head block. Traces that have been cut in the
+// meantime are skipped.
+func (i *instance) evictTraces(tokens []uint32) error {
+	tracesToCut := i.liveTracesToEvict(tokens)
+	metricTracesCutUnderPressureTotal.WithLabelValues(i.instanceID).Add(float64(len(tracesToCut)))
+
+	return i.cutTraces(tracesToCut)
+}
+
+// cutTraces writes the traces to the head block, they must have been removed from the live traces.
+func (i *instance) cutTraces(tracesToCut []*liveTrace) error {
 	segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
 
 	for _, t := range tracesToCut {
+		err := t.markTruncated()
+		if err != nil {
+			return err
+		}
+
 		// sort batches before cutting to reduce combinations during compaction
 		sortByteSlices(t.batches)
 
@@ -307,7 +385,7 @@ func (i *instance) CompleteBlock(blockID uuid.UUID) error {
 	i.blocksMtx.RUnlock()
 
 	var newSearch *search.BackendSearchBlock
-	if oldSearch != nil {
+	if oldSearch != nil && oldSearch.b != nil {
 		newSearch, err = i.writer.CompleteSearchBlockWithBackend(oldSearch.b, backendBlock.BlockMeta().BlockID, backendBlock.BlockMeta().TenantID, i.localReader, i.localWriter)
 		if err != nil {
 			return err
@@ -321,6 +399,8 @@ func (i *instance) CompleteBlock(blockID uuid.UUID) error {
 		i.searchCompleteBlocks[ingesterBlock] = &searchLocalBlockEntry{
 			b: newSearch,
 		}
+	} else if backendBlock.BlockMeta().Version == vparquet.VersionString {
+		i.searchCompleteBlocks[ingesterBlock] = &searchLocalBlockEntry{}
 	}
 	i.completeBlocks = append(i.completeBlocks, ingesterBlock)
 
@@ -346,7 +426,9 @@ func (i *instance) ClearCompletingBlock(blockID uuid.UUID) error {
 			// Take write lock to ensure no searches are reading.
 			entry.mtx.Lock()
 			defer entry.mtx.Unlock()
-			_ = entry.b.Clear()
+			if entry.b != nil {
+				_ = entry.b.Clear()
+			}
 			delete(i.searchAppendBlocks, completingBlock)
 		}
 
@@ -404,56 +486,58 @@ func (i *instance) ClearFlushedBlocks(completeBlockTimeout time.Duration) error
 }
 
 func (i *instance) FindTraceByID(ctx context.Context, id []byte) (*tempopb.Trace, error) {
-	var err error
+	span, ctx := opentracing.StartSpanFromContext(ctx, "instance.FindTraceByID")
+	defer span.Finish()
+
 	var completeTrace *tempopb.Trace
+	var completeBlockTraces []*tempopb.Trace
 
-	// live traces
-	i.tracesMtx.Lock()
-	if liveTrace, ok := i.traces[i.tokenForTraceID(id)]; ok {
-		completeTrace, err = model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForRead(liveTrace.batches)
-		if err != nil {
-			i.tracesMtx.Unlock()
-			return nil, fmt.Errorf("unable to unmarshal liveTrace: %w", err)
-		}
-	}
-	i.tracesMtx.Unlock()
+	timer := newStageTimer()
+	defer timer.logToSpan(span)
 
-	i.blocksMtx.RLock()
-	defer i.blocksMtx.RUnlock()
+	err := i.walk(ctx, &instanceVisitor{
+		liveTraces: func(_ context.Context, traces map[uint32]*liveTrace) error {
+			liveTrace, ok := traces[i.tokenForTraceID(id)]
+			if !ok {
+				return nil
+			}
 
-	// headBlock
-	foundBytes, err := i.headBlock.Find(id, model.StaticCombiner)
-	if err != nil {
-		return nil, fmt.Errorf("headBlock.Find failed: %w", err)
-	}
-	completeTrace, err = model.CombineForRead(foundBytes, i.headBlock.Meta().DataEncoding, completeTrace)
+			var err error
+			completeTrace, err = model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForRead(liveTrace.batches)
+			if err != nil {
+				return fmt.Errorf("unable to unmarshal liveTrace: %w", err)
+			}
+			return nil
+		},
+		appendBlock: func(_ context.Context, b *wal.AppendBlock, _ *searchStreamingBlockEntry) error {
+			foundBytes, err := b.Find(id, model.StaticCombiner)
+			if err != nil {
+				return fmt.Errorf("wal block find failed: %w", err)
+			}
+			completeTrace, err = model.CombineForRead(foundBytes, b.Meta().DataEncoding, completeTrace)
+			if err != nil {
+				return fmt.Errorf("wal block combine failed in FindTraceByID: %w", err)
+			}
+			return nil
+		},
+		localBlock: func(ctx context.Context, b *wal.LocalBlock, _ *searchLocalBlockEntry) error {
+			found, err := b.FindTraceByID(ctx, id)
+			if err != nil {
+				return fmt.Errorf("completeBlock.FindTraceByID failed: %w", err)
+			}
+			completeBlockTraces = append(completeBlockTraces, found)
+			return nil
+		},
+	}, false, timer)
 	if err != nil {
-		return nil, fmt.Errorf("headblock unmarshal failed in FindTraceByID: %w", err)
-	}
-
-	// completingBlock
-	for _, c := range i.completingBlocks {
-		foundBytes, err = c.Find(id, model.StaticCombiner)
-		if err != nil {
-			return nil, fmt.Errorf("completingBlock.Find failed: %w", err)
-		}
-		completeTrace, err = model.CombineForRead(foundBytes, c.Meta().DataEncoding, completeTrace)
-		if err != nil {
-			return nil, fmt.Errorf("completingBlocks combine failed in FindTraceByID: %w", err)
-		}
+		return nil, err
 	}
 
-	// completeBlock
 	combiner := trace.NewCombiner()
 	combiner.Consume(completeTrace)
-	for _, c := range i.completeBlocks {
-		found, err := c.FindTraceByID(ctx, id)
-		if err != nil {
-			return nil, fmt.Errorf("completeBlock.FindTraceByID failed: %w", err)
-		}
-		combiner.Consume(found)
+	for _, t := range completeBlockTraces {
+		combiner.Consume(t)
 	}
-
 	result, _ := combiner.Result()
 	return result, nil
 }
@@ -468,7 +552,7 @@ func (i *instance) AddCompletingBlock(b *wal.AppendBlock, s *search.StreamingSea
 	i.completingBlocks = append(i.completingBlocks, b)
 
 	// search WAL
-	if s == nil {
+	if s == nil && !i.searchParquet {
 		return
 	}
 	i.searchAppendBlocks[b] = &searchStreamingBlockEntry{b: s}
@@ -486,6 +570,12 @@ func (i *instance) getOrCreateTrace(traceID []byte) *liveTrace {
 	maxBytes := i.limiter.limits.MaxBytesPerTrace(i.instanceID)
 	maxSearchBytes := i.limiter.limits.MaxSearchBytesPerTrace(i.instanceID)
 	trace = newTrace(traceID, maxBytes, maxSearchBytes)
+	trace.truncate = i.limiter.limits.MaxBytesPerTraceMode(i.instanceID) == overrides.MaxBytesPerTraceModeTruncate
+	// traces that were truncated before are only continued with root and error spans
+	if droppedSpans, ok := i.truncatedTraces[fp]; ok {
+		trace.truncated = true
+		trace.droppedSpans = droppedSpans
+	}
 	i.traces[fp] = trace
 	i.tracesCreatedTotal.Inc()
 	i.traceCount.Inc()
@@ -493,6 +583,27 @@ func (i *instance) getOrCreateTrace(traceID []byte) *liveTrace {
 	return trace
 }
 
+// droppedSpans returns the number of spans dropped from the trace since the last block was cut if it was truncated
+func (i *instance) droppedSpans(id []byte) int {
+	i.tracesMtx.Lock()
+	defer i.tracesMtx.Unlock()
+
+	return i.truncatedTraces[i.tokenForTraceID(id)]
+}
+
+// isLive returns true if any of the traces has not been cut to the head block yet
+func (i *instance) isLive(ids [][]byte) bool {
+	i.tracesMtx.Lock()
+	defer i.tracesMtx.Unlock()
+
+	for _, id := range ids {
+		if _, ok := i.traces[i.tokenForTraceID(id)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // tokenForTraceID hash trace ID, should be called under lock
 func (i *instance) tokenForTraceID(id []byte) uint32 {
 	i.hash.Reset()
@@ -506,6 +617,7 @@ func (i *instance) resetHeadBlock() error {
 	// Clear large traces when cutting block
 	i.tracesMtx.Lock()
 	i.largeTraces = map[uint32]int{}
+	i.truncatedTraces = map[uint32]int{}
 	i.tracesMtx.Unlock()
 
 	oldHeadBlock := i.headBlock
@@ -518,6 +630,15 @@ func (i *instance) resetHeadBlock() error {
 	i.headBlock = newHeadBlock
 	i.lastBlockCut = time.Now()
 
+	if i.searchHeadBlock != nil {
+		i.searchAppendBlocks[oldHeadBlock] = i.searchHeadBlock
+	}
+
+	if i.searchParquet {
+		i.searchHeadBlock = &searchStreamingBlockEntry{}
+		return nil
+	}
+
 	// Create search data wal file
 	f, enc, err := i.writer.WAL().NewFile(i.headBlock.BlockID(), i.instanceID, searchDir)
 	if err != nil {
@@ -528,9 +649,6 @@ func (i *instance) resetHeadBlock() error {
 	if err != nil {
 		return err
 	}
-	if i.searchHeadBlock != nil {
-		i.searchAppendBlocks[oldHeadBlock] = i.searchHeadBlock
-	}
 	i.searchHeadBlock = &searchStreamingBlockEntry{
 		b: b,
 	}
@@ -551,13 +669,64 @@ func (i *instance) tracesToCut(cutoff time.Duration, immediate bool) []*liveTrac
 		if cutoffTime.After(trace.lastAppend) || immediate {
 			tracesToCut = append(tracesToCut, trace)
 			delete(i.traces, key)
+			i.addLiveTracesBytes(-trace.currentBytes)
+		}
+	}
+	i.traceCount.Store(int32(len(i.traces)))
+	metricLiveTracesBytes.WithLabelValues(i.instanceID).Set(float64(i.liveTracesBytes.Load()))
+
+	return tracesToCut
+}
+
+// liveTraceAge is the last append and the size of a live trace, used to pick the traces to evict across instances
+type liveTraceAge struct {
+	token      uint32
+	lastAppend time.Time
+	bytes      int
+}
+
+// liveTraceAges returns the last append and the size of all live traces, the oldest first
+func (i *instance) liveTraceAges() []liveTraceAge {
+	i.tracesMtx.Lock()
+	ages := make([]liveTraceAge, 0, len(i.traces))
+	for token, trace := range i.traces {
+		ages = append(ages, liveTraceAge{token: token, lastAppend: trace.lastAppend, bytes: trace.currentBytes})
+	}
+	i.tracesMtx.Unlock()
+
+	sort.Slice(ages, func(a, b int) bool {
+		return ages[a].lastAppend.Before(ages[b].lastAppend)
+	})
+	return ages
+}
+
+// liveTracesToEvict removes the traces with the given tokens from the live traces
+func (i *instance) liveTracesToEvict(tokens []uint32) []*liveTrace {
+	i.tracesMtx.Lock()
+	defer i.tracesMtx.Unlock()
+
+	tracesToCut := make([]*liveTrace, 0, len(tokens))
+	for _, token := range tokens {
+		trace, ok := i.traces[token]
+		if !ok {
+			continue
 		}
+		tracesToCut = append(tracesToCut, trace)
+		delete(i.traces, token)
+		i.addLiveTracesBytes(-trace.currentBytes)
 	}
 	i.traceCount.Store(int32(len(i.traces)))
+	metricLiveTracesBytes.WithLabelValues(i.instanceID).Set(float64(i.liveTracesBytes.Load()))
 
 	return tracesToCut
 }
 
+// addLiveTracesBytes tracks the bytes of the live traces of the instance and of all instances
+func (i *instance) addLiveTracesBytes(bytes int) {
+	i.liveTracesBytes.Add(int64(bytes))
+	i.limiter.liveTracesBytes.Add(int64(bytes))
+}
+
 func (i *instance) writeTraceToHeadBlock(id common.ID, b []byte, searchData [][]byte, start, end uint32) error {
 	i.blocksMtx.Lock()
 	defer i.blocksMtx.Unlock()
@@ -568,7 +737,7 @@ func (i *instance) writeTraceToHeadBlock(id common.ID, b []byte, searchData [][]
 	}
 
 	entry := i.searchHeadBlock
-	if entry != nil {
+	if entry != nil && entry.b != nil {
 		// Don't take a write lock on the block here. It is safe
 		// for the appender to write to its file while a search
 		// is reading it. This prevents stalling the write path
@@ -637,11 +806,15 @@ func (i *instance) rediscoverLocalBlocks(ctx context.Context) ([]*wal.LocalBlock
 
 		rediscoveredBlocks = append(rediscoveredBlocks, ib)
 
-		sb := search.OpenBackendSearchBlock(b.BlockMeta().BlockID, b.BlockMeta().TenantID, i.localReader)
+		// vparquet blocks are searched directly
+		entry := &searchLocalBlockEntry{}
+		if meta.Version != vparquet.VersionString {
+			entry.b = search.OpenBackendSearchBlock(b.BlockMeta().BlockID, b.BlockMeta().TenantID, i.localReader)
+		}
 
 		i.blocksMtx.Lock()
 		i.completeBlocks = append(i.completeBlocks, ib)
-		i.searchCompleteBlocks[ib] = &searchLocalBlockEntry{b: sb}
+		i.searchCompleteBlocks[ib] = entry
 		i.blocksMtx.Unlock()
 
 		level.Info(log.Logger).Log("msg", "reloaded local block", "tenantID", i.instanceID, "block", id.String(), "flushed", ib.FlushedTime())
@@ -0,0 +1,209 @@
+package ingester
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	ot_log "github.com/opentracing/opentracing-go/log"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+// Every query of an instance walks the stages of its data in this order
+const (
+	stageLiveTraces       = "live_traces"
+	stageHeadBlock        = "head_block"
+	stageCompletingBlocks = "completing_blocks"
+	stageCompleteBlocks   = "complete_blocks"
+)
+
+var queryStages = []string{stageLiveTraces, stageHeadBlock, stageCompletingBlocks, stageCompleteBlocks}
+
+// instanceVisitor is called by walk with the data of an instance. The search entries of the blocks are nil if the
+// block has no search entry.
+type instanceVisitor struct {
+	// liveTraces is called under the traces lock. Optional.
+	liveTraces func(ctx context.Context, traces map[uint32]*liveTrace) error
+	// liveTraceCopies is called with copies of the live traces outside of the traces lock, visitors that decode
+	// all live traces don't block pushes. Optional.
+	liveTraceCopies func(ctx context.Context, traces []*liveTraceCopy) error
+	// appendBlock is called with the head and completing blocks under the read lock of their search entry
+	appendBlock func(ctx context.Context, b *wal.AppendBlock, entry *searchStreamingBlockEntry) error
+	// localBlock is called with the complete blocks under the read lock of their search entry
+	localBlock func(ctx context.Context, b *wal.LocalBlock, entry *searchLocalBlockEntry) error
+	// done stops the walk early if it returns true, e.g. because a limit is reached. Optional.
+	done func() bool
+}
+
+// stageTimer sums up the time spent per stage of a query
+type stageTimer struct {
+	mtx       sync.Mutex
+	durations map[string]time.Duration
+}
+
+func newStageTimer() *stageTimer {
+	return &stageTimer{
+		durations: map[string]time.Duration{},
+	}
+}
+
+func (t *stageTimer) observe(stage string, start time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.durations[stage] += time.Since(start)
+}
+
+// metrics returns the time spent in the stages walked so far
+func (t *stageTimer) metrics() []*tempopb.SearchStageMetrics {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	metrics := make([]*tempopb.SearchStageMetrics, 0, len(t.durations))
+	for _, stage := range queryStages {
+		if d, ok := t.durations[stage]; ok {
+			metrics = append(metrics, &tempopb.SearchStageMetrics{
+				Stage:         stage,
+				DurationNanos: uint64(d.Nanoseconds()),
+			})
+		}
+	}
+	return metrics
+}
+
+// logToSpan records the time spent per stage on the span of the query
+func (t *stageTimer) logToSpan(span opentracing.Span) {
+	fields := make([]ot_log.Field, 0, len(queryStages))
+	for _, m := range t.metrics() {
+		fields = append(fields, ot_log.String(m.Stage, time.Duration(m.DurationNanos).String()))
+	}
+	span.LogFields(fields...)
+}
+
+// liveTraceCopy is the data of a live trace needed to search it
+type liveTraceCopy struct {
+	traceID    []byte
+	batches    [][]byte
+	searchData [][]byte
+}
+
+// copyLiveTraces copies the live traces under the traces lock. The batches are returned to a pool once the trace is
+// cut, so they are only copied if the traces are searched directly and not with their search data.
+func (i *instance) copyLiveTraces() []*liveTraceCopy {
+	i.tracesMtx.Lock()
+	defer i.tracesMtx.Unlock()
+
+	copies := make([]*liveTraceCopy, 0, len(i.traces))
+	for _, t := range i.traces {
+		c := &liveTraceCopy{
+			traceID:    t.traceID,
+			searchData: append([][]byte(nil), t.searchData...),
+		}
+		if i.searchParquet {
+			c.batches = make([][]byte, 0, len(t.batches))
+			for _, b := range t.batches {
+				c.batches = append(c.batches, append([]byte(nil), b...))
+			}
+		}
+		copies = append(copies, c)
+	}
+	return copies
+}
+
+// walk calls the visitor with the live traces, the head block, the completing blocks and the complete blocks of the
+// instance in this order and records the time spent per stage. If parallel is set the live traces and the blocks are
+// visited concurrently and the errors of the visitor are ignored, otherwise the walk stops at the first error. The
+// blocks can't be removed while they are walked.
+func (i *instance) walk(ctx context.Context, v *instanceVisitor, parallel bool, timer *stageTimer) error {
+	done := func() bool {
+		return ctx.Err() != nil || (v.done != nil && v.done())
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	visit := func(stage string, fn func() error) error {
+		if !parallel {
+			defer timer.observe(stage, time.Now())
+			return fn()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer timer.observe(stage, time.Now())
+			_ = fn()
+		}()
+		return nil
+	}
+
+	err := visit(stageLiveTraces, func() error {
+		if v.liveTraceCopies != nil {
+			return v.liveTraceCopies(ctx, i.copyLiveTraces())
+		}
+
+		i.tracesMtx.Lock()
+		defer i.tracesMtx.Unlock()
+
+		return v.liveTraces(ctx, i.traces)
+	})
+	if err != nil {
+		return err
+	}
+	if done() {
+		return ctx.Err()
+	}
+
+	// hold the lock until all blocks are visited so they are not cleared in between
+	i.blocksMtx.RLock()
+	defer i.blocksMtx.RUnlock()
+	defer wg.Wait()
+
+	visitAppendBlock := func(stage string, b *wal.AppendBlock, entry *searchStreamingBlockEntry) error {
+		return visit(stage, func() error {
+			if entry != nil {
+				entry.mtx.RLock()
+				defer entry.mtx.RUnlock()
+			}
+			return v.appendBlock(ctx, b, entry)
+		})
+	}
+
+	err = visitAppendBlock(stageHeadBlock, i.headBlock, i.searchHeadBlock)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range i.completingBlocks {
+		if done() {
+			return ctx.Err()
+		}
+		err = visitAppendBlock(stageCompletingBlocks, b, i.searchAppendBlocks[b])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range i.completeBlocks {
+		if done() {
+			return ctx.Err()
+		}
+
+		b, entry := b, i.searchCompleteBlocks[b]
+		err = visit(stageCompleteBlocks, func() error {
+			if entry != nil {
+				entry.mtx.RLock()
+				defer entry.mtx.RUnlock()
+			}
+			return v.localBlock(ctx, b, entry)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
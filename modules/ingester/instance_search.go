@@ -3,17 +3,22 @@ package ingester
 import (
 	"context"
 	"sort"
+	"strconv"
 
 	"github.com/go-kit/log/level"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/opentracing/opentracing-go"
-	ot_log "github.com/opentracing/opentracing-go/log"
 	"github.com/weaveworks/common/user"
 
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/pkg/tempofb"
 	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/grafana/tempo/tempodb/search"
+	"github.com/grafana/tempo/tempodb/wal"
 )
 
 func (i *instance) Search(ctx context.Context, req *tempopb.SearchRequest) (*tempopb.SearchResponse, error) {
@@ -32,15 +37,29 @@ func (i *instance) Search(ctx context.Context, req *tempopb.SearchRequest) (*tem
 	sr := search.NewResults()
 	defer sr.Close()
 
-	i.searchLiveTraces(ctx, p, sr)
+	timer := newStageTimer()
 
-	// Lock blocks mutex until all search tasks have been created. This avoids
-	// deadlocking with other activity (ingest, flushing), caused by releasing
-	// and then attempting to retake the lock.
-	i.blocksMtx.RLock()
-	i.searchWAL(ctx, p, sr)
-	i.searchLocalBlocks(ctx, p, sr)
-	i.blocksMtx.RUnlock()
+	sr.StartWorker()
+	go func() {
+		defer sr.FinishWorker()
+
+		// errors are logged per block, the search continues with the other blocks
+		_ = i.walk(ctx, &instanceVisitor{
+			liveTraceCopies: func(ctx context.Context, traces []*liveTraceCopy) error {
+				i.searchLiveTraces(ctx, traces, req, p, sr)
+				return nil
+			},
+			appendBlock: func(ctx context.Context, b *wal.AppendBlock, e *searchStreamingBlockEntry) error {
+				i.searchAppendBlock(ctx, b, e, req, p, sr)
+				return nil
+			},
+			localBlock: func(ctx context.Context, b *wal.LocalBlock, e *searchLocalBlockEntry) error {
+				i.searchLocalBlock(ctx, b, e, req, p, sr)
+				return nil
+			},
+			done: sr.Quit,
+		}, true, timer)
+	}()
 
 	sr.AllWorkersStarted()
 
@@ -76,112 +95,123 @@ func (i *instance) Search(ctx context.Context, req *tempopb.SearchRequest) (*tem
 			InspectedBytes:  sr.BytesInspected(),
 			InspectedBlocks: sr.BlocksInspected(),
 			SkippedBlocks:   sr.BlocksSkipped(),
+			Stages:          timer.metrics(),
 		},
 	}, nil
 }
 
-func (i *instance) searchLiveTraces(ctx context.Context, p search.Pipeline, sr *search.Results) {
-	sr.StartWorker()
-
-	go func() {
-		span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchLiveTraces")
-		defer span.Finish()
+// searchLiveTraces searches copies of the live traces
+func (i *instance) searchLiveTraces(ctx context.Context, traces []*liveTraceCopy, req *tempopb.SearchRequest, p search.Pipeline, sr *search.Results) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchLiveTraces")
+	defer span.Finish()
 
-		defer sr.FinishWorker()
+	entry := &tempofb.SearchEntry{} // buffer
+	segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
 
-		i.tracesMtx.Lock()
-		defer i.tracesMtx.Unlock()
+	for _, t := range traces {
+		if sr.Quit() {
+			return
+		}
 
-		span.LogFields(ot_log.Event("live traces mtx acquired"))
+		sr.AddTraceInspected(1)
 
-		entry := &tempofb.SearchEntry{} // buffer
+		var result *tempopb.TraceSearchMetadata
 
-		for _, t := range i.traces {
-			if sr.Quit() {
-				return
+		if i.searchParquet {
+			for _, b := range t.batches {
+				sr.AddBytesInspected(uint64(len(b)))
 			}
 
-			sr.AddTraceInspected(1)
-
-			var result *tempopb.TraceSearchMetadata
-
-			// Search and combine from all segments for the trace.
-			for _, s := range t.searchData {
-				sr.AddBytesInspected(uint64(len(s)))
+			tr, err := segmentDecoder.PrepareForRead(t.batches)
+			if err != nil {
+				level.Error(log.Logger).Log("msg", "error decoding live trace", "err", err)
+				continue
+			}
+			result, err = trace.MatchesProto(t.traceID, tr, req)
+			if err != nil {
+				level.Error(log.Logger).Log("msg", "error searching live trace", "err", err)
+				continue
+			}
+		}
 
-				entry.Reset(s)
-				if p.Matches(entry) {
-					newResult := search.GetSearchResultFromData(entry)
-					if result != nil {
-						search.CombineSearchResults(result, newResult)
-					} else {
-						result = newResult
-					}
+		// Search and combine from all segments for the trace.
+		for _, s := range t.searchData {
+			sr.AddBytesInspected(uint64(len(s)))
+
+			entry.Reset(s)
+			if p.Matches(entry) {
+				newResult := search.GetSearchResultFromData(entry)
+				if result != nil {
+					search.CombineSearchResults(result, newResult)
+				} else {
+					result = newResult
 				}
 			}
+		}
 
-			if result != nil {
-				if quit := sr.AddResult(ctx, result); quit {
-					return
-				}
+		if result != nil {
+			if quit := sr.AddResult(ctx, result); quit {
+				return
 			}
 		}
-	}()
+	}
 }
 
-// searchWAL starts a search task for every WAL block. Must be called under lock.
-func (i *instance) searchWAL(ctx context.Context, p search.Pipeline, sr *search.Results) {
-	searchFunc := func(e *searchStreamingBlockEntry) {
-		span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchWAL")
-		defer span.Finish()
-
-		defer sr.FinishWorker()
-
-		e.mtx.RLock()
-		defer e.mtx.RUnlock()
+// searchAppendBlock searches a wal block with its flatbuffer search data if it has any or directly otherwise
+func (i *instance) searchAppendBlock(ctx context.Context, b *wal.AppendBlock, e *searchStreamingBlockEntry, req *tempopb.SearchRequest, p search.Pipeline, sr *search.Results) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchWAL")
+	defer span.Finish()
 
-		span.LogFields(ot_log.Event("streaming block entry mtx acquired"))
-		span.SetTag("blockID", e.b.BlockID().String())
+	span.SetTag("blockID", b.BlockID().String())
 
-		err := e.b.Search(ctx, p, sr)
-		if err != nil {
-			level.Error(log.Logger).Log("msg", "error searching wal block", "blockID", e.b.BlockID().String(), "err", err)
-		}
+	var err error
+	if e != nil && e.b != nil {
+		err = e.b.Search(ctx, p, sr)
+	} else {
+		err = searchBlock(ctx, b, req, sr)
 	}
-
-	// head block
-	sr.StartWorker()
-	go searchFunc(i.searchHeadBlock)
-
-	// completing blocks
-	for _, e := range i.searchAppendBlocks {
-		sr.StartWorker()
-		go searchFunc(e)
+	if err != nil {
+		level.Error(log.Logger).Log("msg", "error searching wal block", "blockID", b.BlockID().String(), "err", err)
 	}
 }
 
-// searchLocalBlocks starts a search task for every local block. Must be called under lock.
-func (i *instance) searchLocalBlocks(ctx context.Context, p search.Pipeline, sr *search.Results) {
-	for _, e := range i.searchCompleteBlocks {
-		sr.StartWorker()
-		go func(e *searchLocalBlockEntry) {
-			span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchLocalBlocks")
-			defer span.Finish()
+// searchLocalBlock searches a local block with its flatbuffer search data if it has any or directly otherwise
+func (i *instance) searchLocalBlock(ctx context.Context, b *wal.LocalBlock, e *searchLocalBlockEntry, req *tempopb.SearchRequest, p search.Pipeline, sr *search.Results) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchLocalBlocks")
+	defer span.Finish()
 
-			defer sr.FinishWorker()
+	span.SetTag("blockID", b.BlockMeta().BlockID.String())
 
-			e.mtx.RLock()
-			defer e.mtx.RUnlock()
+	var err error
+	if e != nil && e.b != nil {
+		err = e.b.Search(ctx, p, sr)
+	} else {
+		err = searchBlock(ctx, b, req, sr)
+	}
+	if err != nil {
+		level.Error(log.Logger).Log("msg", "error searching local block", "blockID", b.BlockMeta().BlockID.String(), "err", err)
+	}
+}
 
-			span.LogFields(ot_log.Event("local block entry mtx acquired"))
-			span.SetTag("blockID", e.b.BlockID().String())
+// searchBlock searches a block without flatbuffer search data directly and adds its results
+func searchBlock(ctx context.Context, b common.Searcher, req *tempopb.SearchRequest, sr *search.Results) error {
+	resp, err := b.Search(ctx, req, common.DefaultSearchOptions())
+	if err != nil {
+		return err
+	}
 
-			err := e.b.Search(ctx, p, sr)
-			if err != nil {
-				level.Error(log.Logger).Log("msg", "error searching local block", "blockID", e.b.BlockID().String(), "err", err)
-			}
-		}(e)
+	sr.AddBlockInspected()
+	if resp.Metrics != nil {
+		sr.AddTraceInspected(resp.Metrics.InspectedTraces)
+		sr.AddBytesInspected(resp.Metrics.InspectedBytes)
 	}
+
+	for _, result := range resp.Traces {
+		if quit := sr.AddResult(ctx, result); quit {
+			return nil
+		}
+	}
+	return nil
 }
 
 func (i *instance) SearchTags(ctx context.Context) (*tempopb.SearchTagsResponse, error) {
@@ -193,21 +223,7 @@ func (i *instance) SearchTags(ctx context.Context) (*tempopb.SearchTagsResponse,
 	limit := i.limiter.limits.MaxBytesPerTagValuesQuery(userID)
 	distinctValues := util.NewDistinctStringCollector(limit)
 
-	kv := &tempofb.KeyValues{}
-	err = i.visitSearchEntriesLiveTraces(ctx, func(entry *tempofb.SearchEntry) {
-		for i, ii := 0, entry.TagsLength(); i < ii; i++ {
-			entry.Tags(kv, i)
-			key := string(kv.Key())
-			distinctValues.Collect(key)
-		}
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	err = i.visitSearchableBlocks(ctx, func(block search.SearchableBlock) error {
-		return block.Tags(ctx, distinctValues.Collect)
-	})
+	err = i.walkTags(ctx, "", distinctValues)
 	if err != nil {
 		return nil, err
 	}
@@ -230,24 +246,7 @@ func (i *instance) SearchTagValues(ctx context.Context, tagName string) (*tempop
 	limit := i.limiter.limits.MaxBytesPerTagValuesQuery(userID)
 	distinctValues := util.NewDistinctStringCollector(limit)
 
-	kv := &tempofb.KeyValues{}
-	tagNameBytes := []byte(tagName)
-	err = i.visitSearchEntriesLiveTraces(ctx, func(entry *tempofb.SearchEntry) {
-		kv := tempofb.FindTag(entry, kv, tagNameBytes)
-		if kv != nil {
-			for i, ii := 0, kv.ValueLength(); i < ii; i++ {
-				key := string(kv.Value(i))
-				distinctValues.Collect(key)
-			}
-		}
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	err = i.visitSearchableBlocks(ctx, func(block search.SearchableBlock) error {
-		return block.TagValues(ctx, tagName, distinctValues.Collect)
-	})
+	err = i.walkTags(ctx, tagName, distinctValues)
 	if err != nil {
 		return nil, err
 	}
@@ -261,91 +260,178 @@ func (i *instance) SearchTagValues(ctx context.Context, tagName string) (*tempop
 	}, nil
 }
 
-func (i *instance) visitSearchEntriesLiveTraces(ctx context.Context, visitFn func(entry *tempofb.SearchEntry)) error {
-	span, _ := opentracing.StartSpanFromContext(ctx, "instance.visitSearchEntriesLiveTraces")
-	defer span.Finish()
+// traceIterator is implemented by complete blocks whose traces can be iterated, i.e. vParquet blocks
+type traceIterator interface {
+	IterateTraces(ctx context.Context, fn func(*tempopb.Trace) bool) error
+}
 
-	i.tracesMtx.Lock()
-	defer i.tracesMtx.Unlock()
+// walkTags collects the tag names of all traces of the instance or, if tagName is set, the values of the tag. Blocks
+// are visited with their flatbuffer search data if they have any and by decoding their traces otherwise. The walk
+// stops once the limit of the collector is exceeded.
+func (i *instance) walkTags(ctx context.Context, tagName string, distinctValues *util.DistinctStringCollector) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "instance.walkTags")
+	defer span.Finish()
 
-	se := &tempofb.SearchEntry{}
-	for _, t := range i.traces {
-		for _, s := range t.searchData {
-			se.Reset(s)
-			visitFn(se)
+	kv := &tempofb.KeyValues{}
+	tagNameBytes := []byte(tagName)
+	collectEntry := func(entry *tempofb.SearchEntry) {
+		if tagName == "" {
+			for j, jj := 0, entry.TagsLength(); j < jj; j++ {
+				entry.Tags(kv, j)
+				distinctValues.Collect(string(kv.Key()))
+			}
+			return
+		}
 
-			if err := ctx.Err(); err != nil {
-				return err
+		kv := tempofb.FindTag(entry, kv, tagNameBytes)
+		if kv != nil {
+			for j, jj := 0, kv.ValueLength(); j < jj; j++ {
+				distinctValues.Collect(string(kv.Value(j)))
 			}
 		}
 	}
-	return nil
-}
-
-func (i *instance) visitSearchableBlocks(ctx context.Context, visitFn func(block search.SearchableBlock) error) error {
-	i.blocksMtx.RLock()
-	defer i.blocksMtx.RUnlock()
-
-	err := i.visitSearchableBlocksWAL(ctx, visitFn)
-	if err != nil {
-		return err
+	allowedTags := i.limiter.limits.SearchTagsAllowList(i.instanceID)
+	extractTag := func(tag string) bool {
+		// same as the distributor: the per tenant allow list overrules the global deny list
+		if _, ok := allowedTags[tag]; ok {
+			return true
+		}
+		_, drop := i.tagsToDrop[tag]
+		return !drop
+	}
+	collectTrace := func(t *tempopb.Trace) {
+		visitTags(t, extractTag, func(key, value string) {
+			if tagName == "" {
+				distinctValues.Collect(key)
+			} else if key == tagName {
+				distinctValues.Collect(value)
+			}
+		})
+	}
+	collectBlock := func(block search.SearchableBlock) error {
+		if tagName == "" {
+			return block.Tags(ctx, distinctValues.Collect)
+		}
+		return block.TagValues(ctx, tagName, distinctValues.Collect)
 	}
 
-	return i.visitSearchableBlocksLocalBlocks(ctx, visitFn)
-}
+	timer := newStageTimer()
+	defer timer.logToSpan(span)
+
+	return i.walk(ctx, &instanceVisitor{
+		liveTraceCopies: func(ctx context.Context, traces []*liveTraceCopy) error {
+			se := &tempofb.SearchEntry{}
+			segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
+			for _, t := range traces {
+				for _, s := range t.searchData {
+					se.Reset(s)
+					collectEntry(se)
+				}
 
-// visitSearchableBlocksWAL visits every WAL block. Must be called under lock.
-func (i *instance) visitSearchableBlocksWAL(ctx context.Context, visitFn func(block search.SearchableBlock) error) error {
-	span, _ := opentracing.StartSpanFromContext(ctx, "instance.visitSearchableBlocksWAL")
-	defer span.Finish()
+				if i.searchParquet {
+					tr, err := segmentDecoder.PrepareForRead(t.batches)
+					if err != nil {
+						return err
+					}
+					collectTrace(tr)
+				}
 
-	visitUnderLock := func(entry *searchStreamingBlockEntry) error {
-		entry.mtx.RLock()
-		defer entry.mtx.RUnlock()
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if distinctValues.Exceeded() {
+					return nil
+				}
+			}
+			return nil
+		},
+		appendBlock: func(ctx context.Context, b *wal.AppendBlock, e *searchStreamingBlockEntry) error {
+			if e != nil && e.b != nil {
+				return collectBlock(e.b)
+			}
 
-		return visitFn(entry.b)
-	}
+			dec, err := model.NewObjectDecoder(b.Meta().DataEncoding)
+			if err != nil {
+				return err
+			}
 
-	err := visitUnderLock(i.searchHeadBlock)
-	if err != nil {
-		return err
-	}
-	if err := ctx.Err(); err != nil {
-		return err
-	}
+			var decodeErr error
+			err = b.IterateObjects(ctx, model.StaticCombiner, func(_ common.ID, obj []byte) bool {
+				var t *tempopb.Trace
+				t, decodeErr = dec.PrepareForRead(obj)
+				if decodeErr != nil {
+					return false
+				}
+				collectTrace(t)
+				return ctx.Err() == nil && !distinctValues.Exceeded()
+			})
+			if err != nil {
+				return err
+			}
+			if decodeErr != nil {
+				return decodeErr
+			}
+			return ctx.Err()
+		},
+		localBlock: func(ctx context.Context, b *wal.LocalBlock, e *searchLocalBlockEntry) error {
+			if e != nil && e.b != nil {
+				return collectBlock(e.b)
+			}
 
-	for _, b := range i.searchAppendBlocks {
-		err := visitUnderLock(b)
-		if err != nil {
-			return err
-		}
-		if err := ctx.Err(); err != nil {
-			return err
-		}
-	}
-	return nil
+			// blocks of other encodings without flatbuffer search data can't be visited
+			iter, ok := b.BackendBlock.(traceIterator)
+			if !ok {
+				return nil
+			}
+			err := iter.IterateTraces(ctx, func(t *tempopb.Trace) bool {
+				collectTrace(t)
+				return ctx.Err() == nil && !distinctValues.Exceeded()
+			})
+			if err != nil {
+				return err
+			}
+			return ctx.Err()
+		},
+		done: distinctValues.Exceeded,
+	}, false, timer)
 }
 
-// visitSearchableBlocksWAL visits every local block. Must be called under lock.
-func (i *instance) visitSearchableBlocksLocalBlocks(ctx context.Context, visitFn func(block search.SearchableBlock) error) error {
-	span, _ := opentracing.StartSpanFromContext(ctx, "instance.visitSearchableBlocksLocalBlocks")
-	defer span.Finish()
-
-	visitUnderLock := func(entry *searchLocalBlockEntry) error {
-		entry.mtx.RLock()
-		defer entry.mtx.RUnlock()
-
-		return visitFn(entry.b)
+// visitTags calls visitFn with the same tags the distributor extracts into the flatbuffer search data. Attributes
+// are only visited if extractTag returns true for their key.
+func visitTags(t *tempopb.Trace, extractTag func(tag string) bool, visitFn func(key, value string)) {
+	visitAttributes := func(attrs []*v1_common.KeyValue) {
+		for _, a := range attrs {
+			if a.Value != nil && extractTag(a.Key) {
+				visitFn(a.Key, util.StringifyAnyValue(a.Value))
+			}
+		}
 	}
 
-	for _, b := range i.searchCompleteBlocks {
-		err := visitUnderLock(b)
-		if err != nil {
-			return err
+	for _, b := range t.Batches {
+		if b.Resource != nil {
+			visitAttributes(b.Resource.Attributes)
 		}
-		if err := ctx.Err(); err != nil {
-			return err
+
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				if len(s.ParentSpanId) == 0 {
+					visitFn(trace.RootSpanNameTag, s.Name)
+
+					if b.Resource != nil {
+						for _, a := range b.Resource.Attributes {
+							if a.Key == trace.ServiceNameTag && a.Value != nil {
+								visitFn(trace.RootServiceNameTag, util.StringifyAnyValue(a.Value))
+							}
+						}
+					}
+				}
+
+				visitFn(trace.SpanNameTag, s.Name)
+				if s.Status != nil {
+					visitFn(trace.StatusCodeTag, strconv.Itoa(int(s.Status.Code)))
+				}
+				visitAttributes(s.Attributes)
+			}
 		}
 	}
-	return nil
 }
@@ -21,15 +21,17 @@ import (
 	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/pkg/tempofb"
 	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 	"github.com/grafana/tempo/tempodb/search"
 )
 
 func TestInstanceSearch(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	assert.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	tempDir := t.TempDir()
 
@@ -114,7 +116,7 @@ func checkEqual(t *testing.T, ids [][]byte, sr *tempopb.SearchResponse) {
 func TestInstanceSearchTags(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	assert.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	tempDir := t.TempDir()
 
@@ -172,7 +174,7 @@ func TestInstanceSearchMaxBytesPerTagValuesQueryReturnsPartial(t *testing.T) {
 		MaxBytesPerTagValuesQuery: 10,
 	})
 	assert.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	tempDir := t.TempDir()
 
@@ -243,7 +245,7 @@ func writeTracesWithSearchData(t *testing.T, i *instance, tagKey string, tagValu
 func TestInstanceSearchNoData(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	assert.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 	i, err := newInstance("fake", limiter, ingester.store, ingester.local)
@@ -261,7 +263,7 @@ func TestInstanceSearchNoData(t *testing.T) {
 func TestInstanceSearchDoesNotRace(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err)
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 	i, err := newInstance("fake", limiter, ingester.store, ingester.local)
@@ -369,7 +371,7 @@ func TestInstanceSearchDoesNotRace(t *testing.T) {
 func TestWALBlockDeletedDuringSearch(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err)
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 	i, err := newInstance("fake", limiter, ingester.store, ingester.local)
@@ -479,11 +481,21 @@ func TestInstanceSearchMetrics(t *testing.T) {
 		return sr.Metrics
 	}
 
+	stages := func(m *tempopb.SearchMetrics) []string {
+		var stages []string
+		for _, s := range m.Stages {
+			require.NotZero(t, s.DurationNanos)
+			stages = append(stages, s.Stage)
+		}
+		return stages
+	}
+
 	// Live traces
 	m := search()
 	require.Equal(t, numTraces, m.InspectedTraces)
 	require.Equal(t, numBytes, m.InspectedBytes)
 	require.Equal(t, uint32(1), m.InspectedBlocks) // 1 head block
+	require.Equal(t, []string{stageLiveTraces, stageHeadBlock}, stages(m))
 
 	// Test after appending to WAL
 	err := i.CutCompleteTraces(0, true)
@@ -500,6 +512,7 @@ func TestInstanceSearchMetrics(t *testing.T) {
 	require.Equal(t, numTraces, m.InspectedTraces)
 	require.Equal(t, numBytes, m.InspectedBytes)
 	require.Equal(t, uint32(2), m.InspectedBlocks) // 1 head block, 1 completing block
+	require.Equal(t, []string{stageLiveTraces, stageHeadBlock, stageCompletingBlocks}, stages(m))
 
 	// Test after completing a block
 	err = i.CompleteBlock(blockID)
@@ -511,6 +524,7 @@ func TestInstanceSearchMetrics(t *testing.T) {
 	require.Equal(t, numTraces, m.InspectedTraces)
 	require.Less(t, m.InspectedBytes, numBytes)
 	require.Equal(t, uint32(2), m.InspectedBlocks) // 1 head block, 1 complete block
+	require.Equal(t, []string{stageLiveTraces, stageHeadBlock, stageCompleteBlocks}, stages(m))
 }
 
 func BenchmarkInstanceSearchUnderLoad(b *testing.B) {
@@ -615,3 +629,85 @@ func BenchmarkInstanceSearchUnderLoad(b *testing.B) {
 	// exiting and cleaning up
 	time.Sleep(1 * time.Second)
 }
+
+func TestInstanceSearchParquet(t *testing.T) {
+	i := instanceWithBlockVersion(t, t.TempDir(), vparquet.VersionString)
+	ctx := user.InjectOrgID(context.Background(), testTenantID)
+
+	// no flatbuffer search data is written
+	require.NotNil(t, i.searchHeadBlock)
+	require.Nil(t, i.searchHeadBlock.b)
+
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	ids := [][]byte{}
+	for j := 0; j < 100; j++ {
+		id := test.ValidTraceID(nil)
+		testTrace := test.MakeTrace(10, id)
+		if j%10 == 0 {
+			testTrace.Batches[0].InstrumentationLibrarySpans[0].Spans[0].Attributes = append(testTrace.Batches[0].InstrumentationLibrarySpans[0].Spans[0].Attributes,
+				&v1_common.KeyValue{Key: "foo", Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: "bar"}}})
+			ids = append(ids, id)
+		}
+		trace.SortTrace(testTrace)
+
+		traceBytes, err := dec.PrepareForWrite(testTrace, 0, 0)
+		require.NoError(t, err)
+
+		// search data is ignored
+		err = i.PushBytes(ctx, id, traceBytes, []byte{0x01})
+		require.NoError(t, err)
+	}
+
+	req := &tempopb.SearchRequest{
+		Tags:  map[string]string{"foo": "bar"},
+		Limit: 100,
+	}
+
+	check := func() {
+		sr, err := i.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Len(t, sr.Traces, len(ids))
+		checkEqual(t, ids, sr)
+
+		tags, err := i.SearchTags(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, tags.TagNames, "foo")
+		assert.Contains(t, tags.TagNames, trace.RootServiceNameTag)
+
+		values, err := i.SearchTagValues(ctx, "foo")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bar"}, values.TagValues)
+	}
+
+	// live traces
+	check()
+
+	// the deny list of the distributor applies
+	i.tagsToDrop = map[string]struct{}{"foo": {}}
+	tags, err := i.SearchTags(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, tags.TagNames, "foo")
+	assert.Contains(t, tags.TagNames, trace.RootServiceNameTag)
+	i.tagsToDrop = nil
+
+	// head block
+	err = i.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+	check()
+
+	// completing block
+	blockID, err := i.CutBlockIfReady(0, 0, true)
+	require.NoError(t, err)
+	assert.NotEqual(t, blockID, uuid.Nil)
+	check()
+
+	// complete block
+	err = i.CompleteBlock(blockID)
+	require.NoError(t, err)
+	err = i.ClearCompletingBlock(blockID)
+	require.NoError(t, err)
+
+	require.Len(t, i.completeBlocks, 1)
+	assert.Equal(t, vparquet.VersionString, i.completeBlocks[0].BlockMeta().Version)
+	check()
+}
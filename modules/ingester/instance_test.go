@@ -40,7 +40,7 @@ func (m *ringCountMock) HealthyInstancesCount() int {
 func TestInstance(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 	request := makeRequest([]byte{})
@@ -87,7 +87,7 @@ func TestInstance(t *testing.T) {
 func TestInstanceFind(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local)
@@ -165,7 +165,7 @@ func queryAll(t *testing.T, i *instance, ids [][]byte, traces []*tempopb.Trace)
 func TestInstanceDoesNotRace(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 
@@ -230,7 +230,7 @@ func TestInstanceLimits(t *testing.T) {
 		MaxLocalTracesPerUser: 4,
 	})
 	require.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 
@@ -486,7 +486,7 @@ func TestInstanceCutBlockIfReady(t *testing.T) {
 func TestInstanceMetrics(t *testing.T) {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
 
@@ -527,7 +527,7 @@ func TestInstanceFailsLargeTracesEvenAfterFlushing(t *testing.T) {
 		MaxBytesPerTrace: maxTraceBytes,
 	})
 	require.NoError(t, err)
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local)
 	require.NoError(t, err)
@@ -557,6 +557,179 @@ func TestInstanceFailsLargeTracesEvenAfterFlushing(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestInstanceLiveTracesBytesLimits(t *testing.T) {
+	ctx := context.Background()
+	ingester, _, _ := defaultIngester(t, t.TempDir())
+
+	limits, err := overrides.NewOverrides(overrides.Limits{
+		MaxLocalLiveTracesBytesPerUser: 1000,
+	})
+	require.NoError(t, err)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 1500)
+
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local)
+	require.NoError(t, err)
+	other, err := newInstance("other", limiter, ingester.store, ingester.local)
+	require.NoError(t, err)
+
+	ingester.instances = map[string]*instance{testTenantID: i, "other": other}
+	ingester.limiter = limiter
+
+	ids := make([][]byte, 0, 6)
+	for j := 0; j < 6; j++ {
+		ids = append(ids, test.ValidTraceID(nil))
+	}
+
+	// fill up the tenant, eviction is signaled once it gets close to its limit
+	for _, id := range ids[:3] {
+		err = i.PushBytes(ctx, id, make([]byte, 300), nil)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, int64(900), i.liveTracesBytes.Load())
+	require.Len(t, limiter.evict, 1)
+
+	// pushes over the limit are refused, nothing is cut on the push path
+	err = i.PushBytes(ctx, ids[3], make([]byte, 300), nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), overrides.ErrorPrefixLiveTracesBytesExceeded)
+	require.Equal(t, int32(3), i.traceCount.Load())
+
+	// eviction cuts the oldest traces of the tenant down to the target
+	ingester.evictLiveTraces()
+	require.Equal(t, int64(600), i.liveTracesBytes.Load())
+	require.False(t, i.isLive(ids[:1]))
+	require.True(t, i.isLive(ids[1:2]))
+	require.True(t, i.isLive(ids[2:3]))
+	require.NotZero(t, i.headBlock.DataLength())
+
+	// the limit of the ingester applies to all tenants, the oldest traces across tenants are cut
+	for _, id := range ids[3:6] {
+		err = other.PushBytes(ctx, id, make([]byte, 250), nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int64(1350), limiter.liveTracesBytes.Load())
+
+	ingester.evictLiveTraces()
+	require.Equal(t, int64(1050), limiter.liveTracesBytes.Load())
+	require.False(t, i.isLive(ids[1:2]))
+	require.True(t, i.isLive(ids[2:3]))
+	require.Equal(t, int32(3), other.traceCount.Load())
+
+	err = i.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+	err = other.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), i.liveTracesBytes.Load())
+	require.Equal(t, int64(0), limiter.liveTracesBytes.Load())
+}
+
+func TestInstanceTruncatesLargeTraces(t *testing.T) {
+	ctx := context.Background()
+	id := test.ValidTraceID(nil)
+	rootID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	// a batch of child spans, the first one is the root span if requested
+	makeBatch := func(spans int, root bool, errorSpans int) *v1_trace.ResourceSpans {
+		ils := &v1_trace.InstrumentationLibrarySpans{}
+		for j := 0; j < spans; j++ {
+			s := test.MakeSpan(id)
+			s.ParentSpanId = rootID
+			if j >= spans-errorSpans {
+				s.Status.Code = v1_trace.Status_STATUS_CODE_ERROR
+			}
+			ils.Spans = append(ils.Spans, s)
+		}
+		if root {
+			ils.Spans[0].SpanId = rootID
+			ils.Spans[0].ParentSpanId = nil
+		}
+		return &v1_trace.ResourceSpans{InstrumentationLibrarySpans: []*v1_trace.InstrumentationLibrarySpans{ils}}
+	}
+
+	maxTraceBytes := 3 * len(makePushBytesRequest(id, makeBatch(10, false, 0)).Traces[0].Slice)
+
+	tmpDir := t.TempDir()
+	ingester, _, _ := defaultIngester(t, tmpDir)
+	limits, err := overrides.NewOverrides(overrides.Limits{
+		MaxBytesPerTrace:     maxTraceBytes,
+		MaxBytesPerTraceMode: overrides.MaxBytesPerTraceModeTruncate,
+	})
+	require.NoError(t, err)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
+
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local)
+	require.NoError(t, err)
+
+	push := func(batch *v1_trace.ResourceSpans) {
+		err := i.PushBytesRequest(ctx, makePushBytesRequest(id, batch))
+		require.NoError(t, err)
+	}
+
+	// the first two batches fit, the third one exceeds the share reserved for the first bytes
+	push(makeBatch(10, false, 0))
+	push(makeBatch(10, false, 0))
+	push(makeBatch(10, false, 0))
+
+	// root and error spans are still kept
+	push(makeBatch(10, true, 2))
+
+	// and the trace keeps being truncated after it was cut
+	err = i.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+	push(makeBatch(10, false, 1))
+	err = i.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+
+	tr, err := i.FindTraceByID(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	spans := 0
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			spans += len(ils.Spans)
+		}
+	}
+
+	// 20 spans fit, of the other 30 only the root and the 3 error spans are kept. no spans are added to the trace.
+	assert.Equal(t, 20+4, spans)
+	assert.Equal(t, 26, i.droppedSpans(id))
+
+	// the cut trace is marked
+	dropped, truncated := trace.DroppedSpans(tr)
+	assert.True(t, truncated)
+	assert.Equal(t, 26, dropped)
+
+	// once a new block is cut the trace is accepted again
+	blockID, err := i.CutBlockIfReady(0, 0, true)
+	require.NoError(t, err)
+	push(makeBatch(10, false, 0))
+	assert.False(t, i.traces[i.tokenForTraceID(id)].truncated)
+	assert.Zero(t, i.droppedSpans(id))
+
+	// the marker is kept in the flushed block
+	err = i.CompleteBlock(blockID)
+	require.NoError(t, err)
+	completed := i.GetBlockToBeFlushed(blockID)
+	err = ingester.store.WriteBlock(ctx, completed)
+	require.NoError(t, err)
+	err = i.ClearFlushedBlocks(0)
+	require.NoError(t, err)
+
+	r, _, _, err := local.New(&local.Config{Path: tmpDir})
+	require.NoError(t, err)
+	block, err := encoding.OpenBlock(completed.BlockMeta(), backend.NewReader(r))
+	require.NoError(t, err)
+	flushed, err := block.FindTraceByID(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, flushed)
+
+	dropped, truncated = trace.DroppedSpans(flushed)
+	assert.True(t, truncated)
+	assert.Equal(t, 26, dropped)
+}
+
 func TestSortByteSlices(t *testing.T) {
 	numTraces := 100
 
@@ -594,9 +767,13 @@ func TestSortByteSlices(t *testing.T) {
 }
 
 func defaultInstance(t require.TestingT, tmpDir string) *instance {
+	return instanceWithBlockVersion(t, tmpDir, encoding.DefaultEncoding().Version())
+}
+
+func instanceWithBlockVersion(t require.TestingT, tmpDir string, version string) *instance {
 	limits, err := overrides.NewOverrides(overrides.Limits{})
 	require.NoError(t, err, "unexpected error creating limits")
-	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1, 0)
 
 	l, err := local.NewBackend(&local.Config{
 		Path: tmpDir + "/blocks",
@@ -613,9 +790,10 @@ func defaultInstance(t require.TestingT, tmpDir string) *instance {
 				IndexDownsampleBytes: 2,
 				BloomFP:              0.01,
 				BloomShardSizeBytes:  100_000,
-				Version:              encoding.DefaultEncoding().Version(),
+				Version:              version,
 				Encoding:             backend.EncLZ4_1M,
 				IndexPageSizeBytes:   1000,
+				RowGroupSizeBytes:    30_000_000,
 			},
 			WAL: &wal.Config{
 				Filepath:       tmpDir,
@@ -4,11 +4,23 @@ import (
 	"fmt"
 	"math"
 
+	"go.uber.org/atomic"
+
 	"github.com/grafana/tempo/modules/overrides"
 )
 
 const (
-	errMaxTracesPerUserLimitExceeded = "per-user traces limit (local: %d global: %d actual local: %d) exceeded"
+	errMaxTracesPerUserLimitExceeded          = "per-user traces limit (local: %d global: %d actual local: %d) exceeded"
+	errMaxLiveTracesBytesPerUserLimitExceeded = "per-user live traces bytes limit (%d) exceeded while adding %d bytes"
+	errMaxLiveTracesBytesLimitExceeded        = "live traces bytes limit of the ingester (%d) exceeded while adding %d bytes"
+)
+
+const (
+	// live traces are evicted in the background once they reach this share of a live traces bytes limit, so pushes
+	// are only refused if eviction can't keep up
+	liveTracesBytesEvictionThreshold = 0.9
+	// eviction releases live traces until they are down to this share of the limit
+	liveTracesBytesEvictionTarget = 0.8
 )
 
 // RingCount is the interface exposed by a ring implementation which allows
@@ -23,14 +35,22 @@ type Limiter struct {
 	limits            *overrides.Overrides
 	ring              RingCount
 	replicationFactor int
+
+	// bytes of the live traces of all tenants in this ingester
+	maxLiveTracesBytes int
+	liveTracesBytes    atomic.Int64
+	// signals the ingester to evict live traces
+	evict chan struct{}
 }
 
 // NewLimiter makes a new limiter
-func NewLimiter(limits *overrides.Overrides, ring RingCount, replicationFactor int) *Limiter {
+func NewLimiter(limits *overrides.Overrides, ring RingCount, replicationFactor int, maxLiveTracesBytes int) *Limiter {
 	return &Limiter{
-		limits:            limits,
-		ring:              ring,
-		replicationFactor: replicationFactor,
+		limits:             limits,
+		ring:               ring,
+		replicationFactor:  replicationFactor,
+		maxLiveTracesBytes: maxLiveTracesBytes,
+		evict:              make(chan struct{}, 1),
 	}
 }
 
@@ -48,6 +68,57 @@ func (l *Limiter) AssertMaxTracesPerUser(userID string, traces int) error {
 	return fmt.Errorf(errMaxTracesPerUserLimitExceeded, localLimit, globalLimit, actualLimit)
 }
 
+// AssertMaxLiveTracesBytes ensures adding reqSize bytes to the live traces of the user keeps both the live traces
+// of the user and the live traces of all users within their limits and returns an error if not.
+func (l *Limiter) AssertMaxLiveTracesBytes(userID string, userBytes int, reqSize int) error {
+	if limit := l.limits.MaxLocalLiveTracesBytesPerUser(userID); limit > 0 && userBytes+reqSize > limit {
+		return fmt.Errorf(errMaxLiveTracesBytesPerUserLimitExceeded, limit, reqSize)
+	}
+
+	if l.maxLiveTracesBytes > 0 && int(l.liveTracesBytes.Load())+reqSize > l.maxLiveTracesBytes {
+		return fmt.Errorf(errMaxLiveTracesBytesLimitExceeded, l.maxLiveTracesBytes, reqSize)
+	}
+
+	return nil
+}
+
+// liveTracesBytesAboveThreshold returns true if the live traces of the user or of all users reached the share of
+// their limit at which they are evicted
+func (l *Limiter) liveTracesBytesAboveThreshold(userID string, userBytes int) bool {
+	if limit := l.limits.MaxLocalLiveTracesBytesPerUser(userID); limit > 0 && userBytes >= int(float64(limit)*liveTracesBytesEvictionThreshold) {
+		return true
+	}
+	return l.maxLiveTracesBytes > 0 && int(l.liveTracesBytes.Load()) >= int(float64(l.maxLiveTracesBytes)*liveTracesBytesEvictionThreshold)
+}
+
+// userLiveTracesBytesToEvict returns how many bytes of the live traces of the user have to be evicted to get back
+// to the eviction target. It is 0 or negative if there is nothing to evict.
+func (l *Limiter) userLiveTracesBytesToEvict(userID string, userBytes int) int {
+	limit := l.limits.MaxLocalLiveTracesBytesPerUser(userID)
+	if limit <= 0 || userBytes < int(float64(limit)*liveTracesBytesEvictionThreshold) {
+		return 0
+	}
+	return userBytes - int(float64(limit)*liveTracesBytesEvictionTarget)
+}
+
+// liveTracesBytesToEvict returns how many bytes of the live traces of all users have to be evicted to get back to
+// the eviction target. It is 0 or negative if there is nothing to evict.
+func (l *Limiter) liveTracesBytesToEvict() int {
+	total := int(l.liveTracesBytes.Load())
+	if l.maxLiveTracesBytes <= 0 || total < int(float64(l.maxLiveTracesBytes)*liveTracesBytesEvictionThreshold) {
+		return 0
+	}
+	return total - int(float64(l.maxLiveTracesBytes)*liveTracesBytesEvictionTarget)
+}
+
+// signalEviction wakes up the eviction of live traces of the ingester
+func (l *Limiter) signalEviction() {
+	select {
+	case l.evict <- struct{}{}:
+	default:
+	}
+}
+
 func (l *Limiter) maxTracesPerUser(userID string) int {
 	localLimit := l.limits.MaxLocalTracesPerUser(userID)
 
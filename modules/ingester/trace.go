@@ -10,9 +10,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/model/trace"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/log"
 )
 
+const (
+	// truncatedReserveDivisor reserves a share of the max bytes of truncated traces for root and error spans
+	truncatedReserveDivisor = 10
+)
+
 var (
 	metricTraceSearchBytesDiscardedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "tempo",
@@ -33,6 +40,11 @@ type liveTrace struct {
 	maxBytes     int
 	currentBytes int
 
+	// truncate keeps root and error spans once the trace reached the max bytes instead of refusing the data
+	truncate     bool
+	truncated    bool
+	droppedSpans int
+
 	// List of flatbuffers
 	searchData         [][]byte
 	maxSearchBytes     int
@@ -52,26 +64,46 @@ func newTrace(traceID []byte, maxBytes int, maxSearchBytes int) *liveTrace {
 
 func (t *liveTrace) Push(_ context.Context, instanceID string, trace []byte, searchData []byte) error {
 	t.lastAppend = time.Now()
+	reqSize := len(trace)
 	if t.maxBytes != 0 {
-		reqSize := len(trace)
+		if t.truncate && (t.truncated || t.currentBytes+reqSize > t.maxBytes-t.maxBytes/truncatedReserveDivisor) {
+			return t.pushTruncated(trace)
+		}
 		if t.currentBytes+reqSize > t.maxBytes {
 			return newTraceTooLargeError(t.traceID, instanceID, t.maxBytes, reqSize)
 		}
-
-		t.currentBytes += reqSize
 	}
+	// counted even without limit, the instance tracks the bytes of all live traces
+	t.currentBytes += reqSize
+
+	return t.push(instanceID, trace, searchData)
+}
 
+// pushTruncated only pushes the root and error spans of the segment. The search data is discarded, most of the
+// spans it was extracted from are dropped.
+func (t *liveTrace) pushTruncated(trace []byte) error {
 	start, end, err := t.decoder.FastRange(trace)
 	if err != nil {
 		return fmt.Errorf("failed to get range while adding segment: %w", err)
 	}
-	t.batches = append(t.batches, trace)
-	if t.start == 0 || start < t.start {
-		t.start = start
+	t.updateRange(start, end)
+
+	trace, err = t.truncateSegment(trace)
+	if err != nil || trace == nil {
+		return err
 	}
-	if t.end == 0 || end > t.end {
-		t.end = end
+	t.currentBytes += len(trace)
+	t.batches = append(t.batches, trace)
+	return nil
+}
+
+func (t *liveTrace) push(instanceID string, trace []byte, searchData []byte) error {
+	start, end, err := t.decoder.FastRange(trace)
+	if err != nil {
+		return fmt.Errorf("failed to get range while adding segment: %w", err)
 	}
+	t.batches = append(t.batches, trace)
+	t.updateRange(start, end)
 
 	if searchDataSize := len(searchData); searchDataSize > 0 {
 		// disable limit when set to 0
@@ -87,3 +119,78 @@ func (t *liveTrace) Push(_ context.Context, instanceID string, trace []byte, sea
 
 	return nil
 }
+
+func (t *liveTrace) updateRange(start, end uint32) {
+	if t.start == 0 || start < t.start {
+		t.start = start
+	}
+	if t.end == 0 || end > t.end {
+		t.end = end
+	}
+}
+
+// truncateSegment returns the root and error spans of the segment. They are kept as long as they fit into the share
+// of the max bytes reserved for them, otherwise nil is returned. All other spans are counted as dropped.
+func (t *liveTrace) truncateSegment(segment []byte) ([]byte, error) {
+	t.truncated = true
+
+	tr, err := t.decoder.PrepareForRead([][]byte{segment})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal segment while truncating trace: %w", err)
+	}
+
+	kept := 0
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			spans := ils.Spans[:0]
+			for _, s := range ils.Spans {
+				if len(s.ParentSpanId) == 0 || (s.Status != nil && s.Status.Code == v1.Status_STATUS_CODE_ERROR) {
+					spans = append(spans, s)
+				} else {
+					t.droppedSpans++
+				}
+			}
+			ils.Spans = spans
+			kept += len(spans)
+		}
+	}
+	if kept == 0 {
+		return nil, nil
+	}
+
+	start, end, err := t.decoder.FastRange(segment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get range while truncating trace: %w", err)
+	}
+	truncated, err := t.decoder.PrepareForWrite(tr, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal segment while truncating trace: %w", err)
+	}
+
+	if t.currentBytes+len(truncated) > t.maxBytes {
+		t.droppedSpans += kept
+		return nil, nil
+	}
+	return truncated, nil
+}
+
+// markTruncated replaces the segments of a truncated trace with one segment whose batches carry the truncation
+// marker and the number of dropped spans, so both are kept with the trace once it's cut to the head block.
+func (t *liveTrace) markTruncated() error {
+	if !t.truncated || len(t.batches) == 0 {
+		return nil
+	}
+
+	tr, err := t.decoder.PrepareForRead(t.batches)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal segments while marking truncated trace: %w", err)
+	}
+	trace.MarkTruncated(tr, t.droppedSpans)
+
+	segment, err := t.decoder.PrepareForWrite(tr, t.start, t.end)
+	if err != nil {
+		return fmt.Errorf("failed to marshal segment while marking truncated trace: %w", err)
+	}
+	t.batches = [][]byte{segment}
+	return nil
+}
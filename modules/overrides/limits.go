@@ -2,6 +2,7 @@ package overrides
 
 import (
 	"flag"
+	"fmt"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -16,20 +17,39 @@ const (
 
 	// ErrorPrefixLiveTracesExceeded is used to flag batches from the ingester that were rejected b/c they had too many traces
 	ErrorPrefixLiveTracesExceeded = "LIVE_TRACES_EXCEEDED:"
+	// ErrorPrefixLiveTracesBytesExceeded is used to flag batches from the ingester that were rejected b/c the live traces used too many bytes
+	ErrorPrefixLiveTracesBytesExceeded = "LIVE_TRACES_BYTES_EXCEEDED:"
 	// ErrorPrefixTraceTooLarge is used to flag batches from the ingester that were rejected b/c they exceeded the single trace limit
 	ErrorPrefixTraceTooLarge = "TRACE_TOO_LARGE:"
 	// ErrorPrefixRateLimited is used to flag batches that have exceeded the spans/second of the tenant
 	ErrorPrefixRateLimited = "RATE_LIMITED:"
 
+	// SpanValidationDisabled indicates that the distributor only rejects requests with trace ids that are not 128 bits
+	SpanValidationDisabled = "disabled"
+	// SpanValidationReject indicates that the distributor drops malformed spans and accepts the rest of the request
+	SpanValidationReject = "reject"
+	// SpanValidationRepair indicates that the distributor repairs malformed spans where possible and drops the rest
+	SpanValidationRepair = "repair"
+
+	// MaxBytesPerTraceModeReject indicates that the ingester refuses all data for a trace once it exceeds the max bytes
+	MaxBytesPerTraceModeReject = "reject"
+	// MaxBytesPerTraceModeTruncate indicates that the ingester keeps the first bytes of a trace that exceeds the max bytes
+	// and only root and error spans after that
+	MaxBytesPerTraceModeTruncate = "truncate"
+
 	// metrics
 	MetricMaxLocalTracesPerUser     = "max_local_traces_per_user"
 	MetricMaxGlobalTracesPerUser    = "max_global_traces_per_user"
+	MetricMaxLiveTracesBytesPerUser = "max_live_traces_bytes_per_user"
 	MetricMaxBytesPerTrace          = "max_bytes_per_trace"
 	MetricMaxSearchBytesPerTrace    = "max_search_bytes_per_trace"
 	MetricMaxBytesPerTagValuesQuery = "max_bytes_per_tag_values_query"
 	MetricIngestionRateLimitBytes   = "ingestion_rate_limit_bytes"
 	MetricIngestionBurstSizeBytes   = "ingestion_burst_size_bytes"
 	MetricBlockRetention            = "block_retention"
+	MetricTraceIdlePeriod           = "trace_idle_period"
+	MetricMaxBlockDuration          = "max_block_duration"
+	MetricMaxBlockBytes             = "max_block_bytes"
 )
 
 var (
@@ -49,11 +69,18 @@ type Limits struct {
 	IngestionRateLimitBytes int       `yaml:"ingestion_rate_limit_bytes" json:"ingestion_rate_limit_bytes"`
 	IngestionBurstSizeBytes int       `yaml:"ingestion_burst_size_bytes" json:"ingestion_burst_size_bytes"`
 	SearchTagsAllowList     ListToMap `yaml:"search_tags_allow_list" json:"search_tags_allow_list"`
+	SpanValidation          string    `yaml:"span_validation" json:"span_validation"`
 
 	// Ingester enforced limits.
-	MaxLocalTracesPerUser  int `yaml:"max_traces_per_user" json:"max_traces_per_user"`
-	MaxGlobalTracesPerUser int `yaml:"max_global_traces_per_user" json:"max_global_traces_per_user"`
-	MaxSearchBytesPerTrace int `yaml:"max_search_bytes_per_trace" json:"max_search_bytes_per_trace"`
+	MaxLocalTracesPerUser          int `yaml:"max_traces_per_user" json:"max_traces_per_user"`
+	MaxGlobalTracesPerUser         int `yaml:"max_global_traces_per_user" json:"max_global_traces_per_user"`
+	MaxLocalLiveTracesBytesPerUser int `yaml:"max_live_traces_bytes_per_user" json:"max_live_traces_bytes_per_user"`
+	MaxSearchBytesPerTrace         int `yaml:"max_search_bytes_per_trace" json:"max_search_bytes_per_trace"`
+
+	// Ingester block cut policies. 0 falls back to the ingester config.
+	MaxTraceIdle     model.Duration `yaml:"trace_idle_period" json:"trace_idle_period"`
+	MaxBlockDuration model.Duration `yaml:"max_block_duration" json:"max_block_duration"`
+	MaxBlockBytes    uint64         `yaml:"max_block_bytes" json:"max_block_bytes"`
 
 	// Metrics-generator config
 	MetricsGeneratorRingSize                               int           `yaml:"metrics_generator_ring_size" json:"metrics_generator_ring_size"`
@@ -79,24 +106,39 @@ type Limits struct {
 
 	// MaxBytesPerTrace is enforced in the Ingester, Compactor, Querier (Search) and Serverless (Search). It
 	//  is not used when doing a trace by id lookup.
-	MaxBytesPerTrace int `yaml:"max_bytes_per_trace" json:"max_bytes_per_trace"`
+	MaxBytesPerTrace     int    `yaml:"max_bytes_per_trace" json:"max_bytes_per_trace"`
+	MaxBytesPerTraceMode string `yaml:"max_bytes_per_trace_mode" json:"max_bytes_per_trace_mode"`
 
 	// Configuration for overrides, convenient if it goes here.
 	PerTenantOverrideConfig string         `yaml:"per_tenant_override_config" json:"per_tenant_override_config"`
 	PerTenantOverridePeriod model.Duration `yaml:"per_tenant_override_period" json:"per_tenant_override_period"`
 }
 
+// Validate returns an error if a mode of the limits is unknown. Modes that are not set use their default.
+func (l *Limits) Validate() error {
+	switch l.SpanValidation {
+	case "", SpanValidationDisabled, SpanValidationReject, SpanValidationRepair:
+	default:
+		return fmt.Errorf("unknown span_validation %q, must be one of %s, %s or %s", l.SpanValidation, SpanValidationDisabled, SpanValidationReject, SpanValidationRepair)
+	}
+
+	return nil
+}
+
 // RegisterFlags adds the flags required to config this to the given FlagSet
 func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	// Distributor Limits
 	f.StringVar(&l.IngestionRateStrategy, "distributor.rate-limit-strategy", "local", "Whether the various ingestion rate limits should be applied individually to each distributor instance (local), or evenly shared across the cluster (global).")
 	f.IntVar(&l.IngestionRateLimitBytes, "distributor.ingestion-rate-limit-bytes", 15e6, "Per-user ingestion rate limit in bytes per second.")
 	f.IntVar(&l.IngestionBurstSizeBytes, "distributor.ingestion-burst-size-bytes", 20e6, "Per-user ingestion burst size in bytes. Should be set to the expected size (in bytes) of a single push request.")
+	f.StringVar(&l.SpanValidation, "distributor.span-validation", SpanValidationDisabled, "How malformed spans are handled: disabled, reject (drop them) or repair (fix them where possible).")
 
 	// Ingester limits
 	f.IntVar(&l.MaxLocalTracesPerUser, "ingester.max-traces-per-user", 10e3, "Maximum number of active traces per user, per ingester. 0 to disable.")
 	f.IntVar(&l.MaxGlobalTracesPerUser, "ingester.max-global-traces-per-user", 0, "Maximum number of active traces per user, across the cluster. 0 to disable.")
+	f.IntVar(&l.MaxLocalLiveTracesBytesPerUser, "ingester.max-live-traces-bytes-per-user", 0, "Maximum size in bytes of the live traces per user, per ingester. 0 to disable.")
 	f.IntVar(&l.MaxBytesPerTrace, "ingester.max-bytes-per-trace", 50e5, "Maximum size of a trace in bytes.  0 to disable.")
+	f.StringVar(&l.MaxBytesPerTraceMode, "ingester.max-bytes-per-trace-mode", MaxBytesPerTraceModeReject, "How traces exceeding the maximum size are handled: reject (refuse all further data) or truncate (keep only root and error spans).")
 	f.IntVar(&l.MaxSearchBytesPerTrace, "ingester.max-search-bytes-per-trace", 5e3, "Maximum size of search data per trace in bytes.  0 to disable.")
 
 	// Querier limits
@@ -114,10 +156,14 @@ func (l *Limits) Describe(ch chan<- *prometheus.Desc) {
 func (l *Limits) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxLocalTracesPerUser), MetricMaxLocalTracesPerUser)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxGlobalTracesPerUser), MetricMaxGlobalTracesPerUser)
+	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxLocalLiveTracesBytesPerUser), MetricMaxLiveTracesBytesPerUser)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxBytesPerTrace), MetricMaxBytesPerTrace)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxSearchBytesPerTrace), MetricMaxSearchBytesPerTrace)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxBytesPerTagValuesQuery), MetricMaxBytesPerTagValuesQuery)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.IngestionRateLimitBytes), MetricIngestionRateLimitBytes)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.IngestionBurstSizeBytes), MetricIngestionBurstSizeBytes)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.BlockRetention), MetricBlockRetention)
+	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxTraceIdle), MetricTraceIdlePeriod)
+	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxBlockDuration), MetricMaxBlockDuration)
+	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.MaxBlockBytes), MetricMaxBlockBytes)
 }
@@ -51,6 +51,15 @@ func loadPerTenantOverrides(r io.Reader) (interface{}, error) {
 		return nil, err
 	}
 
+	for tenantID, l := range overrides.TenantLimits {
+		if l == nil {
+			continue
+		}
+		if err := l.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid overrides for tenant %s: %w", tenantID, err)
+		}
+	}
+
 	return overrides, nil
 }
 
@@ -78,6 +87,10 @@ type Overrides struct {
 // are defaulted to those values.  As such, the last call to NewOverrides will
 // become the new global defaults.
 func NewOverrides(defaults Limits) (*Overrides, error) {
+	if err := defaults.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid default limits: %w", err)
+	}
+
 	var manager *runtimeconfig.Manager
 	subservices := []services.Service(nil)
 
@@ -233,11 +246,22 @@ func (o *Overrides) MaxGlobalTracesPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalTracesPerUser
 }
 
+// MaxLocalLiveTracesBytesPerUser returns the maximum size in bytes of the live traces a user is allowed to keep
+// in a single ingester.
+func (o *Overrides) MaxLocalLiveTracesBytesPerUser(userID string) int {
+	return o.getOverridesForUser(userID).MaxLocalLiveTracesBytesPerUser
+}
+
 // MaxBytesPerTrace returns the maximum size of a single trace in bytes allowed for a user.
 func (o *Overrides) MaxBytesPerTrace(userID string) int {
 	return o.getOverridesForUser(userID).MaxBytesPerTrace
 }
 
+// MaxBytesPerTraceMode returns how traces that exceed the max bytes per trace are handled for this tenant.
+func (o *Overrides) MaxBytesPerTraceMode(userID string) string {
+	return o.getOverridesForUser(userID).MaxBytesPerTraceMode
+}
+
 // MaxSearchBytesPerTrace returns the maximum size of search data for trace (in bytes) allowed for a user.
 func (o *Overrides) MaxSearchBytesPerTrace(userID string) int {
 	return o.getOverridesForUser(userID).MaxSearchBytesPerTrace
@@ -263,6 +287,11 @@ func (o *Overrides) SearchTagsAllowList(userID string) map[string]struct{} {
 	return o.getOverridesForUser(userID).SearchTagsAllowList.GetMap()
 }
 
+// SpanValidation returns how the distributor handles malformed spans for this tenant.
+func (o *Overrides) SpanValidation(userID string) string {
+	return o.getOverridesForUser(userID).SpanValidation
+}
+
 // MetricsGeneratorRingSize is the desired size of the metrics-generator ring for this tenant.
 // Using shuffle sharding, a tenant can use a smaller ring than the entire ring.
 func (o *Overrides) MetricsGeneratorRingSize(userID string) int {
@@ -336,6 +365,21 @@ func (o *Overrides) MaxSearchDuration(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxSearchDuration)
 }
 
+// MaxTraceIdle is the duration after which the ingester considers a trace of this tenant complete.
+func (o *Overrides) MaxTraceIdle(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).MaxTraceIdle)
+}
+
+// MaxBlockDuration is the duration the ingester appends to the head block of this tenant before cutting it.
+func (o *Overrides) MaxBlockDuration(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).MaxBlockDuration)
+}
+
+// MaxBlockBytes is the size of the head block of this tenant at which the ingester cuts it.
+func (o *Overrides) MaxBlockBytes(userID string) uint64 {
+	return o.getOverridesForUser(userID).MaxBlockBytes
+}
+
 func (o *Overrides) getOverridesForUser(userID string) *Limits {
 	if tenantOverrides := o.tenantOverrides(); tenantOverrides != nil {
 		l := tenantOverrides.forUser(userID)
@@ -365,10 +409,14 @@ func (o *Overrides) Collect(ch chan<- prometheus.Metric) {
 	for tenant, limits := range overrides.TenantLimits {
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxLocalTracesPerUser), MetricMaxLocalTracesPerUser, tenant)
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxGlobalTracesPerUser), MetricMaxGlobalTracesPerUser, tenant)
+		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxLocalLiveTracesBytesPerUser), MetricMaxLiveTracesBytesPerUser, tenant)
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxBytesPerTrace), MetricMaxBytesPerTrace, tenant)
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxSearchBytesPerTrace), MetricMaxSearchBytesPerTrace, tenant)
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.IngestionRateLimitBytes), MetricIngestionRateLimitBytes, tenant)
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.IngestionBurstSizeBytes), MetricIngestionBurstSizeBytes, tenant)
 		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.BlockRetention), MetricBlockRetention, tenant)
+		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxTraceIdle), MetricTraceIdlePeriod, tenant)
+		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxBlockDuration), MetricMaxBlockDuration, tenant)
+		ch <- prometheus.MustNewConstMetric(metricOverridesLimitsDesc, prometheus.GaugeValue, float64(limits.MaxBlockBytes), MetricMaxBlockBytes, tenant)
 	}
 }
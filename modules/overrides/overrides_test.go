@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -157,3 +158,27 @@ func TestOverrides(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadPerTenantOverridesValidatesModes(t *testing.T) {
+	_, err := loadPerTenantOverrides(strings.NewReader(`
+overrides:
+  user1:
+    span_validation: repair
+  user2:
+    span_validation: rejct
+`))
+	require.EqualError(t, err, `invalid overrides for tenant user2: unknown span_validation "rejct", must be one of disabled, reject or repair`)
+
+	overrides, err := loadPerTenantOverrides(strings.NewReader(`
+overrides:
+  user1:
+    span_validation: repair
+  user2:
+    max_traces_per_user: 1
+`))
+	require.NoError(t, err)
+	require.Equal(t, SpanValidationRepair, overrides.(*perTenantOverrides).forUser("user1").SpanValidation)
+
+	_, err = NewOverrides(Limits{SpanValidation: "rejct"})
+	require.Error(t, err)
+}
@@ -18,6 +18,9 @@ type Config struct {
 	MaxConcurrentQueries    int           `yaml:"max_concurrent_queries"`
 	Worker                  worker.Config `yaml:"frontend_worker"`
 	QueryRelevantIngesters  bool          `yaml:"query_relevant_ingesters"`
+
+	// Injected internally from the ingester ring config
+	ZoneAwarenessEnabled bool `yaml:"-"`
 }
 
 type SearchConfig struct {
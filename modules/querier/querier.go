@@ -183,6 +183,7 @@ func (q *Querier) FindTraceByID(ctx context.Context, req *tempopb.TraceByIDReque
 
 	combiner := trace.NewCombiner()
 	var spanCount, spanCountTotal, traceCountTotal int
+	var droppedSpans uint32
 	if req.QueryMode == QueryModeIngesters || req.QueryMode == QueryModeAll {
 		var replicationSet ring.ReplicationSet
 		var err error
@@ -198,7 +199,7 @@ func (q *Querier) FindTraceByID(ctx context.Context, req *tempopb.TraceByIDReque
 
 		span.LogFields(ot_log.String("msg", "searching ingesters"))
 		// get responses from all ingesters in parallel
-		responses, err := q.forGivenIngesters(ctx, replicationSet, func(client tempopb.QuerierClient) (interface{}, error) {
+		responses, err := q.forGivenIngesters(ctx, replicationSet, func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error) {
 			return client.FindTraceByID(opentracing.ContextWithSpan(ctx, span), req)
 		})
 		if err != nil {
@@ -207,7 +208,13 @@ func (q *Querier) FindTraceByID(ctx context.Context, req *tempopb.TraceByIDReque
 
 		found := false
 		for _, r := range responses {
-			t := r.response.(*tempopb.TraceByIDResponse).Trace
+			resp := r.response.(*tempopb.TraceByIDResponse)
+			// replicas drop the same spans of a truncated trace
+			if resp.Metrics != nil && resp.Metrics.DroppedSpans > droppedSpans {
+				droppedSpans = resp.Metrics.DroppedSpans
+			}
+
+			t := resp.Trace
 			if t != nil {
 				spanCount = combiner.Consume(t)
 				spanCountTotal += spanCount
@@ -246,29 +253,28 @@ func (q *Querier) FindTraceByID(ctx context.Context, req *tempopb.TraceByIDReque
 	}
 
 	completeTrace, _ := combiner.Result()
+	// truncated traces that were cut by the ingesters carry the dropped spans
+	if dropped, _ := trace.DroppedSpans(completeTrace); uint32(dropped) > droppedSpans {
+		droppedSpans = uint32(dropped)
+	}
 
 	return &tempopb.TraceByIDResponse{
 		Trace: completeTrace,
 		Metrics: &tempopb.TraceByIDMetrics{
 			FailedBlocks: uint32(failedBlocks),
+			DroppedSpans: droppedSpans,
 		},
 	}, nil
 }
 
 // forGivenIngesters runs f, in parallel, for given ingesters
-func (q *Querier) forGivenIngesters(ctx context.Context, replicationSet ring.ReplicationSet, f func(client tempopb.QuerierClient) (interface{}, error)) ([]responseFromIngesters, error) {
-	results, err := replicationSet.Do(ctx, q.cfg.ExtraQueryDelay, func(ctx context.Context, ingester *ring.InstanceDesc) (interface{}, error) {
-		client, err := q.pool.GetClientFor(ingester.Addr)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := f(client.(tempopb.QuerierClient))
-		if err != nil {
-			return nil, err
-		}
+func (q *Querier) forGivenIngesters(ctx context.Context, replicationSet ring.ReplicationSet, f func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error)) ([]responseFromIngesters, error) {
+	if q.cfg.ZoneAwarenessEnabled {
+		return q.forGivenIngestersByZone(ctx, replicationSet, f)
+	}
 
-		return responseFromIngesters{ingester.Addr, resp}, nil
+	results, err := replicationSet.Do(ctx, q.cfg.ExtraQueryDelay, func(ctx context.Context, ingester *ring.InstanceDesc) (interface{}, error) {
+		return q.queryIngester(ctx, ingester, f)
 	})
 	if err != nil {
 		return nil, err
@@ -282,6 +288,74 @@ func (q *Querier) forGivenIngesters(ctx context.Context, replicationSet ring.Rep
 	return responses, err
 }
 
+// forGivenIngestersByZone runs f, in parallel, for given ingesters and returns once all ingesters of one zone have
+// responded. With zone-aware replication every zone holds a replica of each trace, so a whole zone can be down or
+// slow. The requests still outstanding are canceled then, the responses of other zones received by then are returned
+// as well. It fails once there is a failing ingester in every zone.
+func (q *Querier) forGivenIngestersByZone(ctx context.Context, replicationSet ring.ReplicationSet, f func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error)) ([]responseFromIngesters, error) {
+	type result struct {
+		zone     string
+		response responseFromIngesters
+		err      error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	waitingByZone := map[string]int{}
+	for _, ingester := range replicationSet.Instances {
+		waitingByZone[ingester.Zone]++
+	}
+
+	// buffered, so the outstanding requests don't block once this returns
+	ch := make(chan result, len(replicationSet.Instances))
+	for i := range replicationSet.Instances {
+		go func(ingester *ring.InstanceDesc) {
+			resp, err := q.queryIngester(ctx, ingester, f)
+			ch <- result{zone: ingester.Zone, response: resp, err: err}
+		}(&replicationSet.Instances[i])
+	}
+
+	failedZones := map[string]struct{}{}
+	responses := make([]responseFromIngesters, 0, len(replicationSet.Instances))
+	for range replicationSet.Instances {
+		select {
+		case r := <-ch:
+			if r.err != nil {
+				failedZones[r.zone] = struct{}{}
+				if len(failedZones) == len(waitingByZone) {
+					return nil, r.err
+				}
+				continue
+			}
+
+			responses = append(responses, r.response)
+			waitingByZone[r.zone]--
+			if _, failed := failedZones[r.zone]; !failed && waitingByZone[r.zone] == 0 {
+				return responses, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return responses, nil
+}
+
+func (q *Querier) queryIngester(ctx context.Context, ingester *ring.InstanceDesc, f func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error)) (responseFromIngesters, error) {
+	client, err := q.pool.GetClientFor(ingester.Addr)
+	if err != nil {
+		return responseFromIngesters{}, err
+	}
+
+	resp, err := f(ctx, client.(tempopb.QuerierClient))
+	if err != nil {
+		return responseFromIngesters{}, err
+	}
+
+	return responseFromIngesters{ingester.Addr, resp}, nil
+}
+
 func (q *Querier) SearchRecent(ctx context.Context, req *tempopb.SearchRequest) (*tempopb.SearchResponse, error) {
 	_, err := user.ExtractOrgID(ctx)
 	if err != nil {
@@ -293,7 +367,7 @@ func (q *Querier) SearchRecent(ctx context.Context, req *tempopb.SearchRequest)
 		return nil, errors.Wrap(err, "error finding ingesters in Querier.Search")
 	}
 
-	responses, err := q.forGivenIngesters(ctx, replicationSet, func(client tempopb.QuerierClient) (interface{}, error) {
+	responses, err := q.forGivenIngesters(ctx, replicationSet, func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error) {
 		return client.SearchRecent(ctx, req)
 	})
 	if err != nil {
@@ -322,7 +396,7 @@ func (q *Querier) SearchTags(ctx context.Context, req *tempopb.SearchTagsRequest
 	if err != nil {
 		return nil, errors.Wrap(err, "error finding ingesters in Querier.SearchTags")
 	}
-	lookupResults, err := q.forGivenIngesters(ctx, replicationSet, func(client tempopb.QuerierClient) (interface{}, error) {
+	lookupResults, err := q.forGivenIngesters(ctx, replicationSet, func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error) {
 		return client.SearchTags(ctx, req)
 	})
 	if err != nil {
@@ -364,7 +438,7 @@ func (q *Querier) SearchTagValues(ctx context.Context, req *tempopb.SearchTagVal
 	if err != nil {
 		return nil, errors.Wrap(err, "error finding ingesters in Querier.SearchTagValues")
 	}
-	lookupResults, err := q.forGivenIngesters(ctx, replicationSet, func(client tempopb.QuerierClient) (interface{}, error) {
+	lookupResults, err := q.forGivenIngesters(ctx, replicationSet, func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error) {
 		return client.SearchTagValues(ctx, req)
 	})
 	if err != nil {
@@ -436,6 +510,7 @@ func (q *Querier) internalSearchBlock(ctx context.Context, req *tempopb.SearchBl
 		TotalRecords:  req.TotalRecords,
 		BlockID:       blockID,
 		DataEncoding:  req.DataEncoding,
+		Tier:          req.Tier,
 	}
 
 	opts := common.DefaultSearchOptions()
@@ -466,6 +541,7 @@ func (q *Querier) postProcessSearchResults(req *tempopb.SearchRequest, rr []resp
 			response.Metrics.InspectedTraces += sr.Metrics.InspectedTraces
 			response.Metrics.InspectedBlocks += sr.Metrics.InspectedBlocks
 			response.Metrics.SkippedBlocks += sr.Metrics.SkippedBlocks
+			search.CombineSearchStageMetrics(response.Metrics, sr.Metrics)
 		}
 	}
 
@@ -2,17 +2,23 @@ package querier
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/ring"
+	ring_client "github.com/grafana/dskit/ring/client"
 	"github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"github.com/uber-go/atomic"
 	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestQuerierUsesSearchExternalEndpoint(t *testing.T) {
@@ -78,3 +84,116 @@ func TestQuerierUsesSearchExternalEndpoint(t *testing.T) {
 		require.Equal(t, tc.externalExpected, numExternalRequests.Load())
 	}
 }
+
+type mockIngesterClient struct {
+	tempopb.QuerierClient
+	grpc_health_v1.HealthClient
+
+	addr string
+}
+
+func (c *mockIngesterClient) Close() error {
+	return nil
+}
+
+func TestForGivenIngestersByZone(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		{Addr: "a1", Zone: "zone-a"},
+		{Addr: "a2", Zone: "zone-a"},
+		{Addr: "b1", Zone: "zone-b"},
+		{Addr: "c1", Zone: "zone-c"},
+		{Addr: "c2", Zone: "zone-c"},
+	}
+
+	tests := []struct {
+		name        string
+		failing     map[string]bool
+		blocking    map[string]bool
+		expectedErr bool
+		// all ingesters of one of these zones must have responded
+		expectedZones []string
+	}{
+		{
+			name:          "one zone down",
+			failing:       map[string]bool{"b1": true},
+			expectedZones: []string{"zone-a", "zone-c"},
+		},
+		{
+			name:          "one zone slow, return after the first complete zone",
+			blocking:      map[string]bool{"a2": true},
+			expectedZones: []string{"zone-b", "zone-c"},
+		},
+		{
+			name:          "one zone down and one zone slow",
+			failing:       map[string]bool{"b1": true},
+			blocking:      map[string]bool{"a2": true},
+			expectedZones: []string{"zone-c"},
+		},
+		{
+			name:        "failing ingester in every zone",
+			failing:     map[string]bool{"a1": true, "b1": true, "c2": true},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			canceled := make(chan struct{}, len(instances))
+
+			q := &Querier{
+				cfg: Config{ZoneAwarenessEnabled: true},
+				pool: ring_client.NewPool("test", ring_client.PoolConfig{}, nil, func(addr string) (ring_client.PoolClient, error) {
+					return &mockIngesterClient{addr: addr}, nil
+				}, prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}), log.NewNopLogger()),
+			}
+
+			responses, err := q.forGivenIngesters(context.Background(), ring.ReplicationSet{Instances: instances}, func(ctx context.Context, client tempopb.QuerierClient) (interface{}, error) {
+				addr := client.(*mockIngesterClient).addr
+				if tc.blocking[addr] {
+					<-ctx.Done()
+					canceled <- struct{}{}
+					return nil, ctx.Err()
+				}
+				if tc.failing[addr] {
+					return nil, errors.New("ingester down")
+				}
+				return addr, nil
+			})
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			responded := map[string]bool{}
+			for _, r := range responses {
+				responded[r.addr] = true
+			}
+			for addr := range tc.blocking {
+				require.NotContains(t, responded, addr)
+			}
+			for addr := range tc.failing {
+				require.NotContains(t, responded, addr)
+			}
+
+			// the responses of other zones received by then are returned as well
+			var complete bool
+			for _, zone := range tc.expectedZones {
+				zoneComplete := true
+				for _, ingester := range instances {
+					if ingester.Zone == zone && !responded[ingester.Addr] {
+						zoneComplete = false
+					}
+				}
+				complete = complete || zoneComplete
+			}
+			require.True(t, complete)
+
+			// outstanding requests are canceled
+			for range tc.blocking {
+				<-canceled
+			}
+		})
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"github.com/grafana/tempo/tempodb"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/azure"
+	"github.com/grafana/tempo/tempodb/backend/encryption"
+	"github.com/grafana/tempo/tempodb/backend/filesystem"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/ratelimit"
+	"github.com/grafana/tempo/tempodb/backend/retry"
 	"github.com/grafana/tempo/tempodb/backend/s3"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
@@ -31,7 +35,7 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.Trace.BlocklistPollConcurrency = tempodb.DefaultBlocklistPollConcurrency
 	cfg.Trace.BlocklistPollTenantIndexBuilders = tempodb.DefaultTenantIndexBuilders
 
-	f.StringVar(&cfg.Trace.Backend, util.PrefixConfig(prefix, "trace.backend"), "", "Trace backend (s3, azure, gcs, local)")
+	f.StringVar(&cfg.Trace.Backend, util.PrefixConfig(prefix, "trace.backend"), "", "Trace backend (s3, azure, gcs, local, filesystem)")
 	f.DurationVar(&cfg.Trace.BlocklistPoll, util.PrefixConfig(prefix, "trace.blocklist_poll"), tempodb.DefaultBlocklistPoll, "Period at which to run the maintenance cycle.")
 
 	cfg.Trace.WAL = &wal.Config{}
@@ -39,6 +43,9 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.Trace.WAL.Encoding = backend.EncSnappy
 	cfg.Trace.WAL.SearchEncoding = backend.EncNone
 	cfg.Trace.WAL.IngestionSlack = 2 * time.Minute
+	cfg.Trace.WAL.SyncMode = wal.SyncModeNone
+	cfg.Trace.WAL.SyncInterval = time.Second
+	f.BoolVar(&cfg.Trace.WAL.Checksums, util.PrefixConfig(prefix, "trace.wal.checksums"), false, "Add checksums to the records of new wal files. Releases without wal checksums can't replay them.")
 
 	cfg.Trace.Search = &tempodb.SearchConfig{}
 	cfg.Trace.Search.ChunkSizeBytes = tempodb.DefaultSearchChunkSizeBytes
@@ -79,10 +86,44 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.Trace.Local = &local.Config{}
 	f.StringVar(&cfg.Trace.Local.Path, util.PrefixConfig(prefix, "trace.local.path"), "", "path to store traces at.")
 
+	cfg.Trace.Filesystem = &filesystem.Config{}
+	f.StringVar(&cfg.Trace.Filesystem.Path, util.PrefixConfig(prefix, "trace.filesystem.path"), "", "path on a shared filesystem to store traces at.")
+	cfg.Trace.Filesystem.ReadRetries = 3
+	cfg.Trace.Filesystem.ReadRetryBackoff = 100 * time.Millisecond
+	cfg.Trace.Filesystem.LockTimeout = time.Minute
+
+	cfg.Trace.ColdTier = &tempodb.ColdTierConfig{}
+	cfg.Trace.ColdTier.Local = &local.Config{}
+	cfg.Trace.ColdTier.Filesystem = &filesystem.Config{}
+	cfg.Trace.ColdTier.Filesystem.ReadRetries = 3
+	cfg.Trace.ColdTier.Filesystem.ReadRetryBackoff = 100 * time.Millisecond
+	cfg.Trace.ColdTier.Filesystem.LockTimeout = time.Minute
+	cfg.Trace.ColdTier.Azure = &azure.Config{}
+	cfg.Trace.ColdTier.Azure.Endpoint = "blob.core.windows.net"
+	cfg.Trace.ColdTier.Azure.MaxBuffers = 4
+	cfg.Trace.ColdTier.Azure.BufferSize = 3 * 1024 * 1024
+	cfg.Trace.ColdTier.Azure.HedgeRequestsUpTo = 2
+	cfg.Trace.ColdTier.S3 = &s3.Config{}
+	cfg.Trace.ColdTier.S3.HedgeRequestsUpTo = 2
+	cfg.Trace.ColdTier.GCS = &gcs.Config{}
+	cfg.Trace.ColdTier.GCS.ChunkBufferSize = 10 * 1024 * 1024
+	cfg.Trace.ColdTier.GCS.HedgeRequestsUpTo = 2
+
 	cfg.Trace.BackgroundCache = &cache.BackgroundConfig{}
 	cfg.Trace.BackgroundCache.WriteBackBuffer = 10000
 	cfg.Trace.BackgroundCache.WriteBackGoroutines = 10
 
+	cfg.Trace.RateLimit = &ratelimit.Config{}
+	cfg.Trace.RateLimit.ThrottleMaxBackoff = ratelimit.DefaultThrottleMaxBackoff
+
+	cfg.Trace.Retry = &retry.Config{}
+	cfg.Trace.Retry.MinBackoff = 100 * time.Millisecond
+	cfg.Trace.Retry.MaxBackoff = 5 * time.Second
+	cfg.Trace.Retry.CircuitBreaker.Timeout = 30 * time.Second
+
+	cfg.Trace.Encryption = &encryption.Config{}
+	cfg.Trace.Encryption.ChunkSizeBytes = encryption.DefaultChunkSizeBytes
+
 	cfg.Trace.Pool = &pool.Config{}
 	f.IntVar(&cfg.Trace.Pool.MaxWorkers, util.PrefixConfig(prefix, "trace.pool.max-workers"), 50, "Workers in the worker pool.")
 	f.IntVar(&cfg.Trace.Pool.QueueDepth, util.PrefixConfig(prefix, "trace.pool.queue-depth"), 10000, "Work item queue depth.")
@@ -39,6 +39,7 @@ const (
 	urlParamVersion       = "version"
 	urlParamSize          = "size"
 	urlParamFooterSize    = "footerSize"
+	urlParamTier          = "tier"
 
 	// maxBytes (serverless only)
 	urlParamMaxBytes = "maxBytes"
@@ -47,6 +48,8 @@ const (
 	HeaderContentType    = "Content-Type"
 	HeaderAcceptProtobuf = "application/protobuf"
 	HeaderAcceptJSON     = "application/json"
+	// HeaderDroppedSpans is the number of spans dropped from a truncated trace
+	HeaderDroppedSpans = "X-Tempo-Dropped-Spans"
 
 	PathPrefixQuerier = "/querier"
 
@@ -282,6 +285,9 @@ func ParseSearchBlockRequest(r *http.Request) (*tempopb.SearchBlockRequest, erro
 	}
 	req.FooterSize = uint32(footerSize)
 
+	// the tier is empty for blocks in the hot tier
+	req.Tier = r.URL.Query().Get(urlParamTier)
+
 	return req, nil
 }
 
@@ -355,6 +361,9 @@ func BuildSearchBlockRequest(req *http.Request, searchReq *tempopb.SearchBlockRe
 	q.Set(urlParamDataEncoding, searchReq.DataEncoding)
 	q.Set(urlParamVersion, searchReq.Version)
 	q.Set(urlParamFooterSize, strconv.FormatUint(uint64(searchReq.FooterSize), 10))
+	if searchReq.Tier != "" {
+		q.Set(urlParamTier, searchReq.Tier)
+	}
 
 	req.URL.RawQuery = q.Encode()
 
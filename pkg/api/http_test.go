@@ -344,6 +344,22 @@ func TestBuildSearchBlockRequest(t *testing.T) {
 			httpReq: httptest.NewRequest("GET", "/test/path", nil),
 			query:   "/test/path?blockID=b92ec614-3fd7-4299-b6db-f657e7025a9b&dataEncoding=v1&encoding=s2&footerSize=2000&indexPageSize=10&pagesToSearch=10&size=1000&startPage=0&totalRecords=11&version=v2",
 		},
+		{
+			req: &tempopb.SearchBlockRequest{
+				StartPage:     0,
+				PagesToSearch: 10,
+				BlockID:       "b92ec614-3fd7-4299-b6db-f657e7025a9b",
+				Encoding:      "s2",
+				IndexPageSize: 10,
+				TotalRecords:  11,
+				DataEncoding:  "v1",
+				Version:       "v2",
+				Size_:         1000,
+				FooterSize:    2000,
+				Tier:          "cold",
+			},
+			query: "?blockID=b92ec614-3fd7-4299-b6db-f657e7025a9b&dataEncoding=v1&encoding=s2&footerSize=2000&indexPageSize=10&pagesToSearch=10&size=1000&startPage=0&tier=cold&totalRecords=11&version=v2",
+		},
 		{
 			req: &tempopb.SearchBlockRequest{
 				SearchReq: &tempopb.SearchRequest{
@@ -0,0 +1,364 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	diskCacheTempFilePrefix = ".tmp-"
+	// every file starts with the checksum of its data
+	diskCacheHeaderSize = 4
+)
+
+var diskCacheTable = crc32.MakeTable(crc32.Castagnoli)
+
+// DiskCacheConfig is config for a DiskCache.
+type DiskCacheConfig struct {
+	Path               string `yaml:"path"`
+	MaxSizeBytes       int64  `yaml:"max_size_bytes"`
+	MaxObjectSizeBytes int    `yaml:"max_object_size_bytes"`
+}
+
+// DiskCache caches objects in files on the local disk. The least recently used files are removed once the cache is
+// larger than its max size. Files are written to a temporary file and renamed, so a crash doesn't leave partial files
+// behind, and carry a checksum, so files that were corrupted on disk are dropped when they are fetched. The files are
+// loaded again when the cache is created, the cache survives restarts.
+type DiskCache struct {
+	name   string
+	cfg    DiskCacheConfig
+	logger log.Logger
+
+	mtx sync.Mutex
+	// filename -> size of the file
+	lru  *simplelru.LRU
+	size int64
+	// evicted are the files moved aside by onEvict. they are removed once mtx is released.
+	evicted []string
+
+	requests  prometheus.Counter
+	hits      prometheus.Counter
+	evictions prometheus.Counter
+	corrupted prometheus.Counter
+	sizeBytes prometheus.Gauge
+	items     prometheus.Gauge
+}
+
+// NewDiskCache creates a new DiskCache in the directory of the config and loads the files that are already in it.
+func NewDiskCache(name string, cfg DiskCacheConfig, reg prometheus.Registerer, logger log.Logger) (*DiskCache, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("disk cache path is required")
+	}
+	if cfg.MaxSizeBytes <= 0 {
+		return nil, errors.New("disk cache max size must be greater than 0")
+	}
+
+	err := os.MkdirAll(cfg.Path, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create disk cache path: %w", err)
+	}
+
+	c := &DiskCache{
+		name:   name,
+		cfg:    cfg,
+		logger: logger,
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "tempo",
+			Name:        "disk_cache_requests_total",
+			Help:        "Total number of keys fetched from the disk cache.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "tempo",
+			Name:        "disk_cache_hits_total",
+			Help:        "Total number of keys found in the disk cache.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+		evictions: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "tempo",
+			Name:        "disk_cache_evictions_total",
+			Help:        "Total number of files evicted from the disk cache.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+		corrupted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "tempo",
+			Name:        "disk_cache_corrupted_files_total",
+			Help:        "Total number of files dropped from the disk cache because their checksum didn't match.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+		sizeBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Namespace:   "tempo",
+			Name:        "disk_cache_size_bytes",
+			Help:        "Size of the files in the disk cache.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+		items: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Namespace:   "tempo",
+			Name:        "disk_cache_items",
+			Help:        "Number of files in the disk cache.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+	}
+
+	// the size of the cache is bounded by bytes, not by the number of files
+	c.lru, err = simplelru.NewLRU(math.MaxInt32, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load disk cache: %w", err)
+	}
+
+	return c, nil
+}
+
+// Fetch gets keys from the cache. The keys that are found must be in the order of the keys requested.
+func (c *DiskCache) Fetch(ctx context.Context, keys []string) (found []string, bufs [][]byte, missed []string) {
+	for _, key := range keys {
+		c.requests.Inc()
+
+		buf, ok := c.fetch(key)
+		if !ok {
+			missed = append(missed, key)
+			continue
+		}
+
+		c.hits.Inc()
+		found = append(found, key)
+		bufs = append(bufs, buf)
+	}
+
+	return
+}
+
+// Store stores the keys in the cache. Objects larger than the max object size are not stored.
+func (c *DiskCache) Store(ctx context.Context, keys []string, bufs [][]byte) {
+	for i, key := range keys {
+		if c.cfg.MaxObjectSizeBytes > 0 && len(bufs[i]) > c.cfg.MaxObjectSizeBytes {
+			continue
+		}
+
+		err := c.store(key, bufs[i])
+		if err != nil {
+			level.Error(c.logger).Log("msg", "failed to store to disk cache", "name", c.name, "err", err)
+		}
+	}
+}
+
+// Stop implements Cache. The files are kept for the next start.
+func (c *DiskCache) Stop() {
+}
+
+func (c *DiskCache) fetch(key string) ([]byte, bool) {
+	filename := c.filename(key)
+
+	c.mtx.Lock()
+	_, ok := c.lru.Get(filename)
+	c.mtx.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		// the file was evicted since
+		if !os.IsNotExist(err) {
+			level.Error(c.logger).Log("msg", "failed to fetch from disk cache", "name", c.name, "err", err)
+		}
+		return nil, false
+	}
+
+	if len(b) < diskCacheHeaderSize || binary.LittleEndian.Uint32(b) != crc32.Checksum(b[diskCacheHeaderSize:], diskCacheTable) {
+		level.Warn(c.logger).Log("msg", "dropping corrupted file from disk cache", "name", c.name, "file", filename)
+		c.corrupted.Inc()
+
+		c.mtx.Lock()
+		c.lru.Remove(filename)
+		c.unlock()
+		return nil, false
+	}
+
+	// the modification time keeps the order of the files when they are loaded again
+	now := time.Now()
+	_ = os.Chtimes(filename, now, now)
+
+	return b[diskCacheHeaderSize:], true
+}
+
+func (c *DiskCache) store(key string, buf []byte) error {
+	filename := c.filename(key)
+	dir := filepath.Dir(filename)
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, diskCacheTempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, diskCacheHeaderSize)
+	binary.LittleEndian.PutUint32(header, crc32.Checksum(buf, diskCacheTable))
+	_, err = f.Write(header)
+	if err == nil {
+		_, err = f.Write(buf)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.unlock()
+
+	// the file is renamed while c.mtx is held, so an eviction of the previous file of the key doesn't move it aside
+	// before it's added
+	err = os.Rename(f.Name(), filename)
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	c.add(filename, int64(len(header)+len(buf)))
+	c.evict()
+
+	return nil
+}
+
+// load adds the files in the directory of the cache to the cache, the least recently used first. Temporary files left
+// behind by a crash are removed.
+func (c *DiskCache) load() error {
+	type file struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []file
+	err := filepath.WalkDir(c.cfg.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), diskCacheTempFilePrefix) {
+			return os.Remove(path)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, file{
+			name:    path,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	c.mtx.Lock()
+	defer c.unlock()
+
+	for _, f := range files {
+		c.add(f.name, f.size)
+	}
+	c.evict()
+
+	return nil
+}
+
+// add adds the file to the lru. requires c.mtx to be held.
+func (c *DiskCache) add(filename string, size int64) {
+	if old, ok := c.lru.Peek(filename); ok {
+		c.size -= old.(int64)
+	}
+
+	c.lru.Add(filename, size)
+	c.size += size
+
+	c.sizeBytes.Set(float64(c.size))
+	c.items.Set(float64(c.lru.Len()))
+}
+
+// evict removes the least recently used files until the cache fits its max size. requires c.mtx to be held.
+func (c *DiskCache) evict() {
+	for c.size > c.cfg.MaxSizeBytes && c.lru.Len() > 0 {
+		c.lru.RemoveOldest()
+		c.evictions.Inc()
+	}
+}
+
+// onEvict moves the file of an entry removed from the lru aside, it's removed by unlock. Renaming is cheaper than
+// removing a large file and a file stored again under the same name isn't removed. called with c.mtx held.
+func (c *DiskCache) onEvict(key interface{}, value interface{}) {
+	filename := key.(string)
+	evicted := filepath.Join(filepath.Dir(filename), diskCacheTempFilePrefix+filepath.Base(filename))
+
+	err := os.Rename(filename, evicted)
+	if err == nil {
+		c.evicted = append(c.evicted, evicted)
+	} else if !os.IsNotExist(err) {
+		level.Error(c.logger).Log("msg", "failed to remove file from disk cache", "name", c.name, "file", filename, "err", err)
+	}
+
+	c.size -= value.(int64)
+	c.sizeBytes.Set(float64(c.size))
+	c.items.Set(float64(c.lru.Len()))
+}
+
+// unlock releases c.mtx and removes the files evicted while it was held
+func (c *DiskCache) unlock() {
+	evicted := c.evicted
+	c.evicted = nil
+	c.mtx.Unlock()
+
+	for _, filename := range evicted {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			level.Error(c.logger).Log("msg", "failed to remove file from disk cache", "name", c.name, "file", filename, "err", err)
+		}
+	}
+}
+
+// filename returns the file of the key. The files are spread over subdirectories to keep directories small.
+func (c *DiskCache) filename(key string) string {
+	h := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(h[:])
+
+	return filepath.Join(c.cfg.Path, name[:2], name)
+}
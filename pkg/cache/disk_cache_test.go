@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskCache(t *testing.T, cfg DiskCacheConfig) *DiskCache {
+	c, err := NewDiskCache("test", cfg, prometheus.NewRegistry(), log.NewNopLogger())
+	require.NoError(t, err)
+	return c
+}
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	c := newTestDiskCache(t, DiskCacheConfig{
+		Path:         t.TempDir(),
+		MaxSizeBytes: 1000,
+	})
+
+	c.Store(ctx, []string{"foo", "bar"}, [][]byte{[]byte("foo"), []byte("bar")})
+
+	found, bufs, missed := c.Fetch(ctx, []string{"foo", "baz", "bar"})
+	require.Equal(t, []string{"foo", "bar"}, found)
+	require.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, bufs)
+	require.Equal(t, []string{"baz"}, missed)
+
+	// stored again
+	c.Store(ctx, []string{"foo"}, [][]byte{[]byte("foo2")})
+	_, bufs, _ = c.Fetch(ctx, []string{"foo"})
+	require.Equal(t, [][]byte{[]byte("foo2")}, bufs)
+	require.Equal(t, int64(2*diskCacheHeaderSize+7), c.size)
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	c := newTestDiskCache(t, DiskCacheConfig{
+		Path:               t.TempDir(),
+		MaxSizeBytes:       3 * (diskCacheHeaderSize + 10),
+		MaxObjectSizeBytes: 10,
+	})
+
+	obj := make([]byte, 10)
+	c.Store(ctx, []string{"a", "b", "c"}, [][]byte{obj, obj, obj})
+
+	// a is used, b is the least recently used and evicted
+	found, _, _ := c.Fetch(ctx, []string{"a"})
+	require.Equal(t, []string{"a"}, found)
+	c.Store(ctx, []string{"d"}, [][]byte{obj})
+
+	found, _, missed := c.Fetch(ctx, []string{"a", "b", "c", "d"})
+	require.Equal(t, []string{"a", "c", "d"}, found)
+	require.Equal(t, []string{"b"}, missed)
+	require.NoFileExists(t, c.filename("b"))
+
+	// objects larger than the max object size are not stored
+	c.Store(ctx, []string{"e"}, [][]byte{make([]byte, 11)})
+	_, _, missed = c.Fetch(ctx, []string{"e"})
+	require.Equal(t, []string{"e"}, missed)
+}
+
+func TestDiskCacheConcurrentStores(t *testing.T) {
+	ctx := context.Background()
+	cfg := DiskCacheConfig{
+		Path:         t.TempDir(),
+		MaxSizeBytes: 5 * (diskCacheHeaderSize + 10),
+	}
+	c := newTestDiskCache(t, cfg)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Store(ctx, []string{strconv.Itoa(j % 10)}, [][]byte{make([]byte, 10)})
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every file in the cache is on disk and every file on disk is in the cache
+	var files []string
+	require.NoError(t, filepath.WalkDir(cfg.Path, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			require.False(t, strings.HasPrefix(d.Name(), diskCacheTempFilePrefix))
+			files = append(files, path)
+		}
+		return err
+	}))
+	require.Len(t, files, c.lru.Len())
+	for _, f := range files {
+		require.True(t, c.lru.Contains(f))
+	}
+	require.Equal(t, int64(len(files)*(diskCacheHeaderSize+10)), c.size)
+}
+
+func TestDiskCacheLoad(t *testing.T) {
+	ctx := context.Background()
+	cfg := DiskCacheConfig{
+		Path:         t.TempDir(),
+		MaxSizeBytes: 1000,
+	}
+
+	c := newTestDiskCache(t, cfg)
+	c.Store(ctx, []string{"foo", "bar"}, [][]byte{[]byte("foo"), []byte("bar")})
+	c.Stop()
+
+	// temporary files left behind by a crash are removed
+	tempFile := filepath.Join(cfg.Path, diskCacheTempFilePrefix+"123")
+	require.NoError(t, os.WriteFile(tempFile, []byte("partial"), 0o644))
+
+	// corrupted files are dropped
+	require.NoError(t, os.WriteFile(c.filename("bar"), []byte("corrupted"), 0o644))
+
+	c = newTestDiskCache(t, cfg)
+	require.NoFileExists(t, tempFile)
+
+	found, bufs, missed := c.Fetch(ctx, []string{"foo", "bar"})
+	require.Equal(t, []string{"foo"}, found)
+	require.Equal(t, [][]byte{[]byte("foo")}, bufs)
+	require.Equal(t, []string{"bar"}, missed)
+	require.NoFileExists(t, c.filename("bar"))
+	require.Equal(t, int64(diskCacheHeaderSize+3), c.size)
+}
+
+func TestDiskCacheConfig(t *testing.T) {
+	_, err := NewDiskCache("test", DiskCacheConfig{MaxSizeBytes: 1}, prometheus.NewRegistry(), log.NewNopLogger())
+	require.Error(t, err)
+
+	_, err = NewDiskCache("test", DiskCacheConfig{Path: t.TempDir()}, prometheus.NewRegistry(), log.NewNopLogger())
+	require.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package ingest
+
+import (
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/grafana/tempo/pkg/util"
+)
+
+// Config configures the durable ingestion log between distributors and ingesters
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Path is the directory of the local disk log. It must be shared by the distributor and the ingesters.
+	Path string `yaml:"path"`
+	// Partitions is the number of partitions of the log. It can be increased but not reduced once data has been written.
+	Partitions      int           `yaml:"partitions"`
+	MaxSegmentBytes int64         `yaml:"max_segment_bytes"`
+	Retention       time.Duration `yaml:"retention"`
+	Fsync           bool          `yaml:"fsync"`
+	// ConsumerGroupTimeout is the time after which a consumer group that hasn't committed an offset no longer
+	// holds back the retention, e.g. the group of an ingester that crashed and never came back
+	ConsumerGroupTimeout time.Duration `yaml:"consumer_group_timeout"`
+}
+
+// RegisterFlagsAndApplyDefaults registers flags and applies defaults
+func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, util.PrefixConfig(prefix, "enabled"), false, "Enable to write received traces to a durable log that ingesters consume from instead of pushing them to the ingesters directly.")
+	f.StringVar(&cfg.Path, util.PrefixConfig(prefix, "path"), "/var/tempo/ingest", "Path of the local disk ingest log.")
+	f.IntVar(&cfg.Partitions, util.PrefixConfig(prefix, "partitions"), 64, "Number of partitions of the ingest log. Can be increased but not reduced once data has been written.")
+	f.Int64Var(&cfg.MaxSegmentBytes, util.PrefixConfig(prefix, "max-segment-bytes"), 64*1024*1024, "Maximum size of a segment file of a partition before a new one is started.")
+	f.DurationVar(&cfg.Retention, util.PrefixConfig(prefix, "retention"), 2*time.Hour, "Minimum duration segments are kept after they were last written to. Segments not consumed by all ingesters are kept longer.")
+	f.BoolVar(&cfg.Fsync, util.PrefixConfig(prefix, "fsync"), true, "Fsync every append before it is acknowledged.")
+	f.DurationVar(&cfg.ConsumerGroupTimeout, util.PrefixConfig(prefix, "consumer-group-timeout"), 24*time.Hour, "Duration after which the offsets of a consumer group that stopped committing are removed, so they no longer hold back the retention. 0 to keep them.")
+}
+
+// Validate checks the config is usable
+func (cfg *Config) Validate() error {
+	if cfg.Path == "" {
+		return errors.New("ingest log path must be set")
+	}
+	if cfg.Partitions <= 0 {
+		return errors.New("ingest log partitions must be greater than 0")
+	}
+	if cfg.MaxSegmentBytes <= 0 {
+		return errors.New("ingest log max segment bytes must be greater than 0")
+	}
+	return nil
+}
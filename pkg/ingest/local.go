@@ -0,0 +1,754 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	segmentExtension = ".log"
+	offsetsDir       = "offsets"
+	writerLockFile   = "writer.lock"
+
+	// recordHeaderSize is the length and the checksum of the record body
+	recordHeaderSize = 8
+	// recordBodyMinSize is the offset and the length of the tenant
+	recordBodyMinSize = 10
+	// maxRecordBytes protects against allocating huge buffers for a corrupt length
+	maxRecordBytes = 1 << 30
+
+	// readPositionsPerPartition is the number of read positions remembered per partition. Consumers continuing
+	// where they stopped don't have to scan the segment from the start again.
+	readPositionsPerPartition = 16
+)
+
+var (
+	metricAppendedRecords = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingest_log_appended_records_total",
+		Help:      "The total number of records appended to the ingest log.",
+	})
+	metricAppendedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingest_log_appended_bytes_total",
+		Help:      "The total number of bytes appended to the ingest log.",
+	})
+	metricAppendFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingest_log_append_failures_total",
+		Help:      "The total number of failed appends to the ingest log.",
+	})
+	metricSegmentsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingest_log_segments_deleted_total",
+		Help:      "The total number of segments deleted after the retention period.",
+	})
+	metricExpiredGroups = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingest_log_expired_consumer_group_offsets_total",
+		Help:      "The total number of partition offsets removed because their consumer group stopped committing.",
+	})
+	metricCorruptRecords = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingest_log_corrupt_records_total",
+		Help:      "The total number of corrupt records skipped in sealed segments.",
+	})
+)
+
+var errLocked = errors.New("locked")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// localLog stores each partition as a directory of append only segment files. Segments are named after the
+// offset of their first record. Each record is written as:
+//
+//	length uint32 | crc32 uint32 | offset int64 | tenant length uint16 | tenant | value
+//
+// Only one process may append to a log, it holds an exclusive lock on the writer lock file. Any number of processes
+// can open the log read only and consume from it.
+type localLog struct {
+	cfg        Config
+	readOnly   bool
+	lock       *os.File
+	partitions []*localPartition
+
+	positionsMtx sync.Mutex
+	positions    map[int][]readPosition
+}
+
+type localPartition struct {
+	dir string
+
+	mtx        sync.Mutex
+	segment    *os.File
+	size       int64
+	nextOffset int64
+}
+
+// readPosition is the location of a record in a segment
+type readPosition struct {
+	segment int64 // offset of the first record of the segment
+	pos     int64 // byte position in the segment
+	offset  int64 // offset of the record at the byte position
+}
+
+func newLocalLog(cfg Config, readOnly bool) (*localLog, error) {
+	err := os.MkdirAll(filepath.Join(cfg.Path, offsetsDir), 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingest log directory: %w", err)
+	}
+
+	// partitions can be added but removing them would strand the data in them
+	entries, err := os.ReadDir(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		p, err := strconv.Atoi(e.Name())
+		if err == nil && e.IsDir() && p >= cfg.Partitions {
+			return nil, fmt.Errorf("ingest log %s contains partition %d, partitions can not be reduced to %d", cfg.Path, p, cfg.Partitions)
+		}
+	}
+
+	l := &localLog{
+		cfg:        cfg,
+		readOnly:   readOnly,
+		partitions: make([]*localPartition, cfg.Partitions),
+		positions:  map[int][]readPosition{},
+	}
+
+	if readOnly {
+		for i := range l.partitions {
+			l.partitions[i] = &localPartition{dir: filepath.Join(cfg.Path, strconv.Itoa(i))}
+		}
+		return l, nil
+	}
+
+	// opening a partition for writing truncates its last segment, a second writer would destroy appended records
+	l.lock, err = os.OpenFile(filepath.Join(cfg.Path, writerLockFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ingest log lock file: %w", err)
+	}
+	if err := lockFile(l.lock); err != nil {
+		_ = l.lock.Close()
+		if err == errLocked {
+			return nil, fmt.Errorf("ingest log %s is opened for writing by another process, only a single distributor may write to it", cfg.Path)
+		}
+		return nil, fmt.Errorf("failed to lock ingest log: %w", err)
+	}
+
+	for i := range l.partitions {
+		l.partitions[i], err = openLocalPartition(filepath.Join(cfg.Path, strconv.Itoa(i)), cfg.Fsync)
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("failed to open ingest log partition %d: %w", i, err)
+		}
+	}
+
+	return l, nil
+}
+
+func openLocalPartition(dir string, fsync bool) (*localPartition, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &localPartition{dir: dir}
+	if len(segments) == 0 {
+		return p, p.roll(fsync)
+	}
+
+	last := segments[len(segments)-1]
+	f, err := os.OpenFile(segmentPath(dir, last), os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	// a crash can leave a partially written record behind, drop it so appends start at a record boundary
+	end, next := scanSegment(f, last)
+	if err := f.Truncate(end); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	p.segment = f
+	p.size = end
+	p.nextOffset = next
+	return p, nil
+}
+
+// roll starts a new segment at the next offset. It must be called under lock.
+func (p *localPartition) roll(fsync bool) error {
+	f, err := os.OpenFile(segmentPath(p.dir, p.nextOffset), os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if fsync {
+		if err := syncDir(p.dir); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+
+	if p.segment != nil {
+		_ = p.segment.Close()
+	}
+	p.segment = f
+	p.size = 0
+	return nil
+}
+
+// Partitions implements Log
+func (l *localLog) Partitions() int {
+	return len(l.partitions)
+}
+
+// Append implements Log
+func (l *localLog) Append(partition int, records []*Record) error {
+	if l.readOnly {
+		return ErrReadOnly
+	}
+
+	p, err := l.partition(partition)
+	if err != nil {
+		return err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.size >= l.cfg.MaxSegmentBytes {
+		if err := p.roll(l.cfg.Fsync); err != nil {
+			metricAppendFailures.Inc()
+			return fmt.Errorf("failed to roll segment: %w", err)
+		}
+		l.deleteExpiredSegments(partition, p)
+	}
+
+	var buf []byte
+	offset := p.nextOffset
+	for _, r := range records {
+		if len(r.TenantID) > math.MaxUint16 {
+			return fmt.Errorf("tenant id too long: %d", len(r.TenantID))
+		}
+		r.Offset = offset
+		offset++
+		buf = appendRecord(buf, r)
+	}
+
+	_, err = p.segment.Write(buf)
+	if err == nil && l.cfg.Fsync {
+		err = p.segment.Sync()
+	}
+	if err != nil {
+		metricAppendFailures.Inc()
+		// drop what might have been written so the next append starts at a record boundary
+		_ = p.segment.Truncate(p.size)
+		_, _ = p.segment.Seek(p.size, io.SeekStart)
+		return fmt.Errorf("failed to append to partition %d: %w", partition, err)
+	}
+
+	p.size += int64(len(buf))
+	p.nextOffset = offset
+
+	metricAppendedRecords.Add(float64(len(records)))
+	metricAppendedBytes.Add(float64(len(buf)))
+	return nil
+}
+
+// deleteExpiredSegments removes the segments that have been consumed by all consumer groups and have not been written
+// to within the retention period. The current segment is never removed. It must be called under lock.
+func (l *localLog) deleteExpiredSegments(partition int, p *localPartition) {
+	if l.cfg.Retention <= 0 {
+		return
+	}
+
+	committed, ok := l.minCommitted(partition)
+	if !ok {
+		return
+	}
+
+	segments, err := listSegments(p.dir)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-l.cfg.Retention)
+	for i, s := range segments[:len(segments)-1] {
+		// all records of a segment are consumed once the next segment starts at or below the committed offset
+		if segments[i+1] > committed {
+			break
+		}
+
+		path := segmentPath(p.dir, s)
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if os.Remove(path) == nil {
+			metricSegmentsDeleted.Inc()
+		}
+	}
+}
+
+// minCommitted returns the lowest offset committed for the partition by any consumer group or false if no group has
+// committed one. The offsets of groups that haven't committed within the consumer group timeout are removed, the
+// consumer is gone and would otherwise keep the segments forever.
+func (l *localLog) minCommitted(partition int) (int64, bool) {
+	groups, err := os.ReadDir(filepath.Join(l.cfg.Path, offsetsDir))
+	if err != nil {
+		return 0, false
+	}
+
+	min, found := int64(math.MaxInt64), false
+	for _, g := range groups {
+		if !g.IsDir() {
+			continue
+		}
+
+		if l.expireGroup(g.Name(), partition) {
+			continue
+		}
+
+		offset, ok, err := l.Committed(g.Name(), partition)
+		if err != nil {
+			// an unreadable offset must not let the segments of the group be deleted
+			return 0, false
+		}
+		if ok && offset < min {
+			min, found = offset, true
+		}
+	}
+
+	return min, found
+}
+
+// expireGroup removes the offset of the group for the partition if it hasn't been committed within the consumer group
+// timeout and returns true if it was removed
+func (l *localLog) expireGroup(group string, partition int) bool {
+	if l.cfg.ConsumerGroupTimeout <= 0 {
+		return false
+	}
+
+	path := filepath.Join(l.cfg.Path, offsetsDir, group, strconv.Itoa(partition))
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) < l.cfg.ConsumerGroupTimeout {
+		return false
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false
+	}
+	metricExpiredGroups.Inc()
+
+	// fails while the group has offsets of other partitions
+	_ = os.Remove(filepath.Join(l.cfg.Path, offsetsDir, group))
+	return true
+}
+
+// Fetch implements Log
+func (l *localLog) Fetch(partition int, offset int64, max int) ([]*Record, error) {
+	p, err := l.partition(partition)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(p.dir)
+	if os.IsNotExist(err) {
+		// not created by the writer yet
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	start, ok := l.takePosition(partition, offset)
+	if !ok {
+		start = readPosition{segment: segments[0], offset: offset}
+		for _, s := range segments {
+			if s <= offset {
+				start.segment = s
+			}
+		}
+	}
+
+	var records []*Record
+	end := start
+	for i, s := range segments {
+		if s < start.segment {
+			continue
+		}
+
+		pos := int64(0)
+		if s == start.segment {
+			pos = start.pos
+		}
+
+		// the last segment is still written to and can end in a record that is not complete yet, corrupt records
+		// in sealed segments are skipped
+		sealed := i < len(segments)-1
+		recs, n, err := readSegment(segmentPath(p.dir, s), pos, offset, max-len(records), sealed)
+		if err != nil && (sealed || !errors.Is(err, ErrCorruptRecord)) {
+			return nil, fmt.Errorf("failed to read segment %d of partition %d: %w", s, partition, err)
+		}
+
+		records = append(records, recs...)
+		end = readPosition{segment: s, pos: n, offset: offset}
+		if len(records) > 0 {
+			end.offset = records[len(records)-1].Offset + 1
+		}
+
+		if len(records) >= max {
+			break
+		}
+	}
+
+	l.storePosition(partition, end)
+	return records, nil
+}
+
+// Commit implements Log
+func (l *localLog) Commit(group string, partition int, offset int64) error {
+	if err := validateGroup(group); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(l.cfg.Path, offsetsDir, group)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, strconv.Itoa(partition))
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(strconv.FormatInt(offset, 10))
+	if err == nil && l.cfg.Fsync {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Committed implements Log
+func (l *localLog) Committed(group string, partition int) (int64, bool, error) {
+	if err := validateGroup(group); err != nil {
+		return 0, false, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(l.cfg.Path, offsetsDir, group, strconv.Itoa(partition)))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	offset, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid committed offset for group %s and partition %d: %w", group, partition, err)
+	}
+	return offset, true, nil
+}
+
+// Release implements Log
+func (l *localLog) Release(group string, partition int) error {
+	if err := validateGroup(group); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(l.cfg.Path, offsetsDir, group, strconv.Itoa(partition)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Close implements Log
+func (l *localLog) Close() error {
+	var lastErr error
+	for _, p := range l.partitions {
+		if p == nil {
+			continue
+		}
+
+		p.mtx.Lock()
+		if p.segment != nil {
+			if err := p.segment.Close(); err != nil {
+				lastErr = err
+			}
+			p.segment = nil
+		}
+		p.mtx.Unlock()
+	}
+
+	if l.lock != nil {
+		_ = unlockFile(l.lock)
+		if err := l.lock.Close(); err != nil {
+			lastErr = err
+		}
+		l.lock = nil
+	}
+	return lastErr
+}
+
+func (l *localLog) partition(partition int) (*localPartition, error) {
+	if partition < 0 || partition >= len(l.partitions) {
+		return nil, fmt.Errorf("partition %d out of range, ingest log has %d partitions", partition, len(l.partitions))
+	}
+	return l.partitions[partition], nil
+}
+
+func (l *localLog) takePosition(partition int, offset int64) (readPosition, bool) {
+	l.positionsMtx.Lock()
+	defer l.positionsMtx.Unlock()
+
+	positions := l.positions[partition]
+	for i, p := range positions {
+		if p.offset == offset {
+			l.positions[partition] = append(positions[:i], positions[i+1:]...)
+			return p, true
+		}
+	}
+	return readPosition{}, false
+}
+
+func (l *localLog) storePosition(partition int, p readPosition) {
+	l.positionsMtx.Lock()
+	defer l.positionsMtx.Unlock()
+
+	positions := append(l.positions[partition], p)
+	if len(positions) > readPositionsPerPartition {
+		positions = positions[1:]
+	}
+	l.positions[partition] = positions
+}
+
+// readSegment reads up to max records with an offset of at least minOffset starting at the byte position. It
+// returns the byte position after the last record read. Corrupt records of sealed segments are skipped, reading
+// continues at the next valid record.
+func readSegment(path string, pos int64, minOffset int64, max int, sealed bool) ([]*Record, int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// deleted by retention while reading
+		return nil, pos, nil
+	}
+	if err != nil {
+		return nil, pos, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		return nil, pos, err
+	}
+
+	var records []*Record
+	r := bufio.NewReader(f)
+	for len(records) < max {
+		rec, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if sealed && (err == ErrCorruptRecord || err == io.ErrUnexpectedEOF) {
+			metricCorruptRecords.Inc()
+			pos, err = findRecord(f, pos+1)
+			if err != nil {
+				return records, pos, err
+			}
+			if _, err := f.Seek(pos, io.SeekStart); err != nil {
+				return records, pos, err
+			}
+			r.Reset(f)
+			continue
+		}
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return records, pos, err
+		}
+
+		if rec.Offset >= minOffset {
+			records = append(records, rec)
+		}
+		pos += n
+	}
+
+	return records, pos, nil
+}
+
+// findRecord returns the byte position of the next valid record at or after pos or the end of the segment if there
+// is none
+func findRecord(f *os.File, pos int64) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return pos, err
+	}
+	if pos >= info.Size() {
+		return info.Size(), nil
+	}
+
+	data := make([]byte, info.Size()-pos)
+	if _, err := f.ReadAt(data, pos); err != nil && err != io.EOF {
+		return pos, err
+	}
+
+	for i := 0; i+recordHeaderSize <= len(data); i++ {
+		length := int(binary.LittleEndian.Uint32(data[i:]))
+		if length < recordBodyMinSize || length > len(data)-i-recordHeaderSize {
+			continue
+		}
+
+		body := data[i+recordHeaderSize : i+recordHeaderSize+length]
+		if crc32.Checksum(body, crcTable) == binary.LittleEndian.Uint32(data[i+4:]) {
+			return pos + int64(i), nil
+		}
+	}
+
+	return info.Size(), nil
+}
+
+// scanSegment returns the byte position after the last complete record and the offset following it
+func scanSegment(f *os.File, base int64) (int64, int64) {
+	end, next := int64(0), base
+
+	r := bufio.NewReader(f)
+	for {
+		rec, n, err := readRecord(r)
+		if err != nil {
+			return end, next
+		}
+		end += n
+		next = rec.Offset + 1
+	}
+}
+
+func readRecord(r *bufio.Reader) (*Record, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:])
+	if length < recordBodyMinSize || length > maxRecordBytes {
+		return nil, 0, ErrCorruptRecord
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+
+	if crc32.Checksum(body, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, 0, ErrCorruptRecord
+	}
+
+	tenantLength := int(binary.LittleEndian.Uint16(body[8:]))
+	if len(body) < recordBodyMinSize+tenantLength {
+		return nil, 0, ErrCorruptRecord
+	}
+
+	return &Record{
+		Offset:   int64(binary.LittleEndian.Uint64(body[0:])),
+		TenantID: string(body[recordBodyMinSize : recordBodyMinSize+tenantLength]),
+		Value:    body[recordBodyMinSize+tenantLength:],
+	}, recordHeaderSize + int64(length), nil
+}
+
+func appendRecord(buf []byte, r *Record) []byte {
+	length := recordBodyMinSize + len(r.TenantID) + len(r.Value)
+
+	start := len(buf)
+	buf = append(buf, make([]byte, recordHeaderSize+recordBodyMinSize)...)
+	binary.LittleEndian.PutUint32(buf[start:], uint32(length))
+	binary.LittleEndian.PutUint64(buf[start+recordHeaderSize:], uint64(r.Offset))
+	binary.LittleEndian.PutUint16(buf[start+recordHeaderSize+8:], uint16(len(r.TenantID)))
+	buf = append(buf, r.TenantID...)
+	buf = append(buf, r.Value...)
+
+	binary.LittleEndian.PutUint32(buf[start+4:], crc32.Checksum(buf[start+recordHeaderSize:], crcTable))
+	return buf
+}
+
+func listSegments(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExtension) {
+			continue
+		}
+		base, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), segmentExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, base)
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+func segmentPath(dir string, base int64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", base, segmentExtension))
+}
+
+func validateGroup(group string) error {
+	if group == "" || group == "." || group == ".." || strings.ContainsAny(group, `/\`) {
+		return fmt.Errorf("invalid consumer group %q", group)
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
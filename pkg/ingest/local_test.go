@@ -0,0 +1,311 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(t *testing.T) Config {
+	return Config{
+		Enabled:         true,
+		Path:            t.TempDir(),
+		Partitions:      2,
+		MaxSegmentBytes: 1024,
+		Fsync:           true,
+	}
+}
+
+func testRecords(tenant string, count int) []*Record {
+	records := make([]*Record, count)
+	for i := range records {
+		records[i] = &Record{TenantID: tenant, Value: []byte(fmt.Sprintf("value-%d", i))}
+	}
+	return records
+}
+
+func TestLocalLogAppendFetch(t *testing.T) {
+	cfg := testConfig(t)
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	// enough records to roll multiple segments
+	records := testRecords("tenant", 200)
+	for i := 0; i < len(records); i += 10 {
+		require.NoError(t, l.Append(1, records[i:i+10]))
+	}
+	for i, r := range records {
+		require.Equal(t, int64(i), r.Offset)
+	}
+
+	segments, err := listSegments(filepath.Join(cfg.Path, "1"))
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+
+	// read everything in small batches
+	var actual []*Record
+	offset := int64(0)
+	for {
+		fetched, err := l.Fetch(1, offset, 7)
+		require.NoError(t, err)
+		if len(fetched) == 0 {
+			break
+		}
+		actual = append(actual, fetched...)
+		offset = fetched[len(fetched)-1].Offset + 1
+	}
+	assert.Equal(t, records, actual)
+
+	// start in the middle of a segment
+	fetched, err := l.Fetch(1, 123, 2)
+	require.NoError(t, err)
+	assert.Equal(t, records[123:125], fetched)
+
+	// other partitions are not affected
+	fetched, err = l.Fetch(0, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, fetched)
+
+	_, err = l.Fetch(2, 0, 10)
+	require.Error(t, err)
+}
+
+func TestLocalLogReopen(t *testing.T) {
+	cfg := testConfig(t)
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Append(0, testRecords("tenant", 5)))
+	require.NoError(t, l.Close())
+
+	// simulate a crash during an append
+	segments, err := listSegments(filepath.Join(cfg.Path, "0"))
+	require.NoError(t, err)
+	f, err := os.OpenFile(segmentPath(filepath.Join(cfg.Path, "0"), segments[len(segments)-1]), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write(appendRecord(nil, &Record{Offset: 5, TenantID: "tenant", Value: []byte("torn")})[:12])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// a consumer stops at the torn record
+	l, err = New(cfg, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	fetched, err := l.Fetch(0, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, fetched, 5)
+
+	// and the writer continues after the last complete record
+	records := testRecords("other", 1)
+	require.NoError(t, l.Append(0, records))
+	assert.Equal(t, int64(5), records[0].Offset)
+
+	fetched, err = l.Fetch(0, 5, 10)
+	require.NoError(t, err)
+	assert.Equal(t, records, fetched)
+}
+
+func TestLocalLogSingleWriter(t *testing.T) {
+	cfg := testConfig(t)
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+
+	_, err = New(cfg, false)
+	require.Error(t, err)
+
+	// consumers open the log read only next to the writer
+	r, err := New(cfg, true)
+	require.NoError(t, err)
+	defer r.Close()
+
+	records := testRecords("tenant", 3)
+	require.NoError(t, l.Append(0, records))
+	require.ErrorIs(t, r.Append(0, testRecords("tenant", 1)), ErrReadOnly)
+
+	fetched, err := r.Fetch(0, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, records, fetched)
+
+	// the lock is released on close
+	require.NoError(t, l.Close())
+	l, err = New(cfg, false)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+}
+
+func TestLocalLogRetention(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Retention = time.Nanosecond
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	dir := filepath.Join(cfg.Path, "0")
+	appendRecords := func() {
+		for i := 0; i < 10; i++ {
+			require.NoError(t, l.Append(0, testRecords("tenant", 10)))
+		}
+	}
+
+	// nothing is deleted before it is consumed
+	appendRecords()
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 2)
+	assert.Equal(t, int64(0), segments[0])
+
+	// segments are deleted up to the lowest committed offset of all groups
+	require.NoError(t, l.Commit("ingester-1", 0, 100))
+	require.NoError(t, l.Commit("ingester-2", 0, 40))
+	appendRecords()
+	segments, err = listSegments(dir)
+	require.NoError(t, err)
+	assert.Greater(t, segments[0], int64(0))
+	assert.LessOrEqual(t, segments[0], int64(40))
+
+	fetched, err := l.Fetch(0, 40, 1)
+	require.NoError(t, err)
+	require.Len(t, fetched, 1)
+	assert.Equal(t, int64(40), fetched[0].Offset)
+
+	// a released group no longer holds back the retention
+	require.NoError(t, l.Release("ingester-2", 0))
+	appendRecords()
+	segments, err = listSegments(dir)
+	require.NoError(t, err)
+	assert.Greater(t, segments[0], int64(40))
+	assert.LessOrEqual(t, segments[0], int64(100))
+}
+
+func TestLocalLogExpiresConsumerGroups(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Retention = time.Nanosecond
+	cfg.ConsumerGroupTimeout = time.Hour
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	dir := filepath.Join(cfg.Path, "0")
+	appendRecords := func() {
+		for i := 0; i < 10; i++ {
+			require.NoError(t, l.Append(0, testRecords("tenant", 10)))
+		}
+	}
+
+	appendRecords()
+	require.NoError(t, l.Commit("ingester-1", 0, 100))
+	require.NoError(t, l.Commit("ingester-2", 0, 0))
+
+	// the group of an ingester that is gone holds back the retention until it times out
+	appendRecords()
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), segments[0])
+
+	stale := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(cfg.Path, offsetsDir, "ingester-2", "0"), stale, stale))
+	require.NoError(t, l.Commit("ingester-1", 0, 200))
+
+	appendRecords()
+	segments, err = listSegments(dir)
+	require.NoError(t, err)
+	assert.Greater(t, segments[0], int64(100))
+	assert.LessOrEqual(t, segments[0], int64(200))
+
+	_, ok, err := l.Committed("ingester-2", 0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoDirExists(t, filepath.Join(cfg.Path, offsetsDir, "ingester-2"))
+}
+
+func TestLocalLogSkipsCorruptRecords(t *testing.T) {
+	cfg := testConfig(t)
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	records := testRecords("tenant", 100)
+	for i := 0; i < len(records); i += 10 {
+		require.NoError(t, l.Append(0, records[i:i+10]))
+	}
+
+	// corrupt the value of the second record of the first, sealed segment
+	dir := filepath.Join(cfg.Path, "0")
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+
+	f, err := os.OpenFile(segmentPath(dir, segments[0]), os.O_RDWR, 0)
+	require.NoError(t, err)
+	recordSize := int64(len(appendRecord(nil, records[0])))
+	_, err = f.WriteAt([]byte("x"), 2*recordSize-1)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	fetched, err := l.Fetch(0, 0, len(records))
+	require.NoError(t, err)
+	require.Len(t, fetched, len(records)-1)
+	assert.Equal(t, records[0], fetched[0])
+	assert.Equal(t, records[2:], fetched[1:])
+}
+
+func TestLocalLogCommit(t *testing.T) {
+	l, err := New(testConfig(t), false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, ok, err := l.Committed("ingester-1", 0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, l.Commit("ingester-1", 0, 10))
+	require.NoError(t, l.Commit("ingester-1", 0, 20))
+	require.NoError(t, l.Commit("ingester-2", 0, 5))
+
+	offset, ok, err := l.Committed("ingester-1", 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), offset)
+
+	offset, ok, err = l.Committed("ingester-2", 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), offset)
+
+	require.Error(t, l.Commit("../escape", 0, 1))
+}
+
+func TestLocalLogPartitionsCanNotBeReduced(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Partitions = 4
+	l, err := New(cfg, false)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	cfg.Partitions = 8
+	l, err = New(cfg, false)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	cfg.Partitions = 4
+	_, err = New(cfg, false)
+	require.Error(t, err)
+}
+
+func TestPartitionToken(t *testing.T) {
+	assert.Equal(t, uint32(0), PartitionToken(0, 4))
+	assert.Equal(t, uint32(1<<30), PartitionToken(1, 4))
+	assert.Equal(t, uint32(3<<30), PartitionToken(3, 4))
+
+	for token := uint32(0); token < 100; token++ {
+		p := PartitionFor(token, 7)
+		assert.True(t, p >= 0 && p < 7)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package ingest
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive flock of the file. Linux NFS clients emulate flock with byte range locks, so the lock
+// is held across nodes.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package ingest
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(_ *os.File) error {
+	return errors.New("the local disk ingest log is not supported on windows")
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrCorruptRecord is returned when a record can not be read back from the log
+	ErrCorruptRecord = errors.New("corrupt record")
+	// ErrReadOnly is returned when appending to a log that was opened for consuming only
+	ErrReadOnly = errors.New("ingest log is read only")
+)
+
+// Record is a single entry of a partition. Offsets are assigned on append and increase by one per record.
+type Record struct {
+	Offset   int64
+	TenantID string
+	Value    []byte
+}
+
+// Log is a partitioned, durable log of push requests. The distributor appends to it and ingesters consume the
+// partitions they own, keeping track of their progress with committed offsets.
+type Log interface {
+	// Partitions returns the number of partitions
+	Partitions() int
+	// Append durably appends the records to the partition and sets their offsets
+	Append(partition int, records []*Record) error
+	// Fetch returns up to max records of the partition starting at offset. If the offset is no longer retained
+	// reading starts at the oldest record.
+	Fetch(partition int, offset int64, max int) ([]*Record, error)
+	// Commit stores the next offset the group has to consume from the partition. Groups have to commit their offsets
+	// within the consumer group timeout, even if they didn't change, or they are expired.
+	Commit(group string, partition int, offset int64) error
+	// Committed returns the committed offset of the group or false if there is none
+	Committed(group string, partition int) (int64, bool, error)
+	// Release removes the committed offset of a group that stopped consuming the partition, so it no longer
+	// holds back the retention of the partition
+	Release(group string, partition int) error
+	Close() error
+}
+
+// New returns the log described by the config. Only a single process may open the log for writing, consumers
+// open it read only.
+func New(cfg Config, readOnly bool) (Log, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return newLocalLog(cfg, readOnly)
+}
+
+// PartitionFor returns the partition of the trace token
+func PartitionFor(token uint32, partitions int) int {
+	return int(token % uint32(partitions))
+}
+
+// PartitionToken returns the ring token used to find the ingesters owning the partition. Tokens are spread
+// evenly over the ring.
+func PartitionToken(partition, partitions int) uint32 {
+	return uint32(uint64(partition) * (math.MaxUint32 + 1) / uint64(partitions))
+}
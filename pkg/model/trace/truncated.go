@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+)
+
+const (
+	// AttributeTruncated marks the batches of a trace that was truncated because it exceeded the max bytes per trace
+	AttributeTruncated = "tempo.truncated"
+	// AttributeDroppedSpans is the number of spans dropped from the truncated trace
+	AttributeDroppedSpans = "tempo.dropped_spans"
+)
+
+// MarkTruncated sets the truncation resource attributes on all batches of the trace. Attributes set by a previous call
+// are replaced.
+func MarkTruncated(t *tempopb.Trace, droppedSpans int) {
+	for _, b := range t.Batches {
+		if b.Resource == nil {
+			b.Resource = &v1resource.Resource{}
+		}
+
+		attrs := b.Resource.Attributes[:0]
+		for _, a := range b.Resource.Attributes {
+			if a.Key != AttributeTruncated && a.Key != AttributeDroppedSpans {
+				attrs = append(attrs, a)
+			}
+		}
+		b.Resource.Attributes = append(attrs,
+			&v1common.KeyValue{Key: AttributeTruncated, Value: &v1common.AnyValue{Value: &v1common.AnyValue_BoolValue{BoolValue: true}}},
+			&v1common.KeyValue{Key: AttributeDroppedSpans, Value: &v1common.AnyValue{Value: &v1common.AnyValue_IntValue{IntValue: int64(droppedSpans)}}},
+		)
+	}
+}
+
+// DroppedSpans returns the number of spans dropped from the trace if it was truncated. Parts of the trace cut or
+// flushed separately carry their own count, the largest one is returned.
+func DroppedSpans(t *tempopb.Trace) (droppedSpans int, truncated bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	for _, b := range t.Batches {
+		if b.Resource == nil {
+			continue
+		}
+		for _, a := range b.Resource.Attributes {
+			switch a.Key {
+			case AttributeTruncated:
+				truncated = truncated || a.Value.GetBoolValue()
+			case AttributeDroppedSpans:
+				if n := int(a.Value.GetIntValue()); n > droppedSpans {
+					droppedSpans = n
+				}
+			}
+		}
+	}
+
+	return droppedSpans, truncated
+}
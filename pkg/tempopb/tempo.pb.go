@@ -151,6 +151,8 @@ func (m *TraceByIDResponse) GetMetrics() *TraceByIDMetrics {
 
 type TraceByIDMetrics struct {
 	FailedBlocks uint32 `protobuf:"varint,1,opt,name=failedBlocks,proto3" json:"failedBlocks,omitempty"`
+	// spans dropped from the trace because it exceeded the max bytes per trace of a tenant that truncates traces
+	DroppedSpans uint32 `protobuf:"varint,2,opt,name=droppedSpans,proto3" json:"droppedSpans,omitempty"`
 }
 
 func (m *TraceByIDMetrics) Reset()         { *m = TraceByIDMetrics{} }
@@ -193,6 +195,13 @@ func (m *TraceByIDMetrics) GetFailedBlocks() uint32 {
 	return 0
 }
 
+func (m *TraceByIDMetrics) GetDroppedSpans() uint32 {
+	if m != nil {
+		return m.DroppedSpans
+	}
+	return 0
+}
+
 // SearchRequest takes no block parameters and implies a "recent traces" search
 type SearchRequest struct {
 	// case insensitive partial match
@@ -293,6 +302,7 @@ type SearchBlockRequest struct {
 	Version       string         `protobuf:"bytes,9,opt,name=version,proto3" json:"version,omitempty"`
 	Size_         uint64         `protobuf:"varint,10,opt,name=size,proto3" json:"size,omitempty"`
 	FooterSize    uint32         `protobuf:"varint,11,opt,name=footerSize,proto3" json:"footerSize,omitempty"`
+	Tier          string         `protobuf:"bytes,12,opt,name=tier,proto3" json:"tier,omitempty"`
 }
 
 func (m *SearchBlockRequest) Reset()         { *m = SearchBlockRequest{} }
@@ -405,6 +415,13 @@ func (m *SearchBlockRequest) GetFooterSize() uint32 {
 	return 0
 }
 
+func (m *SearchBlockRequest) GetTier() string {
+	if m != nil {
+		return m.Tier
+	}
+	return ""
+}
+
 type SearchResponse struct {
 	Traces  []*TraceSearchMetadata `protobuf:"bytes,1,rep,name=traces,proto3" json:"traces,omitempty"`
 	Metrics *SearchMetrics         `protobuf:"bytes,2,opt,name=metrics,proto3" json:"metrics,omitempty"`
@@ -540,6 +557,8 @@ type SearchMetrics struct {
 	SkippedBlocks   uint32 `protobuf:"varint,4,opt,name=skippedBlocks,proto3" json:"skippedBlocks,omitempty"`
 	SkippedTraces   uint32 `protobuf:"varint,5,opt,name=skippedTraces,proto3" json:"skippedTraces,omitempty"`
 	TotalBlockBytes uint64 `protobuf:"varint,6,opt,name=totalBlockBytes,proto3" json:"totalBlockBytes,omitempty"`
+	// time spent per stage of the search in the ingesters
+	Stages []*SearchStageMetrics `protobuf:"bytes,7,rep,name=stages,proto3" json:"stages,omitempty"`
 }
 
 func (m *SearchMetrics) Reset()         { *m = SearchMetrics{} }
@@ -617,6 +636,67 @@ func (m *SearchMetrics) GetTotalBlockBytes() uint64 {
 	return 0
 }
 
+func (m *SearchMetrics) GetStages() []*SearchStageMetrics {
+	if m != nil {
+		return m.Stages
+	}
+	return nil
+}
+
+// SearchStageMetrics is the time spent searching one stage of the data of an ingester,
+// i.e. live traces, head block, completing blocks or complete blocks, summed over its blocks
+type SearchStageMetrics struct {
+	Stage         string `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`
+	DurationNanos uint64 `protobuf:"varint,2,opt,name=durationNanos,proto3" json:"durationNanos,omitempty"`
+}
+
+func (m *SearchStageMetrics) Reset()         { *m = SearchStageMetrics{} }
+func (m *SearchStageMetrics) String() string { return proto.CompactTextString(m) }
+func (*SearchStageMetrics) ProtoMessage()    {}
+func (*SearchStageMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{8}
+}
+func (m *SearchStageMetrics) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SearchStageMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SearchStageMetrics.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SearchStageMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchStageMetrics.Merge(m, src)
+}
+func (m *SearchStageMetrics) XXX_Size() int {
+	return m.Size()
+}
+func (m *SearchStageMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchStageMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchStageMetrics proto.InternalMessageInfo
+
+func (m *SearchStageMetrics) GetStage() string {
+	if m != nil {
+		return m.Stage
+	}
+	return ""
+}
+
+func (m *SearchStageMetrics) GetDurationNanos() uint64 {
+	if m != nil {
+		return m.DurationNanos
+	}
+	return 0
+}
+
 type SearchTagsRequest struct {
 }
 
@@ -624,7 +704,7 @@ func (m *SearchTagsRequest) Reset()         { *m = SearchTagsRequest{} }
 func (m *SearchTagsRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchTagsRequest) ProtoMessage()    {}
 func (*SearchTagsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{8}
+	return fileDescriptor_f22805646f4f62b6, []int{9}
 }
 func (m *SearchTagsRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -661,7 +741,7 @@ func (m *SearchTagsResponse) Reset()         { *m = SearchTagsResponse{} }
 func (m *SearchTagsResponse) String() string { return proto.CompactTextString(m) }
 func (*SearchTagsResponse) ProtoMessage()    {}
 func (*SearchTagsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{9}
+	return fileDescriptor_f22805646f4f62b6, []int{10}
 }
 func (m *SearchTagsResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -705,7 +785,7 @@ func (m *SearchTagValuesRequest) Reset()         { *m = SearchTagValuesRequest{}
 func (m *SearchTagValuesRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchTagValuesRequest) ProtoMessage()    {}
 func (*SearchTagValuesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{10}
+	return fileDescriptor_f22805646f4f62b6, []int{11}
 }
 func (m *SearchTagValuesRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -749,7 +829,7 @@ func (m *SearchTagValuesResponse) Reset()         { *m = SearchTagValuesResponse
 func (m *SearchTagValuesResponse) String() string { return proto.CompactTextString(m) }
 func (*SearchTagValuesResponse) ProtoMessage()    {}
 func (*SearchTagValuesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{11}
+	return fileDescriptor_f22805646f4f62b6, []int{12}
 }
 func (m *SearchTagValuesResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -793,7 +873,7 @@ func (m *Trace) Reset()         { *m = Trace{} }
 func (m *Trace) String() string { return proto.CompactTextString(m) }
 func (*Trace) ProtoMessage()    {}
 func (*Trace) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{12}
+	return fileDescriptor_f22805646f4f62b6, []int{13}
 }
 func (m *Trace) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -837,7 +917,7 @@ func (m *PushResponse) Reset()         { *m = PushResponse{} }
 func (m *PushResponse) String() string { return proto.CompactTextString(m) }
 func (*PushResponse) ProtoMessage()    {}
 func (*PushResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{13}
+	return fileDescriptor_f22805646f4f62b6, []int{14}
 }
 func (m *PushResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -881,7 +961,7 @@ func (m *PushBytesRequest) Reset()         { *m = PushBytesRequest{} }
 func (m *PushBytesRequest) String() string { return proto.CompactTextString(m) }
 func (*PushBytesRequest) ProtoMessage()    {}
 func (*PushBytesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{14}
+	return fileDescriptor_f22805646f4f62b6, []int{15}
 }
 func (m *PushBytesRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -919,7 +999,7 @@ func (m *PushSpansRequest) Reset()         { *m = PushSpansRequest{} }
 func (m *PushSpansRequest) String() string { return proto.CompactTextString(m) }
 func (*PushSpansRequest) ProtoMessage()    {}
 func (*PushSpansRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{15}
+	return fileDescriptor_f22805646f4f62b6, []int{16}
 }
 func (m *PushSpansRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -964,7 +1044,7 @@ func (m *TraceBytes) Reset()         { *m = TraceBytes{} }
 func (m *TraceBytes) String() string { return proto.CompactTextString(m) }
 func (*TraceBytes) ProtoMessage()    {}
 func (*TraceBytes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{16}
+	return fileDescriptor_f22805646f4f62b6, []int{17}
 }
 func (m *TraceBytes) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1010,6 +1090,7 @@ func init() {
 	proto.RegisterType((*SearchResponse)(nil), "tempopb.SearchResponse")
 	proto.RegisterType((*TraceSearchMetadata)(nil), "tempopb.TraceSearchMetadata")
 	proto.RegisterType((*SearchMetrics)(nil), "tempopb.SearchMetrics")
+	proto.RegisterType((*SearchStageMetrics)(nil), "tempopb.SearchStageMetrics")
 	proto.RegisterType((*SearchTagsRequest)(nil), "tempopb.SearchTagsRequest")
 	proto.RegisterType((*SearchTagsResponse)(nil), "tempopb.SearchTagsResponse")
 	proto.RegisterType((*SearchTagValuesRequest)(nil), "tempopb.SearchTagValuesRequest")
@@ -1024,77 +1105,81 @@ func init() {
 func init() { proto.RegisterFile("pkg/tempopb/tempo.proto", fileDescriptor_f22805646f4f62b6) }
 
 var fileDescriptor_f22805646f4f62b6 = []byte{
-	// 1120 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x4b, 0x6f, 0xdb, 0xc6,
-	0x13, 0x37, 0x2d, 0xc9, 0xb2, 0x46, 0x92, 0x1f, 0x9b, 0xc4, 0xe6, 0x9f, 0x31, 0x64, 0x81, 0x30,
-	0xfe, 0xd5, 0xa1, 0x91, 0x13, 0x25, 0x6d, 0xda, 0x5c, 0x8a, 0x0a, 0x76, 0x53, 0x03, 0x55, 0xe0,
-	0x52, 0xae, 0xef, 0x2b, 0x72, 0x2d, 0x13, 0x96, 0xb8, 0x0c, 0xb9, 0x32, 0xec, 0x9e, 0xda, 0x4b,
-	0x4f, 0x45, 0xd1, 0xaf, 0xd0, 0x6f, 0x93, 0x4b, 0x81, 0x1c, 0x8b, 0x1e, 0x82, 0xc2, 0xfe, 0x1e,
-	0x45, 0xb1, 0x4f, 0x91, 0xf4, 0xe3, 0xd0, 0x9e, 0xc4, 0xf9, 0xcd, 0x6f, 0xe7, 0xb5, 0x33, 0xb3,
-	0x82, 0xcd, 0xf8, 0x6c, 0xbc, 0xcb, 0xc8, 0x34, 0xa6, 0xf1, 0x48, 0xfe, 0x76, 0xe3, 0x84, 0x32,
-	0x8a, 0xaa, 0x0a, 0x74, 0x1e, 0xb2, 0x04, 0xfb, 0x64, 0xf7, 0xfc, 0xd9, 0xae, 0xf8, 0x90, 0x6a,
-	0xe7, 0xc9, 0x38, 0x64, 0xa7, 0xb3, 0x51, 0xd7, 0xa7, 0xd3, 0xdd, 0x31, 0x1d, 0xd3, 0x5d, 0x01,
-	0x8f, 0x66, 0x27, 0x42, 0x12, 0x82, 0xf8, 0x92, 0x74, 0xf7, 0x27, 0x0b, 0xd6, 0x8e, 0xf8, 0xf1,
-	0xfe, 0xe5, 0xc1, 0x9e, 0x47, 0xde, 0xce, 0x48, 0xca, 0x90, 0x0d, 0x55, 0x61, 0xf2, 0x60, 0xcf,
-	0xb6, 0xda, 0x56, 0xa7, 0xe1, 0x69, 0x11, 0xb5, 0x00, 0x46, 0x13, 0xea, 0x9f, 0x0d, 0x19, 0x4e,
-	0x98, 0xbd, 0xd8, 0xb6, 0x3a, 0x35, 0x2f, 0x83, 0x20, 0x07, 0x96, 0x85, 0xb4, 0x1f, 0x05, 0x76,
-	0x49, 0x68, 0x8d, 0x8c, 0xb6, 0xa0, 0xf6, 0x76, 0x46, 0x92, 0xcb, 0x01, 0x0d, 0x88, 0x5d, 0x11,
-	0xca, 0x39, 0xe0, 0x46, 0xb0, 0x9e, 0x89, 0x23, 0x8d, 0x69, 0x94, 0x12, 0xb4, 0x03, 0x15, 0xe1,
-	0x59, 0x84, 0x51, 0xef, 0xad, 0x74, 0x55, 0xee, 0x5d, 0x41, 0xf5, 0xa4, 0x12, 0x3d, 0x87, 0xea,
-	0x94, 0xb0, 0x24, 0xf4, 0x53, 0x11, 0x51, 0xbd, 0xf7, 0xbf, 0x3c, 0x8f, 0x9b, 0x1c, 0x48, 0x82,
-	0xa7, 0x99, 0xee, 0xa7, 0x99, 0xbc, 0x95, 0x12, 0xb9, 0xd0, 0x38, 0xc1, 0xe1, 0x84, 0x04, 0x7d,
-	0x1e, 0x73, 0x2a, 0xbc, 0x36, 0xbd, 0x1c, 0xe6, 0xfe, 0xb2, 0x08, 0xcd, 0x21, 0xc1, 0x89, 0x7f,
-	0xaa, 0xab, 0xf5, 0x0a, 0xca, 0x47, 0x78, 0xcc, 0xd9, 0xa5, 0x4e, 0xbd, 0xd7, 0x36, 0xbe, 0x73,
-	0xac, 0x2e, 0xa7, 0xec, 0x47, 0x2c, 0xb9, 0xec, 0x97, 0xdf, 0x7d, 0xd8, 0x5e, 0xf0, 0xc4, 0x19,
-	0xb4, 0x03, 0xcd, 0x41, 0x18, 0xed, 0xcd, 0x12, 0xcc, 0x42, 0x1a, 0x0d, 0x64, 0x02, 0x4d, 0x2f,
-	0x0f, 0x0a, 0x16, 0xbe, 0xc8, 0xb0, 0x4a, 0x8a, 0x95, 0x05, 0xd1, 0x43, 0xa8, 0x7c, 0x13, 0x4e,
-	0x43, 0x66, 0x97, 0x85, 0x56, 0x0a, 0x1c, 0x4d, 0xc5, 0x65, 0x55, 0x24, 0x2a, 0x04, 0xb4, 0x06,
-	0x25, 0x12, 0x05, 0xf6, 0x92, 0xc0, 0xf8, 0xa7, 0xf3, 0x12, 0x6a, 0x26, 0x44, 0xae, 0x3e, 0x23,
-	0x97, 0x22, 0xff, 0x9a, 0xc7, 0x3f, 0xb9, 0x99, 0x73, 0x3c, 0x99, 0x11, 0x75, 0xe7, 0x52, 0x78,
-	0xb5, 0xf8, 0x99, 0xe5, 0xfe, 0x50, 0x02, 0x24, 0x53, 0x15, 0x15, 0xd2, 0x55, 0x79, 0x01, 0xb5,
-	0x54, 0x17, 0x40, 0x5d, 0xdf, 0xc6, 0xed, 0xa5, 0xf1, 0xe6, 0x44, 0xde, 0x79, 0xa2, 0x5f, 0x0e,
-	0xf6, 0x94, 0x23, 0x2d, 0xf2, 0xee, 0x11, 0xa1, 0x1f, 0xe2, 0x31, 0x51, 0xf9, 0xcf, 0x01, 0x5e,
-	0xa1, 0x18, 0x8f, 0x49, 0x7a, 0x44, 0xa5, 0x69, 0x55, 0x83, 0x3c, 0xc8, 0xbb, 0x93, 0x44, 0x3e,
-	0x0d, 0xc2, 0x68, 0xac, 0x1a, 0xd0, 0xc8, 0xdc, 0x42, 0x18, 0x05, 0xe4, 0x82, 0x9b, 0x1b, 0x86,
-	0xdf, 0x13, 0x55, 0x9b, 0x3c, 0xc8, 0x3b, 0x84, 0x51, 0x86, 0x27, 0x1e, 0xf1, 0x69, 0x12, 0xa4,
-	0x76, 0x55, 0x76, 0x48, 0x16, 0xe3, 0x9c, 0x00, 0x33, 0xbc, 0xaf, 0x3d, 0x2d, 0x0b, 0x4f, 0x39,
-	0x8c, 0xe7, 0x79, 0x4e, 0x92, 0x34, 0xa4, 0x91, 0x5d, 0x93, 0x79, 0x2a, 0x11, 0x21, 0x28, 0xa7,
-	0xdc, 0x3d, 0xb4, 0xad, 0x4e, 0xd9, 0x13, 0xdf, 0x7c, 0xea, 0x4e, 0x28, 0x65, 0x24, 0x11, 0x81,
-	0xd5, 0x85, 0xcf, 0x0c, 0xe2, 0x5e, 0xc0, 0x8a, 0xae, 0xa8, 0x1a, 0x9c, 0x17, 0xb0, 0x24, 0x66,
-	0x43, 0x77, 0xe5, 0x56, 0x7e, 0x22, 0x24, 0x7b, 0x40, 0x18, 0xe6, 0x51, 0x79, 0x8a, 0x8b, 0x9e,
-	0x16, 0x07, 0xa9, 0x78, 0x63, 0x37, 0xa6, 0xe8, 0x77, 0x0b, 0x1e, 0xdc, 0x62, 0xb1, 0xb8, 0x41,
-	0x6a, 0xf3, 0x0d, 0xd2, 0x81, 0xd5, 0x84, 0x52, 0x36, 0x24, 0xc9, 0x79, 0xe8, 0x93, 0x37, 0x78,
-	0xaa, 0x5b, 0xaa, 0x08, 0xf3, 0x1b, 0xe1, 0x90, 0x30, 0x2f, 0x78, 0x72, 0xa1, 0xe4, 0x41, 0xf4,
-	0x31, 0xac, 0x8b, 0x36, 0x38, 0x0a, 0xa7, 0xe4, 0xbb, 0x28, 0xbc, 0x78, 0x83, 0x23, 0x2a, 0x6e,
-	0xbf, 0xec, 0xdd, 0x54, 0xf0, 0x4a, 0x06, 0xf3, 0x31, 0x92, 0x23, 0x91, 0x41, 0xdc, 0x1f, 0xcd,
-	0x74, 0xeb, 0x9d, 0xd0, 0x81, 0xd5, 0x30, 0x4a, 0x63, 0xe2, 0x33, 0x12, 0x1c, 0xe9, 0x92, 0xf2,
-	0x63, 0x45, 0x18, 0xfd, 0x1f, 0x56, 0x0c, 0xd4, 0xbf, 0x64, 0x44, 0x16, 0xb1, 0xec, 0x15, 0xd0,
-	0x9c, 0x45, 0xb5, 0x68, 0x4a, 0x05, 0x8b, 0x12, 0xe6, 0x15, 0x48, 0xcf, 0xc2, 0x38, 0x36, 0x3c,
-	0xd5, 0xd5, 0x39, 0x30, 0xc3, 0x52, 0xf1, 0x55, 0x72, 0x2c, 0x15, 0x5d, 0x07, 0x56, 0x45, 0x97,
-	0x8a, 0x43, 0x32, 0xbc, 0x25, 0x11, 0x5e, 0x11, 0x76, 0x1f, 0xc0, 0xba, 0x2c, 0x01, 0xdf, 0x07,
-	0x6a, 0x46, 0xdd, 0xa7, 0x7a, 0xc8, 0x25, 0xa8, 0xda, 0xcc, 0x81, 0x65, 0x86, 0xc7, 0xfc, 0x1e,
-	0x64, 0xa3, 0xd5, 0x3c, 0x23, 0xbb, 0x3d, 0xd8, 0x30, 0x27, 0x8e, 0xf9, 0xb6, 0x48, 0xb3, 0xcf,
-	0x8b, 0x64, 0x99, 0xe6, 0x90, 0xa2, 0xfb, 0x12, 0x36, 0x6f, 0x9c, 0x51, 0xae, 0xb6, 0xa0, 0xc6,
-	0x34, 0xa8, 0x7c, 0xcd, 0x01, 0xb7, 0x0f, 0x15, 0x91, 0x27, 0xfa, 0x1c, 0xaa, 0x23, 0xcc, 0xfc,
-	0x53, 0xd3, 0xf9, 0xdb, 0xa6, 0x85, 0xe5, 0x2b, 0x79, 0xfe, 0xac, 0xeb, 0x91, 0x94, 0xce, 0x12,
-	0x9f, 0x0c, 0x63, 0x1c, 0xa5, 0x9e, 0xe6, 0xbb, 0x2b, 0xd0, 0x38, 0x9c, 0xa5, 0x66, 0x86, 0xdc,
-	0xdf, 0x2c, 0x58, 0xe3, 0x80, 0xa8, 0x8a, 0x8e, 0xfd, 0x89, 0x19, 0xac, 0xc5, 0x76, 0xa9, 0xd3,
-	0xe8, 0x3f, 0xe2, 0xcb, 0xfc, 0xcf, 0x0f, 0xdb, 0xcd, 0xc3, 0x84, 0xe0, 0xc9, 0x84, 0xfa, 0x92,
-	0xad, 0x27, 0xea, 0x23, 0x28, 0x85, 0x01, 0xbf, 0xdf, 0x7b, 0xb8, 0x9c, 0x81, 0x3e, 0x01, 0x90,
-	0x5b, 0x70, 0x0f, 0x33, 0x6c, 0x97, 0xef, 0xe3, 0x67, 0x88, 0xee, 0x40, 0x86, 0x28, 0x33, 0x51,
-	0x21, 0xfe, 0x87, 0x12, 0xec, 0x00, 0xa8, 0x47, 0x91, 0x37, 0xea, 0x46, 0x6e, 0x89, 0x34, 0x74,
-	0x52, 0xbd, 0x9f, 0x2d, 0x58, 0xe2, 0x5e, 0x49, 0x82, 0xbe, 0x80, 0x9a, 0x29, 0x11, 0x9a, 0x3f,
-	0xbb, 0xc5, 0xb2, 0x39, 0x8f, 0x72, 0x2a, 0x53, 0xe2, 0x05, 0xf4, 0x25, 0xd4, 0x0d, 0xf9, 0xb8,
-	0xf7, 0x6f, 0x4c, 0xf4, 0x86, 0xb0, 0xa6, 0x86, 0xf5, 0x35, 0x89, 0x48, 0x82, 0x19, 0x35, 0x71,
-	0x89, 0xf4, 0x0a, 0x46, 0xb3, 0xb5, 0xba, 0xdb, 0xe8, 0xdf, 0x8b, 0x50, 0xfd, 0x76, 0x46, 0x92,
-	0x90, 0x24, 0xe8, 0x6b, 0x68, 0x7e, 0x15, 0x46, 0x81, 0xf9, 0xbb, 0x80, 0x6e, 0xf9, 0x7f, 0xa1,
-	0x0d, 0x3a, 0xb7, 0xa9, 0x32, 0xd9, 0x36, 0xf4, 0xa2, 0xf6, 0x49, 0xc4, 0xd0, 0x1d, 0x2f, 0xa2,
-	0xb3, 0x79, 0x03, 0x37, 0x26, 0xf6, 0xa1, 0x9e, 0x79, 0x6d, 0xd1, 0xe3, 0x02, 0x33, 0xfb, 0x06,
-	0xdf, 0x67, 0xe6, 0x35, 0xc0, 0x7c, 0x9e, 0x91, 0x53, 0x20, 0x66, 0x26, 0xdf, 0x79, 0x7c, 0xab,
-	0xce, 0x18, 0x3a, 0x86, 0xd5, 0xc2, 0xc8, 0xa2, 0xed, 0x9b, 0x27, 0x72, 0x0b, 0xc0, 0x69, 0xdf,
-	0x4d, 0xd0, 0x76, 0xfb, 0xf6, 0xbb, 0xab, 0x96, 0xf5, 0xfe, 0xaa, 0x65, 0xfd, 0x75, 0xd5, 0xb2,
-	0x7e, 0xbd, 0x6e, 0x2d, 0xbc, 0xbf, 0x6e, 0x2d, 0xfc, 0x71, 0xdd, 0x5a, 0x18, 0x2d, 0x89, 0x7f,
-	0xae, 0xcf, 0xff, 0x09, 0x00, 0x00, 0xff, 0xff, 0xd9, 0xe5, 0xf4, 0x21, 0x22, 0x0b, 0x00, 0x00,
+	// 1184 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0xcf, 0x6e, 0xdb, 0xc6,
+	0x13, 0x36, 0xad, 0x7f, 0xd1, 0x48, 0xf2, 0x9f, 0x4d, 0x62, 0xf3, 0xa7, 0x18, 0xb2, 0x40, 0x18,
+	0xbf, 0xea, 0xd0, 0xc8, 0x89, 0x9c, 0x22, 0x6d, 0x2e, 0x45, 0x05, 0xbb, 0xa9, 0x81, 0x2a, 0x70,
+	0x29, 0xd7, 0x87, 0xde, 0x28, 0x72, 0x2d, 0x13, 0x96, 0xb8, 0x0c, 0xb9, 0x32, 0xec, 0x3e, 0x40,
+	0x4f, 0x45, 0xd1, 0x43, 0x5f, 0xa0, 0x2f, 0xd1, 0x67, 0xc8, 0xa5, 0x40, 0x8e, 0x45, 0x0f, 0x41,
+	0x61, 0xbf, 0x47, 0x51, 0xec, 0xec, 0x2e, 0x45, 0x52, 0xb6, 0x0f, 0xed, 0x89, 0x9c, 0x6f, 0xbe,
+	0x9d, 0x9d, 0x1d, 0x7e, 0x33, 0x4b, 0xd8, 0x0c, 0xcf, 0xc7, 0xbb, 0x9c, 0x4e, 0x43, 0x16, 0x8e,
+	0xe4, 0xb3, 0x1b, 0x46, 0x8c, 0x33, 0x52, 0x51, 0x60, 0xf3, 0x11, 0x8f, 0x1c, 0x97, 0xee, 0x5e,
+	0x3c, 0xdf, 0xc5, 0x17, 0xe9, 0x6e, 0x3e, 0x1d, 0xfb, 0xfc, 0x6c, 0x36, 0xea, 0xba, 0x6c, 0xba,
+	0x3b, 0x66, 0x63, 0xb6, 0x8b, 0xf0, 0x68, 0x76, 0x8a, 0x16, 0x1a, 0xf8, 0x26, 0xe9, 0xd6, 0x0f,
+	0x06, 0xac, 0x1d, 0x8b, 0xe5, 0xfd, 0xab, 0xc3, 0x7d, 0x9b, 0xbe, 0x9d, 0xd1, 0x98, 0x13, 0x13,
+	0x2a, 0x18, 0xf2, 0x70, 0xdf, 0x34, 0xda, 0x46, 0xa7, 0x6e, 0x6b, 0x93, 0xb4, 0x00, 0x46, 0x13,
+	0xe6, 0x9e, 0x0f, 0xb9, 0x13, 0x71, 0x73, 0xb9, 0x6d, 0x74, 0xaa, 0x76, 0x0a, 0x21, 0x4d, 0x78,
+	0x80, 0xd6, 0x41, 0xe0, 0x99, 0x05, 0xf4, 0x26, 0x36, 0xd9, 0x82, 0xea, 0xdb, 0x19, 0x8d, 0xae,
+	0x06, 0xcc, 0xa3, 0x66, 0x09, 0x9d, 0x73, 0xc0, 0x0a, 0x60, 0x3d, 0x95, 0x47, 0x1c, 0xb2, 0x20,
+	0xa6, 0x64, 0x07, 0x4a, 0xb8, 0x33, 0xa6, 0x51, 0xeb, 0xad, 0x74, 0xd5, 0xd9, 0xbb, 0x48, 0xb5,
+	0xa5, 0x93, 0xec, 0x41, 0x65, 0x4a, 0x79, 0xe4, 0xbb, 0x31, 0x66, 0x54, 0xeb, 0xfd, 0x2f, 0xcb,
+	0x13, 0x21, 0x07, 0x92, 0x60, 0x6b, 0xa6, 0xf5, 0x1d, 0xac, 0xe5, 0x9d, 0xc4, 0x82, 0xfa, 0xa9,
+	0xe3, 0x4f, 0xa8, 0xd7, 0x17, 0x39, 0xc7, 0xb8, 0x6b, 0xc3, 0xce, 0x60, 0x82, 0xe3, 0x45, 0x2c,
+	0x0c, 0xa9, 0x37, 0x0c, 0x9d, 0x40, 0xee, 0xd8, 0xb0, 0x33, 0x98, 0xf5, 0xd3, 0x32, 0x34, 0x86,
+	0xd4, 0x89, 0xdc, 0x33, 0x5d, 0xd1, 0x57, 0x50, 0x3c, 0x76, 0xc6, 0x22, 0x62, 0xa1, 0x53, 0xeb,
+	0xb5, 0x93, 0xfc, 0x32, 0xac, 0xae, 0xa0, 0x1c, 0x04, 0x3c, 0xba, 0xea, 0x17, 0xdf, 0x7d, 0xd8,
+	0x5e, 0xb2, 0x71, 0x0d, 0xd9, 0x81, 0xc6, 0xc0, 0x0f, 0xf6, 0x67, 0x91, 0xc3, 0x7d, 0x16, 0x0c,
+	0xf4, 0x96, 0x59, 0x10, 0x59, 0xce, 0x65, 0x8a, 0x55, 0x50, 0xac, 0x34, 0x48, 0x1e, 0x41, 0xe9,
+	0x6b, 0x7f, 0xea, 0x73, 0xb3, 0x88, 0x5e, 0x69, 0x08, 0x34, 0xc6, 0x0f, 0x5a, 0x92, 0x28, 0x1a,
+	0x64, 0x0d, 0x0a, 0x34, 0xf0, 0xcc, 0x32, 0x62, 0xe2, 0xb5, 0xf9, 0x12, 0xaa, 0x49, 0x8a, 0xc2,
+	0x7d, 0x4e, 0xaf, 0xb0, 0x46, 0x55, 0x5b, 0xbc, 0x8a, 0x30, 0x17, 0xce, 0x64, 0x46, 0x95, 0x2e,
+	0xa4, 0xf1, 0x6a, 0xf9, 0x53, 0xc3, 0xfa, 0xa5, 0x00, 0x44, 0x1e, 0x15, 0xab, 0xa8, 0xab, 0xf2,
+	0x02, 0xaa, 0xb1, 0x2e, 0x80, 0xfa, 0xc4, 0x1b, 0xb7, 0x97, 0xc6, 0x9e, 0x13, 0x85, 0x3a, 0x51,
+	0x53, 0x87, 0xfb, 0x6a, 0x23, 0x6d, 0x0a, 0x85, 0x61, 0xea, 0x47, 0xce, 0x98, 0xaa, 0xf3, 0xcf,
+	0x01, 0x51, 0xa1, 0xd0, 0x19, 0xd3, 0xf8, 0x98, 0xc9, 0xd0, 0xaa, 0x06, 0x59, 0x50, 0x28, 0x98,
+	0x06, 0x2e, 0xf3, 0xfc, 0x60, 0xac, 0x44, 0x9a, 0xd8, 0x22, 0x82, 0x1f, 0x78, 0xf4, 0x52, 0x84,
+	0x1b, 0xfa, 0xdf, 0x53, 0x55, 0x9b, 0x2c, 0x28, 0x14, 0xc2, 0x19, 0x77, 0x26, 0x36, 0x75, 0x59,
+	0xe4, 0xc5, 0x66, 0x45, 0x2a, 0x24, 0x8d, 0xa1, 0x8a, 0x1c, 0xee, 0x1c, 0xe8, 0x9d, 0x1e, 0xe0,
+	0x4e, 0x19, 0x4c, 0x9c, 0xf3, 0x82, 0x46, 0xb1, 0xcf, 0x02, 0xb3, 0x2a, 0xcf, 0xa9, 0x4c, 0x42,
+	0xa0, 0x18, 0x8b, 0xed, 0xa1, 0x6d, 0x74, 0x8a, 0x36, 0xbe, 0x8b, 0xce, 0x3c, 0x65, 0x8c, 0xd3,
+	0x08, 0x13, 0xab, 0xe1, 0x9e, 0x29, 0x44, 0xac, 0xe1, 0x3e, 0x8d, 0xcc, 0x3a, 0x86, 0xc2, 0x77,
+	0xeb, 0x12, 0x56, 0x74, 0x95, 0x55, 0xc3, 0xbd, 0x80, 0x32, 0xf6, 0x94, 0x56, 0xea, 0x56, 0xb6,
+	0x93, 0x24, 0x7b, 0x40, 0xb9, 0x23, 0x32, 0xb5, 0x15, 0x97, 0x3c, 0xcb, 0x37, 0x60, 0xfe, 0x2b,
+	0x2e, 0x74, 0xdf, 0xef, 0x06, 0x3c, 0xbc, 0x25, 0x62, 0x7e, 0xf2, 0x54, 0xe7, 0x93, 0xa7, 0x03,
+	0xab, 0x11, 0x63, 0x7c, 0x48, 0xa3, 0x0b, 0xdf, 0xa5, 0x6f, 0x9c, 0xa9, 0x96, 0x59, 0x1e, 0x16,
+	0x5f, 0x49, 0x40, 0x18, 0x1e, 0x79, 0x72, 0x10, 0x65, 0x41, 0xf2, 0x31, 0xac, 0xa3, 0x34, 0x8e,
+	0xfd, 0x29, 0xfd, 0x36, 0xf0, 0x2f, 0xdf, 0x38, 0x01, 0x43, 0x45, 0x14, 0xed, 0x45, 0x87, 0xa8,
+	0xae, 0x37, 0x6f, 0x2d, 0xd9, 0x26, 0x29, 0xc4, 0xfa, 0x2d, 0xe9, 0x78, 0x3d, 0x4b, 0x3a, 0xb0,
+	0xea, 0x07, 0x71, 0x48, 0x5d, 0x4e, 0xbd, 0x63, 0x5d, 0x52, 0xb1, 0x2c, 0x0f, 0x93, 0xff, 0xc3,
+	0x4a, 0x02, 0xf5, 0xaf, 0x38, 0x95, 0x45, 0x2c, 0xda, 0x39, 0x34, 0x13, 0x51, 0x0d, 0xa8, 0x42,
+	0x2e, 0xa2, 0x84, 0x45, 0x05, 0xe2, 0x73, 0x3f, 0x0c, 0x35, 0xa0, 0x95, 0x9e, 0x01, 0x53, 0x2c,
+	0x95, 0x5f, 0x29, 0xc3, 0x52, 0xd9, 0x75, 0x60, 0x15, 0x95, 0x8b, 0x8b, 0x64, 0x7a, 0x65, 0x4c,
+	0x2f, 0x0f, 0x93, 0x3d, 0x28, 0xc7, 0x5c, 0xf4, 0x92, 0x59, 0x41, 0xed, 0x3c, 0xc9, 0x89, 0x60,
+	0x28, 0x9c, 0x5a, 0x09, 0x8a, 0x6a, 0x1d, 0x01, 0x59, 0xf4, 0xaa, 0x81, 0x34, 0xa6, 0x4a, 0x04,
+	0xd2, 0x10, 0x09, 0xeb, 0x92, 0x8b, 0x8f, 0xa2, 0xeb, 0x94, 0x05, 0xad, 0x87, 0xb0, 0x2e, 0x23,
+	0x8a, 0x51, 0xa5, 0xc6, 0x87, 0xf5, 0x0c, 0x48, 0x1a, 0x54, 0x6a, 0x6f, 0xc2, 0x03, 0xee, 0x8c,
+	0x85, 0x1c, 0xa4, 0xde, 0xab, 0x76, 0x62, 0x5b, 0x3d, 0xd8, 0x48, 0x56, 0x9c, 0x88, 0x41, 0x16,
+	0xa7, 0x6f, 0x47, 0xc9, 0x4a, 0x34, 0x2a, 0x4d, 0xeb, 0x25, 0x6c, 0x2e, 0xac, 0x51, 0x5b, 0x6d,
+	0x41, 0x95, 0x6b, 0x50, 0xed, 0x35, 0x07, 0xac, 0x3e, 0x94, 0xb0, 0xdc, 0xe4, 0x33, 0xa8, 0x8c,
+	0x1c, 0xee, 0x9e, 0x25, 0x0d, 0xb8, 0x9d, 0x14, 0x51, 0x5e, 0xf2, 0x17, 0xcf, 0xbb, 0x36, 0x8d,
+	0xd9, 0x2c, 0x72, 0x29, 0xde, 0x35, 0xb6, 0xe6, 0x5b, 0x2b, 0x50, 0x3f, 0x9a, 0xc5, 0x49, 0x2b,
+	0x5b, 0xbf, 0x1a, 0xb0, 0x26, 0x00, 0xfc, 0x38, 0x3a, 0xf7, 0xa7, 0x49, 0x7f, 0x2f, 0xb7, 0x0b,
+	0x9d, 0x7a, 0xff, 0xb1, 0xb8, 0x67, 0xfe, 0xfc, 0xb0, 0xdd, 0x38, 0x8a, 0xa8, 0x33, 0x99, 0x30,
+	0x57, 0xb2, 0x75, 0x63, 0x7f, 0x04, 0x05, 0xdf, 0x13, 0x32, 0xbb, 0x87, 0x2b, 0x18, 0xe4, 0x13,
+	0x00, 0x39, 0xa0, 0xf7, 0x1d, 0xee, 0x98, 0xc5, 0xfb, 0xf8, 0x29, 0xa2, 0x35, 0x90, 0x29, 0xca,
+	0x93, 0xa8, 0x14, 0xff, 0x43, 0x09, 0x76, 0x00, 0xd4, 0x9d, 0x2e, 0xf4, 0xb8, 0x91, 0x99, 0x65,
+	0x75, 0x7d, 0xa8, 0xde, 0x8f, 0x06, 0x94, 0xc5, 0xae, 0x34, 0x22, 0x9f, 0x43, 0x35, 0x29, 0x11,
+	0x99, 0xff, 0x35, 0xe4, 0xcb, 0xd6, 0x7c, 0x9c, 0x71, 0x25, 0x25, 0x5e, 0x22, 0x5f, 0x40, 0x2d,
+	0x21, 0x9f, 0xf4, 0xfe, 0x4d, 0x88, 0xde, 0x10, 0xd6, 0x94, 0xec, 0x5f, 0xd3, 0x80, 0x46, 0x0e,
+	0x67, 0x49, 0x5e, 0x78, 0xbc, 0x5c, 0xd0, 0x74, 0xad, 0xee, 0x0e, 0xfa, 0xf7, 0x32, 0x54, 0xbe,
+	0x99, 0xd1, 0xc8, 0xa7, 0x11, 0xf9, 0x0a, 0x1a, 0x5f, 0xfa, 0x81, 0x97, 0xfc, 0xed, 0x90, 0x5b,
+	0x7e, 0x8f, 0x74, 0xc0, 0xe6, 0x6d, 0xae, 0xd4, 0x69, 0xeb, 0xfa, 0xbe, 0x70, 0x69, 0xc0, 0xc9,
+	0x1d, 0x97, 0x75, 0x73, 0x73, 0x01, 0x4f, 0x42, 0x1c, 0x40, 0x2d, 0xf5, 0x23, 0x40, 0xf2, 0x33,
+	0x22, 0xfd, 0x7b, 0x70, 0x5f, 0x98, 0xd7, 0x00, 0xf3, 0x7e, 0x26, 0xcd, 0x1c, 0x31, 0xd5, 0xf9,
+	0xcd, 0x27, 0xb7, 0xfa, 0x92, 0x40, 0x27, 0xb0, 0x9a, 0x6b, 0x59, 0xb2, 0xbd, 0xb8, 0x22, 0x33,
+	0x00, 0x9a, 0xed, 0xbb, 0x09, 0x3a, 0x6e, 0xdf, 0x7c, 0x77, 0xdd, 0x32, 0xde, 0x5f, 0xb7, 0x8c,
+	0xbf, 0xae, 0x5b, 0xc6, 0xcf, 0x37, 0xad, 0xa5, 0xf7, 0x37, 0xad, 0xa5, 0x3f, 0x6e, 0x5a, 0x4b,
+	0xa3, 0x32, 0xfe, 0x78, 0xef, 0xfd, 0x33, 0x00, 0x31, 0x62, 0x61, 0x0a, 0xe1, 0x0b, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1621,6 +1706,11 @@ func (m *TraceByIDMetrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.DroppedSpans != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.DroppedSpans))
+		i--
+		dAtA[i] = 0x10
+	}
 	if m.FailedBlocks != 0 {
 		i = encodeVarintTempo(dAtA, i, uint64(m.FailedBlocks))
 		i--
@@ -1716,6 +1806,13 @@ func (m *SearchBlockRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Tier) > 0 {
+		i -= len(m.Tier)
+		copy(dAtA[i:], m.Tier)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.Tier)))
+		i--
+		dAtA[i] = 0x62
+	}
 	if m.FooterSize != 0 {
 		i = encodeVarintTempo(dAtA, i, uint64(m.FooterSize))
 		i--
@@ -1912,6 +2009,20 @@ func (m *SearchMetrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Stages) > 0 {
+		for iNdEx := len(m.Stages) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Stages[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTempo(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x3a
+		}
+	}
 	if m.TotalBlockBytes != 0 {
 		i = encodeVarintTempo(dAtA, i, uint64(m.TotalBlockBytes))
 		i--
@@ -1945,6 +2056,41 @@ func (m *SearchMetrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *SearchStageMetrics) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SearchStageMetrics) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SearchStageMetrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.DurationNanos != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.DurationNanos))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Stage) > 0 {
+		i -= len(m.Stage)
+		copy(dAtA[i:], m.Stage)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.Stage)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *SearchTagsRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -2318,6 +2464,9 @@ func (m *TraceByIDMetrics) Size() (n int) {
 	if m.FailedBlocks != 0 {
 		n += 1 + sovTempo(uint64(m.FailedBlocks))
 	}
+	if m.DroppedSpans != 0 {
+		n += 1 + sovTempo(uint64(m.DroppedSpans))
+	}
 	return n
 }
 
@@ -2397,6 +2546,10 @@ func (m *SearchBlockRequest) Size() (n int) {
 	if m.FooterSize != 0 {
 		n += 1 + sovTempo(uint64(m.FooterSize))
 	}
+	l = len(m.Tier)
+	if l > 0 {
+		n += 1 + l + sovTempo(uint64(l))
+	}
 	return n
 }
 
@@ -2470,6 +2623,28 @@ func (m *SearchMetrics) Size() (n int) {
 	if m.TotalBlockBytes != 0 {
 		n += 1 + sovTempo(uint64(m.TotalBlockBytes))
 	}
+	if len(m.Stages) > 0 {
+		for _, e := range m.Stages {
+			l = e.Size()
+			n += 1 + l + sovTempo(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *SearchStageMetrics) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Stage)
+	if l > 0 {
+		n += 1 + l + sovTempo(uint64(l))
+	}
+	if m.DurationNanos != 0 {
+		n += 1 + sovTempo(uint64(m.DurationNanos))
+	}
 	return n
 }
 
@@ -2962,6 +3137,25 @@ func (m *TraceByIDMetrics) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DroppedSpans", wireType)
+			}
+			m.DroppedSpans = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DroppedSpans |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -3562,6 +3756,38 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tier", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Tier = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -4030,6 +4256,141 @@ func (m *SearchMetrics) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Stages", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Stages = append(m.Stages, &SearchStageMetrics{})
+			if err := m.Stages[len(m.Stages)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SearchStageMetrics) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchStageMetrics: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchStageMetrics: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Stage", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Stage = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DurationNanos", wireType)
+			}
+			m.DurationNanos = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DurationNanos |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
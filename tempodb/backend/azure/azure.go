@@ -263,7 +263,7 @@ func (rw *readerWriter) readRange(ctx context.Context, name string, offset int64
 			Parallelism: maxParallelism,
 			Progress:    nil,
 			RetryReaderOptionsPerBlock: blob.RetryReaderOptions{
-				MaxRetryRequests: maxRetries,
+				MaxRetryRequests: rw.readRetries(),
 			},
 		},
 	); err != nil {
@@ -295,7 +295,7 @@ func (rw *readerWriter) readAll(ctx context.Context, name string) ([]byte, error
 			Parallelism: uint16(maxParallelism),
 			Progress:    nil,
 			RetryReaderOptionsPerBlock: blob.RetryReaderOptions{
-				MaxRetryRequests: maxRetries,
+				MaxRetryRequests: rw.readRetries(),
 			},
 		},
 	); err != nil {
@@ -317,3 +317,11 @@ func readError(err error) error {
 	}
 	return errors.Wrap(storageError, "reading Azure blob container")
 }
+
+// readRetries returns the number of times the reads of a block of a blob are retried
+func (rw *readerWriter) readRetries() int {
+	if rw.cfg.DisableRetries {
+		return 0
+	}
+	return maxRetries
+}
@@ -158,3 +158,12 @@ func (rw *readerWriter) delete(ctx context.Context, name string) error {
 	}
 	return nil
 }
+
+func (rw *readerWriter) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return fmt.Errorf("empty tenant id")
+	}
+
+	name := backend.ObjectFileName(backend.KeyPathForDeletionRequest(requestID, tenantID), backend.DeletionRequestName)
+	return rw.delete(context.TODO(), name)
+}
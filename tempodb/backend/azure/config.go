@@ -17,4 +17,6 @@ type Config struct {
 	BufferSize         int            `yaml:"buffer-size"`
 	HedgeRequestsAt    time.Duration  `yaml:"hedge-requests-at"`
 	HedgeRequestsUpTo  int            `yaml:"hedge-requests-up-to"`
+	// DisableRetries disables the retries of the client. It is set if the requests are retried by tempodb.
+	DisableRetries bool `yaml:"-"`
 }
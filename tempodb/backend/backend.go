@@ -29,8 +29,10 @@ type Writer interface {
 	Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker AppendTracker, buffer []byte) (AppendTracker, error)
 	// Closes any resources associated with the AppendTracker
 	CloseAppend(ctx context.Context, tracker AppendTracker) error
-	// WriteTenantIndex writes the two meta slices as a tenant index
-	WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta) error
+	// WriteTenantIndex writes the two meta slices and the deletions as a tenant index
+	WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta, deletions *TenantDeletions) error
+	// WriteDeletionRequest writes a new or updated deletion request
+	WriteDeletionRequest(ctx context.Context, req *DeletionRequest) error
 }
 
 // Reader is a collection of methods to read data from tempodb backends
@@ -49,6 +51,10 @@ type Reader interface {
 	BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*BlockMeta, error)
 	// TenantIndex returns lists of all metas given a tenant
 	TenantIndex(ctx context.Context, tenantID string) (*TenantIndex, error)
+	// DeletionRequestIDs returns a list of deletion request UUIDs given a tenant
+	DeletionRequestIDs(ctx context.Context, tenantID string) ([]uuid.UUID, error)
+	// DeletionRequest returns the deletion request given a request and tenant id
+	DeletionRequest(ctx context.Context, requestID uuid.UUID, tenantID string) (*DeletionRequest, error)
 	// Shutdown shuts...down?
 	Shutdown()
 }
@@ -61,4 +67,6 @@ type Compactor interface {
 	ClearBlock(blockID uuid.UUID, tenantID string) error
 	// CompactedBlockMeta returns the compacted blockmeta given a block and tenant id
 	CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*CompactedBlockMeta, error)
+	// ClearDeletionRequest removes a deletion request from the backend
+	ClearDeletionRequest(requestID uuid.UUID, tenantID string) error
 }
@@ -7,6 +7,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// TierCold is the storage tier old blocks are migrated to by the compactor
+const TierCold = "cold"
+
 type CompactedBlockMeta struct {
 	BlockMeta
 
@@ -14,22 +17,30 @@ type CompactedBlockMeta struct {
 }
 
 type BlockMeta struct {
-	Version         string    `json:"format"`          // Version indicates the block format version. This includes specifics of how the indexes and data is stored
-	BlockID         uuid.UUID `json:"blockID"`         // Unique block id
-	MinID           []byte    `json:"minID"`           // Minimum object id stored in this block
-	MaxID           []byte    `json:"maxID"`           // Maximum object id stored in this block
-	TenantID        string    `json:"tenantID"`        // ID of tehant to which this block belongs
-	StartTime       time.Time `json:"startTime"`       // Roughly matches when the first obj was written to this block. Used to determine block age for different purposes (cacheing, etc)
-	EndTime         time.Time `json:"endTime"`         // Currently mostly meaningless but roughly matches to the time the last obj was written to this block
-	TotalObjects    int       `json:"totalObjects"`    // Total objects in this block
-	Size            uint64    `json:"size"`            // Total size in bytes of the data object
-	CompactionLevel uint8     `json:"compactionLevel"` // Kind of the number of times this block has been compacted
-	Encoding        Encoding  `json:"encoding"`        // Encoding/compression format
-	IndexPageSize   uint32    `json:"indexPageSize"`   // Size of each index page in bytes
-	TotalRecords    uint32    `json:"totalRecords"`    // Total Records stored in the index file
-	DataEncoding    string    `json:"dataEncoding"`    // DataEncoding is a string provided externally, but tracked by tempodb that indicates the way the bytes are encoded
-	BloomShardCount uint16    `json:"bloomShards"`     // Number of bloom filter shards
-	FooterSize      uint32    `json:"footerSize"`      // Size of data file footer (parquet)
+	Version         string    `json:"format"`                    // Version indicates the block format version. This includes specifics of how the indexes and data is stored
+	BlockID         uuid.UUID `json:"blockID"`                   // Unique block id
+	MinID           []byte    `json:"minID"`                     // Minimum object id stored in this block
+	MaxID           []byte    `json:"maxID"`                     // Maximum object id stored in this block
+	TenantID        string    `json:"tenantID"`                  // ID of tehant to which this block belongs
+	StartTime       time.Time `json:"startTime"`                 // Roughly matches when the first obj was written to this block. Used to determine block age for different purposes (cacheing, etc)
+	EndTime         time.Time `json:"endTime"`                   // Currently mostly meaningless but roughly matches to the time the last obj was written to this block
+	TotalObjects    int       `json:"totalObjects"`              // Total objects in this block
+	Size            uint64    `json:"size"`                      // Total size in bytes of the data object
+	CompactionLevel uint8     `json:"compactionLevel"`           // Kind of the number of times this block has been compacted
+	Encoding        Encoding  `json:"encoding"`                  // Encoding/compression format
+	IndexPageSize   uint32    `json:"indexPageSize"`             // Size of each index page in bytes
+	TotalRecords    uint32    `json:"totalRecords"`              // Total Records stored in the index file
+	DataEncoding    string    `json:"dataEncoding"`              // DataEncoding is a string provided externally, but tracked by tempodb that indicates the way the bytes are encoded
+	BloomShardCount uint16    `json:"bloomShards"`               // Number of bloom filter shards
+	FooterSize      uint32    `json:"footerSize"`                // Size of data file footer (parquet)
+	EncryptionKeyID string    `json:"encryptionKeyID,omitempty"` // ID of the tenant key the objects of this block are encrypted with
+	Tier            string    `json:"tier,omitempty"`            // Storage tier the block is stored in. Empty for the hot tier
+
+	Checksums      map[string]uint32 `json:"checksums,omitempty"`      // CRC-32C checksums of the objects of this block as stored in the backend, by object name
+	FooterChecksum uint32            `json:"footerChecksum,omitempty"` // CRC-32C checksum of the data file footer (parquet)
+	Quarantined    string            `json:"quarantined,omitempty"`    // Reason the scrubber quarantined this block. Quarantined blocks are compacted, but not cleared by retention
+
+	Summary *BlockSummary `json:"summary,omitempty"` // Summary of the traces of this block, used to skip the block in searches. Nil if the block is not summarized
 }
 
 func NewBlockMeta(tenantID string, blockID uuid.UUID, version string, encoding Encoding, dataEncoding string) *BlockMeta {
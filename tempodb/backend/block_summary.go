@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/willf/bloom"
+)
+
+const (
+	// maxSummaryServiceNames is the max number of distinct service names of a summary. Blocks with more service names
+	// are not summarized by service name.
+	maxSummaryServiceNames = 100
+	// maxSummaryAttributeValues is the max number of distinct values of an attribute key. Keys with more values are
+	// added to the attribute bloom filter without their values.
+	maxSummaryAttributeValues = 32
+	// maxSummaryAttributes is the max number of entries of the attribute bloom filter. Blocks with more entries are not
+	// summarized by attribute. The summary is stored in the meta of every block in the tenant index, it must stay small.
+	maxSummaryAttributes = 256
+	summaryBloomFP       = 0.02
+	// the size of the attribute bloom filter is rounded up to a multiple of summaryBloomQuantumBits and capped at
+	// maxSummaryBloomBits, 256 bytes
+	summaryBloomQuantumBits = 256
+	maxSummaryBloomBits     = 2048
+
+	// summaryAnyValue is added to the attribute bloom filter with the keys that have too many values
+	summaryAnyValue = "*"
+)
+
+// BlockSummary is a compact summary of the traces of a block. It's stored with the meta of the block in the tenant
+// index, searches use it to skip blocks that can't contain matching traces without reading them. Summaries are
+// conservative, a block that can match is never skipped.
+type BlockSummary struct {
+	ServiceNames     []string           `json:"serviceNames,omitempty"`   // Distinct service names of the traces. Nil if the block has too many to be summarized
+	AttributeBloom   *bloom.BloomFilter `json:"attributeBloom,omitempty"` // Bloom filter of the key/value pairs of the string attributes. Nil if the block has too many to be summarized
+	MinDurationNanos uint64             `json:"minDurationNanos"`         // Duration of the shortest trace
+	MaxDurationNanos uint64             `json:"maxDurationNanos"`         // Duration of the longest trace
+}
+
+// HasServiceName returns false if no service name of the block contains the substring, the case is ignored.
+func (s *BlockSummary) HasServiceName(substring string) bool {
+	if s.ServiceNames == nil {
+		return true
+	}
+
+	substring = strings.ToLower(substring)
+	for _, name := range s.ServiceNames {
+		if strings.Contains(strings.ToLower(name), substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAttribute returns false if no string attribute of the block has the key and the value.
+func (s *BlockSummary) HasAttribute(key, value string) bool {
+	if s.AttributeBloom == nil {
+		return true
+	}
+
+	return s.AttributeBloom.TestString(summaryAttribute(key, value)) ||
+		s.AttributeBloom.TestString(summaryAttribute(key, summaryAnyValue))
+}
+
+// HasDuration returns false if no trace of the block is at least min and at most max nanoseconds long. A max of 0 is
+// unbounded.
+func (s *BlockSummary) HasDuration(min, max uint64) bool {
+	if s.MaxDurationNanos < min {
+		return false
+	}
+	if max > 0 && s.MinDurationNanos > max {
+		return false
+	}
+	return true
+}
+
+// BlockSummaryBuilder builds the summary of a block while its traces are written.
+type BlockSummaryBuilder struct {
+	traces int
+
+	serviceNames        map[string]struct{}
+	tooManyServiceNames bool
+	attributes          map[string]map[string]struct{} // key -> values, nil values if the key has too many
+	attributeCount      int
+	tooManyAttributes   bool
+	minDuration         uint64
+	maxDuration         uint64
+}
+
+// NewBlockSummaryBuilder creates a new BlockSummaryBuilder.
+func NewBlockSummaryBuilder() *BlockSummaryBuilder {
+	return &BlockSummaryBuilder{
+		serviceNames: map[string]struct{}{},
+		attributes:   map[string]map[string]struct{}{},
+	}
+}
+
+// AddTrace adds the duration of a trace. It must be called once for every trace of the block.
+func (b *BlockSummaryBuilder) AddTrace(durationNanos uint64) {
+	if b.traces == 0 || durationNanos < b.minDuration {
+		b.minDuration = durationNanos
+	}
+	if durationNanos > b.maxDuration {
+		b.maxDuration = durationNanos
+	}
+	b.traces++
+}
+
+// AddServiceName adds the service name of a resource.
+func (b *BlockSummaryBuilder) AddServiceName(name string) {
+	if b.tooManyServiceNames {
+		return
+	}
+
+	b.serviceNames[name] = struct{}{}
+	if len(b.serviceNames) > maxSummaryServiceNames {
+		b.tooManyServiceNames = true
+		b.serviceNames = nil
+	}
+}
+
+// AddAttribute adds a string attribute of a resource or a span.
+func (b *BlockSummaryBuilder) AddAttribute(key, value string) {
+	if b.tooManyAttributes {
+		return
+	}
+
+	values, ok := b.attributes[key]
+	if !ok {
+		values = map[string]struct{}{}
+		b.attributes[key] = values
+	}
+	if values == nil {
+		// the key has too many values
+		return
+	}
+	if _, ok := values[value]; ok {
+		return
+	}
+
+	values[value] = struct{}{}
+	b.attributeCount++
+
+	if len(values) > maxSummaryAttributeValues {
+		// the values are replaced by a single entry of the key
+		b.attributes[key] = nil
+		b.attributeCount -= len(values) - 1
+	}
+
+	if b.attributeCount > maxSummaryAttributes {
+		b.tooManyAttributes = true
+		b.attributes = nil
+	}
+}
+
+// Summary returns the summary of the block. It returns nil if no traces were added.
+func (b *BlockSummaryBuilder) Summary() *BlockSummary {
+	if b.traces == 0 {
+		return nil
+	}
+
+	s := &BlockSummary{
+		MinDurationNanos: b.minDuration,
+		MaxDurationNanos: b.maxDuration,
+	}
+
+	if !b.tooManyServiceNames {
+		s.ServiceNames = make([]string, 0, len(b.serviceNames))
+		for name := range b.serviceNames {
+			s.ServiceNames = append(s.ServiceNames, name)
+		}
+		sort.Strings(s.ServiceNames)
+	}
+
+	if !b.tooManyAttributes {
+		n := b.attributeCount
+		if n == 0 {
+			n = 1
+		}
+		m, k := bloom.EstimateParameters(uint(n), summaryBloomFP)
+		m = (m + summaryBloomQuantumBits - 1) / summaryBloomQuantumBits * summaryBloomQuantumBits
+		if m > maxSummaryBloomBits {
+			m = maxSummaryBloomBits
+		}
+		s.AttributeBloom = bloom.New(m, k)
+		for key, values := range b.attributes {
+			if values == nil {
+				s.AttributeBloom.AddString(summaryAttribute(key, summaryAnyValue))
+				continue
+			}
+			for value := range values {
+				s.AttributeBloom.AddString(summaryAttribute(key, value))
+			}
+		}
+	}
+
+	return s
+}
+
+func summaryAttribute(key, value string) string {
+	return key + "\x00" + value
+}
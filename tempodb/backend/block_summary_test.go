@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockSummary(t *testing.T) {
+	b := NewBlockSummaryBuilder()
+	require.Nil(t, b.Summary())
+
+	b.AddTrace(uint64(20))
+	b.AddTrace(uint64(10))
+	b.AddTrace(uint64(30))
+	b.AddServiceName("frontend")
+	b.AddServiceName("backend")
+	b.AddServiceName("frontend")
+	b.AddAttribute("foo", "bar")
+	for i := 0; i <= maxSummaryAttributeValues; i++ {
+		b.AddAttribute("id", fmt.Sprintf("%d", i))
+	}
+
+	// summaries survive the tenant index
+	buf, err := json.Marshal(b.Summary())
+	require.NoError(t, err)
+	s := &BlockSummary{}
+	require.NoError(t, json.Unmarshal(buf, s))
+
+	assert.Equal(t, []string{"backend", "frontend"}, s.ServiceNames)
+	assert.True(t, s.HasServiceName("front"))
+	assert.True(t, s.HasServiceName("END"))
+	assert.False(t, s.HasServiceName("database"))
+
+	assert.True(t, s.HasAttribute("foo", "bar"))
+	assert.False(t, s.HasAttribute("foo", "baz"))
+	assert.False(t, s.HasAttribute("bar", "foo"))
+	// keys with too many values match any value
+	assert.True(t, s.HasAttribute("id", "unknown"))
+
+	assert.Equal(t, uint64(10), s.MinDurationNanos)
+	assert.Equal(t, uint64(30), s.MaxDurationNanos)
+	assert.True(t, s.HasDuration(0, 0))
+	assert.True(t, s.HasDuration(30, 0))
+	assert.True(t, s.HasDuration(0, 10))
+	assert.False(t, s.HasDuration(31, 0))
+	assert.False(t, s.HasDuration(0, 9))
+}
+
+func TestBlockSummarySize(t *testing.T) {
+	b := NewBlockSummaryBuilder()
+	b.AddTrace(0)
+	for i := 0; i < maxSummaryAttributes; i++ {
+		b.AddAttribute(fmt.Sprintf("key-%d", i), "value")
+	}
+
+	s := b.Summary()
+	require.NotNil(t, s.AttributeBloom)
+	assert.Equal(t, uint(maxSummaryBloomBits), s.AttributeBloom.Cap())
+	for i := 0; i < maxSummaryAttributes; i++ {
+		assert.True(t, s.HasAttribute(fmt.Sprintf("key-%d", i), "value"))
+	}
+
+	// the largest bloom filter stays small in the tenant index
+	buf, err := json.Marshal(s.AttributeBloom)
+	require.NoError(t, err)
+	assert.Less(t, len(buf), 400)
+}
+
+func TestBlockSummaryTooLarge(t *testing.T) {
+	b := NewBlockSummaryBuilder()
+	b.AddTrace(0)
+	for i := 0; i <= maxSummaryServiceNames; i++ {
+		b.AddServiceName(fmt.Sprintf("service-%d", i))
+	}
+	for i := 0; i <= maxSummaryAttributes; i++ {
+		b.AddAttribute(fmt.Sprintf("key-%d", i), "value")
+	}
+
+	// blocks that are too large to be summarized match everything
+	s := b.Summary()
+	assert.Nil(t, s.ServiceNames)
+	assert.Nil(t, s.AttributeBloom)
+	assert.True(t, s.HasServiceName("database"))
+	assert.True(t, s.HasAttribute("foo", "bar"))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/grafana/tempo/pkg/cache"
@@ -15,12 +16,20 @@ import (
 type readerWriter struct {
 	nextReader backend.RawReader
 	nextWriter backend.RawWriter
-	cache      cache.Cache
+	caches     map[Role]cache.Cache
+	keyPrefix  string
+	generation func(tenantID string) string
 }
 
-func NewCache(nextReader backend.RawReader, nextWriter backend.RawWriter, cache cache.Cache) (backend.RawReader, backend.RawWriter, error) {
+// NewCache wraps the reader and writer to cache objects and pages of objects in the cache of their role. Roles
+// without a cache are not cached. The keys start with the key prefix and contain the cache generation of the tenant,
+// if generation is not nil. A new generation purges the objects cached for the tenant: they are not read anymore and
+// are evicted by the caches eventually.
+func NewCache(nextReader backend.RawReader, nextWriter backend.RawWriter, caches map[Role]cache.Cache, keyPrefix string, generation func(tenantID string) string) (backend.RawReader, backend.RawWriter, error) {
 	rw := &readerWriter{
-		cache:      cache,
+		caches:     caches,
+		keyPrefix:  keyPrefix,
+		generation: generation,
 		nextReader: nextReader,
 		nextWriter: nextWriter,
 	}
@@ -36,9 +45,13 @@ func (r *readerWriter) List(ctx context.Context, keypath backend.KeyPath) ([]str
 // Read implements backend.RawReader
 func (r *readerWriter) Read(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
 	var k string
+	role := roleOf(ctx, name, keypath, false)
+	c := r.caches[role]
+	shouldCache = shouldCache && c != nil
+
 	if shouldCache {
-		k = key(keypath, name)
-		found, vals, _ := r.cache.Fetch(ctx, []string{k})
+		k = r.key(role, keypath, name)
+		found, vals, _ := c.Fetch(ctx, []string{k})
 		if len(found) > 0 {
 			return io.NopCloser(bytes.NewReader(vals[0])), int64(len(vals[0])), nil
 		}
@@ -51,7 +64,7 @@ func (r *readerWriter) Read(ctx context.Context, name string, keypath backend.Ke
 
 	b, err := tempo_io.ReadAllWithEstimate(object, size)
 	if err == nil && shouldCache {
-		r.cache.Store(ctx, []string{k}, [][]byte{b})
+		c.Store(ctx, []string{k}, [][]byte{b})
 	}
 
 	return io.NopCloser(bytes.NewReader(b)), size, err
@@ -59,13 +72,45 @@ func (r *readerWriter) Read(ctx context.Context, name string, keypath backend.Ke
 
 // ReadRange implements backend.RawReader
 func (r *readerWriter) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
-	return r.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	role := roleOf(ctx, name, keypath, true)
+	c := r.caches[role]
+	if c == nil {
+		return r.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	}
+
+	k := r.key(role, keypath, name) + ":" + strconv.FormatUint(offset, 10) + ":" + strconv.Itoa(len(buffer))
+	found, vals, _ := c.Fetch(ctx, []string{k})
+	if len(found) > 0 && len(vals[0]) == len(buffer) {
+		copy(buffer, vals[0])
+		return nil
+	}
+
+	err := r.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	if err != nil {
+		return err
+	}
+
+	// the buffer belongs to the caller, the cache may store it in the background
+	b := make([]byte, len(buffer))
+	copy(b, buffer)
+	c.Store(ctx, []string{k}, [][]byte{b})
+
+	return nil
 }
 
 // Shutdown implements backend.RawReader
 func (r *readerWriter) Shutdown() {
 	r.nextReader.Shutdown()
-	r.cache.Stop()
+
+	// roles may share a cache
+	stopped := map[cache.Cache]struct{}{}
+	for _, c := range r.caches {
+		if _, ok := stopped[c]; ok {
+			continue
+		}
+		stopped[c] = struct{}{}
+		c.Stop()
+	}
 }
 
 // Write implements backend.Writer
@@ -75,8 +120,9 @@ func (r *readerWriter) Write(ctx context.Context, name string, keypath backend.K
 		return err
 	}
 
-	if shouldCache {
-		r.cache.Store(ctx, []string{key(keypath, name)}, [][]byte{b})
+	role := roleOf(ctx, name, keypath, false)
+	if c := r.caches[role]; c != nil && shouldCache {
+		c.Store(ctx, []string{r.key(role, keypath, name)}, [][]byte{b})
 	}
 	return r.nextWriter.Write(ctx, name, keypath, bytes.NewReader(b), int64(len(b)), false)
 }
@@ -91,6 +137,14 @@ func (r *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTr
 	return r.nextWriter.CloseAppend(ctx, tracker)
 }
 
-func key(keypath backend.KeyPath, name string) string {
-	return strings.Join(keypath, ":") + ":" + name
+// key returns the key of the object. The keys of each role are versioned, so the format of the cached objects of a
+// role can change without flushing the caches.
+func (r *readerWriter) key(role Role, keypath backend.KeyPath, name string) string {
+	k := r.keyPrefix + string(role) + ":v" + strconv.Itoa(roleVersions[role]) + ":"
+	if r.generation != nil && len(keypath) > 0 {
+		if g := r.generation(keypath[0]); g != "" {
+			k += "g" + g + ":"
+		}
+	}
+	return k + strings.Join(keypath, ":") + ":" + name
 }
@@ -40,6 +40,16 @@ func NewMockClient() cache.Cache {
 		client: map[string][]byte{},
 	}
 }
+
+// allRoles caches all roles in the cache
+func allRoles(c cache.Cache) map[Role]cache.Cache {
+	caches := map[Role]cache.Cache{}
+	for _, role := range Roles {
+		caches[role] = c
+	}
+	return caches
+}
+
 func TestReadWrite(t *testing.T) {
 	tenantID := "test"
 	blockID := uuid.New()
@@ -54,7 +64,7 @@ func TestReadWrite(t *testing.T) {
 	}{
 		{
 			name:          "should cache",
-			readerName:    "foo",
+			readerName:    "bloom-0",
 			readerRead:    []byte{0x02},
 			shouldCache:   true,
 			expectedRead:  []byte{0x02},
@@ -77,7 +87,7 @@ func TestReadWrite(t *testing.T) {
 			mockW := &backend.MockRawWriter{}
 
 			// READ
-			r, _, _ := NewCache(mockR, mockW, NewMockClient())
+			r, _, _ := NewCache(mockR, mockW, allRoles(NewMockClient()), "", nil)
 
 			ctx := context.Background()
 			reader, _, _ := r.Read(ctx, tt.readerName, backend.KeyPathForBlock(blockID, tenantID), tt.shouldCache)
@@ -92,7 +102,7 @@ func TestReadWrite(t *testing.T) {
 			assert.Equal(t, len(tt.expectedCache), len(read))
 
 			// WRITE
-			_, w, _ := NewCache(mockR, mockW, NewMockClient())
+			_, w, _ := NewCache(mockR, mockW, allRoles(NewMockClient()), "", nil)
 			_ = w.Write(ctx, tt.readerName, backend.KeyPathForBlock(blockID, tenantID), bytes.NewReader(tt.readerRead), int64(len(tt.readerRead)), tt.shouldCache)
 			reader, _, _ = r.Read(ctx, tt.readerName, backend.KeyPathForBlock(blockID, tenantID), tt.shouldCache)
 			read, _ = io.ReadAll(reader)
@@ -126,7 +136,7 @@ func TestList(t *testing.T) {
 			}
 			mockW := &backend.MockRawWriter{}
 
-			rw, _, _ := NewCache(mockR, mockW, NewMockClient())
+			rw, _, _ := NewCache(mockR, mockW, allRoles(NewMockClient()), "", nil)
 
 			ctx := context.Background()
 			list, _ := rw.List(ctx, backend.KeyPathForBlock(blockID, tenantID))
@@ -141,3 +151,111 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestReadRange(t *testing.T) {
+	tenantID := "test"
+	blockID := uuid.New()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		roles         []Role
+		expectedCache []byte
+	}{
+		{
+			name:          "should cache",
+			ctx:           context.Background(),
+			roles:         []Role{RolePage},
+			expectedCache: []byte{0x01, 0x02},
+		},
+		{
+			name:          "should not cache",
+			ctx:           context.Background(),
+			roles:         []Role{RoleIndex},
+			expectedCache: []byte{0x00, 0x00},
+		},
+		{
+			name:          "role from context",
+			ctx:           ContextWithRole(context.Background(), RoleParquetFooter),
+			roles:         []Role{RoleParquetFooter},
+			expectedCache: []byte{0x01, 0x02},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockR := &backend.MockRawReader{
+				Range: []byte{0x01, 0x02},
+			}
+			mockW := &backend.MockRawWriter{}
+
+			caches := map[Role]cache.Cache{}
+			for _, role := range tt.roles {
+				caches[role] = NewMockClient()
+			}
+			r, _, _ := NewCache(mockR, mockW, caches, "", nil)
+
+			ctx := tt.ctx
+			buffer := make([]byte, 2)
+			err := r.ReadRange(ctx, "foo", backend.KeyPathForBlock(blockID, tenantID), 10, buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{0x01, 0x02}, buffer)
+
+			// clear reader and re-request
+			mockR.Range = nil
+
+			buffer = make([]byte, 2)
+			err = r.ReadRange(ctx, "foo", backend.KeyPathForBlock(blockID, tenantID), 10, buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCache, buffer)
+
+			// other ranges are not cached
+			buffer = make([]byte, 2)
+			err = r.ReadRange(ctx, "foo", backend.KeyPathForBlock(blockID, tenantID), 12, buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{0x00, 0x00}, buffer)
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	ctx := context.Background()
+	blockPath := backend.KeyPathForBlock(uuid.New(), "test")
+
+	assert.Equal(t, RoleBloom, roleOf(ctx, "bloom-0", blockPath, false))
+	assert.Equal(t, RoleIndex, roleOf(ctx, "index", blockPath, true))
+	assert.Equal(t, RoleTenantIndex, roleOf(ctx, backend.TenantIndexName, backend.KeyPath{"test"}, false))
+	assert.Equal(t, RolePage, roleOf(ctx, "data.parquet", blockPath, true))
+	assert.Equal(t, RoleNone, roleOf(ctx, "data", blockPath, false))
+	assert.Equal(t, RoleNone, roleOf(ctx, backend.MetaName, blockPath, false))
+	assert.Equal(t, RoleParquetFooter, roleOf(ContextWithRole(ctx, RoleParquetFooter), "data.parquet", blockPath, true))
+}
+
+func TestKeys(t *testing.T) {
+	c := NewMockClient().(*mockClient)
+	_, w, _ := NewCache(&backend.MockRawReader{}, &backend.MockRawWriter{}, allRoles(c), "prefix-", nil)
+
+	blockID := uuid.New()
+	err := w.Write(context.Background(), "bloom-0", backend.KeyPathForBlock(blockID, "test"), bytes.NewReader([]byte{0x01}), 1, true)
+	assert.NoError(t, err)
+
+	// the keys are prefixed and versioned per role
+	assert.Contains(t, c.client, "prefix-bloom:v1:test:"+blockID.String()+":bloom-0")
+
+	// the keys contain the generation of the tenant
+	generation := func(tenantID string) string {
+		if tenantID == "deleted" {
+			return "1"
+		}
+		return ""
+	}
+	_, w, _ = NewCache(&backend.MockRawReader{}, &backend.MockRawWriter{}, allRoles(c), "prefix-", generation)
+
+	err = w.Write(context.Background(), "bloom-0", backend.KeyPathForBlock(blockID, "deleted"), bytes.NewReader([]byte{0x01}), 1, true)
+	assert.NoError(t, err)
+	assert.Contains(t, c.client, "prefix-bloom:v1:g1:deleted:"+blockID.String()+":bloom-0")
+
+	err = w.Write(context.Background(), "bloom-0", backend.KeyPathForBlock(blockID, "other"), bytes.NewReader([]byte{0x01}), 1, true)
+	assert.NoError(t, err)
+	assert.Contains(t, c.client, "prefix-bloom:v1:other:"+blockID.String()+":bloom-0")
+}
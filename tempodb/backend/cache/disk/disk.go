@@ -0,0 +1,31 @@
+package disk
+
+import (
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/tempo/pkg/cache"
+)
+
+type Config struct {
+	ClientConfig cache.DiskCacheConfig `yaml:",inline"`
+}
+
+// NewClient creates the disk cache. Stores are done in the background if a background config is given. The name
+// distinguishes the metrics of the caches.
+func NewClient(name string, cfg *Config, cfgBackground *cache.BackgroundConfig, logger log.Logger) (cache.Cache, error) {
+	if cfg.ClientConfig.MaxSizeBytes == 0 {
+		cfg.ClientConfig.MaxSizeBytes = 1024 * 1024 * 1024 // 1 GiB
+	}
+
+	c, err := cache.NewDiskCache(name, cfg.ClientConfig, prometheus.DefaultRegisterer, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfgBackground == nil {
+		return c, nil
+	}
+
+	return cache.NewBackground(name, *cfgBackground, c, prometheus.DefaultRegisterer), nil
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// Role is the type of the objects and pages that are cached. Every role has its own cache policy.
+type Role string
+
+const (
+	// RoleNone is not cached
+	RoleNone Role = ""
+	// RoleBloom are the bloom filters of blocks
+	RoleBloom Role = "bloom"
+	// RoleIndex are the pages of the indexes of v2 blocks
+	RoleIndex Role = "index"
+	// RoleTenantIndex are the tenant indexes. They change with every poll and should be cached with a TTL.
+	RoleTenantIndex Role = "tenant_index"
+	// RoleParquetFooter are the footers of parquet files. Readers pass the role in the context.
+	RoleParquetFooter Role = "parquet_footer"
+	// RolePage are the other pages of objects, e.g. the data pages of v2 blocks and the column chunks of parquet files
+	RolePage Role = "page"
+)
+
+// Roles are all roles that are cached
+var Roles = []Role{RoleBloom, RoleIndex, RoleTenantIndex, RoleParquetFooter, RolePage}
+
+// roleVersions are the versions of the formats of the cached objects of every role. The version is part of the key,
+// after a format changes objects cached in the previous format aren't read anymore and are pushed out of the caches.
+var roleVersions = map[Role]int{
+	RoleBloom:         1,
+	RoleIndex:         1,
+	RoleTenantIndex:   1,
+	RoleParquetFooter: 1,
+	RolePage:          1,
+}
+
+// the names of the objects of v2 blocks, see the common encoding package
+const (
+	bloomPrefix = "bloom-"
+	indexName   = "index"
+)
+
+type roleKey struct{}
+
+// ContextWithRole sets the role of the reads with the context. It takes precedence over the role of the object.
+func ContextWithRole(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// roleOf returns the role of the object or of the page of the object that is read
+func roleOf(ctx context.Context, name string, keypath backend.KeyPath, page bool) Role {
+	if role, ok := ctx.Value(roleKey{}).(Role); ok {
+		return role
+	}
+
+	switch {
+	case name == backend.TenantIndexName && len(keypath) == 1:
+		return RoleTenantIndex
+	case name == backend.MetaName || name == backend.CompactedMetaName:
+		return RoleNone
+	case strings.HasPrefix(name, bloomPrefix):
+		return RoleBloom
+	case name == indexName:
+		return RoleIndex
+	case page:
+		return RolePage
+	}
+
+	return RoleNone
+}
@@ -0,0 +1,22 @@
+package backend
+
+import (
+	"errors"
+	"hash"
+	"hash/crc32"
+)
+
+// ErrChecksumMismatch is returned if the checksum of an object doesn't match the checksum recorded in its block meta
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum returns the CRC-32C checksum of the data
+func Checksum(data []byte) uint32 {
+	return crc32.Checksum(data, crc32c)
+}
+
+// NewChecksumHash returns a hash computing the CRC-32C checksum of the data written to it
+func NewChecksumHash() hash.Hash32 {
+	return crc32.New(crc32c)
+}
@@ -0,0 +1,295 @@
+package checksum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var metricMismatches = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "backend_checksum_mismatches_total",
+	Help:      "Total number of objects read from the backend whose checksum didn't match.",
+})
+
+type writer struct {
+	next backend.RawWriter
+
+	// checksums of the objects of the blocks that are written, until their meta is written
+	pending *backend.LRU
+}
+
+type appendTracker struct {
+	next    backend.AppendTracker
+	name    string
+	keypath backend.KeyPath
+	h       hash.Hash32
+}
+
+// NewWriter wraps the writer to record the checksums of the objects of a block in its meta. The meta is written last,
+// once all objects of the block are written.
+func NewWriter(next backend.RawWriter) (backend.RawWriter, error) {
+	pending, err := backend.NewLRU(backend.DefaultLRUSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writer{
+		next:    next,
+		pending: pending,
+	}, nil
+}
+
+// Write implements backend.RawWriter
+func (w *writer) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	if name == backend.MetaName {
+		return w.writeBlockMeta(ctx, keypath, data, size, shouldCache)
+	}
+	if !checksummed(name, keypath) {
+		return w.next.Write(ctx, name, keypath, data, size, shouldCache)
+	}
+
+	h := backend.NewChecksumHash()
+	err := w.next.Write(ctx, name, keypath, io.TeeReader(data, h), size, shouldCache)
+	if err != nil {
+		return err
+	}
+
+	w.record(name, keypath, h.Sum32())
+	return nil
+}
+
+// Append implements backend.RawWriter
+func (w *writer) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	if !checksummed(name, keypath) {
+		return w.next.Append(ctx, name, keypath, tracker, buffer)
+	}
+
+	var t *appendTracker
+	if tracker != nil {
+		t = tracker.(*appendTracker)
+	} else {
+		t = &appendTracker{
+			name:    name,
+			keypath: keypath,
+			h:       backend.NewChecksumHash(),
+		}
+	}
+
+	var err error
+	t.next, err = w.next.Append(ctx, name, keypath, t.next, buffer)
+	if err != nil {
+		return t, err
+	}
+
+	_, _ = t.h.Write(buffer)
+	return t, nil
+}
+
+// CloseAppend implements backend.RawWriter
+func (w *writer) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	t, ok := tracker.(*appendTracker)
+	if !ok {
+		return w.next.CloseAppend(ctx, tracker)
+	}
+
+	err := w.next.CloseAppend(ctx, t.next)
+	if err != nil {
+		return err
+	}
+
+	w.record(t.name, t.keypath, t.h.Sum32())
+	return nil
+}
+
+func (w *writer) record(name string, keypath backend.KeyPath, checksum uint32) {
+	w.pending.Update(backend.BlockPath(keypath), func(value interface{}, ok bool) interface{} {
+		checksums := map[string]uint32{}
+		if ok {
+			checksums = value.(map[string]uint32)
+		}
+		checksums[name] = checksum
+		return checksums
+	})
+}
+
+// writeBlockMeta records the checksums of the objects written since the last meta of the block. They replace the
+// checksums in the meta, e.g. the checksums of a block copied from another backend.
+func (w *writer) writeBlockMeta(ctx context.Context, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	path := backend.BlockPath(keypath)
+
+	pending, ok := w.pending.Get(path)
+	if !ok {
+		return w.next.Write(ctx, backend.MetaName, keypath, data, size, shouldCache)
+	}
+
+	b, err := tempo_io.ReadAllWithEstimate(data, size)
+	if err != nil {
+		return err
+	}
+
+	meta := &backend.BlockMeta{}
+	err = json.Unmarshal(b, meta)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal block meta: %w", err)
+	}
+
+	meta.Checksums = pending.(map[string]uint32)
+	b, err = json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	err = w.next.Write(ctx, backend.MetaName, keypath, bytes.NewReader(b), int64(len(b)), shouldCache)
+	if err != nil {
+		return err
+	}
+
+	w.pending.Remove(path)
+
+	return nil
+}
+
+type reader struct {
+	next backend.RawReader
+
+	// checksums of the blocks read from their metas
+	checksums *backend.LRU
+}
+
+// NewReader wraps the reader to verify the checksums of objects that are read entirely. The checksums are looked up in
+// the meta of the block. Objects without checksums, e.g. of blocks written before checksums were enabled, and ranges of
+// objects are not verified.
+func NewReader(next backend.RawReader) (backend.RawReader, error) {
+	checksums, err := backend.NewLRU(backend.DefaultLRUSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{
+		next:      next,
+		checksums: checksums,
+	}, nil
+}
+
+// List implements backend.RawReader
+func (r *reader) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
+	return r.next.List(ctx, keypath)
+}
+
+// Read implements backend.RawReader. The object is verified once it is read to the end, a mismatch is returned by the
+// last read.
+func (r *reader) Read(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+	object, size, err := r.next.Read(ctx, name, keypath, shouldCache)
+	if err != nil || !checksummed(name, keypath) {
+		return object, size, err
+	}
+
+	checksum, ok, err := r.checksum(ctx, name, keypath)
+	if err != nil {
+		object.Close()
+		return nil, 0, err
+	}
+	if !ok {
+		return object, size, nil
+	}
+
+	return &verifyingReader{
+		ReadCloser: object,
+		h:          backend.NewChecksumHash(),
+		checksum:   checksum,
+		name:       backend.ObjectFileName(keypath, name),
+	}, size, nil
+}
+
+// ReadRange implements backend.RawReader. Ranges are not verified, the checksums are of whole objects. The footer of
+// vParquet blocks has its own checksum in the meta that is verified when the block is opened, corruption elsewhere is
+// found by the scrubber.
+func (r *reader) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	return r.next.ReadRange(ctx, name, keypath, offset, buffer)
+}
+
+// Shutdown implements backend.RawReader
+func (r *reader) Shutdown() {
+	r.next.Shutdown()
+}
+
+// checksum returns the checksum of the object from the meta of its block. Blocks without a meta, e.g. blocks that
+// are still written, have no checksums.
+func (r *reader) checksum(ctx context.Context, name string, keypath backend.KeyPath) (uint32, bool, error) {
+	path := backend.BlockPath(keypath)
+
+	cached, ok := r.checksums.Get(path)
+
+	if !ok {
+		object, size, err := r.next.Read(ctx, backend.MetaName, keypath, false)
+		if errors.Is(err, backend.ErrDoesNotExist) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		defer object.Close()
+
+		b, err := tempo_io.ReadAllWithEstimate(object, size)
+		if err != nil {
+			return 0, false, err
+		}
+
+		meta := &backend.BlockMeta{}
+		err = json.Unmarshal(b, meta)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to unmarshal block meta: %w", err)
+		}
+
+		// the meta of a block doesn't change, the checksums are kept even if there are none
+		cached = meta.Checksums
+		r.checksums.Add(path, cached)
+	}
+
+	checksum, ok := cached.(map[string]uint32)[name]
+	return checksum, ok, nil
+}
+
+type verifyingReader struct {
+	io.ReadCloser
+
+	h        hash.Hash32
+	checksum uint32
+	name     string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	_, _ = v.h.Write(p[:n])
+
+	if err == io.EOF && v.h.Sum32() != v.checksum {
+		metricMismatches.Inc()
+		return n, fmt.Errorf("%w: %s", backend.ErrChecksumMismatch, v.name)
+	}
+
+	return n, err
+}
+
+// checksummed returns true if the object is an object of a block that isn't its meta
+func checksummed(name string, keypath backend.KeyPath) bool {
+	if len(keypath) < 2 {
+		return false
+	}
+
+	switch name {
+	case backend.MetaName, backend.CompactedMetaName, backend.DeletionRequestName:
+		return false
+	}
+	return true
+}
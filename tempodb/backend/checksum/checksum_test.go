@@ -0,0 +1,124 @@
+package checksum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+)
+
+const testTenantID = "test"
+
+func newTestBackend(t *testing.T) (string, backend.RawReader, backend.RawWriter) {
+	dir := t.TempDir()
+	rawR, rawW, _, err := local.New(&local.Config{Path: dir})
+	require.NoError(t, err)
+
+	r, err := NewReader(rawR)
+	require.NoError(t, err)
+	w, err := NewWriter(rawW)
+	require.NoError(t, err)
+
+	return dir, r, w
+}
+
+func writeBlock(t *testing.T, w backend.RawWriter, meta *backend.BlockMeta) {
+	ctx := context.Background()
+	keypath := backend.KeyPathForBlock(meta.BlockID, meta.TenantID)
+
+	require.NoError(t, w.Write(ctx, "data", keypath, bytes.NewReader([]byte("data")), 4, false))
+
+	tracker, err := w.Append(ctx, "appended", keypath, nil, []byte("app"))
+	require.NoError(t, err)
+	tracker, err = w.Append(ctx, "appended", keypath, tracker, []byte("ended"))
+	require.NoError(t, err)
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+
+	b, err := json.Marshal(meta)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(ctx, backend.MetaName, keypath, bytes.NewReader(b), int64(len(b)), false))
+}
+
+func readObject(r backend.RawReader, name string, keypath backend.KeyPath) ([]byte, error) {
+	object, _, err := r.Read(context.Background(), name, keypath, false)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return io.ReadAll(object)
+}
+
+func TestChecksums(t *testing.T) {
+	dir, r, w := newTestBackend(t)
+	meta := backend.NewBlockMeta(testTenantID, uuid.New(), "v2", backend.EncNone, "")
+	keypath := backend.KeyPathForBlock(meta.BlockID, meta.TenantID)
+
+	writeBlock(t, w, meta)
+
+	// the checksums are recorded in the meta
+	b, err := readObject(r, backend.MetaName, keypath)
+	require.NoError(t, err)
+	written := &backend.BlockMeta{}
+	require.NoError(t, json.Unmarshal(b, written))
+	require.Equal(t, map[string]uint32{
+		"data":     backend.Checksum([]byte("data")),
+		"appended": backend.Checksum([]byte("appended")),
+	}, written.Checksums)
+
+	// and verified when the objects are read
+	b, err = readObject(r, "data", keypath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), b)
+	b, err = readObject(r, "appended", keypath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("appended"), b)
+
+	require.NoError(t, os.WriteFile(path.Join(dir, testTenantID, meta.BlockID.String(), "data"), []byte("date"), 0644))
+	_, err = readObject(r, "data", keypath)
+	require.ErrorIs(t, err, backend.ErrChecksumMismatch)
+
+	// ranges are not verified
+	buffer := make([]byte, 4)
+	require.NoError(t, r.ReadRange(context.Background(), "data", keypath, 0, buffer))
+	require.Equal(t, []byte("date"), buffer)
+}
+
+func TestNoChecksums(t *testing.T) {
+	dir, r, _ := newTestBackend(t)
+	rawR, rawW, _, err := local.New(&local.Config{Path: dir})
+	require.NoError(t, err)
+
+	// blocks written without checksums are read as is
+	meta := backend.NewBlockMeta(testTenantID, uuid.New(), "v2", backend.EncNone, "")
+	keypath := backend.KeyPathForBlock(meta.BlockID, meta.TenantID)
+	writeBlock(t, rawW, meta)
+
+	b, err := readObject(r, "data", keypath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), b)
+
+	b, err = readObject(rawR, backend.MetaName, keypath)
+	require.NoError(t, err)
+	written := &backend.BlockMeta{}
+	require.NoError(t, json.Unmarshal(b, written))
+	require.Empty(t, written.Checksums)
+
+	// objects of blocks without a meta are read as is
+	_, r, w := newTestBackend(t)
+	keypath = backend.KeyPathForBlock(uuid.New(), testTenantID)
+	require.NoError(t, w.Write(context.Background(), "data", keypath, bytes.NewReader([]byte("data")), 4, false))
+
+	b, err = readObject(r, "data", keypath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), b)
+}
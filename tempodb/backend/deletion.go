@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DeletionsName is the directory of the deletion requests of a tenant. Every request is stored in its own
+	// directory beneath it, so requests are never overwritten by other requests.
+	DeletionsName = "deletions"
+	// DeletionRequestName is the object of a deletion request in its directory
+	DeletionRequestName = "deletion.json"
+)
+
+// DeletionStatus is the status of a deletion request
+type DeletionStatus string
+
+const (
+	// DeletionPending requests are honoured by queries but not applied to all blocks yet
+	DeletionPending DeletionStatus = "pending"
+	// DeletionCompleted requests have been applied to all blocks
+	DeletionCompleted DeletionStatus = "completed"
+)
+
+// DeletionRequest is a request to delete all traces of a tenant, or only the traces with the trace IDs.
+type DeletionRequest struct {
+	ID          uuid.UUID      `json:"id"`
+	TenantID    string         `json:"tenantID"`
+	TraceIDs    []string       `json:"traceIDs,omitempty"` // Hex encoded IDs of the traces to delete. Empty if all traces of the tenant are deleted
+	CreatedAt   time.Time      `json:"createdAt"`
+	Status      DeletionStatus `json:"status"`
+	CompletedAt time.Time      `json:"completedAt,omitempty"`
+	Blocks      int            `json:"blocks"` // Number of blocks deleted or rewritten so far
+}
+
+// NewDeletionRequest creates a new pending DeletionRequest. Pass no trace IDs to delete all traces of the tenant.
+func NewDeletionRequest(tenantID string, traceIDs []string) *DeletionRequest {
+	return &DeletionRequest{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		TraceIDs:  traceIDs,
+		CreatedAt: time.Now(),
+		Status:    DeletionPending,
+	}
+}
+
+// DeletesTenant returns true if the request deletes all traces of the tenant
+func (r *DeletionRequest) DeletesTenant() bool {
+	return len(r.TraceIDs) == 0
+}
+
+// TenantDeletions are the deletions of a tenant recorded in the tenant index, so components that don't build the
+// tenant index don't read the deletion requests.
+type TenantDeletions struct {
+	Tombstones      []string    `json:"tombstones,omitempty"`      // Hex encoded IDs of the deleted traces
+	CacheGeneration string      `json:"cacheGeneration,omitempty"` // ID of the newest tenant deletion
+	Requests        []uuid.UUID `json:"requests,omitempty"`        // IDs of the requests the deletions were built from
+}
+
+// KeyPathForDeletionRequest returns the keypath of the deletion request
+func KeyPathForDeletionRequest(id uuid.UUID, tenantID string) KeyPath {
+	return []string{tenantID, DeletionsName, id.String()}
+}
@@ -0,0 +1,43 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// KeyProviderKeyfile reads the tenant keys from a local file
+	KeyProviderKeyfile = "keyfile"
+
+	DefaultChunkSizeBytes = 64 * 1024
+)
+
+// Config of the client-side encryption of objects in the backend
+type Config struct {
+	// KeyProvider selects where the tenant keys come from. Encryption is disabled if empty.
+	KeyProvider    string         `yaml:"key_provider"`
+	Keyfile        *KeyfileConfig `yaml:"keyfile"`
+	ChunkSizeBytes uint32         `yaml:"chunk_size_bytes"`
+}
+
+type KeyfileConfig struct {
+	Path string `yaml:"path"`
+}
+
+// Enabled returns true if objects are encrypted
+func (cfg *Config) Enabled() bool {
+	return cfg != nil && cfg.KeyProvider != ""
+}
+
+// NewKeyProvider creates the key provider selected in the config
+func NewKeyProvider(cfg *Config) (KeyProvider, error) {
+	switch cfg.KeyProvider {
+	case KeyProviderKeyfile:
+		if cfg.Keyfile == nil || cfg.Keyfile.Path == "" {
+			return nil, errors.New("encryption keyfile path must be set")
+		}
+		return NewKeyfileProvider(cfg.Keyfile.Path)
+	default:
+		return nil, fmt.Errorf("unknown encryption key provider %s", cfg.KeyProvider)
+	}
+}
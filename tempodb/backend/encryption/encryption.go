@@ -0,0 +1,386 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// Key is a key of a tenant. The data key of every object is wrapped with a tenant key.
+type Key struct {
+	ID       string
+	Material []byte
+}
+
+// KeyProvider provides the keys of the tenants
+type KeyProvider interface {
+	// CurrentKey returns the key new objects of the tenant are encrypted with
+	CurrentKey(ctx context.Context, tenantID string) (Key, error)
+	// Key returns the key of the tenant with the given ID
+	Key(ctx context.Context, tenantID string, keyID string) (Key, error)
+}
+
+type readerWriter struct {
+	nextReader backend.RawReader
+	nextWriter backend.RawWriter
+	keys       KeyProvider
+	chunkSize  uint32
+
+	// headers of the objects read with ReadRange. nil for objects that are not encrypted.
+	headers *backend.LRU
+	// blockKeys pins the key of a block while it's written, so all of its objects and its meta use the same key
+	blockKeys *backend.LRU
+}
+
+type appendTracker struct {
+	next    backend.AppendTracker
+	name    string
+	keypath backend.KeyPath
+	h       *header
+	// sealed holds the header until the first chunk is appended
+	sealed  []byte
+	pending []byte
+	chunks  uint64
+}
+
+// NewEncryption wraps the reader and writer to encrypt the objects of a tenant with its keys. The block metas and the
+// tenant index are not encrypted, but the ID of the key of a block is recorded in its meta. Objects that are not
+// encrypted are read as they are.
+func NewEncryption(nextReader backend.RawReader, nextWriter backend.RawWriter, keys KeyProvider, chunkSize uint32) (backend.RawReader, backend.RawWriter, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSizeBytes
+	}
+
+	headers, err := backend.NewLRU(backend.DefaultLRUSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockKeys, err := backend.NewLRU(backend.DefaultLRUSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rw := &readerWriter{
+		nextReader: nextReader,
+		nextWriter: nextWriter,
+		keys:       keys,
+		chunkSize:  chunkSize,
+		headers:    headers,
+		blockKeys:  blockKeys,
+	}
+
+	return rw, rw, nil
+}
+
+// List implements backend.RawReader
+func (rw *readerWriter) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
+	return rw.nextReader.List(ctx, keypath)
+}
+
+// Read implements backend.RawReader
+func (rw *readerWriter) Read(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+	object, size, err := rw.nextReader.Read(ctx, name, keypath, shouldCache)
+	if err != nil || !encrypted(name, keypath) {
+		return object, size, err
+	}
+	defer object.Close()
+
+	b, err := tempo_io.ReadAllWithEstimate(object, size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	h, err := rw.parseHeader(ctx, name, keypath, b)
+	if errors.Is(err, errNotEncrypted) {
+		return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := h.open(b)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decrypt %s: %w", backend.ObjectFileName(keypath, name), err)
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// ReadRange implements backend.RawReader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	if !encrypted(name, keypath) || len(buffer) == 0 {
+		return rw.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	}
+
+	h, err := rw.header(ctx, name, keypath)
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		return rw.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	}
+
+	chunkSize := uint64(h.chunkSize)
+	first := offset / chunkSize
+	last := (offset + uint64(len(buffer)) - 1) / chunkSize
+
+	sealedChunkSize := uint64(h.sealedChunkSize())
+	sealed := make([]byte, (last-first+1)*sealedChunkSize)
+	err = rw.nextReader.ReadRange(ctx, name, keypath, uint64(headerSize)+first*sealedChunkSize, sealed)
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for i := first; i <= last; i++ {
+		start := (i - first) * sealedChunkSize
+		data, lastChunk, err := h.openChunk(i, sealed[start:start+sealedChunkSize])
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", backend.ObjectFileName(keypath, name), err)
+		}
+
+		if i == first {
+			skip := offset - first*chunkSize
+			if skip > uint64(len(data)) {
+				return io.ErrUnexpectedEOF
+			}
+			data = data[skip:]
+		}
+		n += copy(buffer[n:], data)
+
+		if lastChunk && n < len(buffer) {
+			return io.ErrUnexpectedEOF
+		}
+	}
+
+	return nil
+}
+
+// Shutdown implements backend.RawReader
+func (rw *readerWriter) Shutdown() {
+	rw.nextReader.Shutdown()
+}
+
+// Write implements backend.RawWriter
+func (rw *readerWriter) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	if name == backend.MetaName {
+		return rw.writeBlockMeta(ctx, keypath, data, size, shouldCache)
+	}
+	if !encrypted(name, keypath) {
+		return rw.nextWriter.Write(ctx, name, keypath, data, size, shouldCache)
+	}
+
+	if size < 0 {
+		b, err := tempo_io.ReadAllWithEstimate(data, size)
+		if err != nil {
+			return err
+		}
+		data, size = bytes.NewReader(b), int64(len(b))
+	}
+
+	h, headerBytes, err := rw.newHeader(ctx, name, keypath)
+	if err != nil {
+		return err
+	}
+
+	return rw.nextWriter.Write(ctx, name, keypath, newSealingReader(h, headerBytes, data), h.sealedSize(size), shouldCache)
+}
+
+// Append implements backend.RawWriter
+func (rw *readerWriter) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	if !encrypted(name, keypath) {
+		return rw.nextWriter.Append(ctx, name, keypath, tracker, buffer)
+	}
+
+	var t *appendTracker
+	if tracker != nil {
+		t = tracker.(*appendTracker)
+	} else {
+		h, headerBytes, err := rw.newHeader(ctx, name, keypath)
+		if err != nil {
+			return nil, err
+		}
+		t = &appendTracker{
+			name:    name,
+			keypath: keypath,
+			h:       h,
+			sealed:  headerBytes,
+		}
+	}
+
+	// only whole chunks are appended, the rest is kept until the next append
+	t.pending = append(t.pending, buffer...)
+	chunkSize := int(t.h.chunkSize)
+	for len(t.pending) >= chunkSize {
+		t.sealed = t.h.sealChunk(t.sealed, t.chunks, t.pending[:chunkSize])
+		t.pending = t.pending[chunkSize:]
+		t.chunks++
+	}
+	t.pending = append([]byte(nil), t.pending...)
+
+	if len(t.sealed) == 0 {
+		return t, nil
+	}
+
+	var err error
+	t.next, err = rw.nextWriter.Append(ctx, name, keypath, t.next, t.sealed)
+	t.sealed = nil
+	return t, err
+}
+
+// CloseAppend implements backend.RawWriter
+func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	t, ok := tracker.(*appendTracker)
+	if !ok {
+		return rw.nextWriter.CloseAppend(ctx, tracker)
+	}
+
+	// the last chunk is always shorter than the chunk size, it may be empty
+	sealed := t.h.sealChunk(t.sealed, t.chunks, t.pending)
+	next, err := rw.nextWriter.Append(ctx, t.name, t.keypath, t.next, sealed)
+	if err != nil {
+		return err
+	}
+
+	return rw.nextWriter.CloseAppend(ctx, next)
+}
+
+// writeBlockMeta records the key of the block in its meta
+func (rw *readerWriter) writeBlockMeta(ctx context.Context, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	b, err := tempo_io.ReadAllWithEstimate(data, size)
+	if err != nil {
+		return err
+	}
+
+	meta := &backend.BlockMeta{}
+	err = json.Unmarshal(b, meta)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal block meta: %w", err)
+	}
+
+	key, err := rw.blockKey(ctx, keypath)
+	if err != nil {
+		return err
+	}
+	rw.blockKeys.Remove(backend.BlockPath(keypath))
+
+	meta.EncryptionKeyID = key.ID
+	b, err = json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return rw.nextWriter.Write(ctx, backend.MetaName, keypath, bytes.NewReader(b), int64(len(b)), shouldCache)
+}
+
+// newHeader creates the header of a new object with the key of its block
+func (rw *readerWriter) newHeader(ctx context.Context, name string, keypath backend.KeyPath) (*header, []byte, error) {
+	key, err := rw.blockKey(ctx, keypath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newHeader(key, backend.ObjectFileName(keypath, name), rw.chunkSize)
+}
+
+// blockKey returns the key pinned for the block or pins the current key of the tenant
+func (rw *readerWriter) blockKey(ctx context.Context, keypath backend.KeyPath) (Key, error) {
+	tenantID := keypath[0]
+	path := backend.BlockPath(keypath)
+
+	keyID, ok := rw.blockKeys.Get(path)
+	if ok {
+		return rw.keys.Key(ctx, tenantID, keyID.(string))
+	}
+
+	key, err := rw.keys.CurrentKey(ctx, tenantID)
+	if err != nil {
+		return Key{}, err
+	}
+
+	rw.blockKeys.Add(path, key.ID)
+
+	return key, nil
+}
+
+// header returns the header of an object, or nil if the object isn't encrypted
+func (rw *readerWriter) header(ctx context.Context, name string, keypath backend.KeyPath) (*header, error) {
+	objectName := backend.ObjectFileName(keypath, name)
+
+	cached, ok := rw.headers.Get(objectName)
+	if ok {
+		return cached.(*header), nil
+	}
+
+	b := make([]byte, headerSize)
+	err := rw.nextReader.ReadRange(ctx, name, keypath, 0, b)
+	if err != nil {
+		// objects smaller than a header are not encrypted, let the caller read them. the error of a range beyond the
+		// end of an object depends on the backend, so the size is checked by reading the object.
+		short, shortErr := rw.shorterThanHeader(ctx, name, keypath)
+		if shortErr != nil {
+			return nil, shortErr
+		}
+		if !short {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	h, err := rw.parseHeader(ctx, name, keypath, b)
+	if err != nil && !errors.Is(err, errNotEncrypted) {
+		return nil, err
+	}
+
+	rw.headers.Add(objectName, h)
+
+	return h, nil
+}
+
+// shorterThanHeader returns true if the object is shorter than the header of an encrypted object
+func (rw *readerWriter) shorterThanHeader(ctx context.Context, name string, keypath backend.KeyPath) (bool, error) {
+	object, _, err := rw.nextReader.Read(ctx, name, keypath, false)
+	if err != nil {
+		return false, err
+	}
+	defer object.Close()
+
+	n, err := io.Copy(io.Discard, io.LimitReader(object, int64(headerSize)))
+	if err != nil {
+		return false, err
+	}
+	return n < int64(headerSize), nil
+}
+
+func (rw *readerWriter) parseHeader(ctx context.Context, name string, keypath backend.KeyPath, b []byte) (*header, error) {
+	keyID, err := parseHeaderKeyID(b)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := rw.keys.Key(ctx, keypath[0], keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHeader(b, key, backend.ObjectFileName(keypath, name))
+}
+
+// encrypted returns true if the object is encrypted. The block metas, the tenant index and the deletion requests are not
+// encrypted, they are read and moved by the backend compactor and hold no trace data.
+func encrypted(name string, keypath backend.KeyPath) bool {
+	if len(keypath) == 0 {
+		return false
+	}
+
+	switch name {
+	case backend.MetaName, backend.CompactedMetaName, backend.TenantIndexName, backend.DeletionRequestName:
+		return false
+	}
+	return true
+}
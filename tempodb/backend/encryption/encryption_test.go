@@ -0,0 +1,278 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+)
+
+const testChunkSize = 16
+
+type mockKeyProvider struct {
+	keys    map[string]Key
+	current string
+}
+
+func newMockKeyProvider(ids ...string) *mockKeyProvider {
+	p := &mockKeyProvider{keys: map[string]Key{}}
+	for _, id := range ids {
+		material := make([]byte, dataKeySize)
+		_, _ = rand.Read(material)
+		p.keys[id] = Key{ID: id, Material: material}
+		p.current = id
+	}
+	return p
+}
+
+func (p *mockKeyProvider) CurrentKey(_ context.Context, _ string) (Key, error) {
+	return p.keys[p.current], nil
+}
+
+func (p *mockKeyProvider) Key(_ context.Context, tenantID string, keyID string) (Key, error) {
+	k, ok := p.keys[keyID]
+	if !ok {
+		return Key{}, fmt.Errorf("encryption key %s of tenant %s not found", keyID, tenantID)
+	}
+	return k, nil
+}
+
+func newTestEncryption(t *testing.T, keys KeyProvider) (backend.RawReader, backend.RawWriter, backend.RawReader, backend.RawWriter) {
+	r, w, _, err := local.New(&local.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+
+	encR, encW, err := NewEncryption(r, w, keys, testChunkSize)
+	require.NoError(t, err)
+
+	return encR, encW, r, w
+}
+
+func TestReadWrite(t *testing.T) {
+	ctx := context.Background()
+	r, w, rawR, _ := newTestEncryption(t, newMockKeyProvider("key-1"))
+
+	for _, size := range []int{0, 1, testChunkSize - 1, testChunkSize, testChunkSize + 1, 5*testChunkSize + 3} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			keypath := backend.KeyPathForBlock(uuid.New(), "test")
+			data := make([]byte, size)
+			_, _ = rand.Read(data)
+
+			err := w.Write(ctx, "data", keypath, bytes.NewReader(data), int64(size), false)
+			require.NoError(t, err)
+
+			object, objectSize, err := rawR.Read(ctx, "data", keypath, false)
+			require.NoError(t, err)
+			sealed, err := io.ReadAll(object)
+			require.NoError(t, err)
+			require.Equal(t, int64(headerSize+(size/testChunkSize+1)*(lengthSize+testChunkSize+tagSize)), objectSize)
+			if size >= 8 {
+				require.False(t, bytes.Contains(sealed, data))
+			}
+
+			object, objectSize, err = r.Read(ctx, "data", keypath, false)
+			require.NoError(t, err)
+			actual, err := io.ReadAll(object)
+			require.NoError(t, err)
+			require.Equal(t, int64(size), objectSize)
+			require.Equal(t, data, actual)
+
+			for offset := 0; offset < size; offset++ {
+				for length := 1; offset+length <= size; length += 7 {
+					buffer := make([]byte, length)
+					err = r.ReadRange(ctx, "data", keypath, uint64(offset), buffer)
+					require.NoError(t, err)
+					require.Equal(t, data[offset:offset+length], buffer)
+				}
+			}
+
+			err = r.ReadRange(ctx, "data", keypath, uint64(size), make([]byte, 1))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	ctx := context.Background()
+	r, w, _, _ := newTestEncryption(t, newMockKeyProvider("key-1"))
+
+	keypath := backend.KeyPathForBlock(uuid.New(), "test")
+	var data []byte
+	var tracker backend.AppendTracker
+	for _, size := range []int{3, 0, testChunkSize, 40, 1} {
+		buffer := make([]byte, size)
+		_, _ = rand.Read(buffer)
+		data = append(data, buffer...)
+
+		var err error
+		tracker, err = w.Append(ctx, "data", keypath, tracker, buffer)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+
+	object, size, err := r.Read(ctx, "data", keypath, false)
+	require.NoError(t, err)
+	actual, err := io.ReadAll(object)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), size)
+	require.Equal(t, data, actual)
+
+	buffer := make([]byte, 30)
+	require.NoError(t, r.ReadRange(ctx, "data", keypath, 10, buffer))
+	require.Equal(t, data[10:40], buffer)
+}
+
+func TestBlockMetaRecordsKey(t *testing.T) {
+	ctx := context.Background()
+	keys := newMockKeyProvider("key-1", "key-2")
+	keys.current = "key-1"
+	encR, encW, rawR, _ := newTestEncryption(t, keys)
+	r, w := backend.NewReader(encR), backend.NewWriter(encW)
+
+	meta := backend.NewBlockMeta("test", uuid.New(), "v2", backend.EncNone, "")
+	require.NoError(t, w.Write(ctx, "data", meta.BlockID, meta.TenantID, []byte("data"), false))
+
+	// the key is rotated while the block is written. the block keeps its key.
+	keys.current = "key-2"
+	require.NoError(t, w.Write(ctx, "index", meta.BlockID, meta.TenantID, []byte("index"), false))
+	require.NoError(t, w.WriteBlockMeta(ctx, meta))
+
+	// the meta is not encrypted
+	object, _, err := rawR.Read(ctx, backend.MetaName, backend.KeyPathForBlock(meta.BlockID, meta.TenantID), false)
+	require.NoError(t, err)
+	raw := &backend.BlockMeta{}
+	require.NoError(t, json.NewDecoder(object).Decode(raw))
+	require.Equal(t, "key-1", raw.EncryptionKeyID)
+
+	actual, err := r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	require.NoError(t, err)
+	require.Equal(t, "key-1", actual.EncryptionKeyID)
+
+	object, _, err = rawR.Read(ctx, "index", backend.KeyPathForBlock(meta.BlockID, meta.TenantID), false)
+	require.NoError(t, err)
+	sealed, err := io.ReadAll(object)
+	require.NoError(t, err)
+	keyID, err := parseHeaderKeyID(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "key-1", keyID)
+
+	// the next block uses the new key
+	meta = backend.NewBlockMeta("test", uuid.New(), "v2", backend.EncNone, "")
+	require.NoError(t, w.Write(ctx, "data", meta.BlockID, meta.TenantID, []byte("data"), false))
+	require.NoError(t, w.WriteBlockMeta(ctx, meta))
+	actual, err = r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	require.NoError(t, err)
+	require.Equal(t, "key-2", actual.EncryptionKeyID)
+}
+
+func TestReadUnencrypted(t *testing.T) {
+	ctx := context.Background()
+	r, _, _, rawW := newTestEncryption(t, newMockKeyProvider("key-1"))
+
+	keypath := backend.KeyPathForBlock(uuid.New(), "test")
+	small := []byte("small")
+	large := make([]byte, 3*headerSize)
+	_, _ = rand.Read(large)
+	require.NoError(t, rawW.Write(ctx, "small", keypath, bytes.NewReader(small), int64(len(small)), false))
+	require.NoError(t, rawW.Write(ctx, "large", keypath, bytes.NewReader(large), int64(len(large)), false))
+
+	object, _, err := r.Read(ctx, "small", keypath, false)
+	require.NoError(t, err)
+	actual, err := io.ReadAll(object)
+	require.NoError(t, err)
+	require.Equal(t, small, actual)
+
+	buffer := make([]byte, 3)
+	require.NoError(t, r.ReadRange(ctx, "small", keypath, 1, buffer))
+	require.Equal(t, small[1:4], buffer)
+
+	buffer = make([]byte, headerSize)
+	require.NoError(t, r.ReadRange(ctx, "large", keypath, 10, buffer))
+	require.Equal(t, large[10:10+headerSize], buffer)
+}
+
+// failingRangeReader fails all reads of ranges
+type failingRangeReader struct {
+	backend.RawReader
+}
+
+func (r *failingRangeReader) ReadRange(context.Context, string, backend.KeyPath, uint64, []byte) error {
+	return errors.New("backend unavailable")
+}
+
+func TestReadRangeErrors(t *testing.T) {
+	ctx := context.Background()
+	_, w, rawR, _ := newTestEncryption(t, newMockKeyProvider("key-1"))
+
+	keypath := backend.KeyPathForBlock(uuid.New(), "test")
+	data := make([]byte, 3*testChunkSize)
+	require.NoError(t, w.Write(ctx, "data", keypath, bytes.NewReader(data), int64(len(data)), false))
+
+	r, _, err := NewEncryption(&failingRangeReader{rawR}, nil, newMockKeyProvider("key-1"), testChunkSize)
+	require.NoError(t, err)
+
+	// errors reading the header are returned instead of reading the object as if it wasn't encrypted
+	buffer := make([]byte, testChunkSize)
+	err = r.ReadRange(ctx, "data", keypath, 0, buffer)
+	require.EqualError(t, err, "backend unavailable")
+
+	// so are missing objects
+	err = r.ReadRange(ctx, "missing", keypath, 0, buffer)
+	require.ErrorIs(t, err, backend.ErrDoesNotExist)
+}
+
+func TestTamperedObject(t *testing.T) {
+	ctx := context.Background()
+	keys := newMockKeyProvider("key-1")
+	r, w, rawR, rawW := newTestEncryption(t, keys)
+
+	keypath := backend.KeyPathForBlock(uuid.New(), "test")
+	data := make([]byte, 3*testChunkSize)
+	require.NoError(t, w.Write(ctx, "data", keypath, bytes.NewReader(data), int64(len(data)), false))
+
+	object, _, err := rawR.Read(ctx, "data", keypath, false)
+	require.NoError(t, err)
+	sealed, err := io.ReadAll(object)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		object []byte
+	}{
+		{
+			name:   "flipped bit",
+			object: append(append([]byte{}, sealed[:headerSize+1]...), append([]byte{sealed[headerSize+1] ^ 1}, sealed[headerSize+2:]...)...),
+		},
+		{
+			name:   "truncated at chunk boundary",
+			object: sealed[:len(sealed)-(lengthSize+testChunkSize+tagSize)],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, rawW.Write(ctx, "tampered", keypath, bytes.NewReader(tc.object), int64(len(tc.object)), false))
+			_, _, err := r.Read(ctx, "tampered", keypath, false)
+			require.Error(t, err)
+		})
+	}
+
+	// an object moved to another name can't be decrypted
+	require.NoError(t, rawW.Write(ctx, "moved", keypath, bytes.NewReader(sealed), int64(len(sealed)), false))
+	_, _, err = r.Read(ctx, "moved", keypath, false)
+	require.Error(t, err)
+
+	// an object can't be decrypted without its key
+	delete(keys.keys, "key-1")
+	_, _, err = r.Read(ctx, "data", keypath, false)
+	require.Error(t, err)
+}
@@ -0,0 +1,116 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// keyfile lists the keys of the tenants. The last key of a list encrypts new objects, all of them decrypt objects.
+//
+//	default:
+//	  - id: default-1
+//	    key: <base64 encoded 32 byte key>
+//	tenants:
+//	  tenant-a:
+//	    - id: tenant-a-1
+//	      key: <base64 encoded 32 byte key>
+type keyfile struct {
+	// Default keys are used for tenants without keys of their own
+	Default []keyfileKey            `yaml:"default"`
+	Tenants map[string][]keyfileKey `yaml:"tenants"`
+}
+
+type keyfileKey struct {
+	ID  string `yaml:"id"`
+	Key string `yaml:"key"`
+}
+
+type keyfileProvider struct {
+	defaultKeys []Key
+	tenantKeys  map[string][]Key
+}
+
+// NewKeyfileProvider returns a KeyProvider with the keys in the given file. The file is read once.
+func NewKeyfileProvider(path string) (KeyProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption keyfile: %w", err)
+	}
+
+	f := keyfile{}
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse encryption keyfile: %w", err)
+	}
+
+	p := &keyfileProvider{
+		tenantKeys: map[string][]Key{},
+	}
+	ids := map[string]struct{}{}
+
+	parse := func(keys []keyfileKey) ([]Key, error) {
+		parsed := make([]Key, 0, len(keys))
+		for _, k := range keys {
+			if k.ID == "" || len(k.ID) > maxKeyIDLength {
+				return nil, fmt.Errorf("key id %q must have 1 to %d bytes", k.ID, maxKeyIDLength)
+			}
+			if _, ok := ids[k.ID]; ok {
+				return nil, fmt.Errorf("duplicate key id %s", k.ID)
+			}
+			ids[k.ID] = struct{}{}
+
+			material, err := base64.StdEncoding.DecodeString(k.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode key %s: %w", k.ID, err)
+			}
+			if len(material) != dataKeySize {
+				return nil, fmt.Errorf("key %s must have %d bytes, has %d", k.ID, dataKeySize, len(material))
+			}
+
+			parsed = append(parsed, Key{ID: k.ID, Material: material})
+		}
+		return parsed, nil
+	}
+
+	p.defaultKeys, err = parse(f.Default)
+	if err != nil {
+		return nil, err
+	}
+	for tenant, keys := range f.Tenants {
+		p.tenantKeys[tenant], err = parse(keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// CurrentKey implements KeyProvider
+func (p *keyfileProvider) CurrentKey(_ context.Context, tenantID string) (Key, error) {
+	keys := p.keys(tenantID)
+	if len(keys) == 0 {
+		return Key{}, fmt.Errorf("no encryption key for tenant %s", tenantID)
+	}
+	return keys[len(keys)-1], nil
+}
+
+// Key implements KeyProvider
+func (p *keyfileProvider) Key(_ context.Context, tenantID string, keyID string) (Key, error) {
+	for _, k := range p.keys(tenantID) {
+		if k.ID == keyID {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("encryption key %s of tenant %s not found", keyID, tenantID)
+}
+
+func (p *keyfileProvider) keys(tenantID string) []Key {
+	if keys, ok := p.tenantKeys[tenantID]; ok {
+		return keys
+	}
+	return p.defaultKeys
+}
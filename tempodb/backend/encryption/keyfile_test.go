@@ -0,0 +1,105 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyfileProvider(t *testing.T) {
+	key := func(b byte) string {
+		return base64.StdEncoding.EncodeToString(bytes32(b))
+	}
+
+	tests := []struct {
+		name        string
+		keyfile     string
+		expectedErr bool
+	}{
+		{
+			name: "valid",
+			keyfile: `
+default:
+  - id: default-1
+    key: ` + key(1) + `
+tenants:
+  tenant-a:
+    - id: tenant-a-1
+      key: ` + key(2) + `
+    - id: tenant-a-2
+      key: ` + key(3),
+		},
+		{
+			name: "duplicate id",
+			keyfile: `
+default:
+  - id: key-1
+    key: ` + key(1) + `
+tenants:
+  tenant-a:
+    - id: key-1
+      key: ` + key(2),
+			expectedErr: true,
+		},
+		{
+			name: "short key",
+			keyfile: `
+default:
+  - id: key-1
+    key: ` + base64.StdEncoding.EncodeToString([]byte("short")),
+			expectedErr: true,
+		},
+		{
+			name: "unknown field",
+			keyfile: `
+keys:
+  - id: key-1`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.keyfile), 0o600))
+
+			p, err := NewKeyfileProvider(path)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := context.Background()
+
+			k, err := p.CurrentKey(ctx, "tenant-a")
+			require.NoError(t, err)
+			require.Equal(t, Key{ID: "tenant-a-2", Material: bytes32(3)}, k)
+
+			k, err = p.Key(ctx, "tenant-a", "tenant-a-1")
+			require.NoError(t, err)
+			require.Equal(t, Key{ID: "tenant-a-1", Material: bytes32(2)}, k)
+
+			// tenants without keys of their own use the default keys
+			k, err = p.CurrentKey(ctx, "tenant-b")
+			require.NoError(t, err)
+			require.Equal(t, "default-1", k.ID)
+
+			// tenants can't use the keys of other tenants
+			_, err = p.Key(ctx, "tenant-b", "tenant-a-1")
+			require.Error(t, err)
+		})
+	}
+}
+
+func bytes32(b byte) []byte {
+	key := make([]byte, dataKeySize)
+	for i := range key {
+		key[i] = b
+	}
+	return key
+}
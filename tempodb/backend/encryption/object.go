@@ -0,0 +1,257 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// An encrypted object starts with a fixed size header followed by chunks of a fixed size. The header holds the ID of
+// the tenant key and the data key of the object wrapped with it. Every chunk holds the length of its data, the data
+// and padding up to the chunk size, sealed with the data key. The last chunk is always shorter than the chunk size, so
+// the number of chunks and the size of the object are known without reading all of it.
+//
+//	header: magic (4) | version (1) | chunk size (4) | key id length (1) | key id (64) | nonce (12) | wrapped data key (48)
+//	chunk:  sealed(length (4) | data | padding) + tag (16)
+const (
+	magic          = "TENC"
+	formatVersion  = 1
+	maxKeyIDLength = 64
+	dataKeySize    = 32
+	nonceSize      = 12
+	tagSize        = 16
+	lengthSize     = 4
+
+	headerSize = len(magic) + 1 + 4 + 1 + maxKeyIDLength + nonceSize + dataKeySize + tagSize
+)
+
+var (
+	errNotEncrypted = errors.New("object is not encrypted")
+	errTruncated    = errors.New("encrypted object is truncated")
+)
+
+// header is the parsed header of an encrypted object
+type header struct {
+	chunkSize uint32
+	keyID     string
+	aead      cipher.AEAD
+}
+
+// newHeader creates a header with a new random data key wrapped with the tenant key. The name of the object is used
+// as additional data to bind the data key to the object.
+func newHeader(key Key, objectName string, chunkSize uint32) (*header, []byte, error) {
+	if len(key.ID) > maxKeyIDLength {
+		return nil, nil, fmt.Errorf("key id %s longer than %d bytes", key.ID, maxKeyIDLength)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	keyAEAD, err := newAEAD(key.Material)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chunkSizeBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(chunkSizeBytes, chunkSize)
+
+	b := make([]byte, 0, headerSize)
+	b = append(b, magic...)
+	b = append(b, formatVersion)
+	b = append(b, chunkSizeBytes...)
+	b = append(b, byte(len(key.ID)))
+	b = append(b, key.ID...)
+	b = append(b, make([]byte, maxKeyIDLength-len(key.ID))...)
+	b = append(b, nonce...)
+	b = keyAEAD.Seal(b, nonce, dataKey, []byte(objectName))
+
+	return &header{
+		chunkSize: chunkSize,
+		keyID:     key.ID,
+		aead:      aead,
+	}, b, nil
+}
+
+// parseHeaderKeyID returns the ID of the tenant key of the header. It returns errNotEncrypted if b is not the header
+// of an encrypted object.
+func parseHeaderKeyID(b []byte) (string, error) {
+	if len(b) < headerSize || string(b[:len(magic)]) != magic {
+		return "", errNotEncrypted
+	}
+
+	b = b[len(magic):]
+	if b[0] != formatVersion {
+		return "", fmt.Errorf("unsupported encryption format version %d", b[0])
+	}
+
+	keyIDLength := int(b[5])
+	if keyIDLength > maxKeyIDLength {
+		return "", fmt.Errorf("invalid key id length %d", keyIDLength)
+	}
+	return string(b[6 : 6+keyIDLength]), nil
+}
+
+// parseHeader unwraps the data key of the header with the tenant key.
+func parseHeader(b []byte, key Key, objectName string) (*header, error) {
+	if _, err := parseHeaderKeyID(b); err != nil {
+		return nil, err
+	}
+
+	b = b[len(magic)+1:]
+	chunkSize := binary.BigEndian.Uint32(b)
+	if chunkSize == 0 {
+		return nil, errors.New("invalid chunk size 0")
+	}
+
+	b = b[4+1+maxKeyIDLength:]
+	nonce, wrapped := b[:nonceSize], b[nonceSize:nonceSize+dataKeySize+tagSize]
+
+	keyAEAD, err := newAEAD(key.Material)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := keyAEAD.Open(nil, nonce, wrapped, []byte(objectName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with key %s: %w", key.ID, err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &header{
+		chunkSize: chunkSize,
+		keyID:     key.ID,
+		aead:      aead,
+	}, nil
+}
+
+// sealedChunkSize is the size of a chunk in the object
+func (h *header) sealedChunkSize() int {
+	return lengthSize + int(h.chunkSize) + tagSize
+}
+
+// sealChunk appends the sealed chunk with index i and the given data to dst
+func (h *header) sealChunk(dst []byte, i uint64, data []byte) []byte {
+	plain := make([]byte, lengthSize+int(h.chunkSize))
+	binary.BigEndian.PutUint32(plain, uint32(len(data)))
+	copy(plain[lengthSize:], data)
+
+	return h.aead.Seal(dst, chunkNonce(i), plain, nil)
+}
+
+// openChunk returns the data of the sealed chunk with index i and whether it's the last chunk of the object
+func (h *header) openChunk(i uint64, sealed []byte) ([]byte, bool, error) {
+	plain, err := h.aead.Open(nil, chunkNonce(i), sealed, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decrypt chunk %d: %w", i, err)
+	}
+
+	length := binary.BigEndian.Uint32(plain)
+	if length > h.chunkSize {
+		return nil, false, fmt.Errorf("invalid length %d of chunk %d", length, i)
+	}
+	return plain[lengthSize : lengthSize+length], length < h.chunkSize, nil
+}
+
+// sealedSize returns the size of an object holding size bytes of data
+func (h *header) sealedSize(size int64) int64 {
+	chunks := size/int64(h.chunkSize) + 1
+	return int64(headerSize) + chunks*int64(h.sealedChunkSize())
+}
+
+// open decrypts a whole object
+func (h *header) open(object []byte) ([]byte, error) {
+	sealed := object[headerSize:]
+	if len(sealed)%h.sealedChunkSize() != 0 {
+		return nil, errTruncated
+	}
+
+	chunks := len(sealed) / h.sealedChunkSize()
+	data := make([]byte, 0, chunks*int(h.chunkSize))
+	for i := 0; i < chunks; i++ {
+		chunk, last, err := h.openChunk(uint64(i), sealed[i*h.sealedChunkSize():(i+1)*h.sealedChunkSize()])
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+
+		if last != (i == chunks-1) {
+			return nil, errTruncated
+		}
+	}
+
+	return data, nil
+}
+
+func chunkNonce(i uint64) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce[nonceSize-8:], i)
+	return nonce
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealingReader encrypts the data read from r chunk by chunk
+type sealingReader struct {
+	h      *header
+	r      io.Reader
+	buf    []byte
+	data   []byte
+	sealed []byte
+	i      uint64
+	done   bool
+}
+
+func newSealingReader(h *header, headerBytes []byte, r io.Reader) *sealingReader {
+	return &sealingReader{
+		h:    h,
+		r:    r,
+		buf:  headerBytes,
+		data: make([]byte, h.chunkSize),
+	}
+}
+
+func (s *sealingReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(s.r, s.data)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, err
+		}
+
+		// a short read is the last chunk. if the data ends at a chunk boundary the next read returns an empty last chunk.
+		s.sealed = s.h.sealChunk(s.sealed[:0], s.i, s.data[:n])
+		s.buf = s.sealed
+		s.i++
+		s.done = n < len(s.data)
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package filesystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	lockFileName      = tempFilePrefix + "lock"
+	lockRetryInterval = 10 * time.Millisecond
+	clearRetries      = 10
+)
+
+var errLocked = errors.New("locked by another process")
+
+// MarkBlockCompacted implements backend.Compactor. The block is locked, so only one of the nodes marking a block
+// compacted at the same time succeeds.
+func (rw *Backend) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	unlock, err := rw.lockBlock(blockID, tenantID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	metaFilename := rw.metaFileName(blockID, tenantID)
+	b, err := os.ReadFile(metaFilename)
+	if err != nil {
+		return readError(err)
+	}
+
+	// the compacted meta is written instead of renamed, its modification time is the compacted time
+	err = writeFile(rw.compactedMetaFileName(blockID, tenantID), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(metaFilename)
+}
+
+// ClearBlock implements backend.Compactor
+func (rw *Backend) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return fmt.Errorf("empty tenant id")
+	}
+
+	if blockID == uuid.Nil {
+		return fmt.Errorf("empty block id")
+	}
+
+	unlock, err := rw.lockBlock(blockID, tenantID)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// nodes clearing the block at the same time recreate the lock file while it's removed. their lock is on the
+	// removed file, so the block is removed again.
+	path := rw.rootPath(backend.KeyPathForBlock(blockID, tenantID))
+	for i := 0; ; i++ {
+		err = os.RemoveAll(path)
+		if err == nil || !errors.Is(err, syscall.ENOTEMPTY) || i == clearRetries {
+			return err
+		}
+	}
+}
+
+// CompactedBlockMeta implements backend.Compactor
+func (rw *Backend) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	filename := rw.compactedMetaFileName(blockID, tenantID)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, readError(err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &backend.CompactedBlockMeta{}
+	err = json.NewDecoder(f).Decode(out)
+	if err != nil {
+		return nil, err
+	}
+	out.CompactedTime = fi.ModTime()
+
+	return out, nil
+}
+
+// lockBlock takes the advisory lock of the block. The lock is released if the process dies.
+func (rw *Backend) lockBlock(blockID uuid.UUID, tenantID string) (func(), error) {
+	// the lock file isn't created if the block doesn't exist
+	f, err := os.OpenFile(filepath.Join(rw.rootPath(backend.KeyPathForBlock(blockID, tenantID)), lockFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	deadline := time.Now().Add(rw.cfg.LockTimeout)
+	for {
+		err = lockFile(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLocked) || time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock block %s: %w", blockID, err)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func (rw *Backend) compactedMetaFileName(blockID uuid.UUID, tenantID string) string {
+	return filepath.Join(rw.rootPath(backend.KeyPathForBlock(blockID, tenantID)), backend.CompactedMetaName)
+}
+
+func (rw *Backend) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return fmt.Errorf("empty tenant id")
+	}
+
+	return os.RemoveAll(rw.rootPath(backend.KeyPathForDeletionRequest(requestID, tenantID)))
+}
@@ -0,0 +1,14 @@
+package filesystem
+
+import "time"
+
+type Config struct {
+	Path string `yaml:"path"`
+	// ReadRetries is the number of times a failed read is retried. Shared filesystems return transient errors, e.g.
+	// stale file handles or short reads of files written by another node. Short reads are only retried while the file
+	// changes.
+	ReadRetries      int           `yaml:"read_retries"`
+	ReadRetryBackoff time.Duration `yaml:"read_retry_backoff"`
+	// LockTimeout is how long to wait for the lock of a block held by another node
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+}
@@ -0,0 +1,295 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// tempFilePrefix marks objects that are still written. Temp files are hidden from List and renamed into place once
+// complete.
+const tempFilePrefix = "."
+
+// Backend stores blocks on a POSIX filesystem shared by multiple nodes, e.g. NFS or CephFS. Objects are written to a
+// temp file and atomically renamed into place, so readers on other nodes never see partial objects.
+type Backend struct {
+	cfg *Config
+}
+
+type appendTracker struct {
+	f        *os.File
+	filename string
+}
+
+var _ backend.RawReader = (*Backend)(nil)
+var _ backend.RawWriter = (*Backend)(nil)
+var _ backend.Compactor = (*Backend)(nil)
+
+func NewBackend(cfg *Config) (*Backend, error) {
+	err := os.MkdirAll(cfg.Path, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		cfg: cfg,
+	}, nil
+}
+
+func New(cfg *Config) (backend.RawReader, backend.RawWriter, backend.Compactor, error) {
+	rw, err := NewBackend(cfg)
+	return rw, rw, rw, err
+}
+
+// Write implements backend.Writer
+func (rw *Backend) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, _ int64, _ bool) error {
+	err := os.MkdirAll(rw.rootPath(keypath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(rw.objectFileName(keypath, name), data)
+}
+
+// Append implements backend.Writer
+func (rw *Backend) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	var t *appendTracker
+	if tracker == nil {
+		err := os.MkdirAll(rw.rootPath(keypath), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+
+		filename := rw.objectFileName(keypath, name)
+		f, err := createTempFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		t = &appendTracker{
+			f:        f,
+			filename: filename,
+		}
+	} else {
+		t = tracker.(*appendTracker)
+	}
+
+	_, err := t.f.Write(buffer)
+	if err != nil {
+		_ = t.f.Close()
+		_ = os.Remove(t.f.Name())
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// CloseAppend implements backend.Writer
+func (rw *Backend) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	if tracker == nil {
+		return nil
+	}
+
+	t := tracker.(*appendTracker)
+	return commitTempFile(t.f, t.filename)
+}
+
+// List implements backend.Reader
+func (rw *Backend) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
+	var folders []os.DirEntry
+	err := rw.retry(ctx, func() error {
+		var err error
+		folders, err = os.ReadDir(rw.rootPath(keypath))
+		return readError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]string, 0, len(folders))
+	for _, f := range folders {
+		if !f.IsDir() || strings.HasPrefix(f.Name(), tempFilePrefix) {
+			continue
+		}
+		objects = append(objects, f.Name())
+	}
+
+	return objects, nil
+}
+
+// Read implements backend.Reader
+func (rw *Backend) Read(ctx context.Context, name string, keypath backend.KeyPath, _ bool) (io.ReadCloser, int64, error) {
+	filename := rw.objectFileName(keypath, name)
+
+	var f *os.File
+	var size int64
+	err := rw.retry(ctx, func() error {
+		var err error
+		f, err = os.Open(filename)
+		if err != nil {
+			return readError(err)
+		}
+
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		size = stat.Size()
+
+		return nil
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return f, size, nil
+}
+
+// ReadRange implements backend.Reader
+func (rw *Backend) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "filesystem.ReadRange", opentracing.Tags{
+		"len":    len(buffer),
+		"offset": offset,
+	})
+	defer span.Finish()
+
+	filename := rw.objectFileName(keypath, name)
+
+	lastSize := int64(-1)
+	return rw.retryIf(ctx, func() (bool, error) {
+		// missing files are retried, a file written by another node may not be visible yet
+		f, err := os.Open(filename)
+		if err != nil {
+			return true, readError(err)
+		}
+		defer f.Close()
+
+		_, err = f.ReadAt(buffer, int64(offset))
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			return true, err
+		}
+
+		// a short read is only retried while the file is changing, its size may not be visible yet. ranges beyond
+		// the end of a file that doesn't change anymore fail right away.
+		stat, statErr := f.Stat()
+		if statErr != nil {
+			return true, err
+		}
+		changing := (lastSize >= 0 && stat.Size() != lastSize) || time.Since(stat.ModTime()) < rw.retryWindow()
+		lastSize = stat.Size()
+
+		return changing, err
+	})
+}
+
+// Shutdown implements backend.Reader
+func (rw *Backend) Shutdown() {
+
+}
+
+// retry calls f until it succeeds, the object doesn't exist or the retries are used up. The backoff doubles with
+// every retry.
+func (rw *Backend) retry(ctx context.Context, f func() error) error {
+	return rw.retryIf(ctx, func() (bool, error) {
+		err := f()
+		return !errors.Is(err, backend.ErrDoesNotExist), err
+	})
+}
+
+// retryIf calls f until it succeeds, returns an error that can't be retried or the retries are used up
+func (rw *Backend) retryIf(ctx context.Context, f func() (retryable bool, err error)) error {
+	backoff := rw.cfg.ReadRetryBackoff
+
+	for i := 0; ; i++ {
+		retryable, err := f()
+		if err == nil || !retryable || i >= rw.cfg.ReadRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryWindow returns the time reads are retried for
+func (rw *Backend) retryWindow() time.Duration {
+	return rw.cfg.ReadRetryBackoff * time.Duration(1<<rw.cfg.ReadRetries)
+}
+
+func (rw *Backend) objectFileName(keypath backend.KeyPath, name string) string {
+	return filepath.Join(rw.rootPath(keypath), name)
+}
+
+func (rw *Backend) metaFileName(blockID uuid.UUID, tenantID string) string {
+	return filepath.Join(rw.rootPath(backend.KeyPathForBlock(blockID, tenantID)), backend.MetaName)
+}
+
+func (rw *Backend) rootPath(keypath backend.KeyPath) string {
+	return filepath.Join(rw.cfg.Path, filepath.Join(keypath...))
+}
+
+// writeFile atomically replaces the file with the data
+func writeFile(filename string, data io.Reader) error {
+	f, err := createTempFile(filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, data)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return commitTempFile(f, filename)
+}
+
+// createTempFile creates a temp file next to the file. It is unique, so concurrent writers don't interfere.
+func createTempFile(filename string) (*os.File, error) {
+	dir, name := filepath.Split(filename)
+	return os.OpenFile(filepath.Join(dir, tempFilePrefix+name+".tmp-"+uuid.New().String()), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+}
+
+// commitTempFile syncs and closes the temp file and renames it to the file. The last rename wins if there are
+// concurrent writers.
+func commitTempFile(f *os.File, filename string) error {
+	err := f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	err = os.Rename(f.Name(), filename)
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
+
+func readError(err error) error {
+	if os.IsNotExist(err) {
+		return backend.ErrDoesNotExist
+	}
+
+	return err
+}
@@ -0,0 +1,244 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const testNodes = 4
+
+// newTestNodes creates multiple backends sharing one directory, like multiple nodes mounting the same filesystem
+func newTestNodes(t *testing.T) []*Backend {
+	dir := t.TempDir()
+
+	nodes := make([]*Backend, 0, testNodes)
+	for i := 0; i < testNodes; i++ {
+		rw, err := NewBackend(&Config{
+			Path:             dir,
+			ReadRetries:      3,
+			ReadRetryBackoff: time.Millisecond,
+			LockTimeout:      time.Second,
+		})
+		require.NoError(t, err)
+		nodes = append(nodes, rw)
+	}
+
+	return nodes
+}
+
+func TestReadWrite(t *testing.T) {
+	ctx := context.Background()
+	nodes := newTestNodes(t)
+	keypath := backend.KeyPathForBlock(uuid.New(), "test")
+
+	data := make([]byte, 100)
+	_, _ = rand.Read(data)
+	require.NoError(t, nodes[0].Write(ctx, "data", keypath, bytes.NewReader(data), int64(len(data)), false))
+
+	var tracker backend.AppendTracker
+	var err error
+	for i := 0; i < 10; i++ {
+		tracker, err = nodes[0].Append(ctx, "appended", keypath, tracker, data[i*10:(i+1)*10])
+		require.NoError(t, err)
+	}
+
+	// appended objects are not visible until they are closed
+	_, _, err = nodes[1].Read(ctx, "appended", keypath, false)
+	require.Equal(t, backend.ErrDoesNotExist, err)
+	require.NoError(t, nodes[0].CloseAppend(ctx, tracker))
+
+	for _, name := range []string{"data", "appended"} {
+		object, size, err := nodes[1].Read(ctx, name, keypath, false)
+		require.NoError(t, err)
+		actual, err := io.ReadAll(object)
+		require.NoError(t, err)
+		require.NoError(t, object.Close())
+		require.Equal(t, int64(len(data)), size)
+		require.Equal(t, data, actual)
+
+		buffer := make([]byte, 10)
+		require.NoError(t, nodes[2].ReadRange(ctx, name, keypath, 5, buffer))
+		require.Equal(t, data[5:15], buffer)
+	}
+
+	// short reads fail once the retries are used up
+	require.Error(t, nodes[2].ReadRange(ctx, "data", keypath, 95, make([]byte, 10)))
+
+	_, _, err = nodes[1].Read(ctx, "missing", keypath, false)
+	require.Equal(t, backend.ErrDoesNotExist, err)
+
+	// no temp files are listed or left behind
+	list, err := nodes[3].List(ctx, backend.KeyPath{"test"})
+	require.NoError(t, err)
+	require.Equal(t, []string{keypath[1]}, list)
+
+	files, err := os.ReadDir(nodes[0].rootPath(keypath))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+}
+
+func TestConcurrentMetaWrites(t *testing.T) {
+	ctx := context.Background()
+	nodes := newTestNodes(t)
+	w := backend.NewWriter(nodes[0])
+
+	tenantID := "test"
+	metas := make([]*backend.BlockMeta, 0, 100)
+	for i := 0; i < 100; i++ {
+		metas = append(metas, backend.NewBlockMeta(tenantID, uuid.New(), "v2", backend.EncNone, ""))
+	}
+	require.NoError(t, w.WriteTenantIndex(ctx, tenantID, metas, nil, nil))
+
+	// every node rewrites the tenant index while the others read it. readers never see a partial index.
+	wg := sync.WaitGroup{}
+	stop := make(chan struct{})
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *Backend) {
+			defer wg.Done()
+
+			r := backend.NewReader(n)
+			w := backend.NewWriter(n)
+			for i := 0; i < 20; i++ {
+				require.NoError(t, w.WriteTenantIndex(ctx, tenantID, metas, nil, nil))
+
+				idx, err := r.TenantIndex(ctx, tenantID)
+				require.NoError(t, err)
+				require.Len(t, idx.Meta, len(metas))
+			}
+		}(n)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		r := backend.NewReader(nodes[0])
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			idx, err := r.TenantIndex(ctx, tenantID)
+			require.NoError(t, err)
+			require.Len(t, idx.Meta, len(metas))
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+}
+
+func TestConcurrentCompaction(t *testing.T) {
+	ctx := context.Background()
+	nodes := newTestNodes(t)
+
+	meta := backend.NewBlockMeta("test", uuid.New(), "v2", backend.EncNone, "")
+	require.NoError(t, backend.NewWriter(nodes[0]).WriteBlockMeta(ctx, meta))
+
+	// only one node marks the block compacted
+	marked := atomic.NewInt32(0)
+	wg := sync.WaitGroup{}
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *Backend) {
+			defer wg.Done()
+
+			err := n.MarkBlockCompacted(meta.BlockID, meta.TenantID)
+			if err == nil {
+				marked.Inc()
+				return
+			}
+			require.Equal(t, backend.ErrDoesNotExist, err)
+		}(n)
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), marked.Load())
+
+	_, err := backend.NewReader(nodes[1]).BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	require.Equal(t, backend.ErrDoesNotExist, err)
+
+	compactedMeta, err := nodes[2].CompactedBlockMeta(meta.BlockID, meta.TenantID)
+	require.NoError(t, err)
+	require.Equal(t, meta.BlockID, compactedMeta.BlockID)
+	require.WithinDuration(t, time.Now(), compactedMeta.CompactedTime, time.Minute)
+
+	// all nodes clear the block
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *Backend) {
+			defer wg.Done()
+			require.NoError(t, n.ClearBlock(meta.BlockID, meta.TenantID))
+		}(n)
+	}
+	wg.Wait()
+
+	_, err = os.Stat(nodes[0].rootPath(backend.KeyPathForBlock(meta.BlockID, meta.TenantID)))
+	require.True(t, os.IsNotExist(err))
+	_, err = nodes[3].CompactedBlockMeta(meta.BlockID, meta.TenantID)
+	require.Equal(t, backend.ErrDoesNotExist, err)
+}
+
+func TestLockTimeout(t *testing.T) {
+	ctx := context.Background()
+	nodes := newTestNodes(t)
+	nodes[1].cfg.LockTimeout = 50 * time.Millisecond
+
+	meta := backend.NewBlockMeta("test", uuid.New(), "v2", backend.EncNone, "")
+	require.NoError(t, backend.NewWriter(nodes[0]).WriteBlockMeta(ctx, meta))
+
+	unlock, err := nodes[0].lockBlock(meta.BlockID, meta.TenantID)
+	require.NoError(t, err)
+
+	err = nodes[1].MarkBlockCompacted(meta.BlockID, meta.TenantID)
+	require.ErrorIs(t, err, errLocked)
+
+	unlock()
+	require.NoError(t, nodes[1].MarkBlockCompacted(meta.BlockID, meta.TenantID))
+}
+
+func TestReadRetries(t *testing.T) {
+	ctx := context.Background()
+	nodes := newTestNodes(t)
+	keypath := backend.KeyPathForBlock(uuid.New(), "test")
+
+	data := []byte("data")
+	require.NoError(t, nodes[0].Write(ctx, "data", keypath, bytes.NewReader(data), int64(len(data)), false))
+
+	// the object is completed by another node while the read is retried
+	go func() {
+		time.Sleep(2 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(nodes[0].rootPath(keypath), "data"), append(data, data...), 0o644)
+	}()
+
+	nodes[1].cfg.ReadRetries = 10
+	buffer := make([]byte, 8)
+	require.NoError(t, nodes[1].ReadRange(ctx, "data", keypath, 0, buffer))
+	require.Equal(t, fmt.Sprintf("%s%s", data, data), string(buffer))
+
+	// reads beyond the end of a file that doesn't change anymore are not retried
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(nodes[0].rootPath(keypath), "data"), old, old))
+	nodes[1].cfg.ReadRetryBackoff = time.Second
+	start := time.Now()
+	err := nodes[1].ReadRange(ctx, "data", keypath, 4, buffer)
+	require.ErrorIs(t, err, io.EOF)
+	require.Less(t, time.Since(start), time.Second)
+}
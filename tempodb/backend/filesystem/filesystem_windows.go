@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package filesystem
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(_ *os.File) error {
+	return errors.New("the filesystem backend is not supported on windows")
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
+
+func syncDir(_ string) error {
+	return nil
+}
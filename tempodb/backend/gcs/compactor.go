@@ -79,3 +79,16 @@ func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (
 
 	return out, nil
 }
+
+func (rw *readerWriter) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return fmt.Errorf("empty tenant id")
+	}
+
+	name := backend.ObjectFileName(backend.KeyPathForDeletionRequest(requestID, tenantID), backend.DeletionRequestName)
+	err := rw.bucket.Object(name).Delete(context.TODO())
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	return err
+}
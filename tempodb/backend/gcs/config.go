@@ -13,4 +13,6 @@ type Config struct {
 	Insecure           bool              `yaml:"insecure"`
 	ObjectCacheControl string            `yaml:"object_cache_control"`
 	ObjectMetadata     map[string]string `yaml:"object_metadata"`
+	// DisableRetries disables the retries of the client. It is set if the requests are retried by tempodb.
+	DisableRetries bool `yaml:"-"`
 }
@@ -279,6 +279,9 @@ func createBucket(ctx context.Context, cfg *Config, hedge bool) (*storage.Bucket
 	if err != nil {
 		return nil, errors.Wrap(err, "creating storage client")
 	}
+	if cfg.DisableRetries {
+		client.SetRetry(storage.WithPolicy(storage.RetryNever))
+	}
 
 	// build bucket
 	return client.Bucket(cfg.BucketName), nil
@@ -56,3 +56,11 @@ func (rw *Backend) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*back
 func (rw *Backend) compactedMetaFileName(blockID uuid.UUID, tenantID string) string {
 	return path.Join(rw.rootPath(backend.KeyPathForBlock(blockID, tenantID)), backend.CompactedMetaName)
 }
+
+func (rw *Backend) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return fmt.Errorf("empty tenant id")
+	}
+
+	return os.RemoveAll(rw.rootPath(backend.KeyPathForDeletionRequest(requestID, tenantID)))
+}
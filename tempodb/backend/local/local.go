@@ -101,7 +101,7 @@ func (rw *Backend) List(ctx context.Context, keypath backend.KeyPath) ([]string,
 	path := rw.rootPath(keypath)
 	folders, err := os.ReadDir(path)
 	if err != nil {
-		return nil, err
+		return nil, readError(err)
 	}
 
 	objects := make([]string, 0, len(folders))
@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultLRUSize is the number of blocks or objects the wrappers of the backends keep in memory
+const DefaultLRUSize = 10_000
+
+// LRU is a least recently used cache that is safe for concurrent use
+type LRU struct {
+	mtx sync.Mutex
+	lru *simplelru.LRU
+}
+
+// NewLRU creates a cache that keeps size entries
+func NewLRU(size int) (*LRU, error) {
+	lru, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LRU{lru: lru}, nil
+}
+
+// Get returns the value of the key
+func (l *LRU) Get(key string) (interface{}, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.lru.Get(key)
+}
+
+// Add sets the value of the key
+func (l *LRU) Add(key string, value interface{}) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.lru.Add(key, value)
+}
+
+// Update sets the value of the key to the value returned by f. f is called with the current value while the cache is
+// locked.
+func (l *LRU) Update(key string, f func(value interface{}, ok bool) interface{}) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	value, ok := l.lru.Get(key)
+	l.lru.Add(key, f(value, ok))
+}
+
+// Remove removes the key
+func (l *LRU) Remove(key string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.lru.Remove(key)
+}
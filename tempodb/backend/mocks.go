@@ -84,6 +84,10 @@ func (c *MockCompactor) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (
 	return c.BlockMetaFn(blockID, tenantID)
 }
 
+func (c *MockCompactor) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	return nil
+}
+
 // MockReader
 type MockReader struct {
 	T             []string
@@ -140,12 +144,21 @@ func (m *MockReader) TenantIndex(ctx context.Context, tenantID string) (*TenantI
 	return &TenantIndex{}, nil
 }
 
+func (m *MockReader) DeletionRequestIDs(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (m *MockReader) DeletionRequest(ctx context.Context, requestID uuid.UUID, tenantID string) (*DeletionRequest, error) {
+	return nil, ErrDoesNotExist
+}
+
 func (m *MockReader) Shutdown() {}
 
 // MockWriter
 type MockWriter struct {
 	IndexMeta          map[string][]*BlockMeta
 	IndexCompactedMeta map[string][]*CompactedBlockMeta
+	IndexDeletions     map[string]*TenantDeletions
 }
 
 func (m *MockWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte, shouldCache bool) error {
@@ -163,14 +176,21 @@ func (m *MockWriter) Append(ctx context.Context, name string, blockID uuid.UUID,
 func (m *MockWriter) CloseAppend(ctx context.Context, tracker AppendTracker) error {
 	return nil
 }
-func (m *MockWriter) WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta) error {
+func (m *MockWriter) WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta, deletions *TenantDeletions) error {
 	if m.IndexMeta == nil {
 		m.IndexMeta = make(map[string][]*BlockMeta)
 	}
 	if m.IndexCompactedMeta == nil {
 		m.IndexCompactedMeta = make(map[string][]*CompactedBlockMeta)
 	}
+	if m.IndexDeletions == nil {
+		m.IndexDeletions = make(map[string]*TenantDeletions)
+	}
 	m.IndexMeta[tenantID] = meta
 	m.IndexCompactedMeta[tenantID] = compactedMeta
+	m.IndexDeletions[tenantID] = deletions
+	return nil
+}
+func (m *MockWriter) WriteDeletionRequest(ctx context.Context, req *DeletionRequest) error {
 	return nil
 }
@@ -0,0 +1,63 @@
+package ratelimit
+
+import "time"
+
+// DefaultThrottleMaxBackoff caps the backoff if ThrottleMaxBackoff isn't set
+const DefaultThrottleMaxBackoff = 10 * time.Second
+
+// Config of the rate limits of the requests to the backend. The limits apply to every process separately.
+type Config struct {
+	Limits `yaml:",inline"`
+
+	// Components replaces the limits above for processes running the given target, e.g. querier or compactor
+	Components map[string]Limits `yaml:"components"`
+
+	// Component is the target of the process, it is set by the app
+	Component string `yaml:"-"`
+}
+
+// Limits of the requests to the backend per operation
+type Limits struct {
+	List      OperationLimits `yaml:"list"`
+	Read      OperationLimits `yaml:"read"`
+	ReadRange OperationLimits `yaml:"read_range"`
+	Write     OperationLimits `yaml:"write"`
+	Delete    OperationLimits `yaml:"delete"`
+
+	// ThrottleBackoff is how long requests of an operation are held back after the backend throttled it, e.g. with
+	// 503 SlowDown. It doubles with every throttled request up to ThrottleMaxBackoff and is reset by a successful one.
+	ThrottleBackoff    time.Duration `yaml:"throttle_backoff"`
+	ThrottleMaxBackoff time.Duration `yaml:"throttle_max_backoff"`
+}
+
+// OperationLimits are the limits of one operation. Zero values disable the limit.
+type OperationLimits struct {
+	// RequestsPerSecond is the rate of the token bucket, Burst its size
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	MaxInFlight       int     `yaml:"max_in_flight"`
+}
+
+// LimitsForComponent returns the limits of the component of the process
+func (cfg *Config) LimitsForComponent() Limits {
+	if cfg == nil {
+		return Limits{}
+	}
+
+	if l, ok := cfg.Components[cfg.Component]; ok {
+		return l
+	}
+
+	return cfg.Limits
+}
+
+// Enabled returns true if any requests are limited
+func (l Limits) Enabled() bool {
+	for _, o := range []OperationLimits{l.List, l.Read, l.ReadRange, l.Write, l.Delete} {
+		if o.RequestsPerSecond > 0 || o.MaxInFlight > 0 {
+			return true
+		}
+	}
+
+	return l.ThrottleBackoff > 0
+}
@@ -0,0 +1,348 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	blob "github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/googleapi"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	opList      = "list"
+	opRead      = "read"
+	opReadRange = "read_range"
+	opWrite     = "write"
+	opDelete    = "delete"
+)
+
+var (
+	metricWaitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tempodb",
+		Name:      "backend_rate_limit_wait_duration_seconds",
+		Help:      "Time requests to the backend waited for the rate limits.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"operation"})
+	metricInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "backend_rate_limit_in_flight_requests",
+		Help:      "Number of requests to the backend in flight.",
+	}, []string{"operation"})
+	metricThrottled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_throttled_requests_total",
+		Help:      "Total number of requests throttled by the backend.",
+	}, []string{"operation"})
+)
+
+type readerWriter struct {
+	nextReader    backend.RawReader
+	nextWriter    backend.RawWriter
+	nextCompactor backend.Compactor
+
+	list      *limiter
+	read      *limiter
+	readRange *limiter
+	write     *limiter
+	delete    *limiter
+}
+
+var _ backend.RawReader = (*readerWriter)(nil)
+var _ backend.RawWriter = (*readerWriter)(nil)
+var _ backend.Compactor = (*readerWriter)(nil)
+
+// New wraps the reader, writer and compactor to limit the rate and the number of in flight requests of every operation.
+// Deleting and marking blocks compacted are delete operations.
+func New(nextReader backend.RawReader, nextWriter backend.RawWriter, nextCompactor backend.Compactor, limits Limits) (backend.RawReader, backend.RawWriter, backend.Compactor) {
+	rw := &readerWriter{
+		nextReader:    nextReader,
+		nextWriter:    nextWriter,
+		nextCompactor: nextCompactor,
+
+		list:      newLimiter(opList, limits.List, limits),
+		read:      newLimiter(opRead, limits.Read, limits),
+		readRange: newLimiter(opReadRange, limits.ReadRange, limits),
+		write:     newLimiter(opWrite, limits.Write, limits),
+		delete:    newLimiter(opDelete, limits.Delete, limits),
+	}
+
+	return rw, rw, rw
+}
+
+// List implements backend.RawReader
+func (rw *readerWriter) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
+	done, err := rw.list.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := rw.nextReader.List(ctx, keypath)
+	done(err)
+	return objects, err
+}
+
+// Read implements backend.RawReader
+func (rw *readerWriter) Read(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+	done, err := rw.read.acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	object, size, err := rw.nextReader.Read(ctx, name, keypath, shouldCache)
+	done(err)
+	return object, size, err
+}
+
+// ReadRange implements backend.RawReader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	done, err := rw.readRange.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = rw.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	done(err)
+	return err
+}
+
+// Shutdown implements backend.RawReader
+func (rw *readerWriter) Shutdown() {
+	rw.nextReader.Shutdown()
+}
+
+// Write implements backend.RawWriter
+func (rw *readerWriter) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	done, err := rw.write.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = rw.nextWriter.Write(ctx, name, keypath, data, size, shouldCache)
+	done(err)
+	return err
+}
+
+// Append implements backend.RawWriter
+func (rw *readerWriter) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	done, err := rw.write.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker, err = rw.nextWriter.Append(ctx, name, keypath, tracker, buffer)
+	done(err)
+	return tracker, err
+}
+
+// CloseAppend implements backend.RawWriter
+func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	done, err := rw.write.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = rw.nextWriter.CloseAppend(ctx, tracker)
+	done(err)
+	return err
+}
+
+// MarkBlockCompacted implements backend.Compactor
+func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	done, err := rw.delete.acquire(context.Background())
+	if err != nil {
+		return err
+	}
+
+	err = rw.nextCompactor.MarkBlockCompacted(blockID, tenantID)
+	done(err)
+	return err
+}
+
+// ClearDeletionRequest implements backend.Compactor
+func (rw *readerWriter) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	done, err := rw.delete.acquire(context.Background())
+	if err != nil {
+		return err
+	}
+
+	err = rw.nextCompactor.ClearDeletionRequest(requestID, tenantID)
+	done(err)
+	return err
+}
+
+// ClearBlock implements backend.Compactor
+func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	done, err := rw.delete.acquire(context.Background())
+	if err != nil {
+		return err
+	}
+
+	err = rw.nextCompactor.ClearBlock(blockID, tenantID)
+	done(err)
+	return err
+}
+
+// CompactedBlockMeta implements backend.Compactor
+func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	done, err := rw.read.acquire(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := rw.nextCompactor.CompactedBlockMeta(blockID, tenantID)
+	done(err)
+	return meta, err
+}
+
+// limiter limits the requests of one operation
+type limiter struct {
+	op         string
+	rate       *rate.Limiter
+	inFlight   chan struct{}
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mtx          sync.Mutex
+	backoff      time.Duration
+	backoffUntil time.Time
+}
+
+func newLimiter(op string, o OperationLimits, limits Limits) *limiter {
+	l := &limiter{
+		op:         op,
+		minBackoff: limits.ThrottleBackoff,
+		maxBackoff: limits.ThrottleMaxBackoff,
+	}
+	if l.maxBackoff <= 0 {
+		l.maxBackoff = DefaultThrottleMaxBackoff
+	}
+
+	if o.RequestsPerSecond > 0 {
+		burst := o.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		l.rate = rate.NewLimiter(rate.Limit(o.RequestsPerSecond), burst)
+	}
+
+	if o.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, o.MaxInFlight)
+	}
+
+	return l
+}
+
+// acquire waits until the operation isn't backed off anymore, for a token and for a free in flight slot. done must
+// be called with the result of the request.
+func (l *limiter) acquire(ctx context.Context) (done func(error), err error) {
+	start := time.Now()
+	defer func() {
+		metricWaitDuration.WithLabelValues(l.op).Observe(time.Since(start).Seconds())
+	}()
+
+	l.mtx.Lock()
+	wait := time.Until(l.backoffUntil)
+	l.mtx.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.rate != nil {
+		err = l.rate.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.inFlight != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case l.inFlight <- struct{}{}:
+		}
+	}
+
+	inFlight := metricInFlight.WithLabelValues(l.op)
+	inFlight.Inc()
+
+	return func(err error) {
+		inFlight.Dec()
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+		l.observe(err)
+	}, nil
+}
+
+// observe backs off the operation if the backend throttled the request
+func (l *limiter) observe(err error) {
+	throttled := isThrottled(err)
+	if throttled {
+		metricThrottled.WithLabelValues(l.op).Inc()
+	}
+
+	if l.minBackoff <= 0 {
+		return
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !throttled {
+		if err == nil {
+			l.backoff = 0
+		}
+		return
+	}
+
+	l.backoff *= 2
+	if l.backoff < l.minBackoff {
+		l.backoff = l.minBackoff
+	}
+	if l.backoff > l.maxBackoff {
+		l.backoff = l.maxBackoff
+	}
+	l.backoffUntil = time.Now().Add(l.backoff)
+}
+
+// isThrottled returns true if the backend rejected the request because of its request rate
+func isThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var s3Err minio.ErrorResponse
+	if errors.As(err, &s3Err) {
+		return s3Err.Code == "SlowDown" || s3Err.StatusCode == http.StatusServiceUnavailable || s3Err.StatusCode == http.StatusTooManyRequests
+	}
+
+	var gcsErr *googleapi.Error
+	if errors.As(err, &gcsErr) {
+		return gcsErr.Code == http.StatusServiceUnavailable || gcsErr.Code == http.StatusTooManyRequests
+	}
+
+	var azureErr blob.StorageError
+	if errors.As(err, &azureErr) {
+		return azureErr.ServiceCode() == blob.ServiceCodeServerBusy
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/api/googleapi"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// testReader counts the requests in flight and fails them with err
+type testReader struct {
+	backend.MockRawReader
+
+	delay       time.Duration
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	requests    atomic.Int32
+
+	mtx sync.Mutex
+	err error
+}
+
+func (r *testReader) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	r.requests.Inc()
+	n := r.inFlight.Inc()
+	defer r.inFlight.Dec()
+
+	for {
+		max := r.maxInFlight.Load()
+		if n <= max || r.maxInFlight.CAS(max, n) {
+			break
+		}
+	}
+
+	time.Sleep(r.delay)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.err
+}
+
+func (r *testReader) setErr(err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.err = err
+}
+
+func TestMaxInFlight(t *testing.T) {
+	next := &testReader{delay: 10 * time.Millisecond}
+	r, _, _ := New(next, &backend.MockRawWriter{}, &backend.MockCompactor{}, Limits{
+		ReadRange: OperationLimits{MaxInFlight: 3},
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(20), next.requests.Load())
+	require.Equal(t, int32(3), next.maxInFlight.Load())
+}
+
+func TestRequestsPerSecond(t *testing.T) {
+	next := &testReader{}
+	r, _, _ := New(next, &backend.MockRawWriter{}, &backend.MockCompactor{}, Limits{
+		ReadRange: OperationLimits{RequestsPerSecond: 100, Burst: 5},
+	})
+
+	// the burst is spent right away, the other requests wait for the rate
+	start := time.Now()
+	for i := 0; i < 15; i++ {
+		require.NoError(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	// other operations are not limited
+	start = time.Now()
+	for i := 0; i < 15; i++ {
+		_, err := r.List(context.Background(), backend.KeyPath{"test"})
+		require.NoError(t, err)
+	}
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestWaitCancelled(t *testing.T) {
+	r, _, _ := New(&testReader{}, &backend.MockRawWriter{}, &backend.MockCompactor{}, Limits{
+		ReadRange: OperationLimits{RequestsPerSecond: 0.1},
+	})
+
+	require.NoError(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+}
+
+func TestThrottleBackoff(t *testing.T) {
+	next := &testReader{}
+	r, _, _ := New(next, &backend.MockRawWriter{}, &backend.MockCompactor{}, Limits{
+		ThrottleBackoff:    20 * time.Millisecond,
+		ThrottleMaxBackoff: 40 * time.Millisecond,
+	})
+	l := r.(*readerWriter).readRange
+
+	next.setErr(minio.ErrorResponse{Code: "SlowDown", StatusCode: http.StatusServiceUnavailable})
+	require.Error(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+	require.Equal(t, 20*time.Millisecond, l.backoff)
+
+	// the next request is held back and the backoff doubles up to the max
+	start := time.Now()
+	require.Error(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+	require.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+	require.Equal(t, 40*time.Millisecond, l.backoff)
+	require.Error(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+	require.Equal(t, 40*time.Millisecond, l.backoff)
+
+	// other errors keep the backoff, a successful request resets it
+	next.setErr(errors.New("other"))
+	require.Error(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+	require.Equal(t, 40*time.Millisecond, l.backoff)
+
+	next.setErr(nil)
+	require.NoError(t, r.ReadRange(context.Background(), "test", backend.KeyPath{"test"}, 0, nil))
+	require.Equal(t, time.Duration(0), l.backoff)
+
+	// other operations are not backed off
+	require.Equal(t, time.Duration(0), r.(*readerWriter).list.backoff)
+}
+
+func TestIsThrottled(t *testing.T) {
+	tcs := []struct {
+		err       error
+		throttled bool
+	}{
+		{err: nil},
+		{err: errors.New("test")},
+		{err: backend.ErrDoesNotExist},
+		{err: minio.ErrorResponse{Code: "SlowDown"}, throttled: true},
+		{err: minio.ErrorResponse{StatusCode: http.StatusServiceUnavailable}, throttled: true},
+		{err: fmt.Errorf("wrapped: %w", minio.ErrorResponse{Code: "SlowDown"}), throttled: true},
+		{err: minio.ErrorResponse{Code: "NoSuchKey", StatusCode: http.StatusNotFound}},
+		{err: &googleapi.Error{Code: http.StatusTooManyRequests}, throttled: true},
+		{err: &googleapi.Error{Code: http.StatusServiceUnavailable}, throttled: true},
+		{err: &googleapi.Error{Code: http.StatusForbidden}},
+	}
+
+	for _, tc := range tcs {
+		require.Equal(t, tc.throttled, isThrottled(tc.err), "%v", tc.err)
+	}
+}
+
+func TestLimitsForComponent(t *testing.T) {
+	cfg := &Config{
+		Limits: Limits{List: OperationLimits{MaxInFlight: 1}},
+		Components: map[string]Limits{
+			"compactor": {Write: OperationLimits{MaxInFlight: 2}},
+		},
+	}
+
+	require.Equal(t, cfg.Limits, cfg.LimitsForComponent())
+
+	cfg.Component = "compactor"
+	require.Equal(t, cfg.Components["compactor"], cfg.LimitsForComponent())
+
+	cfg = nil
+	require.False(t, cfg.LimitsForComponent().Enabled())
+}
+
+func TestConfigYAML(t *testing.T) {
+	cfg := &Config{}
+	err := yaml.UnmarshalStrict([]byte(`
+list:
+  max_in_flight: 1
+throttle_backoff: 1s
+components:
+  compactor:
+    write:
+      requests_per_second: 10
+      burst: 20
+`), cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, cfg.List.MaxInFlight)
+	require.Equal(t, time.Second, cfg.ThrottleBackoff)
+	require.Equal(t, OperationLimits{RequestsPerSecond: 10, Burst: 20}, cfg.Components["compactor"].Write)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"path"
@@ -83,15 +84,15 @@ func (w *writer) CloseAppend(ctx context.Context, tracker AppendTracker) error {
 	return w.w.CloseAppend(ctx, tracker)
 }
 
-func (w *writer) WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta) error {
-	b := newTenantIndex(meta, compactedMeta)
+func (w *writer) WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta, deletions *TenantDeletions) error {
+	b := newTenantIndex(meta, compactedMeta, deletions)
 
 	indexBytes, err := b.marshal()
 	if err != nil {
 		return err
 	}
 
-	err = w.w.Write(ctx, TenantIndexName, KeyPath([]string{tenantID}), bytes.NewReader(indexBytes), int64(len(indexBytes)), false)
+	err = w.w.Write(ctx, TenantIndexName, KeyPath([]string{tenantID}), bytes.NewReader(indexBytes), int64(len(indexBytes)), true)
 	if err != nil {
 		return err
 	}
@@ -99,6 +100,15 @@ func (w *writer) WriteTenantIndex(ctx context.Context, tenantID string, meta []*
 	return nil
 }
 
+func (w *writer) WriteDeletionRequest(ctx context.Context, req *DeletionRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	return w.w.Write(ctx, DeletionRequestName, KeyPathForDeletionRequest(req.ID, req.TenantID), bytes.NewReader(b), int64(len(b)), false)
+}
+
 type reader struct {
 	r RawReader
 }
@@ -142,7 +152,7 @@ func (r *reader) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, erro
 	for _, id := range objects {
 		// TODO: this line exists due to behavior differences in backends: https://github.com/grafana/tempo/issues/880
 		// revisit once #880 is resolved.
-		if id == TenantIndexName || id == "" {
+		if id == TenantIndexName || id == DeletionsName || id == "" {
 			continue
 		}
 		uuid, err := uuid.Parse(id)
@@ -177,7 +187,8 @@ func (r *reader) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID stri
 }
 
 func (r *reader) TenantIndex(ctx context.Context, tenantID string) (*TenantIndex, error) {
-	reader, size, err := r.r.Read(ctx, TenantIndexName, KeyPath([]string{tenantID}), false)
+	// the tenant index is only cached if its cache policy sets a cache
+	reader, size, err := r.r.Read(ctx, TenantIndexName, KeyPath([]string{tenantID}), true)
 	if err != nil {
 		return nil, err
 	}
@@ -196,6 +207,48 @@ func (r *reader) TenantIndex(ctx context.Context, tenantID string) (*TenantIndex
 	return i, nil
 }
 
+func (r *reader) DeletionRequestIDs(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	ids, err := r.r.List(ctx, KeyPath{tenantID, DeletionsName})
+	if errors.Is(err, ErrDoesNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	requestIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		requestID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", id, err)
+		}
+		requestIDs = append(requestIDs, requestID)
+	}
+
+	return requestIDs, nil
+}
+
+func (r *reader) DeletionRequest(ctx context.Context, requestID uuid.UUID, tenantID string) (*DeletionRequest, error) {
+	reader, size, err := r.r.Read(ctx, DeletionRequestName, KeyPathForDeletionRequest(requestID, tenantID), false)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bytes, err := tempo_io.ReadAllWithEstimate(reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &DeletionRequest{}
+	err = json.Unmarshal(bytes, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
 func (r *reader) Shutdown() {
 	r.r.Shutdown()
 }
@@ -205,6 +258,11 @@ func KeyPathForBlock(blockID uuid.UUID, tenantID string) KeyPath {
 	return []string{tenantID, blockID.String()}
 }
 
+// BlockPath returns a unique identifier for the block or directory at the keypath
+func BlockPath(keypath KeyPath) string {
+	return path.Join(keypath...)
+}
+
 // ObjectFileName returns a unique identifier for an object in object storage given its name and keypath
 func ObjectFileName(keypath KeyPath, name string) string {
 	return path.Join(path.Join(keypath...), name)
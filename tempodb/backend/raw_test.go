@@ -36,7 +36,7 @@ func TestWriter(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, m.writeBuffer)
 
-	err = w.WriteTenantIndex(ctx, "test", []*BlockMeta{meta}, nil)
+	err = w.WriteTenantIndex(ctx, "test", []*BlockMeta{meta}, nil, nil)
 	assert.NoError(t, err)
 
 	idx := &TenantIndex{}
@@ -45,6 +45,12 @@ func TestWriter(t *testing.T) {
 
 	assert.True(t, cmp.Equal([]*BlockMeta{meta}, idx.Meta))                  // using cmp.Equal to compare json datetimes
 	assert.True(t, cmp.Equal([]*CompactedBlockMeta(nil), idx.CompactedMeta)) // using cmp.Equal to compare json datetimes
+
+	req := NewDeletionRequest("test", []string{"1234"})
+	expected, _ = json.Marshal(req)
+	err = w.WriteDeletionRequest(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, m.writeBuffer)
 }
 
 func TestReader(t *testing.T) {
@@ -77,6 +83,12 @@ func TestReader(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedBlocks, actualBlocks)
 
+	// deletion requests are not blocks
+	m.L = []string{uuid1.String(), DeletionsName, uuid2.String()}
+	actualBlocks, err = r.Blocks(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedBlocks, actualBlocks)
+
 	// should fail b/c meta is not valid
 	meta, err := r.BlockMeta(ctx, uuid.New(), "test")
 	assert.Error(t, err)
@@ -93,11 +105,30 @@ func TestReader(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, idx)
 
-	expectedIdx := newTenantIndex([]*BlockMeta{expectedMeta}, nil)
+	expectedIdx := newTenantIndex([]*BlockMeta{expectedMeta}, nil, &TenantDeletions{Tombstones: []string{"1"}, CacheGeneration: "2"})
 	m.R, _ = expectedIdx.marshal()
 	idx, err = r.TenantIndex(ctx, "test")
 	assert.NoError(t, err)
 	assert.True(t, cmp.Equal(expectedIdx, idx))
+
+	expectedReq := NewDeletionRequest("test", nil)
+	m.L = []string{expectedReq.ID.String()}
+	m.R, _ = json.Marshal(expectedReq)
+	reqIDs, err := r.DeletionRequestIDs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{expectedReq.ID}, reqIDs)
+
+	req, err := r.DeletionRequest(ctx, expectedReq.ID, "test")
+	assert.NoError(t, err)
+	assert.True(t, cmp.Equal(expectedReq, req))
+
+	// no deletion requests
+	m.ListFn = func(ctx context.Context, keypath KeyPath) ([]string, error) {
+		return nil, ErrDoesNotExist
+	}
+	reqIDs, err = r.DeletionRequestIDs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Empty(t, reqIDs)
 }
 
 func TestKeyPathForBlock(t *testing.T) {
@@ -107,3 +138,11 @@ func TestKeyPathForBlock(t *testing.T) {
 
 	assert.Equal(t, KeyPath([]string{tid, b.String()}), keypath)
 }
+
+func TestKeyPathForDeletionRequest(t *testing.T) {
+	id := uuid.New()
+	tid := "test"
+	keypath := KeyPathForDeletionRequest(id, tid)
+
+	assert.Equal(t, KeyPath([]string{tid, DeletionsName, id.String()}), keypath)
+}
@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	OpList      = "list"
+	OpRead      = "read"
+	OpReadRange = "read_range"
+	OpWrite     = "write"
+	OpAppend    = "append"
+	OpDelete    = "delete"
+)
+
+// Config of the retries and the circuit breaker of the requests to the backend
+type Config struct {
+	Policy `yaml:",inline"`
+
+	// Operations replaces the policy above for the given operations
+	Operations map[string]Policy `yaml:"operations"`
+
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+}
+
+// Policy is the retry policy of an operation. The backoff between retries is jittered and doubles with every retry.
+type Policy struct {
+	MaxRetries int           `yaml:"max_retries"`
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures trips the circuit breaker. 0 disables the circuit breaker.
+	ConsecutiveFailures uint `yaml:"consecutive_failures"`
+	// Timeout is how long the circuit breaker stays open before it lets a request through
+	Timeout time.Duration `yaml:"timeout"`
+	// Interval resets the failure count of the closed circuit breaker. 0 never resets it.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Enabled returns true if requests are retried or the circuit breaker is enabled
+func (cfg *Config) Enabled() bool {
+	if cfg == nil {
+		return false
+	}
+
+	return cfg.Retries() || cfg.CircuitBreaker.ConsecutiveFailures > 0
+}
+
+// Retries returns true if the requests of any operation are retried
+func (cfg *Config) Retries() bool {
+	if cfg == nil {
+		return false
+	}
+
+	if cfg.MaxRetries > 0 {
+		return true
+	}
+
+	for _, p := range cfg.Operations {
+		if p.MaxRetries > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate returns an error if the config is invalid
+func (cfg *Config) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+
+	for op := range cfg.Operations {
+		switch op {
+		case OpList, OpRead, OpReadRange, OpWrite, OpDelete:
+		case OpAppend:
+			return fmt.Errorf("backend retry policy for %s is not supported, appends are not idempotent", op)
+		default:
+			return fmt.Errorf("unknown backend operation %s in retry policies", op)
+		}
+	}
+
+	return nil
+}
+
+func (cfg *Config) policy(op string) Policy {
+	if p, ok := cfg.Operations[op]; ok {
+		return p
+	}
+
+	return cfg.Policy
+}
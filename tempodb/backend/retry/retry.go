@@ -0,0 +1,304 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sony/gobreaker"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// ErrCircuitOpen is returned without calling the backend while the circuit breaker is open
+var ErrCircuitOpen = errors.New("backend circuit breaker is open")
+
+var (
+	metricRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_retries_total",
+		Help:      "Total number of retried requests to the backend.",
+	}, []string{"backend", "operation"})
+	metricFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_request_failures_total",
+		Help:      "Total number of requests to the backend that failed after all retries.",
+	}, []string{"backend", "operation"})
+	metricCircuitOpen = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_circuit_breaker_rejected_requests_total",
+		Help:      "Total number of requests to the backend rejected by the open circuit breaker.",
+	}, []string{"backend", "operation"})
+	metricCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "backend_circuit_breaker_state",
+		Help:      "State of the circuit breaker of the backend. 0 closed, 1 half-open, 2 open.",
+	}, []string{"backend"})
+)
+
+type readerWriter struct {
+	name          string
+	cfg           *Config
+	logger        log.Logger
+	cb            *gobreaker.CircuitBreaker
+	nextReader    backend.RawReader
+	nextWriter    backend.RawWriter
+	nextCompactor backend.Compactor
+}
+
+var _ backend.RawReader = (*readerWriter)(nil)
+var _ backend.RawWriter = (*readerWriter)(nil)
+var _ backend.Compactor = (*readerWriter)(nil)
+
+// New wraps the reader, writer and compactor to retry failed requests and to stop sending requests to the backend
+// while it keeps failing. Data of writes that can't be read again is buffered in memory to retry them. Appends are never retried, a failed
+// append may have been applied partially. Objects that don't exist are not failures.
+func New(name string, nextReader backend.RawReader, nextWriter backend.RawWriter, nextCompactor backend.Compactor, cfg *Config, logger log.Logger) (backend.RawReader, backend.RawWriter, backend.Compactor) {
+	rw := &readerWriter{
+		name:          name,
+		cfg:           cfg,
+		logger:        logger,
+		nextReader:    nextReader,
+		nextWriter:    nextWriter,
+		nextCompactor: nextCompactor,
+	}
+
+	if cfg.CircuitBreaker.ConsecutiveFailures > 0 {
+		rw.cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:          name,
+			Interval:      cfg.CircuitBreaker.Interval,
+			Timeout:       cfg.CircuitBreaker.Timeout,
+			OnStateChange: rw.circuitBreakerStateChange,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= uint32(cfg.CircuitBreaker.ConsecutiveFailures)
+			},
+		})
+		metricCircuitState.WithLabelValues(name).Set(float64(gobreaker.StateClosed))
+	}
+
+	return rw, rw, rw
+}
+
+// List implements backend.RawReader
+func (rw *readerWriter) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
+	var objects []string
+	err := rw.retry(ctx, OpList, func() error {
+		var err error
+		objects, err = rw.nextReader.List(ctx, keypath)
+		return err
+	})
+	return objects, err
+}
+
+// Read implements backend.RawReader
+func (rw *readerWriter) Read(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+	var object io.ReadCloser
+	var size int64
+	err := rw.retry(ctx, OpRead, func() error {
+		var err error
+		object, size, err = rw.nextReader.Read(ctx, name, keypath, shouldCache)
+		return err
+	})
+	return object, size, err
+}
+
+// ReadRange implements backend.RawReader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	return rw.retry(ctx, OpReadRange, func() error {
+		return rw.nextReader.ReadRange(ctx, name, keypath, offset, buffer)
+	})
+}
+
+// Shutdown implements backend.RawReader
+func (rw *readerWriter) Shutdown() {
+	rw.nextReader.Shutdown()
+}
+
+// Write implements backend.RawWriter
+func (rw *readerWriter) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	// the data is rewound before every retry. streams that can't be rewound, like the data sealed by the encryption,
+	// are buffered in memory if the writes are retried.
+	seeker, ok := data.(io.Seeker)
+	if !ok {
+		if rw.cfg.policy(OpWrite).MaxRetries <= 0 {
+			return rw.once(OpWrite, func() error {
+				return rw.nextWriter.Write(ctx, name, keypath, data, size, shouldCache)
+			})
+		}
+
+		buffer, err := tempo_io.ReadAllWithEstimate(data, size)
+		if err != nil {
+			return err
+		}
+		reader := bytes.NewReader(buffer)
+		data, seeker = reader, reader
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	attempt := 0
+	return rw.retry(ctx, OpWrite, func() error {
+		if attempt > 0 {
+			_, err := seeker.Seek(start, io.SeekStart)
+			if err != nil {
+				return permanentError{err}
+			}
+		}
+		attempt++
+
+		return rw.nextWriter.Write(ctx, name, keypath, data, size, shouldCache)
+	})
+}
+
+// Append implements backend.RawWriter
+func (rw *readerWriter) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	err := rw.once(OpAppend, func() error {
+		var err error
+		tracker, err = rw.nextWriter.Append(ctx, name, keypath, tracker, buffer)
+		return err
+	})
+	return tracker, err
+}
+
+// CloseAppend implements backend.RawWriter
+func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	return rw.once(OpAppend, func() error {
+		return rw.nextWriter.CloseAppend(ctx, tracker)
+	})
+}
+
+// MarkBlockCompacted implements backend.Compactor
+func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	return rw.retry(context.Background(), OpDelete, func() error {
+		return rw.nextCompactor.MarkBlockCompacted(blockID, tenantID)
+	})
+}
+
+// ClearDeletionRequest implements backend.Compactor
+func (rw *readerWriter) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	return rw.retry(context.Background(), OpDelete, func() error {
+		return rw.nextCompactor.ClearDeletionRequest(requestID, tenantID)
+	})
+}
+
+// ClearBlock implements backend.Compactor
+func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	return rw.retry(context.Background(), OpDelete, func() error {
+		return rw.nextCompactor.ClearBlock(blockID, tenantID)
+	})
+}
+
+// CompactedBlockMeta implements backend.Compactor
+func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	var meta *backend.CompactedBlockMeta
+	err := rw.retry(context.Background(), OpRead, func() error {
+		var err error
+		meta, err = rw.nextCompactor.CompactedBlockMeta(blockID, tenantID)
+		return err
+	})
+	return meta, err
+}
+
+// retry calls f until it succeeds, fails with an error that isn't retried or the retries of the policy of the
+// operation are used up
+func (rw *readerWriter) retry(ctx context.Context, op string, f func() error) error {
+	p := rw.cfg.policy(op)
+	b := backoff.New(ctx, backoff.Config{
+		MinBackoff: p.MinBackoff,
+		MaxBackoff: p.MaxBackoff,
+	})
+
+	for {
+		err := rw.call(op, f)
+		if err == nil {
+			return nil
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			err = permanent.err
+		}
+		if !retryable(err) || permanent.err != nil || b.NumRetries() >= p.MaxRetries {
+			if !errors.Is(err, backend.ErrDoesNotExist) {
+				metricFailures.WithLabelValues(rw.name, op).Inc()
+			}
+			return err
+		}
+
+		b.Wait()
+		if b.Err() != nil {
+			return err
+		}
+		metricRetries.WithLabelValues(rw.name, op).Inc()
+	}
+}
+
+// once calls f without retrying it
+func (rw *readerWriter) once(op string, f func() error) error {
+	err := rw.call(op, f)
+	if err != nil && !errors.Is(err, backend.ErrDoesNotExist) {
+		metricFailures.WithLabelValues(rw.name, op).Inc()
+	}
+	return err
+}
+
+// call calls f through the circuit breaker
+func (rw *readerWriter) call(op string, f func() error) error {
+	if rw.cb == nil {
+		return f()
+	}
+
+	// errors that aren't failures of the backend are passed around the circuit breaker, so they don't trip it
+	var err error
+	_, cbErr := rw.cb.Execute(func() (interface{}, error) {
+		err = f()
+		if err != nil && !isFailure(err) {
+			return nil, nil
+		}
+		return nil, err
+	})
+
+	if errors.Is(cbErr, gobreaker.ErrOpenState) || errors.Is(cbErr, gobreaker.ErrTooManyRequests) {
+		metricCircuitOpen.WithLabelValues(rw.name, op).Inc()
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, rw.name)
+	}
+
+	return err
+}
+
+func (rw *readerWriter) circuitBreakerStateChange(name string, from gobreaker.State, to gobreaker.State) {
+	level.Info(rw.logger).Log("msg", "backend circuit-breaker state change", "name", name, "from-state", from, "to-state", to)
+	metricCircuitState.WithLabelValues(name).Set(float64(to))
+}
+
+// permanentError is not retried
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// isFailure returns true if the error is a failure of the backend
+func isFailure(err error) bool {
+	return !errors.Is(err, backend.ErrDoesNotExist) && !errors.Is(err, context.Canceled)
+}
+
+// retryable returns true if the request may succeed if it is retried
+func retryable(err error) bool {
+	return isFailure(err) && !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, backend.ErrEmptyTenantID) && !errors.Is(err, backend.ErrEmptyBlockID)
+}
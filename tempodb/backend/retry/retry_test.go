@@ -0,0 +1,220 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var errTest = errors.New("test")
+
+// testBackend fails the first failures requests of every operation
+type testBackend struct {
+	backend.MockRawReader
+	backend.MockCompactor
+
+	failures int
+	err      error
+	calls    map[string]int
+	written  []string
+}
+
+func newTestBackend(failures int, err error) *testBackend {
+	return &testBackend{
+		failures: failures,
+		err:      err,
+		calls:    map[string]int{},
+	}
+}
+
+func (b *testBackend) call(op string) error {
+	b.calls[op]++
+	if b.calls[op] <= b.failures {
+		return b.err
+	}
+	return nil
+}
+
+func (b *testBackend) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
+	return []string{"test"}, b.call(OpList)
+}
+
+func (b *testBackend) ReadRange(ctx context.Context, name string, keypath backend.KeyPath, offset uint64, buffer []byte) error {
+	return b.call(OpReadRange)
+}
+
+func (b *testBackend) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	d, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	b.written = append(b.written, string(d))
+	return b.call(OpWrite)
+}
+
+func (b *testBackend) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	return tracker, b.call(OpAppend)
+}
+
+func (b *testBackend) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	return b.call(OpAppend)
+}
+
+func (b *testBackend) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	return b.call(OpDelete)
+}
+
+func newTestRetry(next *testBackend, cfg *Config) (backend.RawReader, backend.RawWriter, backend.Compactor) {
+	return New("test", next, next, next, cfg, log.NewNopLogger())
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{
+		Policy: Policy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+	}
+
+	// succeeds with the last retry
+	next := newTestBackend(2, errTest)
+	r, w, c := newTestRetry(next, cfg)
+
+	_, err := r.List(ctx, backend.KeyPath{"test"})
+	require.NoError(t, err)
+	require.NoError(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+	require.NoError(t, c.ClearBlock(uuid.New(), "test"))
+	require.Equal(t, 3, next.calls[OpList])
+	require.Equal(t, 3, next.calls[OpReadRange])
+	require.Equal(t, 3, next.calls[OpDelete])
+
+	// the data of writes is rewound for every retry
+	require.NoError(t, w.Write(ctx, "test", backend.KeyPath{"test"}, bytes.NewReader([]byte("data")), 4, false))
+	require.Equal(t, []string{"data", "data", "data"}, next.written)
+
+	// streams that can't be rewound are buffered
+	next = newTestBackend(2, errTest)
+	_, w, _ = newTestRetry(next, cfg)
+	require.NoError(t, w.Write(ctx, "test", backend.KeyPath{"test"}, io.NopCloser(strings.NewReader("data")), 4, false))
+	require.Equal(t, []string{"data", "data", "data"}, next.written)
+
+	// fails once the retries are used up
+	next = newTestBackend(3, errTest)
+	r, _, _ = newTestRetry(next, cfg)
+	require.ErrorIs(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil), errTest)
+	require.Equal(t, 3, next.calls[OpReadRange])
+}
+
+func TestRetryPolicies(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{
+		Policy: Policy{MaxRetries: 5},
+		Operations: map[string]Policy{
+			OpList: {MaxRetries: 1},
+		},
+	}
+
+	next := newTestBackend(10, errTest)
+	r, _, _ := newTestRetry(next, cfg)
+
+	_, err := r.List(ctx, backend.KeyPath{"test"})
+	require.ErrorIs(t, err, errTest)
+	require.Error(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+	require.Equal(t, 2, next.calls[OpList])
+	require.Equal(t, 6, next.calls[OpReadRange])
+}
+
+func TestNotRetried(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{
+		Policy: Policy{MaxRetries: 5},
+	}
+
+	// objects that don't exist
+	next := newTestBackend(10, backend.ErrDoesNotExist)
+	r, _, _ := newTestRetry(next, cfg)
+	require.Equal(t, backend.ErrDoesNotExist, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+	require.Equal(t, 1, next.calls[OpReadRange])
+
+	// appends
+	next = newTestBackend(10, errTest)
+	_, w, _ := newTestRetry(next, cfg)
+	_, err := w.Append(ctx, "test", backend.KeyPath{"test"}, nil, []byte("data"))
+	require.ErrorIs(t, err, errTest)
+	require.ErrorIs(t, w.CloseAppend(ctx, nil), errTest)
+	require.Equal(t, 2, next.calls[OpAppend])
+
+	// cancelled requests
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	next = newTestBackend(10, context.Canceled)
+	r, _, _ = newTestRetry(next, cfg)
+	require.ErrorIs(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil), context.Canceled)
+	require.Equal(t, 1, next.calls[OpReadRange])
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{
+		CircuitBreaker: CircuitBreakerConfig{
+			ConsecutiveFailures: 3,
+			Timeout:             50 * time.Millisecond,
+		},
+	}
+
+	next := newTestBackend(3, errTest)
+	r, _, _ := newTestRetry(next, cfg)
+
+	// objects that don't exist don't trip the circuit breaker
+	next.err = backend.ErrDoesNotExist
+	for i := 0; i < 3; i++ {
+		require.Equal(t, backend.ErrDoesNotExist, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+	}
+	next.calls = map[string]int{}
+
+	next.err = errTest
+	for i := 0; i < 3; i++ {
+		require.ErrorIs(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil), errTest)
+	}
+
+	// the open circuit breaker rejects requests of all operations
+	require.ErrorIs(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil), ErrCircuitOpen)
+	_, err := r.List(ctx, backend.KeyPath{"test"})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 3, next.calls[OpReadRange])
+	require.Equal(t, 0, next.calls[OpList])
+
+	// and lets a request through after the timeout
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+	require.NoError(t, r.ReadRange(ctx, "test", backend.KeyPath{"test"}, 0, nil))
+}
+
+func TestValidate(t *testing.T) {
+	var cfg *Config
+	require.NoError(t, cfg.Validate())
+	require.False(t, cfg.Enabled())
+
+	cfg = &Config{Operations: map[string]Policy{OpReadRange: {MaxRetries: 1}}}
+	require.NoError(t, cfg.Validate())
+	require.True(t, cfg.Enabled())
+	require.True(t, cfg.Retries())
+
+	cfg = &Config{CircuitBreaker: CircuitBreakerConfig{ConsecutiveFailures: 1}}
+	require.True(t, cfg.Enabled())
+	require.False(t, cfg.Retries())
+
+	cfg = &Config{Operations: map[string]Policy{OpAppend: {MaxRetries: 1}}}
+	require.Error(t, cfg.Validate())
+
+	cfg = &Config{Operations: map[string]Policy{"foo": {}}}
+	require.Error(t, cfg.Validate())
+}
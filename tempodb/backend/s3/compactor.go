@@ -91,3 +91,12 @@ func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (
 
 	return out, nil
 }
+
+func (rw *readerWriter) ClearDeletionRequest(requestID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return backend.ErrEmptyTenantID
+	}
+
+	name := backend.ObjectFileName(backend.KeyPathForDeletionRequest(requestID, tenantID), backend.DeletionRequestName)
+	return rw.core.RemoveObject(context.TODO(), rw.cfg.Bucket, name, minio.RemoveObjectOptions{})
+}
@@ -20,4 +20,6 @@ type Config struct {
 	// SignatureV2 configures the object storage to use V2 signing instead of V4
 	SignatureV2    bool `yaml:"signature_v2"`
 	ForcePathStyle bool `yaml:"forcepathstyle"`
+	// DisableRetries disables the retries of the client. It is set if the requests are retried by tempodb.
+	DisableRetries bool `yaml:"-"`
 }
@@ -373,6 +373,11 @@ func createCore(cfg *Config, hedge bool) (*minio.Core, error) {
 		opts.BucketLookup = minio.BucketLookupPath
 	}
 
+	// the retries of minio can only be set for all clients. every request is sent once.
+	if cfg.DisableRetries {
+		minio.MaxRetry = 1
+	}
+
 	return minio.NewCore(cfg.Endpoint, opts)
 }
 
@@ -18,13 +18,15 @@ type TenantIndex struct {
 	CreatedAt     time.Time             `json:"created_at"`
 	Meta          []*BlockMeta          `json:"meta"`
 	CompactedMeta []*CompactedBlockMeta `json:"compacted"`
+	Deletions     *TenantDeletions      `json:"deletions,omitempty"`
 }
 
-func newTenantIndex(meta []*BlockMeta, compactedMeta []*CompactedBlockMeta) *TenantIndex {
+func newTenantIndex(meta []*BlockMeta, compactedMeta []*CompactedBlockMeta, deletions *TenantDeletions) *TenantIndex {
 	return &TenantIndex{
 		CreatedAt:     time.Now(),
 		Meta:          meta,
 		CompactedMeta: compactedMeta,
+		Deletions:     deletions,
 	}
 }
 
@@ -13,6 +13,9 @@ type PerTenant map[string][]*backend.BlockMeta
 // PerTenantCompacted is a map of tenant ids to backend.CompactedBlockMetas
 type PerTenantCompacted map[string][]*backend.CompactedBlockMeta
 
+// PerTenantDeletions is a map of tenant ids to backend.TenantDeletions
+type PerTenantDeletions map[string]*backend.TenantDeletions
+
 // List controls access to a per tenant blocklist and compacted blocklist
 type List struct {
 	mtx            sync.Mutex
@@ -74,6 +74,8 @@ type PollerConfig struct {
 	TenantIndexBuilders int
 	StaleTenantIndex    time.Duration
 	PollJitterMs        int
+	// TenantDeletions returns the deletions tenant index builders record in the tenant index. Optional.
+	TenantDeletions func(ctx context.Context, tenantID string) (*backend.TenantDeletions, error)
 }
 
 // JobSharder is used to determine if a particular job is owned by this process
@@ -93,11 +95,19 @@ func (ownsNothingSharder) Owns(_ string) bool {
 
 const jobPrefix = "build-tenant-index-"
 
+// Tier is a backend the blocks of the tenants are polled from in addition to the backend of the poller
+type Tier struct {
+	Name      string
+	Reader    backend.Reader
+	Compactor backend.Compactor
+}
+
 // Poller retrieves the blocklist
 type Poller struct {
-	reader    backend.Reader
-	writer    backend.Writer
-	compactor backend.Compactor
+	reader backend.Reader
+	writer backend.Writer
+	// tiers are polled in order, the first one is the backend of the poller
+	tiers []Tier
 
 	cfg *PollerConfig
 
@@ -105,12 +115,13 @@ type Poller struct {
 	logger  log.Logger
 }
 
-// NewPoller creates the Poller
-func NewPoller(cfg *PollerConfig, sharder JobSharder, reader backend.Reader, compactor backend.Compactor, writer backend.Writer, logger log.Logger) *Poller {
+// NewPoller creates the Poller. The tenant index is read from and written to the backend of the poller, it lists the
+// blocks of all tiers.
+func NewPoller(cfg *PollerConfig, sharder JobSharder, reader backend.Reader, compactor backend.Compactor, writer backend.Writer, logger log.Logger, tiers ...Tier) *Poller {
 	return &Poller{
-		reader:    reader,
-		compactor: compactor,
-		writer:    writer,
+		reader: reader,
+		writer: writer,
+		tiers:  append([]Tier{{Reader: reader, Compactor: compactor}}, tiers...),
 
 		cfg:     cfg,
 		sharder: sharder,
@@ -118,31 +129,33 @@ func NewPoller(cfg *PollerConfig, sharder JobSharder, reader backend.Reader, com
 	}
 }
 
-// Do does the doing of getting a blocklist
-func (p *Poller) Do() (PerTenant, PerTenantCompacted, error) {
+// Do does the doing of getting a blocklist and the deletions of the tenants
+func (p *Poller) Do() (PerTenant, PerTenantCompacted, PerTenantDeletions, error) {
 	start := time.Now()
 	defer func() { metricBlocklistPollDuration.Observe(time.Since(start).Seconds()) }()
 
 	ctx := context.Background()
-	tenants, err := p.reader.Tenants(ctx)
+	tenants, tenantTiers, err := p.tenants(ctx)
 	if err != nil {
 		metricBlocklistErrors.WithLabelValues("").Inc()
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	blocklist := PerTenant{}
 	compactedBlocklist := PerTenantCompacted{}
+	deletions := PerTenantDeletions{}
 
 	for _, tenantID := range tenants {
-		newBlockList, newCompactedBlockList, err := p.pollTenantAndCreateIndex(ctx, tenantID)
+		newBlockList, newCompactedBlockList, newDeletions, err := p.pollTenantAndCreateIndex(ctx, tenantID, tenantTiers[tenantID])
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		metricBlocklistLength.WithLabelValues(tenantID).Set(float64(len(newBlockList)))
 
 		blocklist[tenantID] = newBlockList
 		compactedBlocklist[tenantID] = newCompactedBlockList
+		deletions[tenantID] = newDeletions
 
 		backendMetaMetrics := sumTotalBackendMetaMetrics(newBlockList, newCompactedBlockList)
 		metricBackendObjects.WithLabelValues(tenantID, blockStatusLiveLabel).Set(float64(backendMetaMetrics.blockMetaTotalObjects))
@@ -151,10 +164,10 @@ func (p *Poller) Do() (PerTenant, PerTenantCompacted, error) {
 		metricBackendBytes.WithLabelValues(tenantID, blockStatusCompactedLabel).Set(float64(backendMetaMetrics.compactedBlockMetaTotalBytes))
 	}
 
-	return blocklist, compactedBlocklist, nil
+	return blocklist, compactedBlocklist, deletions, nil
 }
 
-func (p *Poller) pollTenantAndCreateIndex(ctx context.Context, tenantID string) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta, error) {
+func (p *Poller) pollTenantAndCreateIndex(ctx context.Context, tenantID string, tiers []Tier) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta, *backend.TenantDeletions, error) {
 	// are we a tenant index builder?
 	if !p.buildTenantIndex(tenantID) {
 		metricTenantIndexBuilder.WithLabelValues(tenantID).Set(0)
@@ -165,14 +178,14 @@ func (p *Poller) pollTenantAndCreateIndex(ctx context.Context, tenantID string)
 			// success! return the retrieved index
 			metricTenantIndexAgeSeconds.WithLabelValues(tenantID).Set(float64(time.Since(i.CreatedAt) / time.Second))
 			level.Info(p.logger).Log("msg", "successfully pulled tenant index", "tenant", tenantID, "createdAt", i.CreatedAt, "metas", len(i.Meta), "compactedMetas", len(i.CompactedMeta))
-			return i.Meta, i.CompactedMeta, nil
+			return i.Meta, i.CompactedMeta, i.Deletions, nil
 		}
 
 		metricTenantIndexErrors.WithLabelValues(tenantID).Inc()
 
 		// there was an error, return the error if we're not supposed to fallback to polling
 		if !p.cfg.PollFallback {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		// polling fallback is true, log the error and continue in this method to completely poll the backend
@@ -182,25 +195,93 @@ func (p *Poller) pollTenantAndCreateIndex(ctx context.Context, tenantID string)
 	// if we're here then we have been configured to be a tenant index builder OR there was a failure to pull
 	// the tenant index and we are configured to fall back to polling
 	metricTenantIndexBuilder.WithLabelValues(tenantID).Set(1)
-	blocklist, compactedBlocklist, err := p.pollTenantBlocks(ctx, tenantID)
+	blocklist, compactedBlocklist, err := p.pollTenantBlocks(ctx, tenantID, tiers)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	var deletions *backend.TenantDeletions
+	if p.cfg.TenantDeletions != nil {
+		deletions, err = p.cfg.TenantDeletions(ctx, tenantID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
 	// everything is happy, write this tenant index
 	level.Info(p.logger).Log("msg", "writing tenant index", "tenant", tenantID, "metas", len(blocklist), "compactedMetas", len(compactedBlocklist))
-	err = p.writer.WriteTenantIndex(ctx, tenantID, blocklist, compactedBlocklist)
+	err = p.writer.WriteTenantIndex(ctx, tenantID, blocklist, compactedBlocklist, deletions)
 	if err != nil {
 		metricTenantIndexErrors.WithLabelValues(tenantID).Inc()
 		level.Error(p.logger).Log("msg", "failed to write tenant index", "tenant", tenantID, "err", err)
 	}
 	metricTenantIndexAgeSeconds.WithLabelValues(tenantID).Set(0)
 
-	return blocklist, compactedBlocklist, nil
+	return blocklist, compactedBlocklist, deletions, nil
+}
+
+// tenants returns the tenants of all tiers and the tiers each tenant has blocks in
+func (p *Poller) tenants(ctx context.Context) ([]string, map[string][]Tier, error) {
+	tenants := []string{}
+	tenantTiers := map[string][]Tier{}
+	for _, t := range p.tiers {
+		tierTenants, err := t.Reader.Tenants(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, tenantID := range tierTenants {
+			tiers, ok := tenantTiers[tenantID]
+			if !ok {
+				tenants = append(tenants, tenantID)
+			}
+			if len(tiers) > 0 && tiers[len(tiers)-1].Name == t.Name {
+				continue
+			}
+			tenantTiers[tenantID] = append(tiers, t)
+		}
+	}
+
+	return tenants, tenantTiers, nil
+}
+
+func (p *Poller) pollTenantBlocks(ctx context.Context, tenantID string, tiers []Tier) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta, error) {
+	if len(tiers) == 1 {
+		return p.pollTierBlocks(ctx, tiers[0], tenantID)
+	}
+
+	newBlockList := []*backend.BlockMeta{}
+	newCompactedBlocklist := []*backend.CompactedBlockMeta{}
+	live := map[uuid.UUID]struct{}{}
+	for _, t := range tiers {
+		blocklist, compactedBlocklist, err := p.pollTierBlocks(ctx, t, tenantID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// a block is live in two tiers if its migration was interrupted. the block in the first tier is kept so
+		//  that it's migrated again.
+		for _, m := range blocklist {
+			if _, ok := live[m.BlockID]; !ok {
+				live[m.BlockID] = struct{}{}
+				newBlockList = append(newBlockList, m)
+			}
+		}
+		newCompactedBlocklist = append(newCompactedBlocklist, compactedBlocklist...)
+	}
+
+	sort.Slice(newBlockList, func(i, j int) bool {
+		return newBlockList[i].StartTime.Before(newBlockList[j].StartTime)
+	})
+	sort.Slice(newCompactedBlocklist, func(i, j int) bool {
+		return newCompactedBlocklist[i].StartTime.Before(newCompactedBlocklist[j].StartTime)
+	})
+
+	return newBlockList, newCompactedBlocklist, nil
 }
 
-func (p *Poller) pollTenantBlocks(ctx context.Context, tenantID string) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta, error) {
-	blockIDs, err := p.reader.Blocks(ctx, tenantID)
+func (p *Poller) pollTierBlocks(ctx context.Context, t Tier, tenantID string) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta, error) {
+	blockIDs, err := t.Reader.Blocks(ctx, tenantID)
 	if err != nil {
 		metricBlocklistErrors.WithLabelValues(tenantID).Inc()
 		return []*backend.BlockMeta{}, []*backend.CompactedBlockMeta{}, err
@@ -220,7 +301,7 @@ func (p *Poller) pollTenantBlocks(ctx context.Context, tenantID string) ([]*back
 				time.Sleep(time.Duration(rand.Intn(p.cfg.PollJitterMs)) * time.Millisecond)
 			}
 
-			m, cm, err := p.pollBlock(ctx, tenantID, uuid)
+			m, cm, err := p.pollBlock(ctx, t, tenantID, uuid)
 			if m != nil {
 				chMeta <- m
 			} else if cm != nil {
@@ -259,13 +340,13 @@ func (p *Poller) pollTenantBlocks(ctx context.Context, tenantID string) ([]*back
 	return newBlockList, newCompactedBlocklist, nil
 }
 
-func (p *Poller) pollBlock(ctx context.Context, tenantID string, blockID uuid.UUID) (*backend.BlockMeta, *backend.CompactedBlockMeta, error) {
+func (p *Poller) pollBlock(ctx context.Context, t Tier, tenantID string, blockID uuid.UUID) (*backend.BlockMeta, *backend.CompactedBlockMeta, error) {
 	var compactedBlockMeta *backend.CompactedBlockMeta
-	blockMeta, err := p.reader.BlockMeta(ctx, blockID, tenantID)
+	blockMeta, err := t.Reader.BlockMeta(ctx, blockID, tenantID)
 	// if the normal meta doesn't exist maybe it's compacted.
 	if err == backend.ErrDoesNotExist {
 		blockMeta = nil
-		compactedBlockMeta, err = p.compactor.CompactedBlockMeta(blockID, tenantID)
+		compactedBlockMeta, err = t.Compactor.CompactedBlockMeta(blockID, tenantID)
 	}
 
 	// blocks in intermediate states may not have a compacted or normal block meta.
@@ -278,6 +359,14 @@ func (p *Poller) pollBlock(ctx context.Context, tenantID string, blockID uuid.UU
 		return nil, nil, err
 	}
 
+	// the tier the block is found in is the one it's read from
+	if blockMeta != nil {
+		blockMeta.Tier = t.Name
+	}
+	if compactedBlockMeta != nil {
+		compactedBlockMeta.Tier = t.Name
+	}
+
 	return blockMeta, compactedBlockMeta, nil
 }
 
@@ -154,7 +154,7 @@ func TestTenantIndexBuilder(t *testing.T) {
 			}, &mockJobSharder{
 				owns: true,
 			}, r, c, w, log.NewNopLogger())
-			actualList, actualCompactedList, err := poller.Do()
+			actualList, actualCompactedList, _, err := poller.Do()
 
 			// confirm return as expected
 			assert.Equal(t, tc.expectedList, actualList)
@@ -255,7 +255,7 @@ func TestTenantIndexFallback(t *testing.T) {
 			}, &mockJobSharder{
 				owns: tc.isTenantIndexBuilder,
 			}, r, c, w, log.NewNopLogger())
-			_, _, err := poller.Do()
+			_, _, _, err := poller.Do()
 
 			assert.Equal(t, tc.expectsError, err != nil)
 			assert.Equal(t, tc.expectsTenantIndexWritten, w.IndexCompactedMeta != nil)
@@ -264,6 +264,46 @@ func TestTenantIndexFallback(t *testing.T) {
 	}
 }
 
+func TestTenantIndexDeletions(t *testing.T) {
+	deletions := &backend.TenantDeletions{
+		Tombstones:      []string{"1"},
+		CacheGeneration: "2",
+	}
+
+	for _, builder := range []bool{true, false} {
+		r := newMockReader(PerTenant{
+			"test": []*backend.BlockMeta{},
+		}, nil, false)
+		r.(*backend.MockReader).TenantIndexFn = func(ctx context.Context, tenantID string) (*backend.TenantIndex, error) {
+			return &backend.TenantIndex{
+				CreatedAt: time.Now(),
+				Deletions: deletions,
+			}, nil
+		}
+		w := &backend.MockWriter{}
+
+		poller := NewPoller(&PollerConfig{
+			PollConcurrency:     testPollConcurrency,
+			TenantIndexBuilders: testBuilders,
+			TenantDeletions: func(ctx context.Context, tenantID string) (*backend.TenantDeletions, error) {
+				return deletions, nil
+			},
+		}, &mockJobSharder{
+			owns: builder,
+		}, r, &backend.MockCompactor{}, w, log.NewNopLogger())
+		_, _, actualDeletions, err := poller.Do()
+		assert.NoError(t, err)
+
+		// builders record the deletions in the tenant index, readers return the deletions of the tenant index
+		assert.Equal(t, PerTenantDeletions{"test": deletions}, actualDeletions)
+		if builder {
+			assert.Equal(t, deletions, w.IndexDeletions["test"])
+		} else {
+			assert.Nil(t, w.IndexDeletions)
+		}
+	}
+}
+
 func TestPollBlock(t *testing.T) {
 	tests := []struct {
 		name                  string
@@ -342,7 +382,7 @@ func TestPollBlock(t *testing.T) {
 				PollFallback:        testPollFallback,
 				TenantIndexBuilders: testBuilders,
 			}, &mockJobSharder{}, r, c, w, log.NewNopLogger())
-			actualMeta, actualCompactedMeta, err := poller.pollBlock(context.Background(), tc.pollTenantID, tc.pollBlockID)
+			actualMeta, actualCompactedMeta, err := poller.pollBlock(context.Background(), poller.tiers[0], tc.pollTenantID, tc.pollBlockID)
 
 			assert.Equal(t, tc.expectedMeta, actualMeta)
 			assert.Equal(t, tc.expectedCompactedMeta, actualCompactedMeta)
@@ -355,6 +395,62 @@ func TestPollBlock(t *testing.T) {
 	}
 }
 
+func TestPollTiers(t *testing.T) {
+	hotList := PerTenant{
+		"test": []*backend.BlockMeta{
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), StartTime: time.Unix(1, 0)},
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), StartTime: time.Unix(3, 0)},
+		},
+	}
+	hotCompactedList := PerTenantCompacted{
+		"test": []*backend.CompactedBlockMeta{
+			{BlockMeta: backend.BlockMeta{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), StartTime: time.Unix(2, 0)}},
+		},
+	}
+	coldList := PerTenant{
+		"test": []*backend.BlockMeta{
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), StartTime: time.Unix(2, 0)},
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), StartTime: time.Unix(3, 0)},
+		},
+		"cold-only": []*backend.BlockMeta{
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000004"), StartTime: time.Unix(4, 0)},
+		},
+	}
+
+	w := &backend.MockWriter{}
+	poller := NewPoller(&PollerConfig{
+		PollConcurrency:     testPollConcurrency,
+		TenantIndexBuilders: testBuilders,
+	}, &mockJobSharder{owns: true},
+		newMockReader(hotList, hotCompactedList, false), newMockCompactor(hotCompactedList, false), w, log.NewNopLogger(),
+		Tier{
+			Name:      backend.TierCold,
+			Reader:    newMockReader(coldList, nil, false),
+			Compactor: newMockCompactor(nil, false),
+		})
+
+	actualList, actualCompactedList, _, err := poller.Do()
+	assert.NoError(t, err)
+
+	// a block live in both tiers is kept in the hot tier
+	assert.Equal(t, PerTenant{
+		"test": []*backend.BlockMeta{
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), StartTime: time.Unix(1, 0)},
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), StartTime: time.Unix(2, 0), Tier: backend.TierCold},
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), StartTime: time.Unix(3, 0)},
+		},
+		"cold-only": []*backend.BlockMeta{
+			{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000004"), StartTime: time.Unix(4, 0), Tier: backend.TierCold},
+		},
+	}, actualList)
+	assert.Equal(t, hotCompactedList["test"], actualCompactedList["test"])
+	assert.Empty(t, actualCompactedList["cold-only"])
+
+	// the tenant index lists the blocks of all tiers
+	assert.Equal(t, actualList["test"], w.IndexMeta["test"])
+	assert.Equal(t, actualList["cold-only"], w.IndexMeta["cold-only"])
+}
+
 func TestTenantIndexPollError(t *testing.T) {
 	p := NewPoller(&PollerConfig{
 		StaleTenantIndex: time.Minute,
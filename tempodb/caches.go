@@ -0,0 +1,122 @@
+package tempodb
+
+import (
+	"fmt"
+	"time"
+
+	gkLog "github.com/go-kit/log"
+
+	pkg_cache "github.com/grafana/tempo/pkg/cache"
+	"github.com/grafana/tempo/tempodb/backend/cache"
+	"github.com/grafana/tempo/tempodb/backend/cache/disk"
+	"github.com/grafana/tempo/tempodb/backend/cache/memcached"
+	"github.com/grafana/tempo/tempodb/backend/cache/redis"
+)
+
+const (
+	cacheNone      = "none"
+	cacheRedis     = "redis"
+	cacheMemcached = "memcached"
+	cacheDisk      = "disk"
+)
+
+// newCaches creates the caches of the roles of the cached objects. Roles share the cache of their policy, unless
+// their policy sets a TTL. Then the role gets its own client of the cache.
+func newCaches(cfg *Config, logger gkLog.Logger) (map[cache.Role]pkg_cache.Cache, error) {
+	caches := map[cache.Role]pkg_cache.Cache{}
+	shared := map[string]pkg_cache.Cache{}
+
+	for _, role := range cache.Roles {
+		p := cfg.cachePolicy(role)
+		if p.Cache == "" || p.Cache == cacheNone {
+			continue
+		}
+
+		if p.TTL > 0 {
+			c, err := newCacheClient(cfg, p.Cache, "tempo-"+string(role), p.TTL, logger)
+			if err != nil {
+				return nil, err
+			}
+			caches[role] = c
+			continue
+		}
+
+		c, ok := shared[p.Cache]
+		if !ok {
+			// the metrics of the cache of the storage keep their name
+			name := "tempo"
+			if p.Cache != cfg.Cache {
+				name = "tempo-" + p.Cache
+			}
+
+			var err error
+			c, err = newCacheClient(cfg, p.Cache, name, 0, logger)
+			if err != nil {
+				return nil, err
+			}
+			shared[p.Cache] = c
+		}
+		caches[role] = c
+	}
+
+	return caches, nil
+}
+
+// newCacheClient creates a client of the cache. A TTL replaces the TTL of the config of the cache.
+func newCacheClient(cfg *Config, cacheType string, name string, ttl time.Duration, logger gkLog.Logger) (pkg_cache.Cache, error) {
+	switch cacheType {
+	case cacheRedis:
+		redisCfg := *cfg.Redis
+		if ttl > 0 {
+			redisCfg.TTL = ttl
+			redisCfg.ClientConfig.Expiration = ttl
+		}
+		return redis.NewClient(name, &redisCfg, cfg.BackgroundCache, logger), nil
+	case cacheMemcached:
+		memcachedCfg := *cfg.Memcached
+		if ttl > 0 {
+			memcachedCfg.TTL = ttl
+		}
+		return memcached.NewClient(name, &memcachedCfg, cfg.BackgroundCache, logger), nil
+	case cacheDisk:
+		c, err := disk.NewClient(name, cfg.Disk, cfg.BackgroundCache, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create disk cache: %w", err)
+		}
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("unknown cache %s", cacheType)
+}
+
+// cachePolicy returns the policy of the role. Without a policy bloom filters are cached in the cache of the storage.
+// Indexes, parquet footers and pages are only cached by default if the cache of the storage is the disk cache, in
+// memcached and redis they would push out the bloom filters. Tenant indexes are not cached by default.
+func (cfg *Config) cachePolicy(role cache.Role) CachePolicy {
+	if p, ok := cfg.CachePolicies[string(role)]; ok {
+		if p.Cache == "" {
+			p.Cache = cfg.Cache
+		}
+		return p
+	}
+
+	switch role {
+	case cache.RoleBloom:
+		return CachePolicy{Cache: cfg.Cache}
+	case cache.RoleIndex, cache.RoleParquetFooter, cache.RolePage:
+		if cfg.Cache == cacheDisk {
+			return CachePolicy{Cache: cfg.Cache}
+		}
+	}
+
+	return CachePolicy{}
+}
+
+func isCacheRole(role string) bool {
+	for _, r := range cache.Roles {
+		if string(r) == role {
+			return true
+		}
+	}
+	return false
+}
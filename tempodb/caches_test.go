@@ -0,0 +1,123 @@
+package tempodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend/cache"
+	"github.com/grafana/tempo/tempodb/backend/cache/disk"
+	"github.com/grafana/tempo/tempodb/backend/cache/memcached"
+	"github.com/grafana/tempo/tempodb/backend/cache/redis"
+)
+
+func TestCachePolicy(t *testing.T) {
+	cfg := &Config{Cache: cacheMemcached}
+
+	// only bloom filters are cached in memcached and redis by default
+	require.Equal(t, CachePolicy{Cache: cacheMemcached}, cfg.cachePolicy(cache.RoleBloom))
+	require.Equal(t, CachePolicy{}, cfg.cachePolicy(cache.RoleIndex))
+	require.Equal(t, CachePolicy{}, cfg.cachePolicy(cache.RoleParquetFooter))
+	require.Equal(t, CachePolicy{}, cfg.cachePolicy(cache.RoleTenantIndex))
+
+	// the disk cache caches pages too
+	cfg = &Config{Cache: cacheDisk}
+	require.Equal(t, CachePolicy{Cache: cacheDisk}, cfg.cachePolicy(cache.RoleBloom))
+	require.Equal(t, CachePolicy{Cache: cacheDisk}, cfg.cachePolicy(cache.RolePage))
+	require.Equal(t, CachePolicy{}, cfg.cachePolicy(cache.RoleTenantIndex))
+
+	cfg = &Config{
+		Cache: cacheMemcached,
+		CachePolicies: map[string]CachePolicy{
+			"bloom":          {Cache: cacheRedis},
+			"parquet_footer": {TTL: time.Hour},
+			"tenant_index":   {Cache: cacheNone},
+		},
+	}
+	require.Equal(t, CachePolicy{Cache: cacheRedis}, cfg.cachePolicy(cache.RoleBloom))
+	require.Equal(t, CachePolicy{Cache: cacheMemcached, TTL: time.Hour}, cfg.cachePolicy(cache.RoleParquetFooter))
+	require.Equal(t, CachePolicy{Cache: cacheNone}, cfg.cachePolicy(cache.RoleTenantIndex))
+}
+
+func TestValidateCacheConfig(t *testing.T) {
+	tcs := []struct {
+		name     string
+		cfg      *Config
+		expected bool
+	}{
+		{
+			name:     "no cache",
+			cfg:      &Config{},
+			expected: true,
+		},
+		{
+			name:     "disk cache without config",
+			cfg:      &Config{Cache: cacheDisk},
+			expected: false,
+		},
+		{
+			name: "policies",
+			cfg: &Config{
+				Cache:     cacheMemcached,
+				Memcached: &memcached.Config{},
+				Redis:     &redis.Config{},
+				CachePolicies: map[string]CachePolicy{
+					"bloom":          {Cache: cacheRedis, TTL: time.Hour},
+					"parquet_footer": {},
+					"tenant_index":   {TTL: time.Minute},
+					"page":           {Cache: cacheNone},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "unknown role",
+			cfg: &Config{
+				CachePolicies: map[string]CachePolicy{"foo": {}},
+			},
+			expected: false,
+		},
+		{
+			name: "unknown cache",
+			cfg: &Config{
+				CachePolicies: map[string]CachePolicy{"bloom": {Cache: "foo"}},
+			},
+			expected: false,
+		},
+		{
+			name: "cache without config",
+			cfg: &Config{
+				CachePolicies: map[string]CachePolicy{"bloom": {Cache: cacheRedis}},
+			},
+			expected: false,
+		},
+		{
+			name: "disk cache with ttl",
+			cfg: &Config{
+				Disk:          &disk.Config{},
+				CachePolicies: map[string]CachePolicy{"bloom": {Cache: cacheDisk, TTL: time.Hour}},
+			},
+			expected: false,
+		},
+		{
+			name: "tenant index without ttl",
+			cfg: &Config{
+				Memcached:     &memcached.Config{},
+				CachePolicies: map[string]CachePolicy{"tenant_index": {Cache: cacheMemcached}},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCacheConfig(tc.cfg)
+			if tc.expected {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
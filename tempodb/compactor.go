@@ -87,6 +87,18 @@ func (rw *readerWriter) doCompaction() {
 	tenantID := tenants[rw.compactorTenantOffset]
 	blocklist := rw.blocklist.Metas(tenantID)
 
+	// blocks in the cold tier are not compacted again, neither are the blocks migrated to it
+	if rw.cold != nil {
+		cutoff := time.Now().Add(-rw.cfg.ColdTier.MinBlockAge)
+		hotBlocklist := make([]*backend.BlockMeta, 0, len(blocklist))
+		for _, b := range blocklist {
+			if b.Tier != backend.TierCold && !rw.shouldMigrate(b, cutoff) {
+				hotBlocklist = append(hotBlocklist, b)
+			}
+		}
+		blocklist = hotBlocklist
+	}
+
 	blockSelector := newTimeWindowBlockSelector(blocklist,
 		rw.compactorCfg.MaxCompactionRange,
 		rw.compactorCfg.MaxCompactionObjects,
@@ -171,12 +183,21 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 		compactionLevelLabel: compactionLevelLabel,
 	}
 
+	// deleted traces are dropped
+	tombstones, err := rw.Tombstones(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
 	opts := common.DefaultCompactionOptions()
 	opts.BlockConfig = *rw.cfg.Block
 	opts.ChunkSizeBytes = rw.compactorCfg.ChunkSizeBytes
 	opts.FlushSizeBytes = rw.compactorCfg.FlushSizeBytes
 	opts.OutputBlocks = outputBlocks
 	opts.Combiner = combiner
+	if len(tombstones) > 0 {
+		opts.DropObject = tombstones.Has
+	}
 	opts.BytesWritten = func(compactionLevel, bytes int) {
 		metricCompactionBytesWritten.WithLabelValues(strconv.Itoa(compactionLevel)).Add(float64(bytes))
 	}
@@ -6,11 +6,18 @@ import (
 	"time"
 
 	"github.com/grafana/tempo/pkg/cache"
+	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/azure"
+	backend_cache "github.com/grafana/tempo/tempodb/backend/cache"
+	"github.com/grafana/tempo/tempodb/backend/cache/disk"
 	"github.com/grafana/tempo/tempodb/backend/cache/memcached"
 	"github.com/grafana/tempo/tempodb/backend/cache/redis"
+	"github.com/grafana/tempo/tempodb/backend/encryption"
+	"github.com/grafana/tempo/tempodb/backend/filesystem"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/ratelimit"
+	"github.com/grafana/tempo/tempodb/backend/retry"
 	"github.com/grafana/tempo/tempodb/backend/s3"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
@@ -21,6 +28,8 @@ import (
 const (
 	DefaultBlocklistPoll            = 5 * time.Minute
 	DefaultMaxTimePerTenant         = 5 * time.Minute
+	DefaultScrubBlocksPerTenant     = 10
+	DefaultDeletionGracePeriod      = time.Hour
 	DefaultBlocklistPollConcurrency = uint(50)
 	DefaultRetentionConcurrency     = uint(10)
 	DefaultTenantIndexBuilders      = 2
@@ -44,19 +53,71 @@ type Config struct {
 	BlocklistPollJitterMs            int           `yaml:"blocklist_poll_jitter_ms"`
 
 	// backends
-	Backend string        `yaml:"backend"`
-	Local   *local.Config `yaml:"local"`
-	GCS     *gcs.Config   `yaml:"gcs"`
-	S3      *s3.Config    `yaml:"s3"`
-	Azure   *azure.Config `yaml:"azure"`
+	Backend    string             `yaml:"backend"`
+	Local      *local.Config      `yaml:"local"`
+	Filesystem *filesystem.Config `yaml:"filesystem"`
+	GCS        *gcs.Config        `yaml:"gcs"`
+	S3         *s3.Config         `yaml:"s3"`
+	Azure      *azure.Config      `yaml:"azure"`
 
 	// caches
 	Cache                   string                  `yaml:"cache"`
 	CacheMinCompactionLevel uint8                   `yaml:"cache_min_compaction_level"`
 	CacheMaxBlockAge        time.Duration           `yaml:"cache_max_block_age"`
+	CacheKeyPrefix          string                  `yaml:"cache_key_prefix"`
+	CachePolicies           map[string]CachePolicy  `yaml:"cache_policies"`
 	BackgroundCache         *cache.BackgroundConfig `yaml:"background_cache"`
 	Memcached               *memcached.Config       `yaml:"memcached"`
 	Redis                   *redis.Config           `yaml:"redis"`
+	Disk                    *disk.Config            `yaml:"disk"`
+
+	// backend request rate limits
+	RateLimit *ratelimit.Config `yaml:"rate_limit"`
+
+	// backend request retries and circuit breaker
+	Retry *retry.Config `yaml:"retry"`
+
+	// client-side encryption
+	Encryption *encryption.Config `yaml:"encryption"`
+
+	// tiered storage
+	ColdTier *ColdTierConfig `yaml:"cold_tier"`
+}
+
+// CachePolicy is the caching policy of a role of the cached objects, e.g. bloom filters or parquet footers
+type CachePolicy struct {
+	// Cache is the cache of the role: redis, memcached, disk or none. Defaults to the cache of the storage.
+	Cache string `yaml:"cache"`
+	// TTL replaces the TTL of the cache for the role. The disk cache doesn't support TTLs.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// CacheKeyPrefixForTier returns the prefix of the cache keys of the objects of a tier. The copy of a block in the cold
+// tier is encrypted with other data keys, so the tiers don't share cache keys.
+func (cfg *Config) CacheKeyPrefixForTier(tier string) string {
+	if tier == backend.TierCold {
+		return cfg.CacheKeyPrefix + tier + ":"
+	}
+	return cfg.CacheKeyPrefix
+}
+
+// ColdTierConfig configures the backend old blocks are migrated to by the compactor. Blocks are migrated once they
+// reach the compaction level and are older than the block age. Blocks in the cold tier are not compacted again.
+type ColdTierConfig struct {
+	Backend    string             `yaml:"backend"`
+	Local      *local.Config      `yaml:"local"`
+	Filesystem *filesystem.Config `yaml:"filesystem"`
+	GCS        *gcs.Config        `yaml:"gcs"`
+	S3         *s3.Config         `yaml:"s3"`
+	Azure      *azure.Config      `yaml:"azure"`
+
+	MinCompactionLevel uint8         `yaml:"min_compaction_level"`
+	MinBlockAge        time.Duration `yaml:"min_block_age"`
+}
+
+// Enabled returns true if blocks are migrated to the cold tier
+func (cfg *ColdTierConfig) Enabled() bool {
+	return cfg != nil && cfg.Backend != ""
 }
 
 type SearchConfig struct {
@@ -77,6 +138,9 @@ type CompactorConfig struct {
 	IteratorBufferSize      int           `yaml:"iterator_buffer_size"`
 	MaxTimePerTenant        time.Duration `yaml:"max_time_per_tenant"`
 	CompactionCycle         time.Duration `yaml:"compaction_cycle"`
+	ScrubInterval           time.Duration `yaml:"scrub_interval"`
+	ScrubBlocksPerTenant    int           `yaml:"scrub_blocks_per_tenant"`
+	DeletionGracePeriod     time.Duration `yaml:"deletion_grace_period"`
 }
 
 func validateConfig(cfg *Config) error {
@@ -98,5 +162,64 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("block version validation failed: %w", err)
 	}
 
+	err = cfg.Retry.Validate()
+	if err != nil {
+		return fmt.Errorf("retry config validation failed: %w", err)
+	}
+
+	err = validateCacheConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("cache config validation failed: %w", err)
+	}
+
+	if cfg.ColdTier.Enabled() && cfg.ColdTier.MinCompactionLevel == 0 && cfg.ColdTier.MinBlockAge == 0 {
+		return errors.New("cold tier requires a min compaction level or a min block age, otherwise every block is migrated")
+	}
+
+	return nil
+}
+
+func validateCacheConfig(cfg *Config) error {
+	if cfg.Cache == cacheDisk && cfg.Disk == nil {
+		return errors.New("disk cache config should be non-nil")
+	}
+
+	for role, p := range cfg.CachePolicies {
+		if !isCacheRole(role) {
+			return fmt.Errorf("unknown cache role %s", role)
+		}
+
+		c := p.Cache
+		if c == "" {
+			c = cfg.Cache
+		}
+
+		switch c {
+		case "", cacheNone:
+			continue
+		case cacheRedis:
+			if cfg.Redis == nil {
+				return fmt.Errorf("cache role %s uses redis, redis config should be non-nil", role)
+			}
+		case cacheMemcached:
+			if cfg.Memcached == nil {
+				return fmt.Errorf("cache role %s uses memcached, memcached config should be non-nil", role)
+			}
+		case cacheDisk:
+			if cfg.Disk == nil {
+				return fmt.Errorf("cache role %s uses the disk cache, disk cache config should be non-nil", role)
+			}
+			if p.TTL > 0 {
+				return fmt.Errorf("cache role %s sets a ttl, the disk cache doesn't support ttls", role)
+			}
+		default:
+			return fmt.Errorf("unknown cache %s for cache role %s", c, role)
+		}
+
+		if role == string(backend_cache.RoleTenantIndex) && p.TTL == 0 {
+			return fmt.Errorf("cache role %s requires a ttl, tenant indexes change with every poll", role)
+		}
+	}
+
 	return nil
 }
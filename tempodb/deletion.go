@@ -0,0 +1,591 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/blocklist"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var (
+	metricDeletedBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "deletion_blocks_total",
+		Help:      "Total number of blocks deleted or rewritten by deletion requests.",
+	}, []string{"tenant"})
+	metricCompletedDeletions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "deletion_requests_completed_total",
+		Help:      "Total number of deletion requests that were applied to all blocks.",
+	})
+	metricDeletionErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "deletion_errors_total",
+		Help:      "Total number of times an error occurred while applying deletion requests.",
+	})
+)
+
+// Tombstones are the IDs of the deleted traces of a tenant. Queries don't return deleted traces and compactions drop
+// them.
+type Tombstones map[string]struct{}
+
+// Has returns true if the trace is deleted
+func (t Tombstones) Has(id common.ID) bool {
+	return t.HasHexString(util.TraceIDToHexString(id))
+}
+
+// HasHexString returns true if the trace of the hex encoded ID without leading zeros is deleted
+func (t Tombstones) HasHexString(id string) bool {
+	_, ok := t[id]
+	return ok
+}
+
+// deletionsListPeriod is the period Tombstones lists the deletion requests of a tenant, so requests are honoured before
+// the tenant index records them
+const deletionsListPeriod = 10 * time.Second
+
+// deletions caches the tombstones and cache generations of the tenants. They are read from the tenant index with the
+// blocklist poll. Requests that are newer than the tenant index are read when the tombstones are used.
+type deletions struct {
+	mtx     sync.RWMutex
+	tenants map[string]*tenantDeletions
+
+	// completed requests don't change anymore, they are read once
+	completed *backend.LRU
+}
+
+// tenantDeletions are the deletions of a tenant. They are replaced, never modified.
+type tenantDeletions struct {
+	polled *backend.TenantDeletions
+	// recent are the requests that are not in the tenant index yet
+	recent map[uuid.UUID]*backend.DeletionRequest
+	listed time.Time
+
+	tombstones Tombstones
+	generation string
+}
+
+func newDeletions() (*deletions, error) {
+	completed, err := backend.NewLRU(backend.DefaultLRUSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deletions{
+		tenants:   map[string]*tenantDeletions{},
+		completed: completed,
+	}, nil
+}
+
+func (d *deletions) get(tenantID string) *tenantDeletions {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.tenants[tenantID]
+}
+
+// set replaces the deletions of the tenant. Recent requests are dropped once the tenant index has them.
+func (d *deletions) set(tenantID string, polled *backend.TenantDeletions, recent map[uuid.UUID]*backend.DeletionRequest, listed time.Time, tombstoned func(*backend.DeletionRequest) bool) {
+	if polled == nil {
+		polled = &backend.TenantDeletions{}
+	}
+
+	td := &tenantDeletions{
+		polled:     polled,
+		recent:     map[uuid.UUID]*backend.DeletionRequest{},
+		listed:     listed,
+		tombstones: Tombstones{},
+		generation: polled.CacheGeneration,
+	}
+	for _, id := range polled.Tombstones {
+		td.tombstones[id] = struct{}{}
+	}
+
+	known := make(map[uuid.UUID]struct{}, len(polled.Requests))
+	for _, id := range polled.Requests {
+		known[id] = struct{}{}
+	}
+
+	var newest time.Time
+	for id, req := range recent {
+		if _, ok := known[id]; ok {
+			continue
+		}
+		td.recent[id] = req
+
+		if req.DeletesTenant() && req.CreatedAt.After(newest) {
+			td.generation = id.String()
+			newest = req.CreatedAt
+		}
+		if tombstoned(req) {
+			for _, traceID := range req.TraceIDs {
+				td.tombstones[traceID] = struct{}{}
+			}
+		}
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.tenants[tenantID] = td
+}
+
+// expire lists the requests of the tenant again on the next use of its tombstones
+func (d *deletions) expire(tenantID string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if td, ok := d.tenants[tenantID]; ok {
+		expired := *td
+		expired.listed = time.Time{}
+		d.tenants[tenantID] = &expired
+	}
+}
+
+// generation returns the cache generation of the tenant. It's the ID of the newest tenant deletion, so the objects
+// cached before the tenant was deleted are not read anymore. It's never read from the backend here since it's called
+// for every cached object.
+func (d *deletions) generation(tenantID string) string {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	if td, ok := d.tenants[tenantID]; ok {
+		return td.generation
+	}
+	return ""
+}
+
+// DeleteTenant requests the deletion of all traces of the tenant. The blocks of the tenant in all tiers are marked
+// compacted and cleared by retention. Blocks flushed after the request by ingesters are deleted until the deletion
+// grace period has passed.
+func (rw *readerWriter) DeleteTenant(ctx context.Context, tenantID string) (*backend.DeletionRequest, error) {
+	req := backend.NewDeletionRequest(tenantID, nil)
+
+	err := rw.w.WriteDeletionRequest(ctx, req)
+	rw.deletions.expire(tenantID)
+
+	return req, err
+}
+
+// DeleteTraces requests the deletion of the traces. Queries honour the deletion once the query frontends list the
+// requests of the tenant again, within seconds. The compactor rewrites the blocks that contain the traces once the
+// deletion grace period has passed.
+func (rw *readerWriter) DeleteTraces(ctx context.Context, tenantID string, ids []common.ID) (*backend.DeletionRequest, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no trace IDs to delete")
+	}
+
+	traceIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		traceIDs = append(traceIDs, util.TraceIDToHexString(id))
+	}
+	req := backend.NewDeletionRequest(tenantID, traceIDs)
+
+	err := rw.w.WriteDeletionRequest(ctx, req)
+	rw.deletions.expire(tenantID)
+
+	return req, err
+}
+
+// DeletionRequests returns the deletion requests of the tenant, the oldest first. Pending requests are read every
+// time, completed requests only once.
+func (rw *readerWriter) DeletionRequests(ctx context.Context, tenantID string) ([]*backend.DeletionRequest, error) {
+	ids, err := rw.r.DeletionRequestIDs(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := make([]*backend.DeletionRequest, 0, len(ids))
+	for _, id := range ids {
+		req, err := rw.deletionRequest(ctx, id, tenantID)
+		if errors.Is(err, backend.ErrDoesNotExist) {
+			// the request is being written or cleared
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].CreatedAt.Before(reqs[j].CreatedAt)
+	})
+
+	return reqs, nil
+}
+
+func (rw *readerWriter) deletionRequest(ctx context.Context, id uuid.UUID, tenantID string) (*backend.DeletionRequest, error) {
+	if req, ok := rw.deletions.completed.Get(id.String()); ok {
+		return req.(*backend.DeletionRequest), nil
+	}
+
+	req, err := rw.r.DeletionRequest(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Status == backend.DeletionCompleted {
+		rw.deletions.completed.Add(id.String(), req)
+	}
+	return req, nil
+}
+
+// Tombstones returns the IDs of the deleted traces of the tenant. These are the tombstones of the tenant index and of
+// the requests that are newer than the tenant index. The requests of the tenant are listed at most every
+// deletionsListPeriod, only the requests that are not in the tenant index are read.
+func (rw *readerWriter) Tombstones(ctx context.Context, tenantID string) (Tombstones, error) {
+	td := rw.deletions.get(tenantID)
+	if td != nil && time.Since(td.listed) < deletionsListPeriod {
+		return td.tombstones, nil
+	}
+
+	var (
+		polled *backend.TenantDeletions
+		recent = map[uuid.UUID]*backend.DeletionRequest{}
+		known  = map[uuid.UUID]struct{}{}
+	)
+	if td != nil {
+		polled, recent = td.polled, td.recent
+		for _, id := range polled.Requests {
+			known[id] = struct{}{}
+		}
+	}
+
+	listed := time.Now()
+	ids, err := rw.r.DeletionRequestIDs(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	added := map[uuid.UUID]*backend.DeletionRequest{}
+	for _, id := range ids {
+		if _, ok := known[id]; ok {
+			continue
+		}
+		if req, ok := recent[id]; ok {
+			added[id] = req
+			continue
+		}
+
+		req, err := rw.deletionRequest(ctx, id, tenantID)
+		if errors.Is(err, backend.ErrDoesNotExist) {
+			// the request is being written or cleared
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		added[id] = req
+	}
+
+	rw.deletions.set(tenantID, polled, added, listed, rw.tombstoned)
+
+	return rw.deletions.get(tenantID).tombstones, nil
+}
+
+// tombstoned returns true if the traces of the request are tombstones. Completed trace deletions expire from the
+// tombstones after three poll cycles: queries read the compacted blocks of the rewritten blocks for two cycles, and
+// the blocklists of the queriers are up to one cycle old.
+func (rw *readerWriter) tombstoned(req *backend.DeletionRequest) bool {
+	return req.Status != backend.DeletionCompleted || time.Since(req.CompletedAt) <= 3*rw.cfg.BlocklistPoll
+}
+
+// tenantDeletions returns the deletions tenant index builders record in the tenant index
+func (rw *readerWriter) tenantDeletions(ctx context.Context, tenantID string) (*backend.TenantDeletions, error) {
+	reqs, err := rw.DeletionRequests(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	d := &backend.TenantDeletions{}
+	for _, req := range reqs {
+		d.Requests = append(d.Requests, req.ID)
+
+		// the requests are sorted, the newest tenant deletion wins
+		if req.DeletesTenant() {
+			d.CacheGeneration = req.ID.String()
+		}
+		if rw.tombstoned(req) {
+			d.Tombstones = append(d.Tombstones, req.TraceIDs...)
+		}
+	}
+
+	return d, nil
+}
+
+// applyPolledDeletions replaces the deletions of the tenants with the deletions of their tenant indexes
+func (rw *readerWriter) applyPolledDeletions(deletions blocklist.PerTenantDeletions) {
+	for tenantID, polled := range deletions {
+		var (
+			recent map[uuid.UUID]*backend.DeletionRequest
+			listed time.Time
+		)
+		if td := rw.deletions.get(tenantID); td != nil {
+			recent, listed = td.recent, td.listed
+		}
+
+		rw.deletions.set(tenantID, polled, recent, listed, rw.tombstoned)
+	}
+}
+
+func (rw *readerWriter) doDeletions() {
+	ctx := context.Background()
+
+	tenants, err := rw.r.Tenants(ctx)
+	if err != nil {
+		level.Error(rw.logger).Log("msg", "failed to list tenants to apply deletion requests", "err", err)
+		metricDeletionErrors.Inc()
+		return
+	}
+
+	for _, tenantID := range tenants {
+		reqs, err := rw.DeletionRequests(ctx, tenantID)
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to read deletion requests", "tenantID", tenantID, "err", err)
+			metricDeletionErrors.Inc()
+			continue
+		}
+
+		for _, req := range reqs {
+			if req.Status != backend.DeletionPending || !rw.compactorSharder.Owns(req.ID.String()) {
+				continue
+			}
+
+			err = rw.applyDeletion(ctx, req)
+			if err != nil {
+				level.Error(rw.logger).Log("msg", "failed to apply deletion request", "tenantID", tenantID, "id", req.ID, "err", err)
+				metricDeletionErrors.Inc()
+			}
+		}
+
+		rw.clearDeletionRequests(tenantID, reqs)
+	}
+}
+
+// clearDeletionRequests removes the completed requests that are older than the block retention of the tenant. The
+// blocks written before them are cleared by then. The newest tenant deletion is kept, it's the cache generation of
+// the tenant.
+func (rw *readerWriter) clearDeletionRequests(tenantID string, reqs []*backend.DeletionRequest) {
+	retention := rw.compactorCfg.BlockRetention
+	if r := rw.compactorOverrides.BlockRetentionForTenant(tenantID); r != 0 {
+		retention = r
+	}
+
+	var generation uuid.UUID
+	for _, req := range reqs {
+		if req.DeletesTenant() {
+			generation = req.ID
+		}
+	}
+
+	for _, req := range reqs {
+		if req.Status != backend.DeletionCompleted || time.Since(req.CompletedAt) <= retention || req.ID == generation || !rw.compactorSharder.Owns(req.ID.String()) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "clearing completed deletion request", "tenantID", tenantID, "id", req.ID, "completedAt", req.CompletedAt)
+		err := rw.c.ClearDeletionRequest(req.ID, tenantID)
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to clear deletion request", "tenantID", tenantID, "id", req.ID, "err", err)
+			metricDeletionErrors.Inc()
+			continue
+		}
+		rw.deletions.completed.Remove(req.ID.String())
+	}
+}
+
+// applyDeletion applies the deletion request to the blocks of its tenant. Ingesters have flushed all traces received
+// before the request once the deletion grace period has passed. Tenant deletions mark the blocks that were started
+// before the request compacted every cycle until then. Trace deletions are honoured by queries and compactions in the
+// meantime, the blocks that still contain the traces are rewritten once after the grace period. The request is
+// completed then.
+func (rw *readerWriter) applyDeletion(ctx context.Context, req *backend.DeletionRequest) error {
+	complete := time.Since(req.CreatedAt) > rw.compactorCfg.DeletionGracePeriod
+
+	var (
+		n   int
+		err error
+	)
+	switch {
+	case req.DeletesTenant():
+		n, err = rw.deleteTenantBlocks(req)
+	case complete:
+		n, err = rw.deleteTraces(ctx, req)
+	}
+	req.Blocks += n
+	if err != nil {
+		if n > 0 {
+			_ = rw.w.WriteDeletionRequest(ctx, req)
+		}
+		return err
+	}
+
+	if complete {
+		req.Status = backend.DeletionCompleted
+		req.CompletedAt = time.Now()
+		metricCompletedDeletions.Inc()
+		level.Info(rw.logger).Log("msg", "deletion request completed", "tenantID", req.TenantID, "id", req.ID, "blocks", req.Blocks)
+	}
+
+	if n > 0 || complete {
+		return rw.w.WriteDeletionRequest(ctx, req)
+	}
+	return nil
+}
+
+// deleteTenantBlocks marks the blocks of the tenant that were started before the request compacted
+func (rw *readerWriter) deleteTenantBlocks(req *backend.DeletionRequest) (int, error) {
+	deleted := 0
+	for _, meta := range rw.blocklist.Metas(req.TenantID) {
+		if meta.StartTime.After(req.CreatedAt) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "marking block for deletion", "blockID", meta.BlockID, "tenantID", req.TenantID, "request", req.ID)
+		err := rw.getCompactorForBlock(meta).MarkBlockCompacted(meta.BlockID, req.TenantID)
+		if err != nil {
+			return deleted, err
+		}
+
+		rw.blocklist.Update(req.TenantID, nil, []*backend.BlockMeta{meta}, []*backend.CompactedBlockMeta{
+			{
+				BlockMeta:     *meta,
+				CompactedTime: time.Now(),
+			},
+		}, nil)
+		metricDeletedBlocks.WithLabelValues(req.TenantID).Inc()
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// deleteTraces rewrites the blocks of the tenant that contain the traces of the request
+func (rw *readerWriter) deleteTraces(ctx context.Context, req *backend.DeletionRequest) (int, error) {
+	ids := make([]common.ID, 0, len(req.TraceIDs))
+	for _, id := range req.TraceIDs {
+		traceID, err := util.HexStringToTraceID(id)
+		if err != nil {
+			return 0, err
+		}
+		ids = append(ids, traceID)
+	}
+
+	// all tombstones of the tenant are applied to rewritten blocks
+	tombstones, err := rw.Tombstones(ctx, req.TenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+	for _, meta := range rw.blocklist.Metas(req.TenantID) {
+		found, err := rw.blockContainsAny(ctx, meta, ids)
+		if err != nil {
+			return rewritten, err
+		}
+		if !found {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "rewriting block to delete traces", "blockID", meta.BlockID, "tenantID", req.TenantID, "request", req.ID)
+		err = rw.rewriteBlock(ctx, meta, tombstones)
+		if err != nil {
+			return rewritten, err
+		}
+		metricDeletedBlocks.WithLabelValues(req.TenantID).Inc()
+		rewritten++
+	}
+
+	return rewritten, nil
+}
+
+func (rw *readerWriter) blockContainsAny(ctx context.Context, meta *backend.BlockMeta, ids []common.ID) (bool, error) {
+	var block common.BackendBlock
+	for _, id := range ids {
+		if bytes.Compare(id, meta.MinID) == -1 || bytes.Compare(id, meta.MaxID) == 1 {
+			continue
+		}
+
+		if block == nil {
+			var err error
+			block, err = encoding.OpenBlock(meta, rw.getReaderForBlock(meta, time.Now()))
+			if err != nil {
+				return false, err
+			}
+		}
+
+		tr, err := block.FindTraceByID(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		if tr != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// rewriteBlock compacts the block by itself without the deleted traces. The rewritten block is written to the hot
+// tier, blocks of the cold tier are migrated again.
+func (rw *readerWriter) rewriteBlock(ctx context.Context, meta *backend.BlockMeta, tombstones Tombstones) error {
+	err := rw.checkBlock(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	enc, err := encoding.FromVersion(meta.Version)
+	if err != nil {
+		return err
+	}
+
+	opts := common.DefaultCompactionOptions()
+	opts.BlockConfig = *rw.cfg.Block
+	opts.ChunkSizeBytes = rw.compactorCfg.ChunkSizeBytes
+	opts.FlushSizeBytes = rw.compactorCfg.FlushSizeBytes
+	opts.OutputBlocks = 1
+	opts.Combiner = instrumentedObjectCombiner{
+		tenant:               meta.TenantID,
+		inner:                rw.compactorSharder,
+		compactionLevelLabel: strconv.Itoa(int(meta.CompactionLevel)),
+	}
+	opts.DropObject = tombstones.Has
+
+	newBlocks, err := enc.NewCompactor(opts).Compact(ctx, rw.logger, rw.getReaderForBlock(meta, time.Now()), rw.getWriterForBlock, []*backend.BlockMeta{meta})
+	if err != nil {
+		return err
+	}
+
+	err = rw.getCompactorForBlock(meta).MarkBlockCompacted(meta.BlockID, meta.TenantID)
+	if err != nil {
+		return err
+	}
+
+	rw.blocklist.Update(meta.TenantID, newBlocks, []*backend.BlockMeta{meta}, []*backend.CompactedBlockMeta{
+		{
+			BlockMeta:     *meta,
+			CompactedTime: time.Now(),
+		},
+	}, nil)
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package tempodb
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+type mockNonOwningJobSharder struct{}
+
+func (m *mockNonOwningJobSharder) Owns(_ string) bool { return false }
+
+func testDeletionReaderWriter(t *testing.T, gracePeriod time.Duration) *readerWriter {
+	tempDir := t.TempDir()
+
+	r, _, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              0.01,
+			BloomShardSizeBytes:  100_000,
+			Version:              encoding.DefaultEncoding().Version(),
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          time.Hour,
+		CompactedBlockRetention: 0,
+		DeletionGracePeriod:     gracePeriod,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+
+	return r.(*readerWriter)
+}
+
+func TestDeleteTraces(t *testing.T) {
+	rw := testDeletionReaderWriter(t, 0)
+	ctx := context.Background()
+
+	data := make([]testData, 0, 10)
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		now := uint32(time.Now().Unix())
+		data = append(data, testData{id: id, t: test.MakeTrace(2, id), start: now, end: now})
+	}
+	cutTestBlockWithTraces(t, rw, testTenantID, data)
+	other := cutTestBlocks(t, rw, testTenantID, 1, 10)[0]
+	rw.pollBlocklist()
+	require.Len(t, rw.blocklist.Metas(testTenantID), 2)
+
+	deleted := data[0].id
+	req, err := rw.DeleteTraces(ctx, testTenantID, []common.ID{deleted})
+	require.NoError(t, err)
+
+	// queries honour the deletion immediately
+	tombstones, err := rw.Tombstones(ctx, testTenantID)
+	require.NoError(t, err)
+	require.True(t, tombstones.Has(deleted))
+	require.False(t, tombstones.Has(data[1].id))
+
+	rw.doDeletions()
+
+	reqs, err := rw.DeletionRequests(ctx, testTenantID)
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	require.Equal(t, req.ID, reqs[0].ID)
+	require.Equal(t, backend.DeletionCompleted, reqs[0].Status)
+	require.Equal(t, 1, reqs[0].Blocks)
+
+	// only the block with the trace is rewritten
+	metas := rw.blocklist.Metas(testTenantID)
+	require.Len(t, metas, 2)
+	require.Len(t, rw.blocklist.CompactedMetas(testTenantID), 1)
+
+	var otherFound bool
+	for _, meta := range metas {
+		if meta.BlockID == other.BlockMeta().BlockID {
+			otherFound = true
+		}
+	}
+	require.True(t, otherFound)
+
+	// the trace is gone from the rewritten block, the others remain
+	for i, d := range data {
+		var found bool
+		for _, meta := range rw.blocklist.Metas(testTenantID) {
+			ok, err := rw.blockContainsAny(ctx, meta, []common.ID{d.id})
+			require.NoError(t, err)
+			found = found || ok
+		}
+		require.Equal(t, i != 0, found)
+	}
+
+	// completed requests are not applied again
+	rw.doDeletions()
+	require.Len(t, rw.blocklist.Metas(testTenantID), 2)
+}
+
+func TestDeleteTracesGracePeriod(t *testing.T) {
+	rw := testDeletionReaderWriter(t, time.Hour)
+	ctx := context.Background()
+
+	blocks := cutTestBlocks(t, rw, testTenantID, 1, 10)
+	rw.pollBlocklist()
+
+	_, err := rw.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(0, 0)})
+	require.NoError(t, err)
+
+	// blocks are not rewritten until the grace period has passed
+	rw.doDeletions()
+
+	checkBlocklists(t, blocks[0].BlockMeta().BlockID, 1, 0, rw)
+	reqs, err := rw.DeletionRequests(ctx, testTenantID)
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	require.Equal(t, backend.DeletionPending, reqs[0].Status)
+}
+
+func TestDeleteTenant(t *testing.T) {
+	rw := testDeletionReaderWriter(t, 0)
+	ctx := context.Background()
+
+	cutTestBlocks(t, rw, testTenantID, 2, 10)
+	cutTestBlocks(t, rw, "other", 1, 10)
+	rw.pollBlocklist()
+
+	req, err := rw.DeleteTenant(ctx, testTenantID)
+	require.NoError(t, err)
+	require.True(t, req.DeletesTenant())
+
+	// the objects cached for the tenant are not read once the deletion is polled
+	require.Empty(t, rw.deletions.generation(testTenantID))
+	rw.pollBlocklist()
+	require.Equal(t, req.ID.String(), rw.deletions.generation(testTenantID))
+	require.Empty(t, rw.deletions.generation("other"))
+
+	rw.doDeletions()
+
+	require.Empty(t, rw.blocklist.Metas(testTenantID))
+	require.Len(t, rw.blocklist.CompactedMetas(testTenantID), 2)
+	require.Len(t, rw.blocklist.Metas("other"), 1)
+
+	reqs, err := rw.DeletionRequests(ctx, testTenantID)
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	require.Equal(t, backend.DeletionCompleted, reqs[0].Status)
+	require.Equal(t, 2, reqs[0].Blocks)
+
+	// retention clears the blocks
+	rw.doRetention()
+	rw.doRetention()
+	require.Empty(t, rw.blocklist.CompactedMetas(testTenantID))
+}
+
+func TestTombstonesExpire(t *testing.T) {
+	rw := testDeletionReaderWriter(t, 0)
+	ctx := context.Background()
+
+	expired := backend.NewDeletionRequest(testTenantID, []string{"1"})
+	expired.Status = backend.DeletionCompleted
+	expired.CompletedAt = time.Now().Add(-3*rw.cfg.BlocklistPoll - time.Minute)
+	completed := backend.NewDeletionRequest(testTenantID, []string{"2"})
+	completed.Status = backend.DeletionCompleted
+	completed.CompletedAt = time.Now()
+	pending := backend.NewDeletionRequest(testTenantID, []string{"3"})
+
+	for _, req := range []*backend.DeletionRequest{expired, completed, pending} {
+		require.NoError(t, rw.w.WriteDeletionRequest(ctx, req))
+	}
+
+	// completed requests expire from the tombstones, but are still listed
+	tombstones, err := rw.Tombstones(ctx, testTenantID)
+	require.NoError(t, err)
+	require.Equal(t, Tombstones{"2": {}, "3": {}}, tombstones)
+
+	reqs, err := rw.DeletionRequests(ctx, testTenantID)
+	require.NoError(t, err)
+	require.Len(t, reqs, 3)
+}
+
+func TestTombstonesFromTenantIndex(t *testing.T) {
+	rw := testDeletionReaderWriter(t, time.Hour)
+	ctx := context.Background()
+
+	cutTestBlocks(t, rw, testTenantID, 1, 10)
+	req, err := rw.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(0, 0)})
+	require.NoError(t, err)
+	tenantReq, err := rw.DeleteTenant(ctx, testTenantID)
+	require.NoError(t, err)
+
+	// the tenant index builder records the deletions in the tenant index
+	rw.pollBlocklist()
+
+	r, _, _, err := New(rw.cfg, log.NewNopLogger())
+	require.NoError(t, err)
+	r.EnablePolling(&mockNonOwningJobSharder{})
+	reader := r.(*readerWriter)
+
+	// other components read them with the tenant index, without reading the requests
+	reader.pollBlocklist()
+	td := reader.deletions.get(testTenantID)
+	require.NotNil(t, td)
+	require.Equal(t, Tombstones{req.TraceIDs[0]: {}}, td.tombstones)
+	require.Equal(t, tenantReq.ID.String(), reader.deletions.generation(testTenantID))
+	require.ElementsMatch(t, []uuid.UUID{req.ID, tenantReq.ID}, td.polled.Requests)
+	require.Empty(t, td.recent)
+
+	// requests that are newer than the tenant index are listed
+	newer, err := rw.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(1, 1)})
+	require.NoError(t, err)
+	tombstones, err := reader.Tombstones(ctx, testTenantID)
+	require.NoError(t, err)
+	require.True(t, tombstones.Has(makeTraceID(1, 1)))
+	require.Contains(t, reader.deletions.get(testTenantID).recent, newer.ID)
+
+	// and dropped once the tenant index has them
+	rw.pollBlocklist()
+	reader.pollBlocklist()
+	require.Empty(t, reader.deletions.get(testTenantID).recent)
+	require.True(t, reader.deletions.get(testTenantID).tombstones.Has(makeTraceID(1, 1)))
+}
+
+func TestClearDeletionRequests(t *testing.T) {
+	rw := testDeletionReaderWriter(t, 0)
+	ctx := context.Background()
+
+	completedAt := time.Now().Add(-2 * time.Hour)
+	cleared := backend.NewDeletionRequest(testTenantID, []string{"1"})
+	oldTenant := backend.NewDeletionRequest(testTenantID, nil)
+	oldTenant.CreatedAt = cleared.CreatedAt.Add(time.Second)
+	newestTenant := backend.NewDeletionRequest(testTenantID, nil)
+	newestTenant.CreatedAt = cleared.CreatedAt.Add(2 * time.Second)
+	recent := backend.NewDeletionRequest(testTenantID, []string{"2"})
+	recent.CreatedAt = cleared.CreatedAt.Add(3 * time.Second)
+
+	for _, req := range []*backend.DeletionRequest{cleared, oldTenant, newestTenant, recent} {
+		req.Status = backend.DeletionCompleted
+		req.CompletedAt = completedAt
+		require.NoError(t, rw.w.WriteDeletionRequest(ctx, req))
+	}
+	recent.CompletedAt = time.Now()
+	require.NoError(t, rw.w.WriteDeletionRequest(ctx, recent))
+
+	// completed requests past the block retention are cleared, except the newest tenant deletion
+	rw.doDeletions()
+
+	reqs, err := rw.DeletionRequests(ctx, testTenantID)
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, 0, len(reqs))
+	for _, req := range reqs {
+		ids = append(ids, req.ID)
+	}
+	require.Equal(t, []uuid.UUID{newestTenant.ID, recent.ID}, ids)
+}
@@ -19,6 +19,11 @@ type BlockConfig struct {
 
 	// parquet fields
 	RowGroupSizeBytes int `yaml:"row_group_size_bytes"`
+
+	// Checksums records the checksums of the objects of new blocks in their meta. VerifyChecksums verifies them when
+	// objects are read entirely.
+	Checksums       bool `yaml:"checksums"`
+	VerifyChecksums bool `yaml:"verify_checksums"`
 }
 
 // ValidateConfig returns true if the config is valid
@@ -50,6 +50,9 @@ type CompactionOptions struct {
 	BlockConfig        BlockConfig
 	Combiner           model.ObjectCombiner
 
+	// DropObject returns true for the objects that are not written to the compacted blocks, e.g. deleted traces
+	DropObject func(ID) bool
+
 	ObjectsWritten func(compactionLevel, objects int)
 	BytesWritten   func(compactionLevel, bytes int)
 }
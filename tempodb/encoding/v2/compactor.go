@@ -89,6 +89,10 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			return nil, errors.Wrap(err, "error iterating input blocks")
 		}
 
+		if c.opts.DropObject != nil && c.opts.DropObject(id) {
+			continue
+		}
+
 		// make a new block if necessary
 		if currentBlock == nil {
 			currentBlock, err = NewStreamingBlock(&c.opts.BlockConfig, uuid.New(), tenantID, inputs, recordsPerBlock)
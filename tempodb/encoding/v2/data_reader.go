@@ -3,9 +3,12 @@ package v2
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
+	"github.com/cespare/xxhash"
+
 	tempo_io "github.com/grafana/tempo/pkg/io"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
@@ -20,8 +23,13 @@ type dataReader struct {
 	encoding         backend.Encoding
 	pool             ReaderPool
 	compressedReader io.Reader
+
+	checksums bool
 }
 
+// ErrChecksumMismatch is returned when the checksum of a data page does not match its bytes
+var ErrChecksumMismatch = errors.New("mismatched data page checksum")
+
 // constDataHeader is a singleton data header.  the data header is
 //  stateless b/c there are no fields.  to very minorly reduce allocations all
 //  data should just use this.
@@ -41,6 +49,22 @@ func NewDataReader(r backend.ContextReader, encoding backend.Encoding) (common.D
 	}, nil
 }
 
+// NewDataReaderWithChecksums constructs a v2 DataReader that verifies the checksums of pages written by a writer
+// created with NewDataWriterWithChecksums. Pages without checksums are read as well.
+func NewDataReaderWithChecksums(r backend.ContextReader, encoding backend.Encoding) (common.DataReader, error) {
+	pool, err := getReaderPool(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataReader{
+		encoding:      encoding,
+		contextReader: r,
+		pool:          pool,
+		checksums:     true,
+	}, nil
+}
+
 // Read implements common.DataReader
 func (r *dataReader) Read(ctx context.Context, records []common.Record, pagesBuffer [][]byte, buffer []byte) ([][]byte, []byte, error) {
 	if len(records) == 0 {
@@ -84,7 +108,11 @@ func (r *dataReader) Read(ctx context.Context, records []common.Record, pagesBuf
 	// read and strip page data
 	compressedPages := make([][]byte, 0, len(compressedPagesBuffer))
 	for _, v0Page := range compressedPagesBuffer {
-		page, err := unmarshalPageFromBytes(v0Page, constDataHeader)
+		page, err := unmarshalPageFromBytes(v0Page, r.pageHeader())
+		if err != nil {
+			return nil, nil, err
+		}
+		err = verifyChecksum(page)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -137,12 +165,18 @@ func (r *dataReader) NextPage(buffer []byte) ([]byte, uint32, error) {
 		return nil, 0, err
 	}
 
-	page, err := unmarshalPageFromReader(reader, constDataHeader, r.pageBuffer)
+	page, err := unmarshalPageFromReader(reader, r.pageHeader(), r.pageBuffer)
 	if err != nil {
 		return nil, 0, err
 	}
 	r.pageBuffer = page.data
 
+	// the length is returned so corrupt pages can be skipped
+	err = verifyChecksum(page)
+	if err != nil {
+		return nil, page.totalLength, err
+	}
+
 	compressedReader, err := r.getCompressedReader(page.data)
 	if err != nil {
 		return nil, 0, err
@@ -164,6 +198,21 @@ func (r *dataReader) NextPage(buffer []byte) ([]byte, uint32, error) {
 
 }
 
+func (r *dataReader) pageHeader() pageHeader {
+	if r.checksums {
+		return &checksumDataHeader{}
+	}
+	return constDataHeader
+}
+
+func verifyChecksum(p *page) error {
+	header, ok := p.header.(*checksumDataHeader)
+	if ok && header.hasChecksum && xxhash.Sum64(p.data) != header.checksum {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
 func (r *dataReader) getCompressedReader(page []byte) (io.Reader, error) {
 	var err error
 	var reader io.Reader
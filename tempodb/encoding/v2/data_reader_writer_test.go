@@ -31,6 +31,50 @@ func TestReaderRead(t *testing.T) {
 	testRead(t, totalObjects, enc, ids, objs, buffer, recs)
 }
 
+func TestReaderChecksums(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	w, err := NewDataWriterWithChecksums(buffer, backend.EncSnappy)
+	require.NoError(t, err)
+
+	recs := common.Records{}
+	bytesWritten := 0
+	for i := 0; i < 3; i++ {
+		_, err = w.Write([]byte{byte(i)}, []byte{0x01, 0x02, 0x03})
+		require.NoError(t, err)
+		count, err := w.CutPage()
+		require.NoError(t, err)
+
+		recs = append(recs, common.Record{Start: uint64(bytesWritten), Length: uint32(count)})
+		bytesWritten += count
+	}
+	require.NoError(t, w.Complete())
+
+	// flip the last byte of the second page
+	data := buffer.Bytes()
+	data[recs[1].Start+uint64(recs[1].Length)-1] ^= 0xFF
+
+	r, err := NewDataReaderWithChecksums(backend.NewContextReaderWithAllReader(bytes.NewReader(data)), backend.EncSnappy)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var page []byte
+	for i := 0; i < 3; i++ {
+		var length uint32
+		page, length, err = r.NextPage(page)
+		if i == 1 {
+			require.ErrorIs(t, err, ErrChecksumMismatch)
+		} else {
+			require.NoError(t, err)
+		}
+		require.Equal(t, recs[i].Length, length)
+	}
+	_, _, err = r.NextPage(page)
+	require.Equal(t, io.EOF, err)
+
+	_, _, err = r.Read(context.Background(), recs[1:2], nil, nil)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
 func BenchmarkReaderRead(b *testing.B) {
 	totalObjects := 10000
 	objsPerPage := 100
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 
+	"github.com/cespare/xxhash"
 	"github.com/pkg/errors"
 
 	"github.com/grafana/tempo/tempodb/backend"
@@ -19,10 +20,22 @@ type dataWriter struct {
 
 	objectRW     common.ObjectReaderWriter
 	objectBuffer *bytes.Buffer
+
+	checksums bool
 }
 
 // NewDataWriter creates a paged page writer
 func NewDataWriter(writer io.Writer, encoding backend.Encoding) (common.DataWriter, error) {
+	return newDataWriter(writer, encoding, false)
+}
+
+// NewDataWriterWithChecksums creates a paged page writer that adds a checksum of the page bytes to every page. The pages
+// must be read with a reader created by NewDataReaderWithChecksums.
+func NewDataWriterWithChecksums(writer io.Writer, encoding backend.Encoding) (common.DataWriter, error) {
+	return newDataWriter(writer, encoding, true)
+}
+
+func newDataWriter(writer io.Writer, encoding backend.Encoding, checksums bool) (*dataWriter, error) {
 	pool, err := GetWriterPool(encoding)
 	if err != nil {
 		return nil, err
@@ -41,6 +54,7 @@ func NewDataWriter(writer io.Writer, encoding backend.Encoding) (common.DataWrit
 		compressedBuffer:  compressedBuffer,
 		objectRW:          NewObjectReaderWriter(),
 		objectBuffer:      &bytes.Buffer{},
+		checksums:         checksums,
 	}, nil
 }
 
@@ -62,7 +76,14 @@ func (p *dataWriter) CutPage() (int, error) {
 	p.compressionWriter.Close()
 
 	// now marshal the buffer as a page to the output
-	bytesWritten, marshalErr := marshalPageToWriter(p.compressedBuffer.Bytes(), p.outputWriter, constDataHeader)
+	var header pageHeader = constDataHeader
+	if p.checksums {
+		header = &checksumDataHeader{
+			checksum:    xxhash.Sum64(p.compressedBuffer.Bytes()),
+			hasChecksum: true,
+		}
+	}
+	bytesWritten, marshalErr := marshalPageToWriter(p.compressedBuffer.Bytes(), p.outputWriter, header)
 
 	// reset buffers for the next write
 	p.objectBuffer.Reset()
@@ -15,6 +15,9 @@ type pageHeader interface {
 // DataHeaderLength is the length in bytes for the data header
 const DataHeaderLength = 0
 
+// ChecksumDataHeaderLength is the length in bytes for the data header with a checksum
+const ChecksumDataHeaderLength = int(uint64Size) // 64bit checksum (xxhash)
+
 // IndexHeaderLength is the length in bytes for the record header
 const IndexHeaderLength = int(uint64Size) // 64bit checksum (xxhash)
 
@@ -38,6 +41,47 @@ func (h *dataHeader) marshalHeader(b []byte) error {
 	return nil
 }
 
+// checksumDataHeader implements a pageHeader for data pages with a checksum of the page bytes. It is used by the wal to
+// detect partially written or corrupt pages. Data pages without header fields are read as well, they have no checksum.
+//   checksum - 64 bit xxhash
+type checksumDataHeader struct {
+	checksum    uint64
+	hasChecksum bool
+}
+
+func (h *checksumDataHeader) unmarshalHeader(b []byte) error {
+	switch len(b) {
+	case DataHeaderLength:
+		h.hasChecksum = false
+	case ChecksumDataHeaderLength:
+		h.checksum = binary.LittleEndian.Uint64(b[:uint64Size])
+		h.hasChecksum = true
+	default:
+		return fmt.Errorf("unexpected data header len of %d", len(b))
+	}
+
+	return nil
+}
+
+func (h *checksumDataHeader) headerLength() int {
+	if !h.hasChecksum {
+		return DataHeaderLength
+	}
+	return ChecksumDataHeaderLength
+}
+
+func (h *checksumDataHeader) marshalHeader(b []byte) error {
+	if len(b) != h.headerLength() {
+		return fmt.Errorf("unexpected data header len of %d", len(b))
+	}
+
+	if h.hasChecksum {
+		binary.LittleEndian.PutUint64(b, h.checksum)
+	}
+
+	return nil
+}
+
 // indexHeader implements a pageHeader that has index fields
 //   checksum - 64 bit xxhash
 type indexHeader struct {
@@ -178,7 +178,7 @@ func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID) (_
 
 	br := tempo_io.NewBufferedReaderAt(rr, int64(b.meta.Size), 512*1024, 32)
 
-	pf, err := parquet.OpenFile(br, int64(b.meta.Size))
+	pf, err := parquet.OpenFile(b.newFooterReaderAt(derivedCtx, br), int64(b.meta.Size))
 	if err != nil {
 		return nil, errors.Wrap(err, "error opening file in FindTraceByID")
 	}
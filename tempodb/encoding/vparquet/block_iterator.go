@@ -9,6 +9,7 @@ import (
 	"github.com/segmentio/parquet-go"
 
 	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/tempopb"
 )
 
 type blockIterator struct {
@@ -22,7 +23,7 @@ func (b *backendBlock) Iterator(ctx context.Context) (Iterator, error) {
 	// 16 MB memory buffering
 	br := tempo_io.NewBufferedReaderAt(rr, int64(b.meta.Size), 512*1024, 32)
 
-	pf, err := parquet.OpenFile(br, int64(b.meta.Size))
+	pf, err := parquet.OpenFile(b.newFooterReaderAt(ctx, br), int64(b.meta.Size))
 	if err != nil {
 		return nil, err
 	}
@@ -47,3 +48,30 @@ func (i *blockIterator) Next(context.Context) (*Trace, error) {
 func (i *blockIterator) Close() {
 	// parquet reader is shared, lets not close it here
 }
+
+// IterateTraces calls fn with every trace of the block until it returns false
+func (b *backendBlock) IterateTraces(ctx context.Context, fn func(*tempopb.Trace) bool) error {
+	iter, err := b.Iterator(ctx)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for {
+		t, err := iter.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return nil
+		}
+
+		tr, err := parquetTraceToTempopbTrace(t)
+		if err != nil {
+			return err
+		}
+		if !fn(tr) {
+			return nil
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/cache"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/opentracing/opentracing-go"
 	"github.com/segmentio/parquet-go"
@@ -83,6 +84,45 @@ func (r *parquetOptimizedReaderAt) ReadAt(p []byte, off int64) (int, error) {
 	return r.r.ReadAt(p, off)
 }
 
+// footerReaderAt reads the footer of the parquet file with its own reader, so it's cached as a parquet footer, and
+// verifies its checksum
+type footerReaderAt struct {
+	r            io.ReaderAt
+	footer       io.ReaderAt
+	footerOffset int64
+	footerSize   int
+	checksum     uint32
+}
+
+var _ io.ReaderAt = (*footerReaderAt)(nil)
+
+func (b *backendBlock) newFooterReaderAt(ctx context.Context, r io.ReaderAt) io.ReaderAt {
+	if b.meta.FooterSize == 0 /* not present in previous block metas */ {
+		return r
+	}
+
+	return &footerReaderAt{
+		r:            r,
+		footer:       NewBackendReaderAt(cache.ContextWithRole(ctx, cache.RoleParquetFooter), b.r, DataFileName, b.meta.BlockID, b.meta.TenantID),
+		footerOffset: int64(b.meta.Size) - 8 - int64(b.meta.FooterSize),
+		footerSize:   int(b.meta.FooterSize),
+		checksum:     b.meta.FooterChecksum,
+	}
+}
+
+func (r *footerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off != r.footerOffset || len(p) != r.footerSize {
+		return r.r.ReadAt(p, off)
+	}
+
+	n, err := r.footer.ReadAt(p, off)
+	if err == nil && r.checksum != 0 /* not present in previous block metas */ && backend.Checksum(p) != r.checksum {
+		return n, fmt.Errorf("%w: parquet footer", backend.ErrChecksumMismatch)
+	}
+
+	return n, err
+}
+
 func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (_ *tempopb.SearchResponse, err error) {
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.Search",
 		opentracing.Tags{
@@ -97,7 +137,7 @@ func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, o
 
 	br := tempo_io.NewBufferedReaderAt(rr, int64(b.meta.Size), opts.ReadBufferSize, opts.ReadBufferCount)
 
-	or := &parquetOptimizedReaderAt{br, int64(b.meta.Size), b.meta.FooterSize}
+	or := &parquetOptimizedReaderAt{b.newFooterReaderAt(derivedCtx, br), int64(b.meta.Size), b.meta.FooterSize}
 
 	span2, _ := opentracing.StartSpanFromContext(derivedCtx, "parquet.OpenFile")
 	pf, err := parquet.OpenFile(or, int64(b.meta.Size), parquet.SkipPageIndex(true))
@@ -150,6 +150,9 @@ func TestBackendBlockSearch(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, 1, len(res.Traces), req)
 		require.Equal(t, expected, res.Traces[0], "search request:", req)
+
+		// the summary of the block never skips a block that matches
+		require.True(t, SummaryMatches(b.meta.Summary, req), "search request:", req)
 	}
 
 	// Excludes
@@ -186,6 +189,22 @@ func TestBackendBlockSearch(t *testing.T) {
 		require.NoError(t, err)
 		require.Empty(t, res.Traces, "search request:", req)
 	}
+
+	// Excluded by the summary of the block
+	searchesThatSummariesExclude := []*tempopb.SearchRequest{
+		{
+			MinDurationMs: 101,
+		},
+		{
+			MaxDurationMs: 99,
+		},
+		makeReq(LabelServiceName, "foo"),
+		makeReq("foo", "baz"),
+		makeReq("bat", "bar"),
+	}
+	for _, req := range searchesThatSummariesExclude {
+		require.False(t, SummaryMatches(b.meta.Summary, req), "search request:", req)
+	}
 }
 
 func makeBackendBlockWithTrace(t *testing.T, tr *Trace) *backendBlock {
@@ -75,6 +75,10 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			return nil, errors.Wrap(err, "error iterating input blocks")
 		}
 
+		if c.opts.DropObject != nil && c.opts.DropObject(lowestObject.TraceID) {
+			continue
+		}
+
 		// make a new block if necessary
 		if currentBlock == nil {
 			// Start with a copy and then customize
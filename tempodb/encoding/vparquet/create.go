@@ -80,6 +80,10 @@ type streamingBlock struct {
 	r     backend.Reader
 	to    backend.Writer
 
+	// checksums records the checksum of the footer
+	checksums bool
+	summary   *backend.BlockSummaryBuilder
+
 	currentBufferedTraces int
 }
 
@@ -109,6 +113,9 @@ func newStreamingBlock(ctx context.Context, cfg *common.BlockConfig, meta *backe
 		w:     w,
 		r:     r,
 		to:    to,
+
+		checksums: cfg.Checksums,
+		summary:   backend.NewBlockSummaryBuilder(),
 	}
 }
 
@@ -120,6 +127,7 @@ func (b *streamingBlock) Add(tr *Trace, start, end uint32) error {
 	}
 
 	b.bloom.Add(tr.TraceID)
+	addToSummary(b.summary, tr)
 	b.meta.ObjectAdded(tr.TraceID, start, end)
 	b.currentBufferedTraces++
 	return nil
@@ -192,7 +200,17 @@ func (b *streamingBlock) Complete() (int, error) {
 	}
 	b.meta.FooterSize = binary.LittleEndian.Uint32(buf[0:4])
 
+	if b.checksums {
+		footer := make([]byte, b.meta.FooterSize)
+		err = b.r.ReadRange(b.ctx, DataFileName, b.meta.BlockID, b.meta.TenantID, b.meta.Size-8-uint64(b.meta.FooterSize), footer)
+		if err != nil {
+			return 0, errors.Wrap(err, "error reading parquet file footer")
+		}
+		b.meta.FooterChecksum = backend.Checksum(footer)
+	}
+
 	b.meta.BloomShardCount = uint16(b.bloom.GetShardCount())
+	b.meta.Summary = b.summary.Summary()
 
 	return n, writeBlockMeta(b.ctx, b.to, b.meta, b.bloom)
 }
@@ -82,3 +82,40 @@ func (i *testIterator) Next(ctx context.Context) (common.ID, []byte, error) {
 
 func (i *testIterator) Close() {
 }
+
+func TestCreateBlockFooterChecksum(t *testing.T) {
+	ctx := context.Background()
+
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+
+	iter := newTestIterator()
+	iter.Add(test.MakeTrace(10, nil), 100, 401)
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		Checksums:           true,
+	}
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = 1
+
+	outMeta, err := CreateBlock(ctx, cfg, meta, iter, iter.decoder, r, w)
+	require.NoError(t, err)
+	require.NotZero(t, outMeta.FooterChecksum)
+
+	iterator, err := newBackendBlock(outMeta, r).Iterator(ctx)
+	require.NoError(t, err)
+	iterator.Close()
+
+	// the footer is verified when the block is opened
+	outMeta.FooterChecksum++
+	_, err = newBackendBlock(outMeta, r).Iterator(ctx)
+	require.ErrorIs(t, err, backend.ErrChecksumMismatch)
+}
@@ -0,0 +1,77 @@
+package vparquet
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// addToSummary adds the service names, the generic string attributes and the duration of the trace to the summary of
+// the block. The attributes that are projected to their own columns are searched by substring and are not summarized.
+func addToSummary(s *backend.BlockSummaryBuilder, tr *Trace) {
+	s.AddTrace(tr.DurationNanos)
+
+	for _, rs := range tr.ResourceSpans {
+		s.AddServiceName(rs.Resource.ServiceName)
+		addAttrsToSummary(s, rs.Resource.Attrs)
+
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			for _, span := range ils.Spans {
+				addAttrsToSummary(s, span.Attrs)
+			}
+		}
+	}
+}
+
+func addAttrsToSummary(s *backend.BlockSummaryBuilder, attrs []Attribute) {
+	for _, a := range attrs {
+		if a.Value != nil {
+			s.AddAttribute(a.Key, *a.Value)
+		}
+	}
+}
+
+// SummaryMatches returns false if the block of the summary can't contain traces that match the search request. It
+// mirrors the conditions of the search of a block.
+func SummaryMatches(s *backend.BlockSummary, req *tempopb.SearchRequest) bool {
+	if s == nil {
+		return true
+	}
+
+	for k, v := range req.Tags {
+		switch k {
+		case LabelServiceName:
+			if !s.HasServiceName(v) {
+				return false
+			}
+		case LabelCluster, LabelNamespace, LabelPod, LabelContainer,
+			LabelK8sClusterName, LabelK8sNamespaceName, LabelK8sPodName, LabelK8sContainerName,
+			LabelName, LabelHTTPMethod, LabelHTTPUrl, StatusCodeTag:
+			// not summarized
+		case LabelHTTPStatusCode:
+			if _, err := strconv.Atoi(v); err == nil {
+				break
+			}
+			// Non-numeric string field
+			if !s.HasAttribute(k, v) {
+				return false
+			}
+		default:
+			if !s.HasAttribute(k, v) {
+				return false
+			}
+		}
+	}
+
+	if req.MinDurationMs > 0 || req.MaxDurationMs > 0 {
+		min := uint64((time.Millisecond * time.Duration(req.MinDurationMs)).Nanoseconds())
+		max := uint64((time.Millisecond * time.Duration(req.MaxDurationMs)).Nanoseconds())
+		if !s.HasDuration(min, max) {
+			return false
+		}
+	}
+
+	return true
+}
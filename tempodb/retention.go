@@ -51,7 +51,7 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 	for _, b := range blocklist {
 		if b.EndTime.Before(cutoff) && rw.compactorSharder.Owns(b.BlockID.String()) {
 			level.Info(rw.logger).Log("msg", "marking block for deletion", "blockID", b.BlockID, "tenantID", tenantID)
-			err := rw.c.MarkBlockCompacted(b.BlockID, tenantID)
+			err := rw.getCompactorForBlock(b).MarkBlockCompacted(b.BlockID, tenantID)
 			if err != nil {
 				level.Error(rw.logger).Log("msg", "failed to mark block compacted during retention", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
 				metricRetentionErrors.Inc()
@@ -72,9 +72,13 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 	cutoff = time.Now().Add(-rw.compactorCfg.CompactedBlockRetention)
 	compactedBlocklist := rw.blocklist.CompactedMetas(tenantID)
 	for _, b := range compactedBlocklist {
+		// quarantined blocks are kept until an operator clears them
+		if b.Quarantined != "" {
+			continue
+		}
 		if b.CompactedTime.Before(cutoff) && rw.compactorSharder.Owns(b.BlockID.String()) {
 			level.Info(rw.logger).Log("msg", "deleting block", "blockID", b.BlockID, "tenantID", tenantID)
-			err := rw.c.ClearBlock(b.BlockID, tenantID)
+			err := rw.getCompactorForBlock(&b.BlockMeta).ClearBlock(b.BlockID, tenantID)
 			if err != nil {
 				level.Error(rw.logger).Log("msg", "failed to clear compacted block during retention", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
 				metricRetentionErrors.Inc()
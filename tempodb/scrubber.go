@@ -0,0 +1,144 @@
+package tempodb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricScrubbedBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "scrubber_blocks_scrubbed_total",
+		Help:      "Total number of blocks whose checksums were verified by the scrubber.",
+	})
+	metricQuarantinedBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "scrubber_quarantined_blocks_total",
+		Help:      "Total number of blocks quarantined by the scrubber because their checksums didn't match.",
+	}, []string{"tenant"})
+	metricScrubErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "scrubber_errors_total",
+		Help:      "Total number of times an error occurred while scrubbing blocks.",
+	})
+)
+
+func (rw *readerWriter) doScrub() {
+	tenants := rw.blocklist.Tenants()
+
+	bg := boundedwaitgroup.New(rw.compactorCfg.RetentionConcurrency)
+
+	for _, tenantID := range tenants {
+		bg.Add(1)
+		go func(t string) {
+			defer bg.Done()
+			rw.scrubTenant(t)
+		}(tenantID)
+	}
+
+	bg.Wait()
+}
+
+// scrubTenant verifies the checksums of a random sample of the blocks of the tenant. Blocks without checksums, e.g.
+// blocks written before checksums were enabled, are not scrubbed.
+func (rw *readerWriter) scrubTenant(tenantID string) {
+	var candidates []*backend.BlockMeta
+	for _, b := range rw.blocklist.Metas(tenantID) {
+		if len(b.Checksums) > 0 && rw.compactorSharder.Owns(b.BlockID.String()) {
+			candidates = append(candidates, b)
+		}
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if rw.compactorCfg.ScrubBlocksPerTenant > 0 && len(candidates) > rw.compactorCfg.ScrubBlocksPerTenant {
+		candidates = candidates[:rw.compactorCfg.ScrubBlocksPerTenant]
+	}
+
+	for _, b := range candidates {
+		err := rw.scrubBlock(context.Background(), b)
+		if errors.Is(err, backend.ErrChecksumMismatch) {
+			rw.quarantineBlock(b, err)
+			continue
+		}
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to scrub block", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricScrubErrors.Inc()
+			continue
+		}
+
+		metricScrubbedBlocks.Inc()
+	}
+}
+
+// scrubBlock reads all objects of the block with checksums as stored in the backend and verifies them
+func (rw *readerWriter) scrubBlock(ctx context.Context, meta *backend.BlockMeta) error {
+	verifier := rw.verifier
+	if meta.Tier == backend.TierCold && rw.cold != nil {
+		verifier = rw.cold.verifier
+	}
+
+	names := make([]string, 0, len(meta.Checksums))
+	for name := range meta.Checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		object, _, err := verifier.StreamReader(ctx, name, meta.BlockID, meta.TenantID)
+		if errors.Is(err, backend.ErrDoesNotExist) {
+			// retention may clear a compacted block while it is scrubbed
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(io.Discard, object)
+		object.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quarantineBlock records the reason in the meta of the block and marks it compacted, so it isn't queried or compacted
+// anymore. Retention doesn't clear quarantined blocks, an operator inspects and deletes them with tempo-cli.
+func (rw *readerWriter) quarantineBlock(meta *backend.BlockMeta, reason error) {
+	level.Error(rw.logger).Log("msg", "quarantining block with mismatched checksum", "blockID", meta.BlockID, "tenantID", meta.TenantID, "tier", meta.Tier, "err", reason)
+
+	quarantined := *meta
+	quarantined.Quarantined = reason.Error()
+
+	err := rw.getWriterForBlock(meta, time.Now()).WriteBlockMeta(context.Background(), &quarantined)
+	if err == nil {
+		err = rw.getCompactorForBlock(meta).MarkBlockCompacted(meta.BlockID, meta.TenantID)
+	}
+	if err != nil {
+		level.Error(rw.logger).Log("msg", "failed to quarantine block", "blockID", meta.BlockID, "tenantID", meta.TenantID, "err", err)
+		metricScrubErrors.Inc()
+		return
+	}
+
+	rw.blocklist.Update(meta.TenantID, nil, []*backend.BlockMeta{meta}, []*backend.CompactedBlockMeta{
+		{
+			BlockMeta:     quarantined,
+			CompactedTime: time.Now(),
+		},
+	}, nil)
+	metricQuarantinedBlocks.WithLabelValues(meta.TenantID).Inc()
+}
@@ -0,0 +1,117 @@
+package tempodb
+
+import (
+	"os"
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestScrub(t *testing.T) {
+	for _, v := range []string{v2.VersionString, vparquet.VersionString} {
+		t.Run(v, func(t *testing.T) {
+			testScrub(t, v)
+		})
+	}
+}
+
+func testScrub(t *testing.T, blockVersion string) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              blockVersion,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+			Checksums:            true,
+		},
+		WAL: &wal.Config{
+			Filepath:       path.Join(tempDir, "wal"),
+			IngestionSlack: time.Since(time.Time{}),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          time.Hour,
+		CompactedBlockRetention: time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+	rw := r.(*readerWriter)
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	ts := uint32(time.Now().Unix())
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		writeTraceToWal(t, head, dec, id, test.MakeTrace(10, id), ts, ts)
+	}
+
+	complete, err := w.CompleteBlock(head, &mockCombiner{})
+	require.NoError(t, err)
+	blockID := complete.BlockMeta().BlockID
+	checkBlocklists(t, blockID, 1, 0, rw)
+
+	// the checksums of all objects of the block are recorded in its meta
+	meta := rw.blocklist.Metas(testTenantID)[0]
+	require.NotEmpty(t, meta.Checksums)
+	require.NotContains(t, meta.Checksums, backend.MetaName)
+	if blockVersion == vparquet.VersionString {
+		require.NotZero(t, meta.FooterChecksum)
+	}
+
+	// intact blocks are kept
+	rw.doScrub()
+	checkBlocklists(t, blockID, 1, 0, rw)
+
+	// corrupted blocks are quarantined
+	var names []string
+	for name := range meta.Checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filename := path.Join(tempDir, "traces", testTenantID, blockID.String(), names[0])
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	b[len(b)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(filename, b, 0644))
+
+	rw.doScrub()
+	require.Empty(t, rw.blocklist.Metas(testTenantID))
+	require.Len(t, rw.blocklist.CompactedMetas(testTenantID), 1)
+	checkBlocklists(t, blockID, 0, 1, rw)
+	require.Contains(t, rw.blocklist.CompactedMetas(testTenantID)[0].Quarantined, backend.ErrChecksumMismatch.Error())
+
+	// quarantined blocks are not cleared by retention
+	rw.compactorCfg.CompactedBlockRetention = 0
+	rw.doRetention()
+	checkBlocklists(t, blockID, 0, 1, rw)
+}
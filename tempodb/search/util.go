@@ -60,3 +60,23 @@ func CombineSearchResults(existing *tempopb.TraceSearchMetadata, incoming *tempo
 		existing.DurationMs = incoming.DurationMs
 	}
 }
+
+// CombineSearchStageMetrics adds the time spent per stage of the incoming metrics to the existing metrics
+func CombineSearchStageMetrics(existing *tempopb.SearchMetrics, incoming *tempopb.SearchMetrics) {
+	for _, in := range incoming.Stages {
+		found := false
+		for _, s := range existing.Stages {
+			if s.Stage == in.Stage {
+				s.DurationNanos += in.DurationNanos
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing.Stages = append(existing.Stages, &tempopb.SearchStageMetrics{
+				Stage:         in.Stage,
+				DurationNanos: in.DurationNanos,
+			})
+		}
+	}
+}
@@ -22,10 +22,13 @@ import (
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/azure"
 	"github.com/grafana/tempo/tempodb/backend/cache"
-	"github.com/grafana/tempo/tempodb/backend/cache/memcached"
-	"github.com/grafana/tempo/tempodb/backend/cache/redis"
+	"github.com/grafana/tempo/tempodb/backend/checksum"
+	"github.com/grafana/tempo/tempodb/backend/encryption"
+	"github.com/grafana/tempo/tempodb/backend/filesystem"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/ratelimit"
+	"github.com/grafana/tempo/tempodb/backend/retry"
 	"github.com/grafana/tempo/tempodb/backend/s3"
 	"github.com/grafana/tempo/tempodb/blocklist"
 	"github.com/grafana/tempo/tempodb/encoding"
@@ -72,6 +75,7 @@ type Writer interface {
 	CompleteBlockWithBackend(ctx context.Context, block *wal.AppendBlock, combiner model.ObjectCombiner, r backend.Reader, w backend.Writer) (common.BackendBlock, error)
 	CompleteSearchBlockWithBackend(block *search.StreamingSearchBlock, blockID uuid.UUID, tenantID string, r backend.Reader, w backend.Writer) (*search.BackendSearchBlock, error)
 	WAL() *wal.WAL
+	BlockVersion() string
 }
 
 type IterateObjectCallback func(id common.ID, obj []byte) bool
@@ -80,6 +84,7 @@ type Reader interface {
 	Find(ctx context.Context, tenantID string, id common.ID, blockStart string, blockEnd string, timeStart int64, timeEnd int64) ([]*tempopb.Trace, []error, error)
 	Search(ctx context.Context, meta *backend.BlockMeta, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error)
 	BlockMetas(tenantID string) []*backend.BlockMeta
+	Tombstones(ctx context.Context, tenantID string) (Tombstones, error)
 	EnablePolling(sharder blocklist.JobSharder)
 
 	Shutdown()
@@ -87,6 +92,9 @@ type Reader interface {
 
 type Compactor interface {
 	EnableCompaction(cfg *CompactorConfig, sharder CompactorSharder, overrides CompactorOverrides)
+	DeleteTenant(ctx context.Context, tenantID string) (*backend.DeletionRequest, error)
+	DeleteTraces(ctx context.Context, tenantID string, ids []common.ID) (*backend.DeletionRequest, error)
+	DeletionRequests(ctx context.Context, tenantID string) ([]*backend.DeletionRequest, error)
 }
 
 type CompactorSharder interface {
@@ -111,6 +119,12 @@ type readerWriter struct {
 	uncachedReader backend.Reader
 	uncachedWriter backend.Writer
 
+	// verifier reads the objects as stored in the backend and verifies their checksums
+	verifier backend.Reader
+
+	// cold is the tier old blocks are migrated to, nil if tiered storage is disabled
+	cold *tier
+
 	wal  *wal.WAL
 	pool *pool.Pool
 
@@ -120,71 +134,81 @@ type readerWriter struct {
 	blocklistPoller *blocklist.Poller
 	blocklist       *blocklist.List
 
+	deletions *deletions
+
+	// stop stops the loops started by EnableCompaction
+	stop chan struct{}
+
 	compactorCfg          *CompactorConfig
 	compactorSharder      CompactorSharder
 	compactorOverrides    CompactorOverrides
 	compactorTenantOffset uint
 }
 
+// tier is a backend blocks are stored in
+type tier struct {
+	r backend.Reader
+	w backend.Writer
+	c backend.Compactor
+
+	uncachedReader backend.Reader
+	uncachedWriter backend.Writer
+
+	// verifier reads the objects as stored in the backend and verifies their checksums
+	verifier backend.Reader
+}
+
 // New creates a new tempodb
 func New(cfg *Config, logger gkLog.Logger) (Reader, Writer, Compactor, error) {
-	var rawR backend.RawReader
-	var rawW backend.RawWriter
-	var c backend.Compactor
-
 	err := validateConfig(cfg)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("invalid config while creating tempodb: %w", err)
 	}
 
-	switch cfg.Backend {
-	case "local":
-		rawR, rawW, c, err = local.New(cfg.Local)
-	case "gcs":
-		rawR, rawW, c, err = gcs.New(cfg.GCS)
-	case "s3":
-		rawR, rawW, c, err = s3.New(cfg.S3)
-	case "azure":
-		rawR, rawW, c, err = azure.New(cfg.Azure)
-	default:
-		err = fmt.Errorf("unknown backend %s", cfg.Backend)
+	var keys encryption.KeyProvider
+	if cfg.Encryption.Enabled() {
+		keys, err = encryption.NewKeyProvider(cfg.Encryption)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
+	caches, err := newCaches(cfg, logger)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	uncachedReader := backend.NewReader(rawR)
-	uncachedWriter := backend.NewWriter(rawW)
-
-	var cacheBackend pkg_cache.Cache
-
-	switch cfg.Cache {
-	case "redis":
-		cacheBackend = redis.NewClient(cfg.Redis, cfg.BackgroundCache, logger)
-	case "memcached":
-		cacheBackend = memcached.NewClient(cfg.Memcached, cfg.BackgroundCache, logger)
+	// the cache keys of a tenant change when it's deleted
+	deletions, err := newDeletions()
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	if cacheBackend != nil {
-		rawR, rawW, err = cache.NewCache(rawR, rawW, cacheBackend)
-		if err != nil {
-			return nil, nil, nil, err
-		}
+	hot, err := newTier(cfg, cfg.Backend, "", cfg.Backend, cfg.Local, cfg.Filesystem, cfg.GCS, cfg.S3, cfg.Azure, keys, caches, deletions.generation, logger)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	r := backend.NewReader(rawR)
-	w := backend.NewWriter(rawW)
 	rw := &readerWriter{
-		c:              c,
-		r:              r,
-		uncachedReader: uncachedReader,
-		uncachedWriter: uncachedWriter,
-		w:              w,
+		c:              hot.c,
+		r:              hot.r,
+		uncachedReader: hot.uncachedReader,
+		uncachedWriter: hot.uncachedWriter,
+		verifier:       hot.verifier,
+		w:              hot.w,
 		cfg:            cfg,
 		logger:         logger,
 		pool:           pool.NewPool(cfg.Pool),
 		blocklist:      blocklist.New(),
+		deletions:      deletions,
+		stop:           make(chan struct{}),
+	}
+
+	if cfg.ColdTier.Enabled() {
+		rw.cold, err = newTier(cfg, backend.TierCold+"-"+cfg.ColdTier.Backend, backend.TierCold, cfg.ColdTier.Backend, cfg.ColdTier.Local, cfg.ColdTier.Filesystem, cfg.ColdTier.GCS, cfg.ColdTier.S3, cfg.ColdTier.Azure, keys, caches, deletions.generation, logger)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create cold tier: %w", err)
+		}
 	}
 
 	rw.wal, err = wal.New(rw.cfg.WAL)
@@ -195,6 +219,97 @@ func New(cfg *Config, logger gkLog.Logger) (Reader, Writer, Compactor, error) {
 	return rw, rw, rw, nil
 }
 
+// newTier creates the readers, writers and compactor of a backend. Objects are encrypted if keys are given and
+// cached in the caches of their roles under the cache generation of their tenant. name identifies the tier in the
+// metrics and logs of the backend.
+func newTier(cfg *Config, name string, tierName string, backendName string, localCfg *local.Config, filesystemCfg *filesystem.Config, gcsCfg *gcs.Config, s3Cfg *s3.Config, azureCfg *azure.Config, keys encryption.KeyProvider, caches map[cache.Role]pkg_cache.Cache, generation func(tenantID string) string, logger gkLog.Logger) (*tier, error) {
+	var rawR backend.RawReader
+	var rawW backend.RawWriter
+	var c backend.Compactor
+	var err error
+
+	// requests are retried by tempodb or the clients of the backends, not both
+	switch backendName {
+	case "local":
+		rawR, rawW, c, err = local.New(localCfg)
+	case "filesystem":
+		rawR, rawW, c, err = filesystem.New(filesystemCfg)
+	case "gcs":
+		gcsCfg.DisableRetries = cfg.Retry.Retries()
+		rawR, rawW, c, err = gcs.New(gcsCfg)
+	case "s3":
+		s3Cfg.DisableRetries = cfg.Retry.Retries()
+		rawR, rawW, c, err = s3.New(s3Cfg)
+	case "azure":
+		azureCfg.DisableRetries = cfg.Retry.Retries()
+		rawR, rawW, c, err = azure.New(azureCfg)
+	default:
+		err = fmt.Errorf("unknown backend %s", backendName)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limits := cfg.RateLimit.LimitsForComponent(); limits.Enabled() {
+		rawR, rawW, c = ratelimit.New(rawR, rawW, c, limits)
+	}
+
+	// checksums are of the objects as stored in the backend. they are verified below the retries, so objects that
+	// were corrupted in transit are read again.
+	verifier, err := checksum.NewReader(rawR)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Block.VerifyChecksums {
+		rawR = verifier
+	}
+	if cfg.Block.Checksums {
+		rawW, err = checksum.NewWriter(rawW)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Retry.Enabled() {
+		rawR, rawW, c = retry.New(name, rawR, rawW, c, cfg.Retry, logger)
+	}
+
+	cachedR, cachedW := rawR, rawW
+	if len(caches) > 0 {
+		cachedR, cachedW, err = cache.NewCache(rawR, rawW, caches, cfg.CacheKeyPrefixForTier(tierName), generation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// objects are encrypted above the caches, so the caches only hold encrypted objects
+	if keys != nil {
+		rawR, rawW, err = encryption.NewEncryption(rawR, rawW, keys, cfg.Encryption.ChunkSizeBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(caches) > 0 {
+			cachedR, cachedW, err = encryption.NewEncryption(cachedR, cachedW, keys, cfg.Encryption.ChunkSizeBytes)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			cachedR, cachedW = rawR, rawW
+		}
+	}
+
+	return &tier{
+		c:              c,
+		r:              backend.NewReader(cachedR),
+		w:              backend.NewWriter(cachedW),
+		uncachedReader: backend.NewReader(rawR),
+		uncachedWriter: backend.NewWriter(rawW),
+		verifier:       backend.NewReader(verifier),
+	}, nil
+}
+
 func (rw *readerWriter) WriteBlock(ctx context.Context, c WriteableBlock) error {
 	w := rw.getWriterForBlock(c.BlockMeta(), time.Now())
 	return c.Write(ctx, w)
@@ -264,6 +379,11 @@ func (rw *readerWriter) WAL() *wal.WAL {
 	return rw.wal
 }
 
+// BlockVersion returns the version blocks are completed and written to
+func (rw *readerWriter) BlockVersion() string {
+	return rw.cfg.Block.Version
+}
+
 func (rw *readerWriter) BlockMetas(tenantID string) []*backend.BlockMeta {
 	return rw.blocklist.Metas(tenantID)
 }
@@ -349,7 +469,12 @@ func (rw *readerWriter) Find(ctx context.Context, tenantID string, id common.ID,
 // Search the given block.  This method takes the pre-loaded block meta instead of a block ID, which
 // eliminates a read per search request.
 func (rw *readerWriter) Search(ctx context.Context, meta *backend.BlockMeta, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
-	block, err := encoding.OpenBlock(meta, rw.r)
+	r := rw.r
+	if rw.cold != nil && rw.blockTier(meta) == backend.TierCold {
+		r = rw.cold.r
+	}
+
+	block, err := encoding.OpenBlock(meta, r)
 	if err != nil {
 		return nil, err
 	}
@@ -359,8 +484,12 @@ func (rw *readerWriter) Search(ctx context.Context, meta *backend.BlockMeta, req
 
 func (rw *readerWriter) Shutdown() {
 	// todo: stop blocklist poll
+	close(rw.stop)
 	rw.pool.Shutdown()
 	rw.r.Shutdown()
+	if rw.cold != nil {
+		rw.cold.r.Shutdown()
+	}
 }
 
 // EnableCompaction activates the compaction/retention loops
@@ -383,6 +512,21 @@ func (rw *readerWriter) EnableCompaction(cfg *CompactorConfig, c CompactorSharde
 		level.Info(rw.logger).Log("msg", "compaction and retention enabled.")
 		go rw.compactionLoop()
 		go rw.retentionLoop()
+
+		if rw.cold != nil {
+			level.Info(rw.logger).Log("msg", "migration to the cold tier enabled.", "minCompactionLevel", rw.cfg.ColdTier.MinCompactionLevel, "minBlockAge", rw.cfg.ColdTier.MinBlockAge)
+			go runLoop(rw.cfg.BlocklistPoll, rw.stop, rw.doMigration)
+		}
+
+		if cfg.DeletionGracePeriod == 0 {
+			cfg.DeletionGracePeriod = DefaultDeletionGracePeriod
+		}
+		go runLoop(rw.cfg.BlocklistPoll, rw.stop, rw.doDeletions)
+
+		if cfg.ScrubInterval > 0 {
+			level.Info(rw.logger).Log("msg", "block scrubbing enabled.", "interval", cfg.ScrubInterval, "blocksPerTenant", cfg.ScrubBlocksPerTenant)
+			go runLoop(cfg.ScrubInterval, rw.stop, rw.doScrub)
+		}
 	}
 }
 
@@ -407,13 +551,23 @@ func (rw *readerWriter) EnablePolling(sharder blocklist.JobSharder) {
 
 	level.Info(rw.logger).Log("msg", "polling enabled", "interval", rw.cfg.BlocklistPoll, "concurrency", rw.cfg.BlocklistPollConcurrency)
 
+	var tiers []blocklist.Tier
+	if rw.cold != nil {
+		tiers = append(tiers, blocklist.Tier{
+			Name:      backend.TierCold,
+			Reader:    rw.cold.r,
+			Compactor: rw.cold.c,
+		})
+	}
+
 	blocklistPoller := blocklist.NewPoller(&blocklist.PollerConfig{
 		PollConcurrency:     rw.cfg.BlocklistPollConcurrency,
 		PollFallback:        rw.cfg.BlocklistPollFallback,
 		TenantIndexBuilders: rw.cfg.BlocklistPollTenantIndexBuilders,
 		StaleTenantIndex:    rw.cfg.BlocklistPollStaleTenantIndex,
 		PollJitterMs:        rw.cfg.BlocklistPollJitterMs,
-	}, sharder, rw.r, rw.c, rw.w, rw.logger)
+		TenantDeletions:     rw.tenantDeletions,
+	}, sharder, rw.r, rw.c, rw.w, rw.logger, tiers...)
 
 	rw.blocklistPoller = blocklistPoller
 
@@ -432,7 +586,7 @@ func (rw *readerWriter) pollingLoop() {
 }
 
 func (rw *readerWriter) pollBlocklist() {
-	blocklist, compactedBlocklist, err := rw.blocklistPoller.Do()
+	blocklist, compactedBlocklist, deletions, err := rw.blocklistPoller.Do()
 
 	if err != nil {
 		level.Error(rw.logger).Log("msg", "failed to poll blocklist. using previously polled lists", "err", err)
@@ -440,6 +594,34 @@ func (rw *readerWriter) pollBlocklist() {
 	}
 
 	rw.blocklist.ApplyPollResults(blocklist, compactedBlocklist)
+	rw.applyPolledDeletions(deletions)
+}
+
+// runLoop calls f every interval until stop is closed
+func runLoop(interval time.Duration, stop <-chan struct{}, f func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// checkBlock returns backend.ErrDoesNotExist if the block was compacted, migrated or cleared since the last poll.
+// The meta is read from the backend, not the cache.
+func (rw *readerWriter) checkBlock(ctx context.Context, meta *backend.BlockMeta) error {
+	r := rw.uncachedReader
+	if meta.Tier == backend.TierCold && rw.cold != nil {
+		r = rw.cold.uncachedReader
+	}
+
+	_, err := r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	return err
 }
 
 func (rw *readerWriter) shouldCache(meta *backend.BlockMeta, curTime time.Time) bool {
@@ -457,19 +639,53 @@ func (rw *readerWriter) shouldCache(meta *backend.BlockMeta, curTime time.Time)
 }
 
 func (rw *readerWriter) getReaderForBlock(meta *backend.BlockMeta, curTime time.Time) backend.Reader {
+	r, uncachedReader := rw.r, rw.uncachedReader
+	if meta.Tier == backend.TierCold && rw.cold != nil {
+		r, uncachedReader = rw.cold.r, rw.cold.uncachedReader
+	}
+
 	if rw.shouldCache(meta, curTime) {
-		return rw.r
+		return r
 	}
 
-	return rw.uncachedReader
+	return uncachedReader
 }
 
 func (rw *readerWriter) getWriterForBlock(meta *backend.BlockMeta, curTime time.Time) backend.Writer {
+	w, uncachedWriter := rw.w, rw.uncachedWriter
+	if meta.Tier == backend.TierCold && rw.cold != nil {
+		w, uncachedWriter = rw.cold.w, rw.cold.uncachedWriter
+	}
+
 	if rw.shouldCache(meta, curTime) {
-		return rw.w
+		return w
+	}
+
+	return uncachedWriter
+}
+
+func (rw *readerWriter) getCompactorForBlock(meta *backend.BlockMeta) backend.Compactor {
+	if meta.Tier == backend.TierCold && rw.cold != nil {
+		return rw.cold.c
+	}
+
+	return rw.c
+}
+
+// blockTier returns the tier of the block. Metas built from search requests of older frontends don't have a tier, it's
+// looked up in the blocklist.
+func (rw *readerWriter) blockTier(meta *backend.BlockMeta) string {
+	if meta.Tier != "" {
+		return meta.Tier
+	}
+
+	for _, m := range rw.blocklist.Metas(meta.TenantID) {
+		if m.BlockID == meta.BlockID {
+			return m.Tier
+		}
 	}
 
-	return rw.uncachedWriter
+	return ""
 }
 
 // includeBlock indicates whether a given block should be included in a backend search
@@ -497,7 +713,7 @@ func includeBlock(b *backend.BlockMeta, id common.ID, blockStart []byte, blockEn
 // if block is compacted within lookback period, and is within shard ranges, include it in search
 func includeCompactedBlock(c *backend.CompactedBlockMeta, id common.ID, blockStart []byte, blockEnd []byte, poll time.Duration, timeStart int64, timeEnd int64) bool {
 	lookback := time.Now().Add(-(2 * poll))
-	if c.CompactedTime.Before(lookback) {
+	if c.CompactedTime.Before(lookback) || c.Quarantined != "" {
 		return false
 	}
 	return includeBlock(&c.BlockMeta, id, blockStart, blockEnd, timeStart, timeEnd)
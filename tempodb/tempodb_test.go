@@ -1,11 +1,14 @@
 package tempodb
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"math/rand"
 	"os"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,9 +22,13 @@ import (
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/encryption"
+	"github.com/grafana/tempo/tempodb/backend/filesystem"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 	"github.com/grafana/tempo/tempodb/wal"
 )
 
@@ -101,6 +108,191 @@ func TestDB(t *testing.T) {
 	}
 }
 
+func TestDBEncryption(t *testing.T) {
+	for _, v := range []string{v2.VersionString, vparquet.VersionString} {
+		t.Run(v, func(t *testing.T) {
+			testDBEncryption(t, v)
+		})
+	}
+}
+
+func testDBEncryption(t *testing.T, blockVersion string) {
+	tempDir := t.TempDir()
+
+	keyfile := path.Join(tempDir, "keys.yaml")
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	err := os.WriteFile(keyfile, []byte("default:\n  - id: key-1\n    key: "+key+"\n"), 0o600)
+	require.NoError(t, err)
+
+	r, w, _, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              blockVersion,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath:       path.Join(tempDir, "wal"),
+			IngestionSlack: time.Since(time.Time{}),
+		},
+		Encryption: &encryption.Config{
+			KeyProvider:    encryption.KeyProviderKeyfile,
+			Keyfile:        &encryption.KeyfileConfig{Path: keyfile},
+			ChunkSizeBytes: 1000,
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	r.EnablePolling(&mockJobSharder{})
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	numMsgs := 10
+	reqs := make([]*tempopb.Trace, numMsgs)
+	ids := make([]common.ID, numMsgs)
+	for i := 0; i < numMsgs; i++ {
+		ids[i] = test.ValidTraceID(nil)
+		reqs[i] = test.MakeTrace(10, ids[i])
+		writeTraceToWal(t, head, dec, ids[i], reqs[i], 0, 0)
+	}
+
+	_, err = w.CompleteBlock(head, &mockCombiner{})
+	require.NoError(t, err)
+
+	r.(*readerWriter).pollBlocklist()
+	metas := r.(*readerWriter).blocklist.Metas(testTenantID)
+	require.Len(t, metas, 1)
+	require.Equal(t, "key-1", metas[0].EncryptionKeyID)
+
+	// no trace id is stored in plain text
+	blockPath := path.Join(tempDir, "traces", testTenantID, metas[0].BlockID.String())
+	files, err := os.ReadDir(blockPath)
+	require.NoError(t, err)
+	for _, f := range files {
+		if f.Name() == backend.MetaName {
+			continue
+		}
+		b, err := os.ReadFile(path.Join(blockPath, f.Name()))
+		require.NoError(t, err)
+		for _, id := range ids {
+			require.False(t, bytes.Contains(b, id), f.Name())
+		}
+	}
+
+	for i, id := range ids {
+		found, failedBlocks, err := r.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, failedBlocks)
+		require.True(t, proto.Equal(found[0], reqs[i]))
+	}
+}
+
+func TestDBSharedFilesystem(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// multiple instances share one directory, each with its own wal
+	instances := make([]*readerWriter, 0, 3)
+	for i := 0; i < 3; i++ {
+		r, _, c, err := New(&Config{
+			Backend: "filesystem",
+			Filesystem: &filesystem.Config{
+				Path:             path.Join(tempDir, "traces"),
+				ReadRetries:      3,
+				ReadRetryBackoff: time.Millisecond,
+				LockTimeout:      time.Second,
+			},
+			Block: &common.BlockConfig{
+				IndexDownsampleBytes: 17,
+				BloomFP:              .01,
+				BloomShardSizeBytes:  100_000,
+				Version:              encoding.DefaultEncoding().Version(),
+				Encoding:             backend.EncNone,
+				IndexPageSizeBytes:   1000,
+			},
+			WAL: &wal.Config{
+				Filepath: path.Join(tempDir, fmt.Sprintf("wal-%d", i)),
+			},
+			BlocklistPoll: 0,
+		}, log.NewNopLogger())
+		require.NoError(t, err)
+
+		c.EnableCompaction(&CompactorConfig{
+			ChunkSizeBytes:          10,
+			MaxCompactionRange:      time.Hour,
+			BlockRetention:          0,
+			CompactedBlockRetention: time.Hour,
+		}, &mockSharder{}, &mockOverrides{})
+		r.EnablePolling(&mockJobSharder{})
+
+		instances = append(instances, r.(*readerWriter))
+	}
+
+	// every instance writes a block
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	ids := make([]common.ID, 0, len(instances))
+	reqs := make([]*tempopb.Trace, 0, len(instances))
+	for _, rw := range instances {
+		head, err := rw.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+		require.NoError(t, err)
+
+		id := test.ValidTraceID(nil)
+		req := test.MakeTrace(10, id)
+		writeTraceToWal(t, head, dec, id, req, 0, 0)
+		ids = append(ids, id)
+		reqs = append(reqs, req)
+
+		_, err = rw.CompleteBlock(head, &mockCombiner{})
+		require.NoError(t, err)
+	}
+
+	// every instance finds the traces of all instances
+	for _, rw := range instances {
+		checkBlocklists(t, uuid.Nil, len(instances), 0, rw)
+
+		for i, id := range ids {
+			found, failedBlocks, err := rw.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax, 0, 0)
+			require.NoError(t, err)
+			require.Nil(t, failedBlocks)
+			require.True(t, proto.Equal(found[0], reqs[i]))
+		}
+	}
+
+	// all instances run retention at the same time
+	retention := func() {
+		wg := sync.WaitGroup{}
+		for _, rw := range instances {
+			wg.Add(1)
+			go func(rw *readerWriter) {
+				defer wg.Done()
+				rw.doRetention()
+			}(rw)
+		}
+		wg.Wait()
+	}
+
+	retention()
+	for _, rw := range instances {
+		checkBlocklists(t, uuid.Nil, 0, len(instances), rw)
+	}
+
+	for _, rw := range instances {
+		rw.compactorCfg.CompactedBlockRetention = 0
+	}
+	retention()
+	for _, rw := range instances {
+		checkBlocklists(t, uuid.Nil, 0, 0, rw)
+	}
+}
+
 func TestBlockSharding(t *testing.T) {
 	// push a req with some traceID
 	// cut headblock & write to backend
@@ -0,0 +1,119 @@
+package tempodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+var (
+	metricMigratedBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "cold_tier_migrated_blocks_total",
+		Help:      "Total number of blocks migrated to the cold tier.",
+	})
+	metricMigratedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "cold_tier_migrated_bytes_total",
+		Help:      "Total number of bytes migrated to the cold tier.",
+	})
+	metricMigrationErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "cold_tier_migration_errors_total",
+		Help:      "Total number of times an error occurred while migrating blocks to the cold tier.",
+	})
+)
+
+func (rw *readerWriter) doMigration() {
+	tenants := rw.blocklist.Tenants()
+
+	bg := boundedwaitgroup.New(rw.compactorCfg.RetentionConcurrency)
+
+	for _, tenantID := range tenants {
+		bg.Add(1)
+		go func(t string) {
+			defer bg.Done()
+			rw.migrateTenant(t)
+		}(tenantID)
+	}
+
+	bg.Wait()
+}
+
+func (rw *readerWriter) migrateTenant(tenantID string) {
+	cutoff := time.Now().Add(-rw.cfg.ColdTier.MinBlockAge)
+
+	blocklist := rw.blocklist.Metas(tenantID)
+	for _, b := range blocklist {
+		if !rw.shouldMigrate(b, cutoff) || !rw.compactorSharder.Owns(b.BlockID.String()) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "migrating block to the cold tier", "blockID", b.BlockID, "tenantID", tenantID)
+		err := rw.migrateBlock(context.Background(), b)
+		if err == backend.ErrDoesNotExist {
+			continue
+		}
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to migrate block to the cold tier", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricMigrationErrors.Inc()
+			continue
+		}
+
+		metricMigratedBlocks.Inc()
+		metricMigratedBytes.Add(float64(b.Size))
+	}
+}
+
+func (rw *readerWriter) shouldMigrate(meta *backend.BlockMeta, cutoff time.Time) bool {
+	if meta.Tier == backend.TierCold {
+		return false
+	}
+
+	if meta.CompactionLevel < rw.cfg.ColdTier.MinCompactionLevel {
+		return false
+	}
+
+	return rw.cfg.ColdTier.MinBlockAge == 0 || meta.EndTime.Before(cutoff)
+}
+
+// migrateBlock copies the block to the cold tier and marks the copy in the hot tier compacted. Queriers that haven't
+// polled the cold tier yet read the copy in the hot tier until retention clears it. Blocks due for migration are not compacted, but a compaction that started
+// before the block was due may compact it while it's copied. The copy in the cold tier is marked compacted then.
+func (rw *readerWriter) migrateBlock(ctx context.Context, meta *backend.BlockMeta) error {
+	err := rw.checkBlock(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	coldMeta := *meta
+	coldMeta.Tier = backend.TierCold
+	// the checksums are recorded again by the cold tier, which may store the objects differently
+	coldMeta.Checksums = nil
+
+	// the meta is copied last, a block isn't polled from the cold tier until all of its objects are copied
+	err = encoding.CopyBlock(ctx, &coldMeta, rw.uncachedReader, rw.cold.uncachedWriter)
+	if err != nil {
+		return err
+	}
+
+	// the block may have been compacted while it was copied
+	err = rw.checkBlock(ctx, meta)
+	if err == backend.ErrDoesNotExist {
+		level.Info(rw.logger).Log("msg", "block compacted while migrating to the cold tier, dropping the copy", "blockID", meta.BlockID, "tenantID", meta.TenantID)
+		return rw.cold.c.MarkBlockCompacted(meta.BlockID, meta.TenantID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the blocklist is updated by the next poll
+	return rw.c.MarkBlockCompacted(meta.BlockID, meta.TenantID)
+}
@@ -0,0 +1,130 @@
+package tempodb
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestMigration(t *testing.T) {
+	for _, v := range []string{v2.VersionString, vparquet.VersionString} {
+		t.Run(v, func(t *testing.T) {
+			testMigration(t, v)
+		})
+	}
+}
+
+func testMigration(t *testing.T, blockVersion string) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "hot"),
+		},
+		ColdTier: &ColdTierConfig{
+			Backend: "local",
+			Local: &local.Config{
+				Path: path.Join(tempDir, "cold"),
+			},
+			MinBlockAge: time.Second,
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              blockVersion,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath:       path.Join(tempDir, "wal"),
+			IngestionSlack: time.Since(time.Time{}),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          time.Hour,
+		CompactedBlockRetention: 0,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+	rw := r.(*readerWriter)
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	ts := uint32(time.Now().Add(-time.Minute).Unix())
+	numMsgs := 10
+	reqs := make([]*tempopb.Trace, numMsgs)
+	ids := make([]common.ID, numMsgs)
+	for i := 0; i < numMsgs; i++ {
+		ids[i] = test.ValidTraceID(nil)
+		reqs[i] = test.MakeTrace(10, ids[i])
+		writeTraceToWal(t, head, dec, ids[i], reqs[i], ts, ts)
+	}
+
+	complete, err := w.CompleteBlock(head, &mockCombiner{})
+	require.NoError(t, err)
+	blockID := complete.BlockMeta().BlockID
+	checkBlocklists(t, blockID, 1, 0, rw)
+
+	// the block is older than the min block age, it's migrated and the hot copy is compacted
+	rw.doMigration()
+	checkBlocklists(t, blockID, 1, 1, rw)
+	require.Equal(t, backend.TierCold, rw.blocklist.Metas(testTenantID)[0].Tier)
+	require.Equal(t, "", rw.blocklist.CompactedMetas(testTenantID)[0].Tier)
+
+	// blocks in the cold tier are not migrated again
+	rw.doMigration()
+	checkBlocklists(t, blockID, 1, 1, rw)
+
+	// retention clears the hot copy, reads are routed to the cold tier
+	rw.doRetention()
+	checkBlocklists(t, blockID, 1, 0, rw)
+	_, err = os.Stat(path.Join(tempDir, "hot", testTenantID, blockID.String()))
+	require.True(t, os.IsNotExist(err))
+
+	for i, id := range ids {
+		found, failedBlocks, err := r.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, failedBlocks)
+		require.NotEmpty(t, found)
+		require.True(t, proto.Equal(found[0], reqs[i]))
+	}
+
+	// the metas of search requests don't have a tier
+	meta := *rw.blocklist.Metas(testTenantID)[0]
+	meta.Tier = ""
+	resp, err := r.Search(context.Background(), &meta, &tempopb.SearchRequest{Limit: uint32(numMsgs)}, common.DefaultSearchOptions())
+	require.NoError(t, err)
+	require.Len(t, resp.Traces, numMsgs)
+
+	// retention clears the block in the cold tier
+	rw.compactorCfg.BlockRetention = time.Second
+	rw.doRetention()
+	_, err = os.Stat(path.Join(tempDir, "cold", testTenantID, blockID.String()))
+	require.True(t, os.IsNotExist(err))
+}
@@ -3,6 +3,7 @@ package wal
 import (
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
@@ -28,12 +30,20 @@ type AppendBlock struct {
 	appendFile *os.File
 	appender   v2.Appender
 
+	syncMode    SyncMode
+	bytesOnDisk uint64
+
+	// with sync mode interval the append file is fsynced in the background if it was appended to since the last sync
+	syncMtx  sync.Mutex
+	dirty    bool
+	stopSync chan struct{}
+
 	filepath string
 	readFile *os.File
 	once     sync.Once
 }
 
-func newAppendBlock(id uuid.UUID, tenantID string, filepath string, e backend.Encoding, dataEncoding string, ingestionSlack time.Duration) (*AppendBlock, error) {
+func newAppendBlock(id uuid.UUID, tenantID string, filepath string, e backend.Encoding, dataEncoding string, ingestionSlack time.Duration, syncMode SyncMode, syncInterval time.Duration, checksums bool) (*AppendBlock, error) {
 	if strings.ContainsRune(dataEncoding, ':') ||
 		len([]rune(dataEncoding)) > maxDataEncodingLength {
 		return nil, fmt.Errorf("dataEncoding %s is invalid", dataEncoding)
@@ -43,6 +53,7 @@ func newAppendBlock(id uuid.UUID, tenantID string, filepath string, e backend.En
 		meta:           backend.NewBlockMeta(tenantID, id, v2.VersionString, e, dataEncoding),
 		filepath:       filepath,
 		ingestionSlack: ingestionSlack,
+		syncMode:       syncMode,
 	}
 
 	name := h.fullFilename()
@@ -53,13 +64,23 @@ func newAppendBlock(id uuid.UUID, tenantID string, filepath string, e backend.En
 	}
 	h.appendFile = f
 
-	dataWriter, err := v2.NewDataWriter(f, e)
+	var dataWriter common.DataWriter
+	if checksums {
+		dataWriter, err = v2.NewDataWriterWithChecksums(f, e)
+	} else {
+		dataWriter, err = v2.NewDataWriter(f, e)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	h.appender = v2.NewAppender(dataWriter)
 
+	if syncMode == SyncModeInterval {
+		h.stopSync = make(chan struct{})
+		go h.syncLoop(syncInterval, h.stopSync)
+	}
+
 	return h, nil
 }
 
@@ -105,6 +126,13 @@ func newAppendBlockFromFile(filename string, path string, ingestionSlack time.Du
 		return nil, nil, err
 	}
 
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("accessing file: %w", err)
+	}
+	b.bytesOnDisk = uint64(info.Size())
+	metricWALBytes.WithLabelValues(tenantID).Add(float64(b.bytesOnDisk))
+
 	b.appender = v2.NewRecordAppender(records)
 	b.meta.TotalObjects = b.appender.Length()
 	b.meta.StartTime = time.Unix(int64(blockStart), 0)
@@ -116,15 +144,103 @@ func newAppendBlockFromFile(filename string, path string, ingestionSlack time.Du
 // Append adds an id and object to this wal block. start/end should indicate the time range
 // associated with the past object. They are unix epoch seconds.
 func (a *AppendBlock) Append(id common.ID, b []byte, start, end uint32) error {
+	startAppend := time.Now()
+	defer func() { metricWALWriteDuration.Observe(time.Since(startAppend).Seconds()) }()
+
+	length := a.appender.DataLength()
 	err := a.appender.Append(id, b)
 	if err != nil {
 		return err
 	}
+	written := a.appender.DataLength() - length
+	a.bytesOnDisk += written
+	metricWALBytes.WithLabelValues(a.meta.TenantID).Add(float64(written))
+
+	err = a.syncIfRequired()
+	if err != nil {
+		return err
+	}
+
 	start, end = a.adjustTimeRangeForSlack(start, end, 0)
 	a.meta.ObjectAdded(id, start, end)
 	return nil
 }
 
+// syncIfRequired fsyncs the append file according to the sync mode
+func (a *AppendBlock) syncIfRequired() error {
+	switch a.syncMode {
+	case SyncModeAppend:
+		return a.sync(a.appendFile)
+	case SyncModeInterval:
+		a.syncMtx.Lock()
+		a.dirty = true
+		a.syncMtx.Unlock()
+	}
+
+	return nil
+}
+
+// syncLoop fsyncs the append file every interval if it was appended to, until the append file is closed
+func (a *AppendBlock) syncLoop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.syncMtx.Lock()
+			if a.dirty && a.appendFile != nil {
+				// errors are returned by the next sync or close of the file
+				if err := a.sync(a.appendFile); err == nil {
+					a.dirty = false
+				}
+			}
+			a.syncMtx.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (a *AppendBlock) sync(f *os.File) error {
+	start := time.Now()
+	err := f.Sync()
+	if err != nil {
+		return fmt.Errorf("syncing wal file: %w", err)
+	}
+	metricWALSyncDuration.Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+// closeAppendFile stops the background sync and closes the append file. Pending appends are fsynced first with
+// sync mode interval.
+func (a *AppendBlock) closeAppendFile() error {
+	if a.stopSync != nil {
+		close(a.stopSync)
+		a.stopSync = nil
+	}
+
+	a.syncMtx.Lock()
+	defer a.syncMtx.Unlock()
+
+	if a.appendFile == nil {
+		return nil
+	}
+
+	var err error
+	if a.dirty {
+		err = a.sync(a.appendFile)
+		a.dirty = false
+	}
+	if closeErr := a.appendFile.Close(); err == nil {
+		err = closeErr
+	}
+	a.appendFile = nil
+
+	return err
+}
+
 func (a *AppendBlock) BlockID() uuid.UUID {
 	return a.meta.BlockID
 }
@@ -138,12 +254,9 @@ func (a *AppendBlock) Meta() *backend.BlockMeta {
 }
 
 func (a *AppendBlock) Iterator(combiner model.ObjectCombiner) (common.Iterator, error) {
-	if a.appendFile != nil {
-		err := a.appendFile.Close()
-		if err != nil {
-			return nil, err
-		}
-		a.appendFile = nil
+	err := a.closeAppendFile()
+	if err != nil {
+		return nil, err
 	}
 
 	records := a.appender.Records()
@@ -152,7 +265,7 @@ func (a *AppendBlock) Iterator(combiner model.ObjectCombiner) (common.Iterator,
 		return nil, err
 	}
 
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(readFile), a.meta.Encoding)
+	dataReader, err := v2.NewDataReaderWithChecksums(backend.NewContextReaderWithAllReader(readFile), a.meta.Encoding)
 	if err != nil {
 		return nil, err
 	}
@@ -166,6 +279,79 @@ func (a *AppendBlock) Iterator(combiner model.ObjectCombiner) (common.Iterator,
 	return iterator, nil
 }
 
+// IterateObjects calls fn with every object appended so far until it returns false. Objects with the same id are
+// combined. Unlike Iterator it does not close the append file and can be used while the block is still appended to.
+func (a *AppendBlock) IterateObjects(ctx context.Context, combiner model.ObjectCombiner, fn func(id common.ID, obj []byte) bool) error {
+	records := a.appender.Records()
+	readFile, err := a.file()
+	if err != nil {
+		return err
+	}
+
+	dataReader, err := v2.NewDataReaderWithChecksums(backend.NewContextReaderWithAllReader(readFile), a.meta.Encoding)
+	if err != nil {
+		return err
+	}
+
+	iterator, err := v2.NewDedupingIterator(v2.NewRecordIterator(records, dataReader, v2.NewObjectReaderWriter()), combiner, a.meta.DataEncoding)
+	if err != nil {
+		dataReader.Close()
+		return err
+	}
+	defer iterator.Close()
+
+	for {
+		id, obj, err := iterator.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(id, obj) {
+			return nil
+		}
+	}
+}
+
+// Search searches all objects appended so far
+func (a *AppendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, _ common.SearchOptions) (*tempopb.SearchResponse, error) {
+	dec, err := model.NewObjectDecoder(a.meta.DataEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &tempopb.SearchResponse{
+		Metrics: &tempopb.SearchMetrics{
+			InspectedBlocks: 1,
+		},
+	}
+
+	var matchErr error
+	err = a.IterateObjects(ctx, model.StaticCombiner, func(id common.ID, obj []byte) bool {
+		resp.Metrics.InspectedTraces++
+		resp.Metrics.InspectedBytes += uint64(len(obj))
+
+		var metadata *tempopb.TraceSearchMetadata
+		metadata, matchErr = dec.Matches(id, obj, req)
+		if matchErr != nil {
+			return false
+		}
+		if metadata != nil {
+			resp.Traces = append(resp.Traces, metadata)
+		}
+		return req.Limit == 0 || len(resp.Traces) < int(req.Limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if matchErr != nil {
+		return nil, matchErr
+	}
+
+	return resp, nil
+}
+
 func (a *AppendBlock) Find(id common.ID, combiner model.ObjectCombiner) ([]byte, error) {
 	records := a.appender.RecordsForID(id)
 	file, err := a.file()
@@ -176,7 +362,7 @@ func (a *AppendBlock) Find(id common.ID, combiner model.ObjectCombiner) ([]byte,
 		return nil, nil
 	}
 
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(file), a.meta.Encoding)
+	dataReader, err := v2.NewDataReaderWithChecksums(backend.NewContextReaderWithAllReader(file), a.meta.Encoding)
 	if err != nil {
 		return nil, err
 	}
@@ -192,14 +378,14 @@ func (a *AppendBlock) Clear() error {
 		a.readFile = nil
 	}
 
-	if a.appendFile != nil {
-		_ = a.appendFile.Close()
-		a.appendFile = nil
-	}
+	_ = a.closeAppendFile()
 
 	// ignore error, it's important to remove the file above all else
 	_ = a.appender.Complete()
 
+	metricWALBytes.WithLabelValues(a.meta.TenantID).Sub(float64(a.bytesOnDisk))
+	a.bytesOnDisk = 0
+
 	name := a.fullFilename()
 	return os.Remove(name)
 }
@@ -2,25 +2,52 @@ package wal
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 )
 
-// ReplayWALAndGetRecords replays a WAL file that could contain either traces or searchdata
+// ReplayWarning is returned as warning by ReplayWALAndGetRecords if not all records of a WAL file could be replayed
+type ReplayWarning struct {
+	// CorruptRecords is the number of records that were skipped because they are corrupt
+	CorruptRecords int
+	// TruncatedBytes is the number of bytes at the end of the file that could not be read
+	TruncatedBytes int64
+	// Err is the error that stopped the replay before the end of the file
+	Err error
+}
+
+func (w *ReplayWarning) Error() string {
+	msg := fmt.Sprintf("skipped %d corrupt records and %d truncated bytes while replaying wal", w.CorruptRecords, w.TruncatedBytes)
+	if w.Err != nil {
+		msg += ": " + w.Err.Error()
+	}
+	return msg
+}
+
+func (w *ReplayWarning) Unwrap() error {
+	return w.Err
+}
+
+// ReplayWALAndGetRecords replays a WAL file that could contain either traces or searchdata. Records that are corrupt
+// are skipped, the replay stops at the first page that can't be read. A *ReplayWarning is returned as warning in both
+// cases.
 func ReplayWALAndGetRecords(file *os.File, enc backend.Encoding, handleObj func([]byte) error) ([]common.Record, error, error) {
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(file), enc)
+	dataReader, err := v2.NewDataReaderWithChecksums(backend.NewContextReaderWithAllReader(file), enc)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var buffer []byte
 	var records []common.Record
-	var warning error
+	var warning ReplayWarning
 	var pageLen uint32
 	var id []byte
 	objectReader := v2.NewObjectReaderWriter()
@@ -30,29 +57,22 @@ func ReplayWALAndGetRecords(file *os.File, enc backend.Encoding, handleObj func(
 		if err == io.EOF {
 			break
 		}
-		if err != nil {
-			warning = fmt.Errorf("accessing NextPage while replaying wal: %w", err)
-			break
+		if errors.Is(err, v2.ErrChecksumMismatch) {
+			warning.CorruptRecords++
+			currentOffset += uint64(pageLen)
+			continue
 		}
-
-		reader := bytes.NewReader(buffer)
-		id, buffer, err = objectReader.UnmarshalObjectFromReader(reader)
 		if err != nil {
-			warning = fmt.Errorf("unmarshalling object while replaying wal: %w", err)
-			break
-		}
-		// wal should only ever have one object per page, test that here
-		_, _, err = objectReader.UnmarshalObjectFromReader(reader)
-		if err != io.EOF {
-			warning = fmt.Errorf("expected EOF while replaying wal: %w", err)
+			warning.Err = fmt.Errorf("accessing NextPage while replaying wal: %w", err)
 			break
 		}
 
-		// handleObj is primarily used by search replay to record search data in block header
-		err = handleObj(buffer)
+		id, err = replayPage(buffer, objectReader, handleObj)
 		if err != nil {
-			warning = fmt.Errorf("custom obj handler while replaying wal: %w", err)
-			break
+			// the page was read completely, only this record is lost
+			warning.CorruptRecords++
+			currentOffset += uint64(pageLen)
+			continue
 		}
 
 		// make a copy so we don't hold onto the iterator buffer
@@ -67,5 +87,93 @@ func ReplayWALAndGetRecords(file *os.File, enc backend.Encoding, handleObj func(
 
 	common.SortRecords(records)
 
-	return records, warning, nil
+	if warning.Err != nil {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		warning.TruncatedBytes = info.Size() - int64(currentOffset)
+	}
+	if warning.CorruptRecords > 0 || warning.Err != nil {
+		return records, &warning, nil
+	}
+
+	return records, nil, nil
+}
+
+func replayPage(page []byte, objectReader common.ObjectReaderWriter, handleObj func([]byte) error) ([]byte, error) {
+	reader := bytes.NewReader(page)
+	id, obj, err := objectReader.UnmarshalObjectFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling object while replaying wal: %w", err)
+	}
+	// wal should only ever have one object per page, test that here
+	_, _, err = objectReader.UnmarshalObjectFromReader(reader)
+	if err != io.EOF {
+		return nil, fmt.Errorf("expected EOF while replaying wal: %w", err)
+	}
+
+	// handleObj is primarily used by search replay to record search data in block header
+	err = handleObj(obj)
+	if err != nil {
+		return nil, fmt.Errorf("custom obj handler while replaying wal: %w", err)
+	}
+
+	return id, nil
+}
+
+// RepairWALFile rewrites a WAL file with only the records that can be replayed. The file is removed if no records
+// can be replayed and left unchanged if all can. The warning of the replay is returned.
+func RepairWALFile(filename string) (error, error) {
+	_, _, _, enc, _, err := ParseFilename(filepath.Base(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, warning, err := ReplayWALAndGetRecords(f, enc, func([]byte) error { return nil })
+	if err != nil || warning == nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return warning, os.Remove(filename)
+	}
+
+	// copy the pages of the records in the order they were written
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Start < records[j].Start
+	})
+
+	tmpFilename := filename + ".repair"
+	tmp, err := os.OpenFile(tmpFilename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFilename)
+
+	for _, r := range records {
+		_, err = io.Copy(tmp, io.NewSectionReader(f, int64(r.Start), int64(r.Length)))
+		if err != nil {
+			_ = tmp.Close()
+			return nil, err
+		}
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		_ = tmp.Close()
+		return nil, err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return warning, os.Rename(tmpFilename, filename)
 }
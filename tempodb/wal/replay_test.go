@@ -0,0 +1,110 @@
+package wal
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestReplaySkipsCorruptRecords(t *testing.T) {
+	block, records := corruptBlock(t)
+
+	f, err := os.Open(block.fullFilename())
+	require.NoError(t, err)
+	defer f.Close()
+
+	replayed, warning, err := ReplayWALAndGetRecords(f, backend.EncGZIP, func([]byte) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, replayed, len(records)-1)
+
+	var replayWarning *ReplayWarning
+	require.True(t, errors.As(warning, &replayWarning))
+	require.Equal(t, 1, replayWarning.CorruptRecords)
+	require.Equal(t, int64(11), replayWarning.TruncatedBytes)
+	require.Error(t, replayWarning.Err)
+
+	// all records after the corrupt one are replayed
+	for _, r := range records[2:] {
+		require.Contains(t, replayed, r)
+	}
+}
+
+func TestRepairWALFile(t *testing.T) {
+	block, records := corruptBlock(t)
+
+	warning, err := RepairWALFile(block.fullFilename())
+	require.NoError(t, err)
+	require.Error(t, warning)
+
+	f, err := os.Open(block.fullFilename())
+	require.NoError(t, err)
+	defer f.Close()
+
+	replayed, warning, err := ReplayWALAndGetRecords(f, backend.EncGZIP, func([]byte) error { return nil })
+	require.NoError(t, err)
+	require.NoError(t, warning)
+	require.Len(t, replayed, len(records)-1)
+
+	// a healthy file is left unchanged
+	warning, err = RepairWALFile(block.fullFilename())
+	require.NoError(t, err)
+	require.NoError(t, warning)
+}
+
+// corruptBlock writes a block with 10 records, flips a byte of the second record and appends a partial record. The
+// records are returned in the order they were written.
+func corruptBlock(t *testing.T) (*AppendBlock, []common.Record) {
+	wal, err := New(&Config{
+		Filepath:  t.TempDir(),
+		Encoding:  backend.EncGZIP,
+		Checksums: true,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	for i := 0; i < 10; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		obj := test.MakeTrace(rand.Int()%10, id)
+		bObj, err := proto.Marshal(obj)
+		require.NoError(t, err)
+
+		err = block.Append(id, bObj, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	records := block.appender.Records()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Start < records[j].Start
+	})
+
+	f, err := os.OpenFile(block.fullFilename(), os.O_RDWR, 0600)
+	require.NoError(t, err)
+	corrupt := records[1]
+	b := make([]byte, 1)
+	_, err = f.ReadAt(b, int64(corrupt.Start)+int64(corrupt.Length)-1)
+	require.NoError(t, err)
+	b[0] ^= 0xFF
+	_, err = f.WriteAt(b, int64(corrupt.Start)+int64(corrupt.Length)-1)
+	require.NoError(t, err)
+
+	_, err = f.Seek(0, 2)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	return block, records
+}
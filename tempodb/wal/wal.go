@@ -1,6 +1,7 @@
 package wal
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -26,6 +27,35 @@ var (
 		Name:      "warnings_total",
 		Help:      "The total number of warnings per tenant with reason.",
 	}, []string{"tenant", "reason"})
+	metricWALWriteDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "wal_write_duration_seconds",
+		Help:      "Records the amount of time to append an object to the wal including the fsync if configured.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	})
+	metricWALSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "wal_sync_duration_seconds",
+		Help:      "Records the amount of time to fsync a wal file.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	})
+	metricWALBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "wal_bytes",
+		Help:      "The number of bytes of wal blocks on disk per tenant.",
+	}, []string{"tenant"})
+)
+
+// SyncMode controls when wal files are fsynced
+type SyncMode string
+
+const (
+	// SyncModeNone leaves flushing the wal files to disk to the operating system
+	SyncModeNone SyncMode = "none"
+	// SyncModeInterval fsyncs a wal file in the background every sync interval if it was appended to
+	SyncModeInterval SyncMode = "interval"
+	// SyncModeAppend fsyncs a wal file after every append
+	SyncModeAppend SyncMode = "append"
 )
 
 // extracts a time range from an object. start/end times returned are unix epoch
@@ -49,6 +79,10 @@ type Config struct {
 	Encoding          backend.Encoding `yaml:"encoding"`
 	SearchEncoding    backend.Encoding `yaml:"search_encoding"`
 	IngestionSlack    time.Duration    `yaml:"ingestion_time_range_slack"`
+	SyncMode          SyncMode         `yaml:"sync_mode"`
+	SyncInterval      time.Duration    `yaml:"sync_interval"`
+	// Checksums adds checksums to the records of new wal files. Releases without wal checksums can't replay them.
+	Checksums bool `yaml:"checksums"`
 }
 
 func New(c *Config) (*WAL, error) {
@@ -56,6 +90,16 @@ func New(c *Config) (*WAL, error) {
 		return nil, fmt.Errorf("please provide a path for the WAL")
 	}
 
+	switch c.SyncMode {
+	case "", SyncModeNone, SyncModeAppend:
+	case SyncModeInterval:
+		if c.SyncInterval <= 0 {
+			return nil, fmt.Errorf("please provide a positive sync interval for the WAL sync mode %s", c.SyncMode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown WAL sync mode %s", c.SyncMode)
+	}
+
 	// make folder
 	err := os.MkdirAll(c.Filepath, os.ModePerm)
 	if err != nil {
@@ -131,11 +175,23 @@ func (w *WAL) RescanBlocks(fn RangeFunc, additionalStartSlack time.Duration, log
 		}
 
 		if warning != nil {
-			level.Warn(log).Log("msg", "received warning while replaying block. partial replay likely.", "file", f.Name(), "warning", warning, "records", b.appender.Length())
+			var corruptRecords int
+			var truncatedBytes int64
+			var replayWarning *ReplayWarning
+			if errors.As(warning, &replayWarning) {
+				corruptRecords = replayWarning.CorruptRecords
+				truncatedBytes = replayWarning.TruncatedBytes
+			}
+			level.Warn(log).Log("msg", "received warning while replaying block. partial replay likely.", "file", f.Name(), "warning", warning, "records", b.appender.Length(), "corrupt_records", corruptRecords, "truncated_bytes", truncatedBytes)
 		}
 
 		if remove {
-			err = os.Remove(filepath.Join(w.c.Filepath, f.Name()))
+			if b != nil {
+				// clear the block to release the file and its bytes
+				err = b.Clear()
+			} else {
+				err = os.Remove(filepath.Join(w.c.Filepath, f.Name()))
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -151,7 +207,7 @@ func (w *WAL) RescanBlocks(fn RangeFunc, additionalStartSlack time.Duration, log
 }
 
 func (w *WAL) NewBlock(id uuid.UUID, tenantID string, dataEncoding string) (*AppendBlock, error) {
-	return newAppendBlock(id, tenantID, w.c.Filepath, w.c.Encoding, dataEncoding, w.c.IngestionSlack)
+	return newAppendBlock(id, tenantID, w.c.Filepath, w.c.Encoding, dataEncoding, w.c.IngestionSlack, w.c.SyncMode, w.c.SyncInterval, w.c.Checksums)
 }
 
 func (w *WAL) NewFile(blockid uuid.UUID, tenantid string, dir string) (*os.File, backend.Encoding, error) {
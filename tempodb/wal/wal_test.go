@@ -14,6 +14,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/golang/protobuf/proto"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -68,7 +69,7 @@ func TestAppend(t *testing.T) {
 	file, err := block.file()
 	require.NoError(t, err)
 
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(file), backend.EncNone)
+	dataReader, err := v2.NewDataReaderWithChecksums(backend.NewContextReaderWithAllReader(file), backend.EncNone)
 	require.NoError(t, err)
 	iterator := v2.NewRecordIterator(records, dataReader, v2.NewObjectReaderWriter())
 	defer iterator.Close()
@@ -91,6 +92,56 @@ func TestAppend(t *testing.T) {
 	require.Equal(t, numMsgs, i)
 }
 
+func TestSyncModes(t *testing.T) {
+	for _, mode := range []SyncMode{"", SyncModeNone, SyncModeInterval, SyncModeAppend} {
+		t.Run(string(mode), func(t *testing.T) {
+			tenantID := "sync-" + string(mode)
+			wal, err := New(&Config{
+				Filepath:     t.TempDir(),
+				Encoding:     backend.EncZstd,
+				SyncMode:     mode,
+				SyncInterval: time.Millisecond,
+			})
+			require.NoError(t, err, "unexpected error creating temp wal")
+
+			block, err := wal.NewBlock(uuid.New(), tenantID, "")
+			require.NoError(t, err, "unexpected error creating block")
+
+			for i := 0; i < 10; i++ {
+				id := make([]byte, 16)
+				rand.Read(id)
+				bObj, err := proto.Marshal(test.MakeTrace(rand.Int()%10, id))
+				require.NoError(t, err)
+
+				err = block.Append(id, bObj, 0, 0)
+				require.NoError(t, err, "unexpected error writing req")
+			}
+
+			info, err := os.Stat(block.fullFilename())
+			require.NoError(t, err)
+			require.Equal(t, float64(info.Size()), testutil.ToFloat64(metricWALBytes.WithLabelValues(tenantID)))
+
+			// with sync mode interval appends are fsynced in the background without waiting for another append
+			if mode == SyncModeInterval {
+				require.Eventually(t, func() bool {
+					block.syncMtx.Lock()
+					defer block.syncMtx.Unlock()
+					return !block.dirty
+				}, time.Second, time.Millisecond)
+			}
+
+			require.NoError(t, block.Clear())
+			require.Equal(t, float64(0), testutil.ToFloat64(metricWALBytes.WithLabelValues(tenantID)))
+		})
+	}
+
+	_, err := New(&Config{Filepath: t.TempDir(), SyncMode: "sometimes"})
+	require.Error(t, err)
+
+	_, err = New(&Config{Filepath: t.TempDir(), SyncMode: SyncModeInterval})
+	require.Error(t, err)
+}
+
 func TestCompletedDirIsRemoved(t *testing.T) {
 	// Create /completed/testfile and verify it is removed.
 